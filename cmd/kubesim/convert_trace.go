@@ -0,0 +1,156 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/trace"
+)
+
+// traceImporter imports a public trace from its two tables.
+type traceImporter struct {
+	importTrace func(first, second io.Reader, opts trace.Options) ([]*trace.Task, error)
+	// tables are the names of the two tables of the trace.
+	tables string
+	// optional is whether the second table may be omitted.
+	optional bool
+}
+
+var traceImporters = map[string]traceImporter{
+	"google2011":            {trace.ImportGoogle2011, "task_events task_usage", false},
+	"google2019":            {trace.ImportGoogle2019, "instance_events instance_usage", false},
+	"alibaba2018-batch":     {trace.ImportAlibaba2018Batch, "batch_task batch_instance", false},
+	"alibaba2018-container": {trace.ImportAlibaba2018Container, "container_meta [container_usage]", true},
+	"azure":                 {trace.ImportAzure, "vmtable [vm_cpu_readings]", true},
+}
+
+var convertTraceOpts struct {
+	format         string
+	output         string
+	machineCPU     string
+	machineMemory  string
+	usageRatio     float64
+	maxTaskSeconds int32
+	namespace      string
+}
+
+func init() {
+	formats := make([]string, 0, len(traceImporters))
+	for format, importer := range traceImporters {
+		formats = append(formats, format+" ("+importer.tables+")")
+	}
+	sort.Strings(formats)
+
+	flags := convertTraceCmd.Flags()
+	flags.StringVar(&convertTraceOpts.format, "format", "",
+		"format of the trace: "+strings.Join(formats, ", "))
+	flags.StringVarP(&convertTraceOpts.output, "output", "o", "", "output file (default: stdout)")
+	flags.StringVar(&convertTraceOpts.machineCPU, "machine-cpu", "1",
+		"CPU of the machine by which the trace normalizes CPU amounts")
+	flags.StringVar(&convertTraceOpts.machineMemory, "machine-memory", "1Gi",
+		"memory of the machine by which the trace normalizes memory amounts")
+	flags.Float64Var(&convertTraceOpts.usageRatio, "usage-ratio", 1, "ratio scaling the usage of every task")
+	flags.Int32Var(&convertTraceOpts.maxTaskSeconds, "max-task-seconds", 0,
+		"duration in seconds at which tasks are truncated (default: no limit)")
+	flags.StringVar(&convertTraceOpts.namespace, "namespace", "default", "namespace of the tasks")
+	_ = convertTraceCmd.MarkFlagRequired("format")
+	rootCmd.AddCommand(convertTraceCmd)
+}
+
+var convertTraceCmd = &cobra.Command{
+	Use:   "convert-trace --format FORMAT TABLE [TABLE]",
+	Short: "Convert a public trace into the task trace replayed by the workload of a scenario",
+	Long: "Convert the two tables of a public trace (CSV, or JSON lines for google2019; gzipped if " +
+		"their names end with .gz) into the task trace replayed by the workload of a scenario.",
+	Args: cobra.RangeArgs(1, 2),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return convertTrace(args)
+	},
+}
+
+func convertTrace(paths []string) error {
+	importer, ok := traceImporters[convertTraceOpts.format]
+	if !ok {
+		return errors.Errorf("trace format %q is not supported", convertTraceOpts.format)
+	}
+	if len(paths) < 2 && !importer.optional {
+		return errors.Errorf("trace format %s requires two tables: %s", convertTraceOpts.format, importer.tables)
+	}
+
+	opts, err := buildTraceOptions()
+	if err != nil {
+		return err
+	}
+
+	first, err := trace.Open(paths[0])
+	if err != nil {
+		return err
+	}
+	defer first.Close()
+
+	var second io.Reader
+	if len(paths) > 1 {
+		file, err := trace.Open(paths[1])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		second = file
+	}
+
+	tasks, err := importer.importTrace(first, second, opts)
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if convertTraceOpts.output != "" {
+		out, err = os.Create(convertTraceOpts.output)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	return trace.WriteTasks(out, tasks)
+}
+
+func buildTraceOptions() (trace.Options, error) {
+	cpu, err := resource.ParseQuantity(convertTraceOpts.machineCPU)
+	if err != nil {
+		return trace.Options{}, errors.Wrapf(err, "invalid machine-cpu %q", convertTraceOpts.machineCPU)
+	}
+	memory, err := resource.ParseQuantity(convertTraceOpts.machineMemory)
+	if err != nil {
+		return trace.Options{}, errors.Wrapf(err, "invalid machine-memory %q", convertTraceOpts.machineMemory)
+	}
+
+	return trace.Options{
+		MachineCPU:     cpu,
+		MachineMemory:  memory,
+		UsageRatio:     convertTraceOpts.usageRatio,
+		MaxTaskSeconds: convertTraceOpts.maxTaskSeconds,
+		Namespace:      convertTraceOpts.namespace,
+	}, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command kubesim runs simulations from declarative scenario files, without writing Go.
+//
+// A scenario file is a simulator config (see example/config.yaml) that also chooses the scheduler,
+// the workload, and the end of the simulation (see example/scenario.yaml).
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/containerd/containerd/log"
+	"github.com/spf13/cobra"
+)
+
+func main() {
+	if err := rootCmd.Execute(); err != nil {
+		log.L.WithError(err).Fatal("Error executing kubesim")
+	}
+}
+
+var rootCmd = &cobra.Command{
+	Use:   "kubesim",
+	Short: "kubesim runs k8s-cluster-simulator from declarative scenario files.",
+
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func newInterruptableContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// SIGINT (Ctrl-C) and SIGTERM cancel kubesim.Run().
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	return ctx
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
+)
+
+// reportFormat is the format in which the report command prints the KPIs.
+var reportFormat string
+
+func init() {
+	reportCmd.Flags().StringVar(&reportFormat, "format", "table", "output format: table or csv")
+	rootCmd.AddCommand(reportCmd)
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report REPORT...",
+	Short: "Compare the KPIs of the reports written by simulations",
+	Long: "Print the main KPIs of one or more KPI reports (the report.dest of scenarios) side by " +
+		"side, one row per report. Durations are in seconds.",
+	Args: cobra.MinimumNArgs(1),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return printReports(os.Stdout, args)
+	},
+}
+
+var reportColumns = []string{
+	"REPORT", "PODS", "FINISHED", "REJECTED", "PENDING", "MAKESPAN", "THROUGHPUT",
+	"WAIT_P50", "WAIT_P95", "JCT_P50", "JCT_P95", "SLOWDOWN_P95", "CPU_UTIL", "MEMORY_UTIL",
+}
+
+func printReports(w io.Writer, paths []string) error {
+	rows := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		report, err := readReport(path)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, reportRow(path, report))
+	}
+
+	switch reportFormat {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(reportColumns, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(reportColumns); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	default:
+		return errors.Errorf("report format %q is not supported", reportFormat)
+	}
+}
+
+func readReport(path string) (*metrics.Report, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := metrics.Report{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, errors.Wrapf(err, "invalid report %s", path)
+	}
+	return &report, nil
+}
+
+func reportRow(path string, report *metrics.Report) []string {
+	summary := report.Summary
+	return []string{
+		path,
+		fmt.Sprint(report.PodsNum),
+		fmt.Sprint(report.FinishedPodsNum),
+		fmt.Sprint(report.RejectedPodsNum),
+		fmt.Sprint(report.PendingPodsNum),
+		fmt.Sprintf("%.0f", report.Makespan),
+		fmt.Sprintf("%.2f", report.Throughput),
+		fmt.Sprintf("%.1f", summary.WaitTime.P50),
+		fmt.Sprintf("%.1f", summary.WaitTime.P95),
+		fmt.Sprintf("%.1f", summary.CompletionTime.P50),
+		fmt.Sprintf("%.1f", summary.CompletionTime.P95),
+		fmt.Sprintf("%.2f", summary.Slowdown.P95),
+		fmt.Sprintf("%.3f", report.UsageUtilization[v1.ResourceCPU]),
+		fmt.Sprintf("%.3f", report.UsageUtilization[v1.ResourceMemory]),
+	}
+}
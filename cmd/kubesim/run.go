@@ -0,0 +1,98 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/containerd/containerd/log"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+
+	kubesim "github.com/pfnet-research/k8s-cluster-simulator/pkg"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+)
+
+// scenarioPath is the path of the scenario file of the run and validate commands.
+var scenarioPath string
+
+func init() {
+	runCmd.Flags().StringVarP(&scenarioPath, "filename", "f", "", "scenario file")
+	_ = runCmd.MarkFlagRequired("filename")
+	rootCmd.AddCommand(runCmd)
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run -f SCENARIO",
+	Short: "Run the simulation of a scenario file",
+	Args:  cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return run(newInterruptableContext(), scenarioPath)
+	},
+}
+
+func run(ctx context.Context, path string) error {
+	conf, err := kubesim.ReadConfig(path)
+	if err != nil {
+		return errors.Wrapf(err, "error reading scenario %s", path)
+	}
+
+	q, err := config.BuildQueue(conf.Queue)
+	if err != nil {
+		return err
+	}
+	sched, err := config.BuildScheduler(conf.Scheduler)
+	if err != nil {
+		return err
+	}
+	endClock, err := config.BuildEndClock(conf.End, conf.StartClock)
+	if err != nil {
+		return err
+	}
+	workload, closer, err := config.BuildWorkloadSubmitter(conf.Workload, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	k, err := kubesim.NewKubeSim(conf, q, sched, endClock)
+	if err != nil {
+		return err
+	}
+
+	overSubFactor := conf.Scheduler.OverSubFactor
+	if overSubFactor == 0 {
+		overSubFactor = 1
+	}
+	nodes, err := k.List()
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		predicates.NodesOverSubFactors[node.Name] = overSubFactor
+	}
+
+	k.AddSubmitter("Workload", workload)
+
+	if err := k.Run(ctx); err != nil && errors.Cause(err) != context.Canceled {
+		return err
+	}
+	log.L.Infof("Simulation of %s finished", path)
+
+	return nil
+}
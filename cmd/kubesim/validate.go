@@ -0,0 +1,73 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	kubesim "github.com/pfnet-research/k8s-cluster-simulator/pkg"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+)
+
+func init() {
+	validateCmd.Flags().StringVarP(&scenarioPath, "filename", "f", "", "scenario file")
+	_ = validateCmd.MarkFlagRequired("filename")
+	rootCmd.AddCommand(validateCmd)
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate -f SCENARIO",
+	Short: "Validate a scenario file without running it",
+	Args:  cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validate(scenarioPath); err != nil {
+			return err
+		}
+		fmt.Printf("%s is valid\n", scenarioPath)
+		return nil
+	},
+}
+
+// validate validates the scenario file at the path, including the files that it refers to,
+// without creating any output.
+func validate(path string) error {
+	conf, err := kubesim.ReadConfig(path)
+	if err != nil {
+		return errors.Wrapf(err, "error reading scenario %s", path)
+	}
+
+	if err := kubesim.ValidateConfig(conf); err != nil {
+		return err
+	}
+	if _, err := config.BuildQueue(conf.Queue); err != nil {
+		return errors.Wrap(err, "invalid queue")
+	}
+	if _, err := config.BuildScheduler(conf.Scheduler); err != nil {
+		return errors.Wrap(err, "invalid scheduler")
+	}
+	if _, err := config.BuildEndClock(conf.End, conf.StartClock); err != nil {
+		return errors.Wrap(err, "invalid end")
+	}
+	if err := config.ValidateWorkload(conf.Workload, filepath.Dir(path)); err != nil {
+		return errors.Wrap(err, "invalid workload")
+	}
+
+	return nil
+}
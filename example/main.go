@@ -92,6 +92,8 @@ func buildScheduler() scheduler.Scheduler {
 	// 2. Register plugin(s)
 	// Predicate
 	sched.AddPredicate("GeneralPredicates", predicates.GeneralPredicates)
+	sched.AddPredicate("PodTopologySpread", scheduler.PodTopologySpreadPredicate)
+	sched.SetMetadataProducer(scheduler.TopologySpreadMetadata)
 	// Prioritizer
 	sched.AddPrioritizer(priorities.PriorityConfig{
 		Name:   "BalancedResourceAllocation",
@@ -105,6 +107,12 @@ func buildScheduler() scheduler.Scheduler {
 		Reduce: nil,
 		Weight: 1,
 	})
+	sched.AddPrioritizer(priorities.PriorityConfig{
+		Name:   "PodTopologySpread",
+		Map:    scheduler.PodTopologySpreadPriorityMap,
+		Reduce: scheduler.PodTopologySpreadPriorityReduce,
+		Weight: 1,
+	})
 
 	return &sched
 }
@@ -16,6 +16,7 @@ package main
 
 import (
 	"context"
+	"io/ioutil"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -33,9 +34,9 @@ import (
 	"k8s.io/kubernetes/pkg/scheduler/algorithm/priorities"
 
 	kubesim "github.com/pfnet-research/k8s-cluster-simulator/pkg"
-	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/scheduler"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/submitter"
 	kutil "k8s.io/kubernetes/pkg/scheduler/util"
 )
 
@@ -66,12 +67,11 @@ var (
 	targetNum            = 64 * 4
 	totalPodsNum         = uint64(10)
 	workloadSubsetFactor = int(1)
-	submittedPodsNum     = uint64(0)
 	predictionPenalty    = float32(1.0)
 	targetQoS            = float32(0.0)
 	penaltyUpdate        = float32(0.99)
 	penaltyTimeout       = int(1)
-	podMap               = make(map[string][]string)
+	arrivalIndexPath     string
 	// schedulerName    = "bestfit"
 	schedulerName = "default"
 	// schedulerName       = "proposed"
@@ -185,7 +185,13 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			log.L.Fatal(err)
 		}
-		kubesim.AddSubmitter("MySubmitter", newMySubmitter(totalPodsNum, endClock))
+		index, err := os.Open(arrivalIndexPath)
+		if err != nil {
+			log.L.Fatal(err)
+		}
+		defer index.Close()
+		defer os.Remove(arrivalIndexPath)
+		kubesim.AddSubmitter("MySubmitter", newMySubmitter(totalPodsNum, endClock, index))
 
 		// 4. Run the main loop of KubeSim.
 		//    In each execution of the loop, KubeSim
@@ -307,28 +313,7 @@ func buildScheduler() scheduler.Scheduler {
 	}
 
 	start := time.Now()
-	count := uint64(0)
-	err := filepath.Walk(workloadPath,
-		func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			if strings.Contains(path, "json") {
-				arr := strings.Split(path, "/")
-				l := len(arr)
-				fileName := string(arr[l-1])
-				arr = strings.Split(fileName, "@")
-				clockStr := arr[0]
-				if _, ok := podMap[clockStr]; ok {
-					podMap[clockStr] = append(podMap[clockStr], path)
-				} else {
-					strArr := []string{path}
-					podMap[clockStr] = strArr
-				}
-				count++
-			}
-			return nil
-		})
+	count, err := buildArrivalIndex()
 	if err != nil {
 		log.L.Println(err)
 	}
@@ -372,8 +357,6 @@ func buildScheduler() scheduler.Scheduler {
 	scheduler.TargetQoS = targetQoS
 	scheduler.PenaltyUpdate = penaltyUpdate
 
-	clock.LOAD_PHASE_CACHE = loadPhaseCache
-
 	switch schedName := strings.ToLower(schedulerName); schedName {
 	// case ONE_SHOT:
 	// 	log.L.Infof("Scheduler: %s", ONE_SHOT)
@@ -538,6 +521,24 @@ func buildScheduler() scheduler.Scheduler {
 	}
 }
 
+// buildArrivalIndex writes the arrival index of the pods in the workload folder to a temporary
+// file, and returns the number of the pods.
+func buildArrivalIndex() (uint64, error) {
+	origin, err := time.Parse(time.RFC3339, startClockStr)
+	if err != nil {
+		return 0, err
+	}
+
+	index, err := ioutil.TempFile("", "arrival-index")
+	if err != nil {
+		return 0, err
+	}
+	defer index.Close()
+	arrivalIndexPath = index.Name()
+
+	return submitter.WriteArrivalIndex(index, workloadPath, origin)
+}
+
 func newInterruptableContext() context.Context {
 	ctx, cancel := context.WithCancel(context.Background())
 
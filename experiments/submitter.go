@@ -17,8 +17,10 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
+	"strconv"
 	"time"
 
 	"github.com/containerd/containerd/log"
@@ -29,8 +31,7 @@ import (
 
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
-	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
-	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+	kubesimpod "github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/submitter"
 )
 
@@ -40,26 +41,33 @@ type mySubmitter struct {
 	myrand       *rand.Rand
 	tick         time.Duration
 	endClock     clock.Clock
+	trace        *submitter.TraceSubmitter
 }
 
 var totalSimTime = -1
 
-func newMySubmitter(totalPodsNum uint64, endClock clock.Clock) *mySubmitter {
-	return &mySubmitter{
+func newMySubmitter(totalPodsNum uint64, endClock clock.Clock, arrivalIndex io.Reader) *mySubmitter {
+	s := &mySubmitter{
 		podIdx:       0,
 		totalPodsNum: totalPodsNum,
 		myrand:       rand.New(rand.NewSource(time.Now().UnixNano())),
 		tick:         time.Duration(10), //TODO: get tick from viper.config
 		endClock:     endClock,
 	}
+
+	start, _ := BuildClock(startClockStr, 0)
+	s.trace = submitter.NewTraceSubmitter(
+		submitter.NewIndexSource(arrivalIndex, s.loadPod),
+		submitter.TraceSubmitterOptions{Start: &start, MaxPods: totalPodsNum})
+	return s
 }
 
 func (s *mySubmitter) generateWorkloads(
 	clock clock.Clock,
 	met metrics.Metrics) ([]submitter.Event, error) {
 
-	queueMetrics := met[metrics.QueueMetricsKey].(queue.Metrics)
-	nodesMetrics := met[metrics.NodesMetricsKey].(map[string]node.Metrics)
+	queueMetrics := met.Queue()
+	nodesMetrics := met.Nodes()
 	runningPodsNum := int(0)
 	for _, met := range nodesMetrics {
 		runningPodsNum += int(met.RunningPodsNum)
@@ -82,25 +90,7 @@ func (s *mySubmitter) generateWorkloads(
 func (s *mySubmitter) loadWorkload(
 	clock clock.Clock,
 	met metrics.Metrics) ([]submitter.Event, error) {
-	paths := podMap[clock.ToRFC3339()]
-	// load the pods and submit them.
-	events := make([]submitter.Event, 0, len(paths)+1)
-	// log.L.Infof("no of tasks,%v,%v", clock.ToRFC3339(), len(paths))
-	for _, path := range paths {
-		newPod, err := s.loadPod(path)
-		if err != nil {
-			log.L.Errorf("cannot load %s", path)
-			return events, fmt.Errorf("cannot load %s", path)
-		}
-		submittedPodsNum++
-		events = append(events, &submitter.SubmitEvent{Pod: newPod})
-
-		if submittedPodsNum >= uint64(s.totalPodsNum) {
-			events = append(events, &submitter.TerminateSubmitterEvent{})
-			return events, nil
-		}
-	}
-	return events, nil
+	return s.trace.Submit(clock, nil, met)
 }
 
 func (s *mySubmitter) Submit(
@@ -203,6 +193,7 @@ func (s *mySubmitter) loadPod(filePath string) (*v1.Pod, error) {
 	// store filePath for loading more resource usages
 	pod.Annotations["path"] = filePath
 	pod.Annotations["simSpec"] = "" // remove simSpec to reduce memory use.
+	pod.Annotations[kubesimpod.PhaseCacheAnnotation] = strconv.Itoa(loadPhaseCache)
 
 	return &pod, nil
 }
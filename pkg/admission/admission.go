@@ -0,0 +1,559 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admission models the admission of pods: the admission chain run on their submission
+// (LimitRange, ResourceQuota, and user-defined hooks), and quota-based admission of pods before
+// scheduling, in the manner of Kueue.
+package admission
+
+import (
+	"sort"
+
+	"github.com/containerd/containerd/log"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// Preemption policies of a ClusterQueue.
+const (
+	// PreemptNever never preempts admitted workloads.
+	PreemptNever = "Never"
+	// PreemptLowerPriority preempts admitted workloads of lower priority than the pending one.
+	PreemptLowerPriority = "LowerPriority"
+	// PreemptAny preempts admitted workloads of any priority.
+	PreemptAny = "Any"
+)
+
+// Flavor is a resource flavor of a ClusterQueue, i.e. a kind of nodes with its own quota.
+type Flavor struct {
+	Name string
+	// NodeLabels are added to the node selector of the pods admitted with this flavor.
+	NodeLabels map[string]string
+	// NominalQuota is the quota of each resource. Resources not in it are not limited.
+	NominalQuota v1.ResourceList
+	// BorrowingLimit is the maximum amount of each resource that can be borrowed from the cohort
+	// beyond the nominal quota. Resources not in it can be borrowed without limit.
+	BorrowingLimit v1.ResourceList
+}
+
+// ClusterQueue is a pool of quota shared by the workloads submitted to it through local queues.
+type ClusterQueue struct {
+	Name string
+	// Cohort is the name of the cohort in which the ClusterQueues lend their unused quota to each
+	// other. The ClusterQueue neither lends nor borrows if empty.
+	Cohort string
+	// Flavors are tried in this order for each workload.
+	Flavors []Flavor
+	// StrictFIFO blocks the workloads behind the head of the queue until it is admitted.
+	// Otherwise (BestEffortFIFO), workloads that do not fit are skipped.
+	StrictFIFO bool
+	// WithinClusterQueue is the preemption policy among the workloads of this ClusterQueue, either
+	// PreemptNever or PreemptLowerPriority.
+	WithinClusterQueue string
+	// ReclaimWithinCohort is the preemption policy for reclaiming the nominal quota lent to the
+	// other ClusterQueues of the cohort.
+	ReclaimWithinCohort string
+}
+
+// LocalQueue maps the pods labelled with its name in its namespace (in any namespace if empty) to
+// a ClusterQueue.
+type LocalQueue struct {
+	Namespace    string
+	Name         string
+	ClusterQueue string
+}
+
+// Metrics is a metrics of a ClusterQueue at one time point.
+type Metrics struct {
+	PendingWorkloadsNum  int
+	AdmittedWorkloadsNum int
+	// PreemptedWorkloadsNum is the number of preemptions of the workloads of the ClusterQueue
+	// since the start of the simulation.
+	PreemptedWorkloadsNum int
+	// Usage is the total request of the admitted workloads of each flavor.
+	Usage map[string]v1.ResourceList
+	// Utilization is the ratio of Usage to the nominal quota of each flavor. It exceeds 1 while
+	// the ClusterQueue borrows quota.
+	Utilization map[string]map[v1.ResourceName]float64
+}
+
+// workload is a pod submitted to a ClusterQueue.
+type workload struct {
+	key      string
+	pod      *v1.Pod
+	queue    string
+	priority int32
+	request  v1.ResourceList
+
+	submittedAt clock.Clock
+	// flavor is the index of the flavor with which the workload is admitted, or -1 if pending.
+	flavor     int
+	admittedAt clock.Clock
+}
+
+type clusterQueueState struct {
+	ClusterQueue
+
+	pending  map[string]*workload
+	admitted map[string]*workload
+	// usage is the total request of the admitted workloads of each flavor, indexed as Flavors.
+	usage        []v1.ResourceList
+	preemptedNum int
+}
+
+// Controller admits the pods submitted to ClusterQueues when their quota allows.
+type Controller struct {
+	queueLabel string
+	// localQueues maps "namespace/name" of each LocalQueue to its ClusterQueue.
+	localQueues map[string]string
+
+	queues     map[string]*clusterQueueState
+	queueNames []string
+	workloads  map[string]*workload
+}
+
+// NewController creates a new Controller of the ClusterQueues, to which pods are submitted through
+// the LocalQueues named in their queueLabel label.
+// The ClusterQueues referred to by the LocalQueues must exist.
+func NewController(queueLabel string, localQueues []LocalQueue, clusterQueues []ClusterQueue) *Controller {
+	c := Controller{
+		queueLabel:  queueLabel,
+		localQueues: make(map[string]string, len(localQueues)),
+		queues:      make(map[string]*clusterQueueState, len(clusterQueues)),
+		queueNames:  make([]string, 0, len(clusterQueues)),
+		workloads:   map[string]*workload{},
+	}
+
+	for _, lq := range localQueues {
+		c.localQueues[util.PodKeyFromNames(lq.Namespace, lq.Name)] = lq.ClusterQueue
+	}
+	for _, cq := range clusterQueues {
+		usage := make([]v1.ResourceList, len(cq.Flavors))
+		for i := range usage {
+			usage[i] = v1.ResourceList{}
+		}
+		c.queues[cq.Name] = &clusterQueueState{
+			ClusterQueue: cq,
+			pending:      map[string]*workload{},
+			admitted:     map[string]*workload{},
+			usage:        usage,
+		}
+		c.queueNames = append(c.queueNames, cq.Name)
+	}
+	sort.Strings(c.queueNames)
+
+	return &c
+}
+
+// Submit adds the pod submitted at the clock to the pending workloads of its ClusterQueue, and
+// returns the name of the ClusterQueue. Returns false if the pod does not belong to any
+// ClusterQueue, and thus needs no admission.
+func (c *Controller) Submit(clk clock.Clock, pod *v1.Pod) (string, bool) {
+	localQueue, ok := pod.Labels[c.queueLabel]
+	if !ok {
+		return "", false
+	}
+	cq, ok := c.localQueues[util.PodKeyFromNames(pod.Namespace, localQueue)]
+	if !ok {
+		cq, ok = c.localQueues[util.PodKeyFromNames("", localQueue)]
+	}
+	if !ok {
+		log.L.Warnf("Pod %s/%s: no local queue %q; admitted without quota", pod.Namespace, pod.Name, localQueue)
+		return "", false
+	}
+
+	key, err := util.PodKey(pod)
+	if err != nil {
+		return "", false
+	}
+
+	wl := &workload{
+		key:         key,
+		pod:         pod,
+		queue:       cq,
+		priority:    util.PodPriority(pod),
+		request:     util.PodTotalResourceRequests(pod),
+		submittedAt: clk,
+		flavor:      -1,
+	}
+	c.workloads[key] = wl
+	c.queues[cq].pending[key] = wl
+
+	return cq, true
+}
+
+// Delete deletes the workload of the pod, releasing its quota if admitted.
+// Returns true if the workload was pending, i.e. the pod has not been passed to the scheduler.
+func (c *Controller) Delete(podNamespace, podName string) bool {
+	wl, ok := c.workloads[util.PodKeyFromNames(podNamespace, podName)]
+	if !ok {
+		return false
+	}
+
+	pending := wl.flavor < 0
+	c.forget(wl)
+	return pending
+}
+
+// Finish releases the quota of the admitted workload of the pod with the given key, which has
+// terminated.
+func (c *Controller) Finish(key string) {
+	if wl, ok := c.workloads[key]; ok && wl.flavor >= 0 {
+		c.forget(wl)
+	}
+}
+
+// Admitted returns the keys of the pods of all admitted workloads, in the sorted order.
+func (c *Controller) Admitted() []string {
+	keys := []string{}
+	for key, wl := range c.workloads {
+		if wl.flavor >= 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// PendingWorkloadsNum returns the number of the workloads waiting for admission.
+func (c *Controller) PendingWorkloadsNum() int {
+	num := 0
+	for _, cq := range c.queues {
+		num += len(cq.pending)
+	}
+	return num
+}
+
+// Admit admits the pending workloads that fit in the quota at the clock, in the descending order of
+// their priorities and then in the order of their submission.
+// Returns the pods of the admitted workloads, with the node labels of their flavors added to their
+// node selectors, and the pods of the workloads preempted to make room for them, which are pending
+// again.
+func (c *Controller) Admit(clk clock.Clock) (admitted []*v1.Pod, preempted []*v1.Pod) {
+	// StrictFIFO queues whose head has not been admitted.
+	blocked := map[string]bool{}
+
+	for _, wl := range c.pendingWorkloads() {
+		cq := c.queues[wl.queue]
+		if blocked[cq.Name] {
+			continue
+		}
+
+		flavor, victims, ok := c.assign(cq, wl)
+		if !ok {
+			if cq.StrictFIFO {
+				blocked[cq.Name] = true
+			}
+			continue
+		}
+
+		for _, victim := range victims {
+			preempted = append(preempted, victim.pod)
+			c.preempt(victim)
+		}
+		admitted = append(admitted, c.admit(clk, cq, wl, flavor))
+	}
+
+	return admitted, preempted
+}
+
+// Metrics returns the metrics of each ClusterQueue.
+func (c *Controller) Metrics() map[string]Metrics {
+	metrics := make(map[string]Metrics, len(c.queues))
+	for _, name := range c.queueNames {
+		cq := c.queues[name]
+		met := Metrics{
+			PendingWorkloadsNum:   len(cq.pending),
+			AdmittedWorkloadsNum:  len(cq.admitted),
+			PreemptedWorkloadsNum: cq.preemptedNum,
+			Usage:                 make(map[string]v1.ResourceList, len(cq.Flavors)),
+			Utilization:           make(map[string]map[v1.ResourceName]float64, len(cq.Flavors)),
+		}
+		for i, flavor := range cq.Flavors {
+			met.Usage[flavor.Name] = cq.usage[i].DeepCopy()
+			met.Utilization[flavor.Name] = map[v1.ResourceName]float64{}
+			for rsrc, quota := range flavor.NominalQuota {
+				if quota.IsZero() {
+					continue
+				}
+				usage := cq.usage[i][rsrc]
+				met.Utilization[flavor.Name][rsrc] = float64(usage.MilliValue()) / float64(quota.MilliValue())
+			}
+		}
+		metrics[name] = met
+	}
+
+	return metrics
+}
+
+// pendingWorkloads returns all pending workloads in the order of admission.
+func (c *Controller) pendingWorkloads() []*workload {
+	wls := []*workload{}
+	for _, name := range c.queueNames {
+		for _, wl := range c.queues[name].pending {
+			wls = append(wls, wl)
+		}
+	}
+
+	sort.Slice(wls, func(i, j int) bool {
+		if wls[i].priority != wls[j].priority {
+			return wls[i].priority > wls[j].priority
+		}
+		if !wls[i].submittedAt.Before(wls[j].submittedAt) && !wls[j].submittedAt.Before(wls[i].submittedAt) {
+			return wls[i].key < wls[j].key
+		}
+		return wls[i].submittedAt.Before(wls[j].submittedAt)
+	})
+
+	return wls
+}
+
+// assign finds the flavor with which the workload can be admitted, preferring a flavor that fits
+// without borrowing, then one that fits by borrowing, and then one that fits by preempting admitted
+// workloads.
+func (c *Controller) assign(cq *clusterQueueState, wl *workload) (int, []*workload, bool) {
+	borrowing := -1
+	for i := range cq.Flavors {
+		fits, borrows := c.fits(cq, i, wl.request)
+		if fits && !borrows {
+			return i, nil, true
+		}
+		if fits && borrowing < 0 {
+			borrowing = i
+		}
+	}
+	if borrowing >= 0 {
+		return borrowing, nil, true
+	}
+
+	for i := range cq.Flavors {
+		if victims, ok := c.findVictims(cq, i, wl); ok {
+			return i, victims, true
+		}
+	}
+
+	return -1, nil, false
+}
+
+// fits returns whether the request fits in the quota of the flavor of the ClusterQueue, and if so,
+// whether it borrows quota from the cohort.
+func (c *Controller) fits(cq *clusterQueueState, flavor int, request v1.ResourceList) (fits bool, borrows bool) {
+	f := cq.Flavors[flavor]
+	for rsrc, req := range request {
+		quota, ok := f.NominalQuota[rsrc]
+		if !ok {
+			continue
+		}
+
+		usage := cq.usage[flavor][rsrc]
+		usage.Add(req)
+		if cq.Cohort == "" {
+			if usage.Cmp(quota) > 0 {
+				return false, false
+			}
+			continue
+		}
+
+		// Even within the nominal quota, the quota lent to the cohort must be reclaimed first.
+		cohortUsage, cohortQuota := c.cohortTotal(cq.Cohort, f.Name, rsrc)
+		cohortUsage.Add(req)
+		if cohortUsage.Cmp(cohortQuota) > 0 {
+			return false, false
+		}
+		if usage.Cmp(quota) <= 0 {
+			continue
+		}
+
+		borrowed := usage.DeepCopy()
+		borrowed.Sub(quota)
+		if limit, ok := f.BorrowingLimit[rsrc]; ok && borrowed.Cmp(limit) > 0 {
+			return false, false
+		}
+		borrows = true
+	}
+
+	return true, borrows
+}
+
+// cohortTotal returns the total usage and the total nominal quota of the resource of the flavor
+// among the ClusterQueues in the cohort.
+func (c *Controller) cohortTotal(cohort, flavor string, rsrc v1.ResourceName) (resource.Quantity, resource.Quantity) {
+	usage := resource.Quantity{}
+	quota := resource.Quantity{}
+	for _, name := range c.queueNames {
+		cq := c.queues[name]
+		if cq.Cohort != cohort {
+			continue
+		}
+		for i, f := range cq.Flavors {
+			if f.Name == flavor {
+				usage.Add(cq.usage[i][rsrc])
+				quota.Add(f.NominalQuota[rsrc])
+			}
+		}
+	}
+
+	return usage, quota
+}
+
+// findVictims finds the admitted workloads whose preemption lets the workload fit in the flavor
+// of the ClusterQueue, according to its preemption policies.
+// Workloads of ClusterQueues borrowing quota are preempted first, then those of lower priority, and
+// then those admitted more recently.
+func (c *Controller) findVictims(cq *clusterQueueState, flavor int, wl *workload) ([]*workload, bool) {
+	candidates := []*workload{}
+	if cq.WithinClusterQueue == PreemptLowerPriority {
+		for _, adm := range cq.admitted {
+			if adm.flavor == flavor && adm.priority < wl.priority {
+				candidates = append(candidates, adm)
+			}
+		}
+	}
+
+	if cq.Cohort != "" && cq.ReclaimWithinCohort != "" && cq.ReclaimWithinCohort != PreemptNever &&
+		c.withinNominalQuota(cq, flavor, wl.request) {
+
+		flavorName := cq.Flavors[flavor].Name
+		for _, name := range c.queueNames {
+			other := c.queues[name]
+			if other == cq || other.Cohort != cq.Cohort {
+				continue
+			}
+			for _, adm := range other.admitted {
+				if other.Flavors[adm.flavor].Name != flavorName || !other.borrows(adm.flavor) {
+					continue
+				}
+				if cq.ReclaimWithinCohort == PreemptAny || adm.priority < wl.priority {
+					candidates = append(candidates, adm)
+				}
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if (ci.queue == cq.Name) != (cj.queue == cq.Name) {
+			return ci.queue != cq.Name
+		}
+		if ci.priority != cj.priority {
+			return ci.priority < cj.priority
+		}
+		if !ci.admittedAt.Before(cj.admittedAt) && !cj.admittedAt.Before(ci.admittedAt) {
+			return ci.key < cj.key
+		}
+		return cj.admittedAt.Before(ci.admittedAt)
+	})
+
+	// Release the quota of the candidates one by one until the workload fits, and restore it.
+	victims := []*workload{}
+	defer func() {
+		for _, victim := range victims {
+			c.queues[victim.queue].charge(victim, true)
+		}
+	}()
+	for _, candidate := range candidates {
+		c.queues[candidate.queue].charge(candidate, false)
+		victims = append(victims, candidate)
+		if fits, _ := c.fits(cq, flavor, wl.request); fits {
+			return append([]*workload{}, victims...), true
+		}
+	}
+
+	return nil, false
+}
+
+// withinNominalQuota returns whether the request fits in the nominal quota of the flavor of the
+// ClusterQueue, i.e. admitting it only reclaims the quota lent to the cohort.
+func (c *Controller) withinNominalQuota(cq *clusterQueueState, flavor int, request v1.ResourceList) bool {
+	for rsrc, req := range request {
+		quota, ok := cq.Flavors[flavor].NominalQuota[rsrc]
+		if !ok {
+			continue
+		}
+		usage := cq.usage[flavor][rsrc]
+		usage.Add(req)
+		if usage.Cmp(quota) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// borrows returns whether the ClusterQueue uses more than its nominal quota of the flavor.
+func (cq *clusterQueueState) borrows(flavor int) bool {
+	for rsrc, usage := range cq.usage[flavor] {
+		if quota, ok := cq.Flavors[flavor].NominalQuota[rsrc]; ok && usage.Cmp(quota) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// charge adds (or subtracts if !add) the request of the admitted workload to the usage of its
+// flavor.
+func (cq *clusterQueueState) charge(wl *workload, add bool) {
+	if add {
+		cq.usage[wl.flavor] = util.ResourceListSum(cq.usage[wl.flavor], wl.request)
+	} else {
+		cq.usage[wl.flavor] = util.ResourceListSub(cq.usage[wl.flavor], wl.request)
+	}
+}
+
+// admit admits the pending workload with the flavor, and returns the pod to be scheduled.
+func (c *Controller) admit(clk clock.Clock, cq *clusterQueueState, wl *workload, flavor int) *v1.Pod {
+	delete(cq.pending, wl.key)
+	cq.admitted[wl.key] = wl
+	wl.flavor = flavor
+	wl.admittedAt = clk
+	cq.charge(wl, true)
+
+	pod := wl.pod.DeepCopy()
+	if labels := cq.Flavors[flavor].NodeLabels; len(labels) > 0 {
+		if pod.Spec.NodeSelector == nil {
+			pod.Spec.NodeSelector = map[string]string{}
+		}
+		for key, value := range labels {
+			pod.Spec.NodeSelector[key] = value
+		}
+	}
+	log.L.Debugf("Workload %s: admitted by %s with flavor %s", wl.key, cq.Name, cq.Flavors[flavor].Name)
+
+	return pod
+}
+
+// preempt releases the quota of the admitted workload, which is pending again.
+func (c *Controller) preempt(wl *workload) {
+	cq := c.queues[wl.queue]
+	cq.charge(wl, false)
+	delete(cq.admitted, wl.key)
+	cq.pending[wl.key] = wl
+	cq.preemptedNum++
+	wl.flavor = -1
+	log.L.Debugf("Workload %s: preempted", wl.key)
+}
+
+// forget deletes the workload, releasing its quota if admitted.
+func (c *Controller) forget(wl *workload) {
+	cq := c.queues[wl.queue]
+	if wl.flavor >= 0 {
+		cq.charge(wl, false)
+	}
+	delete(cq.pending, wl.key)
+	delete(cq.admitted, wl.key)
+	delete(c.workloads, wl.key)
+}
@@ -0,0 +1,193 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/admission"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+)
+
+const queueLabel = "queue"
+
+func newWorkloadPod(name, localQueue, cpu string, priority int32) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			Labels:    map[string]string{queueLabel: localQueue},
+		},
+		Spec: v1.PodSpec{
+			Priority: &priority,
+			Containers: []v1.Container{{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+				},
+			}},
+		},
+	}
+}
+
+func newClusterQueue(name, cohort, cpu string) admission.ClusterQueue {
+	return admission.ClusterQueue{
+		Name:   name,
+		Cohort: cohort,
+		Flavors: []admission.Flavor{{
+			Name:         "default",
+			NodeLabels:   map[string]string{"flavor": "default"},
+			NominalQuota: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+		}},
+		WithinClusterQueue:  admission.PreemptNever,
+		ReclaimWithinCohort: admission.PreemptNever,
+	}
+}
+
+func names(pods []*v1.Pod) []string {
+	names := []string{}
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestControllerAdmitWithinQuota(t *testing.T) {
+	c := admission.NewController(queueLabel,
+		[]admission.LocalQueue{{Name: "a", ClusterQueue: "cq-a"}},
+		[]admission.ClusterQueue{newClusterQueue("cq-a", "", "4")})
+	clk := clock.NewClock(time.Now())
+
+	for i, name := range []string{"pod-0", "pod-1", "pod-2"} {
+		if cq, ok := c.Submit(clk.Add(time.Duration(i)*time.Second), newWorkloadPod(name, "a", "2", 0)); !ok || cq != "cq-a" {
+			t.Errorf("got: %q, %v\nwant: %q, true", cq, ok, "cq-a")
+		}
+	}
+	if _, ok := c.Submit(clk, &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "no-queue"}}); ok {
+		t.Error("pod without the queue label must not be submitted")
+	}
+
+	admitted, preempted := c.Admit(clk)
+	if !reflect.DeepEqual(names(admitted), []string{"pod-0", "pod-1"}) || len(preempted) > 0 {
+		t.Errorf("got: %v, %v\nwant: [pod-0 pod-1], []", names(admitted), names(preempted))
+	}
+	if admitted[0].Spec.NodeSelector["flavor"] != "default" {
+		t.Errorf("got: %v\nwant: the node labels of the flavor", admitted[0].Spec.NodeSelector)
+	}
+
+	met := c.Metrics()["cq-a"]
+	if met.PendingWorkloadsNum != 1 || met.AdmittedWorkloadsNum != 2 || met.Utilization["default"][v1.ResourceCPU] != 1 {
+		t.Errorf("got: %+v\nwant: 1 pending, 2 admitted, and utilization 1", met)
+	}
+
+	c.Finish("default/pod-0")
+	admitted, _ = c.Admit(clk)
+	if !reflect.DeepEqual(names(admitted), []string{"pod-2"}) {
+		t.Errorf("got: %v\nwant: [pod-2]", names(admitted))
+	}
+	if c.PendingWorkloadsNum() != 0 {
+		t.Errorf("got: %d\nwant: 0", c.PendingWorkloadsNum())
+	}
+}
+
+func TestControllerBorrowAndReclaim(t *testing.T) {
+	cqB := newClusterQueue("cq-b", "cohort", "4")
+	cqB.ReclaimWithinCohort = admission.PreemptAny
+	c := admission.NewController(queueLabel,
+		[]admission.LocalQueue{{Name: "a", ClusterQueue: "cq-a"}, {Name: "b", ClusterQueue: "cq-b"}},
+		[]admission.ClusterQueue{newClusterQueue("cq-a", "cohort", "4"), cqB})
+	clk := clock.NewClock(time.Now())
+
+	// cq-a borrows the unused quota of cq-b.
+	for i, name := range []string{"a-0", "a-1", "a-2"} {
+		now := clk.Add(time.Duration(i) * time.Second)
+		c.Submit(now, newWorkloadPod(name, "a", "2", 0))
+		if admitted, _ := c.Admit(now); !reflect.DeepEqual(names(admitted), []string{name}) {
+			t.Errorf("got: %v\nwant: [%s]", names(admitted), name)
+		}
+	}
+	if util := c.Metrics()["cq-a"].Utilization["default"][v1.ResourceCPU]; util != 1.5 {
+		t.Errorf("got: %v\nwant: 1.5", util)
+	}
+
+	// cq-b reclaims its quota by preempting the last admitted workload of cq-a.
+	c.Submit(clk.Add(time.Minute), newWorkloadPod("b-0", "b", "4", 0))
+	admitted, preempted := c.Admit(clk.Add(time.Minute))
+	if !reflect.DeepEqual(names(admitted), []string{"b-0"}) || !reflect.DeepEqual(names(preempted), []string{"a-2"}) {
+		t.Errorf("got: %v, %v\nwant: [b-0], [a-2]", names(admitted), names(preempted))
+	}
+
+	met := c.Metrics()["cq-a"]
+	if met.PendingWorkloadsNum != 1 || met.AdmittedWorkloadsNum != 2 || met.PreemptedWorkloadsNum != 1 {
+		t.Errorf("got: %+v\nwant: 1 pending, 2 admitted, and 1 preempted", met)
+	}
+}
+
+func TestControllerPreemptLowerPriority(t *testing.T) {
+	cq := newClusterQueue("cq-a", "", "4")
+	cq.WithinClusterQueue = admission.PreemptLowerPriority
+	c := admission.NewController(queueLabel,
+		[]admission.LocalQueue{{Namespace: "default", Name: "a", ClusterQueue: "cq-a"}},
+		[]admission.ClusterQueue{cq})
+	clk := clock.NewClock(time.Now())
+
+	c.Submit(clk, newWorkloadPod("low", "a", "4", 0))
+	c.Admit(clk)
+
+	c.Submit(clk, newWorkloadPod("high", "a", "2", 100))
+	admitted, preempted := c.Admit(clk)
+	if !reflect.DeepEqual(names(admitted), []string{"high"}) || !reflect.DeepEqual(names(preempted), []string{"low"}) {
+		t.Errorf("got: %v, %v\nwant: [high], [low]", names(admitted), names(preempted))
+	}
+
+	// The preempted workload waits for admission again, and can be deleted while pending.
+	if !c.Delete("default", "low") {
+		t.Error("the preempted workload must be pending")
+	}
+	if c.Delete("default", "high") {
+		t.Error("the admitted workload must not be pending")
+	}
+}
+
+func TestControllerStrictFIFO(t *testing.T) {
+	for _, strict := range []bool{false, true} {
+		cq := newClusterQueue("cq-a", "", "4")
+		cq.StrictFIFO = strict
+		c := admission.NewController(queueLabel,
+			[]admission.LocalQueue{{Name: "a", ClusterQueue: "cq-a"}},
+			[]admission.ClusterQueue{cq})
+		clk := clock.NewClock(time.Now())
+
+		c.Submit(clk, newWorkloadPod("running", "a", "2", 0))
+		c.Admit(clk)
+
+		c.Submit(clk.Add(time.Second), newWorkloadPod("large", "a", "4", 0))
+		c.Submit(clk.Add(2*time.Second), newWorkloadPod("small", "a", "2", 0))
+		admitted, _ := c.Admit(clk)
+
+		expected := []string{"small"}
+		if strict {
+			expected = []string{}
+		}
+		if !reflect.DeepEqual(names(admitted), expected) {
+			t.Errorf("strict %v\ngot: %v\nwant: %v", strict, names(admitted), expected)
+		}
+	}
+}
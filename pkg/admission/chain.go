@@ -0,0 +1,175 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// Hook is a user-defined admission hook called with the pod being submitted at the clock.
+// A mutating hook may modify the pod in place. Returning a non-nil error rejects the pod.
+type Hook = func(clk clock.Clock, pod *v1.Pod) error
+
+// RejectionError is the error returned by Chain.Admit when a pod is rejected.
+type RejectionError struct {
+	// Plugin is the name of the admission plugin or hook that rejected the pod.
+	Plugin string
+	Reason string
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Plugin, e.Reason)
+}
+
+// Names of the built-in admission plugins, reported in RejectionError.Plugin.
+const (
+	LimitRangerPlugin   = "LimitRanger"
+	ResourceQuotaPlugin = "ResourceQuota"
+)
+
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+// Chain is an admission chain run on the submission of each pod, in the order of
+// LimitRange defaulting, mutating hooks, LimitRange validation, validating hooks, and
+// ResourceQuota enforcement.
+type Chain struct {
+	limitRanges     map[string][]v1.LimitRangeItem
+	quotas          map[string]*resourceQuota
+	mutatingHooks   []namedHook
+	validatingHooks []namedHook
+
+	// charged maps the key of each pod charged to a ResourceQuota to its charge.
+	charged map[string]v1.ResourceList
+}
+
+// NewChain creates a new empty admission Chain, which admits any pod.
+func NewChain() *Chain {
+	return &Chain{
+		limitRanges: map[string][]v1.LimitRangeItem{},
+		quotas:      map[string]*resourceQuota{},
+		charged:     map[string]v1.ResourceList{},
+	}
+}
+
+// SetLimitRange sets the LimitRange items of the namespace, replacing the existing ones.
+func (c *Chain) SetLimitRange(namespace string, items []v1.LimitRangeItem) {
+	c.limitRanges[namespace] = items
+}
+
+// SetResourceQuota sets the hard limits of the ResourceQuota of the namespace, replacing the
+// existing ones. The usage already charged is kept.
+func (c *Chain) SetResourceQuota(namespace string, hard v1.ResourceList) {
+	if q, ok := c.quotas[namespace]; ok {
+		q.hard = hard
+		return
+	}
+	c.quotas[namespace] = &resourceQuota{hard: hard, used: v1.ResourceList{}}
+}
+
+// AddMutatingHook appends the mutating hook to the chain. Hooks are called in the order that they
+// are added.
+func (c *Chain) AddMutatingHook(name string, hook Hook) {
+	c.mutatingHooks = append(c.mutatingHooks, namedHook{name: name, hook: hook})
+}
+
+// AddValidatingHook appends the validating hook to the chain. Hooks are called in the order that
+// they are added.
+func (c *Chain) AddValidatingHook(name string, hook Hook) {
+	c.validatingHooks = append(c.validatingHooks, namedHook{name: name, hook: hook})
+}
+
+// Admit runs the chain on the pod submitted at the clock, mutating it in place.
+// Returns a *RejectionError if the pod is rejected. Otherwise, the pod is charged to the
+// ResourceQuota of its namespace until released by Release. A pod re-submitted with the same
+// namespace/name replaces its previous charge.
+func (c *Chain) Admit(clk clock.Clock, pod *v1.Pod) error {
+	items := c.limitRanges[pod.Namespace]
+	applyLimitRangeDefaults(pod, items)
+
+	for _, h := range c.mutatingHooks {
+		if err := h.hook(clk, pod); err != nil {
+			return &RejectionError{Plugin: h.name, Reason: err.Error()}
+		}
+	}
+
+	if err := validateLimitRange(pod, items); err != nil {
+		return &RejectionError{Plugin: LimitRangerPlugin, Reason: err.Error()}
+	}
+
+	for _, h := range c.validatingHooks {
+		if err := h.hook(clk, pod); err != nil {
+			return &RejectionError{Plugin: h.name, Reason: err.Error()}
+		}
+	}
+
+	q, ok := c.quotas[pod.Namespace]
+	if !ok {
+		return nil
+	}
+	key, err := util.PodKey(pod)
+	if err != nil {
+		return &RejectionError{Plugin: ResourceQuotaPlugin, Reason: err.Error()}
+	}
+	c.Release(pod.Namespace, pod.Name)
+	charge, err := q.charge(pod)
+	if err != nil {
+		return &RejectionError{Plugin: ResourceQuotaPlugin, Reason: err.Error()}
+	}
+	c.charged[key] = charge
+
+	return nil
+}
+
+// Release releases the ResourceQuota usage charged for the pod, if any.
+func (c *Chain) Release(namespace, name string) {
+	key := util.PodKeyFromNames(namespace, name)
+	charge, ok := c.charged[key]
+	if !ok {
+		return
+	}
+	delete(c.charged, key)
+	if q, ok := c.quotas[namespace]; ok {
+		q.release(charge)
+	}
+}
+
+// Charged returns the keys of the pods charged to ResourceQuotas, in sorted order.
+func (c *Chain) Charged() []string {
+	keys := make([]string, 0, len(c.charged))
+	for key := range c.charged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// QuotaUsage returns the usage of the ResourceQuota of the namespace, or false if the namespace
+// has no ResourceQuota.
+func (c *Chain) QuotaUsage(namespace string) (v1.ResourceList, bool) {
+	q, ok := c.quotas[namespace]
+	if !ok {
+		return nil, false
+	}
+	return q.used.DeepCopy(), true
+}
@@ -0,0 +1,166 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/admission"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+)
+
+func newContainerPod(name string, requests, limits v1.ResourceList) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Name:      "main",
+				Resources: v1.ResourceRequirements{Requests: requests, Limits: limits},
+			}},
+		},
+	}
+}
+
+func cpu(value string) v1.ResourceList {
+	return v1.ResourceList{v1.ResourceCPU: resource.MustParse(value)}
+}
+
+func TestChainLimitRange(t *testing.T) {
+	c := admission.NewChain()
+	c.SetLimitRange("default", []v1.LimitRangeItem{{
+		Type:                 v1.LimitTypeContainer,
+		Min:                  cpu("100m"),
+		Max:                  cpu("2"),
+		Default:              cpu("1"),
+		DefaultRequest:       cpu("500m"),
+		MaxLimitRequestRatio: cpu("4"),
+	}})
+	clk := clock.NewClock(time.Now())
+
+	pod := newContainerPod("defaulted", nil, nil)
+	assert.NoError(t, c.Admit(clk, pod))
+	res := pod.Spec.Containers[0].Resources
+	if res.Requests.Cpu().Cmp(resource.MustParse("500m")) != 0 || res.Limits.Cpu().Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("got: %v, %v\nwant: 500m, 1", res.Requests.Cpu(), res.Limits.Cpu())
+	}
+
+	// The request defaults to DefaultRequest rather than to the limit.
+	pod = newContainerPod("limited", nil, cpu("2"))
+	assert.NoError(t, c.Admit(clk, pod))
+	if pod.Spec.Containers[0].Resources.Requests.Cpu().Cmp(resource.MustParse("500m")) != 0 {
+		t.Errorf("got: %v\nwant: 500m", pod.Spec.Containers[0].Resources.Requests.Cpu())
+	}
+
+	// The lists of the submitted pod are not modified.
+	limits := cpu("2")
+	shared := v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}
+	pod = newContainerPod("shared", shared, limits)
+	assert.NoError(t, c.Admit(clk, pod))
+	if _, ok := shared[v1.ResourceCPU]; ok || len(limits) != 1 {
+		t.Errorf("got: %v, %v\nwant: submitted lists unmodified", shared, limits)
+	}
+	if pod.Spec.Containers[0].Resources.Requests.Cpu().Cmp(resource.MustParse("500m")) != 0 {
+		t.Errorf("got: %v\nwant: 500m", pod.Spec.Containers[0].Resources.Requests.Cpu())
+	}
+
+	// Pods in namespaces without LimitRange items are not defaulted.
+	pod = newContainerPod("other", nil, cpu("2"))
+	pod.Namespace = "other"
+	assert.NoError(t, c.Admit(clk, pod))
+	if requests := pod.Spec.Containers[0].Resources.Requests; requests != nil {
+		t.Errorf("got: %v\nwant: no requests", requests)
+	}
+
+	assert.EqualError(t, c.Admit(clk, newContainerPod("small", cpu("50m"), cpu("100m"))),
+		"LimitRanger: container main: minimum cpu usage is 100m, but request is 50m")
+	assert.EqualError(t, c.Admit(clk, newContainerPod("large", cpu("1"), cpu("3"))),
+		"LimitRanger: container main: maximum cpu usage is 2, but limit is 3")
+	assert.EqualError(t, c.Admit(clk, newContainerPod("bursty", cpu("200m"), cpu("2"))),
+		"LimitRanger: container main: cpu max limit to request ratio is 4, but ratio is 10.000")
+}
+
+func TestChainResourceQuota(t *testing.T) {
+	c := admission.NewChain()
+	c.SetResourceQuota("default", v1.ResourceList{
+		v1.ResourcePods:        resource.MustParse("2"),
+		v1.ResourceRequestsCPU: resource.MustParse("3"),
+		v1.ResourceLimitsCPU:   resource.MustParse("4"),
+	})
+	clk := clock.NewClock(time.Now())
+
+	assert.NoError(t, c.Admit(clk, newContainerPod("pod-0", cpu("2"), cpu("2"))))
+	assert.EqualError(t, c.Admit(clk, newContainerPod("pod-1", cpu("2"), cpu("2"))),
+		"ResourceQuota: exceeded quota: requested: requests.cpu=2, used: requests.cpu=2, limited: requests.cpu=3")
+	assert.EqualError(t, c.Admit(clk, newContainerPod("pod-1", cpu("1"), nil)),
+		"ResourceQuota: must specify limits.cpu")
+	assert.NoError(t, c.Admit(clk, newContainerPod("pod-1", cpu("1"), cpu("2"))))
+	assert.EqualError(t, c.Admit(clk, newContainerPod("pod-2", cpu("0"), cpu("0"))),
+		"ResourceQuota: exceeded quota: requested: pods=1, used: pods=2, limited: pods=2")
+
+	// Pods in other namespaces are not limited.
+	other := newContainerPod("pod-0", nil, nil)
+	other.Namespace = "other"
+	assert.NoError(t, c.Admit(clk, other))
+
+	assert.Equal(t, []string{"default/pod-0", "default/pod-1"}, c.Charged())
+
+	c.Release("default", "pod-0")
+	assert.Equal(t, []string{"default/pod-1"}, c.Charged())
+	assert.NoError(t, c.Admit(clk, newContainerPod("pod-2", cpu("2"), cpu("2"))))
+
+	used, _ := c.QuotaUsage("default")
+	requests := used[v1.ResourceRequestsCPU]
+	if used.Pods().Value() != 2 || requests.Cmp(resource.MustParse("3")) != 0 {
+		t.Errorf("got: %v\nwant: pods=2, requests.cpu=3", used)
+	}
+}
+
+func TestChainHooks(t *testing.T) {
+	c := admission.NewChain()
+	c.SetLimitRange("default", []v1.LimitRangeItem{{Type: v1.LimitTypePod, Max: cpu("2")}})
+	c.AddMutatingHook("team-label", func(clk clock.Clock, pod *v1.Pod) error {
+		pod.Labels = map[string]string{"team": "a"}
+		pod.Spec.Containers[0].Resources.Limits = cpu("4")
+		return nil
+	})
+	c.AddValidatingHook("no-privileged", func(clk clock.Clock, pod *v1.Pod) error {
+		if pod.Name == "privileged" {
+			return errors.New("privileged pods are not allowed")
+		}
+		return nil
+	})
+	clk := clock.NewClock(time.Now())
+
+	// LimitRange is validated after the mutating hooks.
+	pod := newContainerPod("pod-0", cpu("1"), cpu("1"))
+	assert.EqualError(t, c.Admit(clk, pod), "LimitRanger: pod: maximum cpu usage is 2, but limit is 4")
+	assert.Equal(t, "a", pod.Labels["team"])
+
+	c.SetLimitRange("default", nil)
+	assert.NoError(t, c.Admit(clk, newContainerPod("pod-0", cpu("1"), nil)))
+
+	err := c.Admit(clk, newContainerPod("privileged", cpu("1"), nil))
+	assert.EqualError(t, err, "no-privileged: privileged pods are not allowed")
+	if rej, ok := err.(*admission.RejectionError); !ok || rej.Plugin != "no-privileged" {
+		t.Errorf("got: %#v\nwant: *RejectionError of no-privileged", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// applyLimitRangeDefaults sets the default limits and requests of the LimitRange items of type
+// Container to the containers of the pod lacking them.
+// As the API server does, a request missing even after that is defaulted to the limit. Pods are
+// left as submitted in namespaces without LimitRange items.
+func applyLimitRangeDefaults(pod *v1.Pod, items []v1.LimitRangeItem) {
+	if len(items) == 0 {
+		return
+	}
+
+	for i := range pod.Spec.Containers {
+		res := &pod.Spec.Containers[i].Resources
+		for _, item := range items {
+			if item.Type != v1.LimitTypeContainer {
+				continue
+			}
+			res.Limits = withDefaults(res.Limits, item.Default)
+			res.Requests = withDefaults(res.Requests, item.DefaultRequest)
+		}
+		res.Requests = withDefaults(res.Requests, res.Limits)
+	}
+}
+
+// withDefaults returns the list with the values of the defaults set for the resources missing
+// in it. The list is copied rather than modified, as it may be shared by the submitter.
+func withDefaults(list, defaults v1.ResourceList) v1.ResourceList {
+	copied := false
+	for name, value := range defaults {
+		if _, ok := list[name]; ok {
+			continue
+		}
+		if !copied {
+			original := list
+			list = make(v1.ResourceList, len(original)+len(defaults))
+			for n, v := range original {
+				list[n] = v.DeepCopy()
+			}
+			copied = true
+		}
+		list[name] = value.DeepCopy()
+	}
+	return list
+}
+
+// validateLimitRange validates the requests and limits of the pod against the LimitRange items.
+// Items of type Container constrain each container, and items of type Pod the sum over the
+// containers.
+func validateLimitRange(pod *v1.Pod, items []v1.LimitRangeItem) error {
+	for _, item := range items {
+		switch item.Type {
+		case v1.LimitTypeContainer:
+			for _, container := range pod.Spec.Containers {
+				res := container.Resources
+				if err := validateLimitRangeItem(item, res.Requests, res.Limits); err != nil {
+					return fmt.Errorf("container %s: %v", container.Name, err)
+				}
+			}
+		case v1.LimitTypePod:
+			requests := podResourceList(pod, func(c v1.Container) v1.ResourceList { return c.Resources.Requests })
+			limits := podResourceList(pod, func(c v1.Container) v1.ResourceList { return c.Resources.Limits })
+			if err := validateLimitRangeItem(item, requests, limits); err != nil {
+				return fmt.Errorf("pod: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// podResourceList returns the sum of the resource lists of the containers of the pod, omitting
+// the resources missing in any of them.
+func podResourceList(pod *v1.Pod, list func(v1.Container) v1.ResourceList) v1.ResourceList {
+	result := v1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		result = util.ResourceListSum(result, list(container))
+	}
+	for name := range result {
+		for _, container := range pod.Spec.Containers {
+			if _, ok := list(container)[name]; !ok {
+				delete(result, name)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func validateLimitRangeItem(item v1.LimitRangeItem, requests, limits v1.ResourceList) error {
+	for _, name := range sortedResourceNames(item.Min) {
+		min := item.Min[name]
+		req, ok := requests[name]
+		if !ok {
+			return fmt.Errorf("minimum %s usage is %s, but no request is specified", name, min.String())
+		}
+		if req.Cmp(min) < 0 {
+			return fmt.Errorf("minimum %s usage is %s, but request is %s", name, min.String(), req.String())
+		}
+	}
+
+	for _, name := range sortedResourceNames(item.Max) {
+		max := item.Max[name]
+		lim, ok := limits[name]
+		if !ok {
+			return fmt.Errorf("maximum %s usage is %s, but no limit is specified", name, max.String())
+		}
+		if lim.Cmp(max) > 0 {
+			return fmt.Errorf("maximum %s usage is %s, but limit is %s", name, max.String(), lim.String())
+		}
+	}
+
+	for _, name := range sortedResourceNames(item.MaxLimitRequestRatio) {
+		ratio := item.MaxLimitRequestRatio[name]
+		lim, limOk := limits[name]
+		req, reqOk := requests[name]
+		if !limOk || !reqOk || req.IsZero() {
+			return fmt.Errorf("%s max limit to request ratio is %s, but request or limit is not specified", name, ratio.String())
+		}
+		if limitRequestRatio(lim, req) > float64(ratio.MilliValue())/1000 {
+			return fmt.Errorf("%s max limit to request ratio is %s, but ratio is %.3f", name, ratio.String(), limitRequestRatio(lim, req))
+		}
+	}
+
+	return nil
+}
+
+func limitRequestRatio(limit, request resource.Quantity) float64 {
+	return float64(limit.MilliValue()) / float64(request.MilliValue())
+}
+
+// sortedResourceNames returns the names of the resources in the list in sorted order.
+func sortedResourceNames(list v1.ResourceList) []v1.ResourceName {
+	names := make([]v1.ResourceName, 0, len(list))
+	for name := range list {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
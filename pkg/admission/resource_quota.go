@@ -0,0 +1,90 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// resourceQuota is the ResourceQuota of a namespace.
+// The hard limits are keyed as in Kubernetes: "pods" limits the number of pods, "requests.<name>"
+// (or "<name>") the total request of the resource, and "limits.<name>" its total limit.
+// The other keys are ignored.
+type resourceQuota struct {
+	hard v1.ResourceList
+	used v1.ResourceList
+}
+
+// charge charges the usage of the pod to the quota, and returns the charge.
+// Returns an error without charging if the quota would be exceeded, or the pod does not specify
+// the request or limit of a resource limited by the quota.
+func (q *resourceQuota) charge(pod *v1.Pod) (v1.ResourceList, error) {
+	requests := util.PodTotalResourceRequests(pod)
+	limits := util.PodTotalResourceLimits(pod)
+
+	charge := v1.ResourceList{}
+	for _, key := range sortedResourceNames(q.hard) {
+		var list v1.ResourceList
+		var name v1.ResourceName
+		switch {
+		case key == v1.ResourcePods:
+			charge[key] = *resource.NewQuantity(1, resource.DecimalSI)
+			continue
+		case strings.HasPrefix(string(key), "limits."):
+			list, name = limits, v1.ResourceName(strings.TrimPrefix(string(key), "limits."))
+		case strings.HasPrefix(string(key), "requests."):
+			list, name = requests, v1.ResourceName(strings.TrimPrefix(string(key), "requests."))
+		case !strings.Contains(string(key), "."):
+			list, name = requests, key
+		default:
+			continue
+		}
+
+		value, ok := list[name]
+		if !ok {
+			return nil, fmt.Errorf("must specify %s", key)
+		}
+		charge[key] = value
+	}
+
+	for _, key := range sortedResourceNames(charge) {
+		used := q.used[key]
+		total := charge[key]
+		total.Add(used)
+		if hard := q.hard[key]; total.Cmp(hard) > 0 {
+			requested := charge[key]
+			return nil, fmt.Errorf("exceeded quota: requested: %s=%s, used: %s=%s, limited: %s=%s",
+				key, requested.String(), key, used.String(), key, hard.String())
+		}
+	}
+
+	q.used = util.ResourceListSum(q.used, charge)
+	return charge, nil
+}
+
+// release releases the charge from the quota.
+func (q *resourceQuota) release(charge v1.ResourceList) {
+	for key, value := range charge {
+		used := q.used[key]
+		used.Sub(value)
+		q.used[key] = used
+	}
+}
@@ -21,8 +21,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-var LOAD_PHASE_CACHE = 2
-
 // Clock wraps a time.Time to represent a simulated time.
 type Clock struct {
 	inner metav1.Time
@@ -73,3 +71,19 @@ func (c Clock) ToRFC3339() string {
 func (c Clock) MarshalJSON() ([]byte, error) {
 	return json.Marshal(c.ToRFC3339())
 }
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (c *Clock) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+
+	t, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return err
+	}
+	*c = NewClock(t)
+
+	return nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/admission"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// DefaultQueueLabel is the default key of the pod label naming the local queue of a pod.
+const DefaultQueueLabel = "kueue.x-k8s.io/queue-name"
+
+// AdmissionConfig configures quota-based admission of pods before scheduling.
+type AdmissionConfig struct {
+	// QueueLabel is the key of the pod label whose value is the name of the local queue of a pod.
+	// Pods without the label are scheduled without admission.
+	// Optional (default: DefaultQueueLabel)
+	QueueLabel    string
+	LocalQueues   []LocalQueueConfig
+	ClusterQueues []ClusterQueueConfig
+}
+
+type LocalQueueConfig struct {
+	// Namespace is the namespace of the local queue; it is available in all namespaces if empty.
+	Namespace    string
+	Name         string
+	ClusterQueue string
+}
+
+type ClusterQueueConfig struct {
+	Name string
+	// Cohort is the name of the cohort in which the cluster queues lend unused quota to each other.
+	Cohort string
+	// QueueingStrategy is "BestEffortFIFO" (default) or "StrictFIFO".
+	QueueingStrategy string
+	Preemption       PreemptionConfig
+	// Flavors are tried in this order for each pod.
+	Flavors []FlavorConfig
+}
+
+type PreemptionConfig struct {
+	// WithinClusterQueue is "Never" (default) or "LowerPriority".
+	WithinClusterQueue string
+	// ReclaimWithinCohort is "Never" (default), "LowerPriority", or "Any".
+	ReclaimWithinCohort string
+}
+
+type FlavorConfig struct {
+	Name string
+	// NodeLabels are added to the node selector of the pods admitted with this flavor.
+	NodeLabels map[string]string
+	Resources  []FlavorResourceConfig
+}
+
+type FlavorResourceConfig struct {
+	Name         string
+	NominalQuota string
+	// BorrowingLimit limits the borrowing of the resource from the cohort.
+	// Optional (default: unlimited)
+	BorrowingLimit string
+}
+
+// BuildAdmissionController builds admission.Controller with the given AdmissionConfig.
+// Returns nil if no cluster queue is configured, or error if the config is invalid.
+func BuildAdmissionController(conf AdmissionConfig) (*admission.Controller, error) {
+	if len(conf.ClusterQueues) == 0 {
+		if len(conf.LocalQueues) > 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("local queues require cluster queues"))
+		}
+		return nil, nil
+	}
+
+	queueLabel := conf.QueueLabel
+	if queueLabel == "" {
+		queueLabel = DefaultQueueLabel
+	}
+
+	clusterQueues := make([]admission.ClusterQueue, 0, len(conf.ClusterQueues))
+	names := map[string]bool{}
+	for _, cqConf := range conf.ClusterQueues {
+		if cqConf.Name == "" {
+			return nil, strongerrors.InvalidArgument(errors.New("cluster queue name must not be empty"))
+		}
+		if names[cqConf.Name] {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("cluster queue %q is duplicated", cqConf.Name))
+		}
+		names[cqConf.Name] = true
+
+		cq, err := buildClusterQueue(cqConf)
+		if err != nil {
+			return nil, err
+		}
+		clusterQueues = append(clusterQueues, cq)
+	}
+
+	localQueues := make([]admission.LocalQueue, 0, len(conf.LocalQueues))
+	for _, lqConf := range conf.LocalQueues {
+		if lqConf.Name == "" {
+			return nil, strongerrors.InvalidArgument(errors.New("local queue name must not be empty"))
+		}
+		if !names[lqConf.ClusterQueue] {
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("local queue %q refers to unknown cluster queue %q", lqConf.Name, lqConf.ClusterQueue))
+		}
+		localQueues = append(localQueues, admission.LocalQueue{
+			Namespace:    lqConf.Namespace,
+			Name:         lqConf.Name,
+			ClusterQueue: lqConf.ClusterQueue,
+		})
+	}
+
+	return admission.NewController(queueLabel, localQueues, clusterQueues), nil
+}
+
+func buildClusterQueue(conf ClusterQueueConfig) (admission.ClusterQueue, error) {
+	cq := admission.ClusterQueue{
+		Name:                conf.Name,
+		Cohort:              conf.Cohort,
+		Flavors:             make([]admission.Flavor, 0, len(conf.Flavors)),
+		WithinClusterQueue:  admission.PreemptNever,
+		ReclaimWithinCohort: admission.PreemptNever,
+	}
+
+	switch conf.QueueingStrategy {
+	case "", "BestEffortFIFO":
+	case "StrictFIFO":
+		cq.StrictFIFO = true
+	default:
+		return cq, strongerrors.InvalidArgument(
+			errors.Errorf("queueing strategy %q of cluster queue %q is not supported", conf.QueueingStrategy, conf.Name))
+	}
+
+	switch conf.Preemption.WithinClusterQueue {
+	case "", admission.PreemptNever:
+	case admission.PreemptLowerPriority:
+		cq.WithinClusterQueue = admission.PreemptLowerPriority
+	default:
+		return cq, strongerrors.InvalidArgument(
+			errors.Errorf("preemption policy %q within cluster queue %q is not supported",
+				conf.Preemption.WithinClusterQueue, conf.Name))
+	}
+
+	switch conf.Preemption.ReclaimWithinCohort {
+	case "", admission.PreemptNever:
+	case admission.PreemptLowerPriority, admission.PreemptAny:
+		cq.ReclaimWithinCohort = conf.Preemption.ReclaimWithinCohort
+	default:
+		return cq, strongerrors.InvalidArgument(
+			errors.Errorf("preemption policy %q to reclaim within cohort of cluster queue %q is not supported",
+				conf.Preemption.ReclaimWithinCohort, conf.Name))
+	}
+
+	if len(conf.Flavors) == 0 {
+		return cq, strongerrors.InvalidArgument(errors.Errorf("cluster queue %q has no flavor", conf.Name))
+	}
+	for _, flavorConf := range conf.Flavors {
+		flavor := admission.Flavor{
+			Name:           flavorConf.Name,
+			NodeLabels:     flavorConf.NodeLabels,
+			NominalQuota:   v1.ResourceList{},
+			BorrowingLimit: v1.ResourceList{},
+		}
+
+		nominal := map[v1.ResourceName]string{}
+		limit := map[v1.ResourceName]string{}
+		for _, rsrc := range flavorConf.Resources {
+			nominal[v1.ResourceName(rsrc.Name)] = rsrc.NominalQuota
+			if rsrc.BorrowingLimit != "" {
+				limit[v1.ResourceName(rsrc.Name)] = rsrc.BorrowingLimit
+			}
+		}
+
+		var err error
+		if flavor.NominalQuota, err = util.BuildResourceList(nominal); err != nil {
+			return cq, strongerrors.InvalidArgument(
+				errors.Errorf("invalid quota of flavor %q of cluster queue %q: %s", flavor.Name, conf.Name, err.Error()))
+		}
+		if flavor.BorrowingLimit, err = util.BuildResourceList(limit); err != nil {
+			return cq, strongerrors.InvalidArgument(
+				errors.Errorf("invalid borrowing limit of flavor %q of cluster queue %q: %s",
+					flavor.Name, conf.Name, err.Error()))
+		}
+
+		cq.Flavors = append(cq.Flavors, flavor)
+	}
+
+	return cq, nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/admission"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// AdmissionChainConfig configures the admission chain run on the submission of each pod.
+// Pods rejected by the chain are never scheduled.
+type AdmissionChainConfig struct {
+	LimitRanges    []LimitRangeConfig
+	ResourceQuotas []ResourceQuotaConfig
+}
+
+// LimitRangeConfig is an item of the LimitRange of a namespace.
+type LimitRangeConfig struct {
+	Namespace string
+	// Type is "Container" (default) or "Pod". Limits of type Pod constrain the sum over the
+	// containers of a pod.
+	Type string
+	// Min is the minimum request of each resource.
+	Min map[v1.ResourceName]string
+	// Max is the maximum limit of each resource.
+	Max map[v1.ResourceName]string
+	// Default is the limit of each resource set to the containers lacking it. Only for Container.
+	Default map[v1.ResourceName]string
+	// DefaultRequest is the request of each resource set to the containers lacking it. Only for
+	// Container. Requests default to the limits otherwise.
+	DefaultRequest map[v1.ResourceName]string
+	// MaxLimitRequestRatio is the maximum ratio of the limit to the request of each resource.
+	MaxLimitRequestRatio map[v1.ResourceName]string
+}
+
+// ResourceQuotaConfig is the ResourceQuota of a namespace.
+type ResourceQuotaConfig struct {
+	Namespace string
+	// Pods is the maximum number of pods. Not limited if empty.
+	Pods string
+	// Requests is the maximum total request of each resource.
+	Requests map[v1.ResourceName]string
+	// Limits is the maximum total limit of each resource.
+	Limits map[v1.ResourceName]string
+}
+
+// BuildAdmissionChain builds admission.Chain with the given AdmissionChainConfig.
+// Returns error if the config is invalid.
+func BuildAdmissionChain(conf AdmissionChainConfig) (*admission.Chain, error) {
+	chain := admission.NewChain()
+
+	items := map[string][]v1.LimitRangeItem{}
+	for _, lr := range conf.LimitRanges {
+		item, err := buildLimitRangeItem(lr)
+		if err != nil {
+			return nil, err
+		}
+		items[lr.Namespace] = append(items[lr.Namespace], item)
+	}
+	for ns, items := range items {
+		chain.SetLimitRange(ns, items)
+	}
+
+	namespaces := map[string]bool{}
+	for _, rq := range conf.ResourceQuotas {
+		if namespaces[rq.Namespace] {
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("resource quota of namespace %q is duplicated", rq.Namespace))
+		}
+		namespaces[rq.Namespace] = true
+
+		hard, err := buildResourceQuotaHard(rq)
+		if err != nil {
+			return nil, err
+		}
+		chain.SetResourceQuota(rq.Namespace, hard)
+	}
+
+	return chain, nil
+}
+
+func buildLimitRangeItem(conf LimitRangeConfig) (v1.LimitRangeItem, error) {
+	item := v1.LimitRangeItem{}
+	switch conf.Type {
+	case "", string(v1.LimitTypeContainer):
+		item.Type = v1.LimitTypeContainer
+	case string(v1.LimitTypePod):
+		item.Type = v1.LimitTypePod
+		if len(conf.Default) > 0 || len(conf.DefaultRequest) > 0 {
+			return item, strongerrors.InvalidArgument(
+				errors.Errorf("limit range of type Pod in namespace %q must not have defaults", conf.Namespace))
+		}
+	default:
+		return item, strongerrors.InvalidArgument(
+			errors.Errorf("limit range type %q is not supported", conf.Type))
+	}
+
+	var err error
+	if item.Min, err = util.BuildResourceList(conf.Min); err != nil {
+		return item, err
+	}
+	if item.Max, err = util.BuildResourceList(conf.Max); err != nil {
+		return item, err
+	}
+	if item.Default, err = util.BuildResourceList(conf.Default); err != nil {
+		return item, err
+	}
+	if item.DefaultRequest, err = util.BuildResourceList(conf.DefaultRequest); err != nil {
+		return item, err
+	}
+	if item.MaxLimitRequestRatio, err = util.BuildResourceList(conf.MaxLimitRequestRatio); err != nil {
+		return item, err
+	}
+
+	for name, min := range item.Min {
+		if max, ok := item.Max[name]; ok && min.Cmp(max) > 0 {
+			return item, strongerrors.InvalidArgument(
+				errors.Errorf("min %s of limit range in namespace %q is greater than max", name, conf.Namespace))
+		}
+	}
+
+	return item, nil
+}
+
+func buildResourceQuotaHard(conf ResourceQuotaConfig) (v1.ResourceList, error) {
+	hard := v1.ResourceList{}
+	if conf.Pods != "" {
+		pods, err := resource.ParseQuantity(conf.Pods)
+		if err != nil {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("invalid pods value %q", conf.Pods))
+		}
+		hard[v1.ResourcePods] = pods
+	}
+
+	requests, err := util.BuildResourceList(conf.Requests)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range requests {
+		hard["requests."+name] = value
+	}
+
+	limits, err := util.BuildResourceList(conf.Limits)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range limits {
+		hard["limits."+name] = value
+	}
+
+	return hard, nil
+}
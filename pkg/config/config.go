@@ -21,7 +21,9 @@ import (
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
 )
@@ -33,22 +35,80 @@ type Config struct {
 	StartClock    string
 	MetricsTick   int
 	MetricsLogger []MetricsLoggerConfig
+	Report        ReportConfig
+	Resources     []ResourceConfig
 	Cluster       []NodeConfig
+	NodeGroups    []NodeGroupConfig
+	Interference  InterferenceConfig
+	Queue         QueueConfig
+	Admission     AdmissionConfig
+	// AdmissionChain is run on the submission of each pod, before Admission.
+	AdmissionChain AdmissionChainConfig
+	// PriorityClasses are resolved into the priority of the submitted pods.
+	PriorityClasses []PriorityClassConfig
+
+	PowerManagement PowerManagementConfig
+	Spot            SpotConfig
+
+	// Scheduler, Workload, and End are read only by the kubesim command, which builds the
+	// scheduler and the submitter that the library is otherwise given.
+	Scheduler SchedulerConfig
+	Workload  WorkloadConfig
+	// End is the clock at which the simulation ends, in RFC3339 or as a duration from StartClock
+	// such as "24h". The simulation runs until every pod of the workload finishes if empty.
+	End string
 }
 
 // Made public to be parsed from YAML.
 
+type ReportConfig struct {
+	// Dest is an output device or file path in which the KPI report is written at the end of the
+	// simulation. The report is not written if empty.
+	Dest string
+	// RecordsDest is an output device or file path in which the lifecycle records of all pods are
+	// written at the end of the simulation. The records are not written if empty.
+	RecordsDest string
+	// CostLabels are the keys of the pod labels by whose values the cost of the nodes is broken
+	// down in the report, in addition to namespaces.
+	CostLabels []string
+}
+
+type ResourceConfig struct {
+	// Name is a resource name, such as "memory" or "nvidia.com/gpu".
+	Name v1.ResourceName
+	// Compressible specifies whether the resource is throttled (true) or pods are killed (false)
+	// when the usage on a node exceeds its capacity.
+	// Resources not listed in the config are compressible.
+	Compressible bool
+}
+
 type MetricsLoggerConfig struct {
 	// Dest is an output device or file path in which the metrics is written.
 	Dest string
 	// Formatter is a type of metrics format.
 	Formatter string
+
+	// Entities is a list of entity types whose metrics are written, from "nodes", "pods", and
+	// "queue". Defaults to nodes and queue, since pod metrics are large for a large cluster.
+	Entities []string
+	// Namespaces is a list of namespaces whose pods are written. All namespaces if empty.
+	Namespaces []string
+	// LabelSelector is a label selector of pods that are written. All pods if empty.
+	LabelSelector string
+	// PodSamplingRate is the fraction of pods that are written, in [0, 1].
+	// The same pods are sampled throughout the simulation. All pods if 0.
+	PodSamplingRate float64
+	// Deltas specifies whether only nodes and pods whose metrics have changed since the last
+	// record are written.
+	Deltas bool
 }
 
 type NodeConfig struct {
 	Metadata metav1.ObjectMeta
 	Spec     v1.NodeSpec
 	Status   NodeStatus
+	Power    PowerConfig
+	Cost     CostConfig
 }
 
 type NodeStatus struct {
@@ -70,7 +130,12 @@ func BuildMetricsLogger(conf []MetricsLoggerConfig) ([]*metrics.FileWriter, erro
 			return nil, err
 		}
 
-		writer, err := metrics.NewFileWriter(conf.Dest, formatter)
+		filter, err := buildFilter(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		writer, err := metrics.NewFileWriterWithFilter(conf.Dest, formatter, filter)
 		if err != nil {
 			return nil, err
 		}
@@ -81,6 +146,23 @@ func BuildMetricsLogger(conf []MetricsLoggerConfig) ([]*metrics.FileWriter, erro
 	return writers, nil
 }
 
+// ValidateMetricsLogger validates the given MetricsLoggerConfig without creating the files.
+func ValidateMetricsLogger(conf []MetricsLoggerConfig) error {
+	for _, conf := range conf {
+		if conf.Dest == "" {
+			return strongerrors.InvalidArgument(errors.New("destination must not be empty"))
+		}
+		if _, err := buildFormatter(conf.Formatter); err != nil {
+			return err
+		}
+		if _, err := buildFilter(conf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func buildFormatter(conf string) (metrics.Formatter, error) {
 	switch conf {
 	case "JSON":
@@ -94,6 +176,89 @@ func buildFormatter(conf string) (metrics.Formatter, error) {
 	}
 }
 
+func buildFilter(conf MetricsLoggerConfig) (*metrics.Filter, error) {
+	filter := metrics.Filter{
+		Namespaces:      conf.Namespaces,
+		PodSamplingRate: conf.PodSamplingRate,
+		Deltas:          conf.Deltas,
+	}
+
+	entities := conf.Entities
+	if len(entities) == 0 {
+		entities = []string{"nodes", "queue"}
+	}
+	for _, entity := range entities {
+		switch entity {
+		case "nodes":
+			filter.Nodes = true
+		case "pods":
+			filter.Pods = true
+		case "queue":
+			filter.Queue = true
+		default:
+			return nil, strongerrors.InvalidArgument(errors.Errorf("entity %q is not supported", entity))
+		}
+	}
+
+	if conf.LabelSelector != "" {
+		selector, err := labels.Parse(conf.LabelSelector)
+		if err != nil {
+			return nil, strongerrors.InvalidArgument(errors.Wrapf(err, "invalid label selector %q", conf.LabelSelector))
+		}
+		filter.LabelSelector = selector
+	}
+
+	if conf.PodSamplingRate < 0 || conf.PodSamplingRate > 1 {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("pod sampling rate must be in [0, 1], but got %v", conf.PodSamplingRate))
+	}
+
+	return &filter, nil
+}
+
+// BuildResourceModels builds metrics.ResourceModels with the given ResourceConfig.
+// Returns error if the config is invalid.
+func BuildResourceModels(conf []ResourceConfig) (metrics.ResourceModels, error) {
+	models := metrics.ResourceModels{}
+	for _, rsrc := range conf {
+		if rsrc.Name == "" {
+			return nil, strongerrors.InvalidArgument(errors.New("resource name must not be empty"))
+		}
+		if _, ok := models[rsrc.Name]; ok {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("resource %q is duplicated", rsrc.Name))
+		}
+		models[rsrc.Name] = metrics.ResourceModel{Compressible: rsrc.Compressible}
+	}
+
+	return models, nil
+}
+
+// BuildEndClock builds the clock at which the simulation ends with the given End config, either
+// in RFC3339 or as a duration from startClock (the current time if empty).
+// Returns a clock far in the future if end is empty, or error if failed to parse.
+func BuildEndClock(end, startClock string) (clock.Clock, error) {
+	if end == "" {
+		return clock.NewClock(time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)), nil
+	}
+	if t, err := time.Parse(time.RFC3339, end); err == nil {
+		return clock.NewClock(t), nil
+	}
+
+	dur, err := time.ParseDuration(end)
+	if err != nil {
+		return clock.Clock{}, strongerrors.InvalidArgument(
+			errors.Errorf("end %q is neither RFC3339 nor a duration", end))
+	}
+	start := time.Now()
+	if startClock != "" {
+		start, err = time.Parse(time.RFC3339, startClock)
+		if err != nil {
+			return clock.Clock{}, err
+		}
+	}
+	return clock.NewClock(start.Add(dur)), nil
+}
+
 // BuildNode builds a *v1.Node with the given NodeConfig.
 // Returns error if failed to parse.
 func BuildNode(conf NodeConfig, startClock string) (*v1.Node, error) {
@@ -15,6 +15,10 @@
 package config
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -23,8 +27,14 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
 
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/scheduler"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
 )
 
 func TestBuildMetricsLogger(t *testing.T) {
@@ -40,9 +50,199 @@ func TestBuildMetricsLogger(t *testing.T) {
 	}})
 	assert.EqualError(t, err, "formatter \"invalid\" is not supported")
 
+	_, err = BuildMetricsLogger([]MetricsLoggerConfig{{
+		Dest:      "foo",
+		Formatter: "JSON",
+		Entities:  []string{"invalid"},
+	}})
+	assert.EqualError(t, err, "entity \"invalid\" is not supported")
+
 	// TODO: Test correct cases
 }
 
+func TestBuildFilter(t *testing.T) {
+	actual, _ := buildFilter(MetricsLoggerConfig{})
+	if !actual.Nodes || actual.Pods || !actual.Queue {
+		t.Errorf("got: %+v\nwant: nodes and queue", actual)
+	}
+
+	actual, _ = buildFilter(MetricsLoggerConfig{
+		Entities:        []string{"pods"},
+		LabelSelector:   "app=foo",
+		PodSamplingRate: 0.5,
+	})
+	if actual.Nodes || !actual.Pods || actual.Queue || actual.PodSamplingRate != 0.5 {
+		t.Errorf("got: %+v\nwant: pods sampled at 0.5", actual)
+	}
+	if actual.LabelSelector.String() != "app=foo" {
+		t.Errorf("got: %v\nwant: app=foo", actual.LabelSelector)
+	}
+
+	_, err := buildFilter(MetricsLoggerConfig{LabelSelector: "app in"})
+	assert.Error(t, err)
+
+	_, err = buildFilter(MetricsLoggerConfig{PodSamplingRate: 1.5})
+	assert.EqualError(t, err, "pod sampling rate must be in [0, 1], but got 1.5")
+}
+
+func TestBuildResourceModels(t *testing.T) {
+	actual, _ := BuildResourceModels([]ResourceConfig{
+		{Name: "cpu", Compressible: true},
+		{Name: "memory"},
+	})
+	if !actual.IsCompressible("cpu") || actual.IsCompressible("memory") || !actual.IsCompressible("nvidia.com/gpu") {
+		t.Errorf("got: %+v\nwant: only memory incompressible", actual)
+	}
+
+	_, err := BuildResourceModels([]ResourceConfig{{Name: "memory"}, {Name: "memory"}})
+	assert.EqualError(t, err, "resource \"memory\" is duplicated")
+}
+
+func TestBuildInterferenceModel(t *testing.T) {
+	actual, err := BuildInterferenceModel(InterferenceConfig{
+		ClassLabel: "class",
+		Classes: []InterferenceClassConfig{
+			{Name: "service", Sensitivity: 1, Pressure: 0.1},
+			{Name: "batch", Sensitivity: 0.5, Pressure: 0.4},
+		},
+		Matrix: []InterferencePairConfig{{Victim: "service", Aggressor: "batch", Sensitivity: 0.8}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &metrics.InterferenceModel{
+		ClassLabel: "class",
+		Sensitivity: map[string]map[string]float64{
+			"service": {"service": 0.1, "batch": 0.8},
+			"batch":   {"service": 0.05, "batch": 0.2},
+		},
+		SlowDown: true,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %+v\nwant: %+v", actual, expected)
+	}
+
+	if actual, err := BuildInterferenceModel(InterferenceConfig{}); actual != nil || err != nil {
+		t.Errorf("got: %+v, %v\nwant: nil, nil", actual, err)
+	}
+
+	_, err = BuildInterferenceModel(InterferenceConfig{ClassLabel: "class", Effect: "invalid"})
+	assert.EqualError(t, err, "interference effect \"invalid\" is not supported")
+
+	_, err = BuildInterferenceModel(InterferenceConfig{ClassLabel: "class", MaxSlowdown: 0.5})
+	assert.EqualError(t, err, "max slowdown must be >= 1, but got 0.5")
+}
+
+func TestBuildPowerModel(t *testing.T) {
+	actual, err := BuildPowerModel(PowerConfig{
+		IdleWatts:  100,
+		PeakWatts:  300,
+		SleepWatts: 10,
+		Curve:      []PowerPointConfig{{Utilization: 0, Watts: 100}, {Utilization: 1, Watts: 300}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &node.PowerModel{
+		IdleWatts:  100,
+		PeakWatts:  300,
+		SleepWatts: 10,
+		Curve:      []node.PowerPoint{{Utilization: 0, Watts: 100}, {Utilization: 1, Watts: 300}},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %+v\nwant: %+v", actual, expected)
+	}
+
+	if actual, err := BuildPowerModel(PowerConfig{}); actual != nil || err != nil {
+		t.Errorf("got: %+v, %v\nwant: nil, nil", actual, err)
+	}
+
+	_, err = BuildPowerModel(PowerConfig{PeakWatts: 100, PUE: 0.9})
+	assert.EqualError(t, err, "PUE must be >= 1, but got 0.9")
+
+	_, err = BuildPowerModel(PowerConfig{Curve: []PowerPointConfig{{Utilization: 1}, {Utilization: 0.5}}})
+	assert.EqualError(t, err, "power curve must be sorted by utilization")
+}
+
+func TestBuildCostModel(t *testing.T) {
+	actual, _ := BuildCostModel(CostConfig{HourlyPrice: 1.5})
+	expected := &node.CostModel{HourlyPrice: 1.5, Lifecycle: node.OnDemand}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %+v\nwant: %+v", actual, expected)
+	}
+
+	_, err := BuildCostModel(CostConfig{HourlyPrice: 1, Lifecycle: "preemptible"})
+	assert.EqualError(t, err, "lifecycle \"preemptible\" is not supported")
+}
+
+func TestBuildSpotInterrupter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "interruptions.jsonl")
+	trace := `{"clock": "2019-01-01T01:00:00+09:00", "node": "spot-0"}` + "\n\n"
+	if err := ioutil.WriteFile(path, []byte(trace), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := BuildSpotInterrupter(SpotConfig{Trace: path}); actual == nil || err != nil {
+		t.Errorf("got: %+v, %v\nwant: interrupter", actual, err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"clock": "1h", "node": "spot-0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuildSpotInterrupter(SpotConfig{Trace: path}); err == nil {
+		t.Errorf("got: no error\nwant: error")
+	}
+
+	if actual, err := BuildSpotInterrupter(SpotConfig{}); actual != nil || err != nil {
+		t.Errorf("got: %+v, %v\nwant: nil, nil", actual, err)
+	}
+}
+
+func TestBuildQueueOrder(t *testing.T) {
+	actual, err := BuildQueueOrder(QueueConfig{
+		Order: []QueueOrderConfig{
+			{Key: "priorityClass", Classes: []string{"high", "low"}},
+			{Key: "priority"},
+			{Key: "shortestJob", Annotation: "duration"},
+			{Key: "largestRequest"},
+		},
+		AgingRate: 0.01,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &queue.Order{
+		Keys: []queue.OrderKey{
+			{Name: queue.OrderByPriorityClass, Classes: []string{"high", "low"}},
+			{Name: queue.OrderByPriority},
+			{Name: queue.OrderByShortestJob, Annotation: "duration"},
+			{Name: queue.OrderByLargestRequest, Resource: "cpu"},
+		},
+		AgingRate: 0.01,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %+v\nwant: %+v", actual, expected)
+	}
+
+	if actual, err := BuildQueueOrder(QueueConfig{}); actual != nil || err != nil {
+		t.Errorf("got: %+v, %v\nwant: nil, nil", actual, err)
+	}
+
+	_, err = BuildQueueOrder(QueueConfig{Order: []QueueOrderConfig{{Key: "random"}}})
+	assert.EqualError(t, err, "queue order \"random\" is not supported")
+
+	_, err = BuildQueueOrder(QueueConfig{Order: []QueueOrderConfig{{Key: "shortestJob"}}})
+	assert.EqualError(t, err, "queue order \"shortestJob\" requires an annotation")
+
+	_, err = BuildQueueOrder(QueueConfig{AgingRate: 1})
+	assert.EqualError(t, err, "aging requires the queue order")
+}
+
 func TestBuildFormatter(t *testing.T) {
 	actual0, _ := buildFormatter("JSON")
 	expected0 := &metrics.JSONFormatter{}
@@ -173,3 +373,441 @@ func TestBuildNodeConfig(t *testing.T) {
 		t.Errorf("got: %+v\nwant: %+v", actual, expected)
 	}
 }
+
+func TestExpandNodeGroup(t *testing.T) {
+	conf := NodeGroupConfig{
+		Name:  "node-{index}",
+		Count: 4,
+		Template: NodeConfig{
+			Metadata: metav1.ObjectMeta{Name: "ignored", Labels: map[string]string{"os": "simulated"}},
+			Status:   NodeStatus{Allocatable: map[v1.ResourceName]string{"cpu": "4"}},
+		},
+		Labels: map[string]LabelGenerator{
+			"zone": {RoundRobin: []string{"a", "b", "c"}},
+			"pool": {Blocks: []string{"x", "y"}},
+			"rack": {Pattern: "rack-{index/2}-{index%2}"},
+		},
+	}
+	nodes, err := ExpandNodeGroup(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]string{
+		{"os": "simulated", "zone": "a", "pool": "x", "rack": "rack-0-0"},
+		{"os": "simulated", "zone": "b", "pool": "x", "rack": "rack-0-1"},
+		{"os": "simulated", "zone": "c", "pool": "y", "rack": "rack-1-0"},
+		{"os": "simulated", "zone": "a", "pool": "y", "rack": "rack-1-1"},
+	}
+	if len(nodes) != len(expected) {
+		t.Fatalf("got: %d nodes\nwant: %d nodes", len(nodes), len(expected))
+	}
+	for i, node := range nodes {
+		if name := fmt.Sprintf("node-%d", i); node.Metadata.Name != name {
+			t.Errorf("got: %s\nwant: %s", node.Metadata.Name, name)
+		}
+		if !reflect.DeepEqual(node.Metadata.Labels, expected[i]) {
+			t.Errorf("got: %v\nwant: %v", node.Metadata.Labels, expected[i])
+		}
+	}
+	if len(conf.Template.Metadata.Labels) != 1 {
+		t.Errorf("got: %v\nwant: the template unchanged", conf.Template.Metadata.Labels)
+	}
+
+	nodes, err = ExpandNodeGroup(NodeGroupConfig{
+		Name:     "node-{index}",
+		Count:    5,
+		Topology: TopologyConfig{Region: "r", Zones: []string{"a", "b"}, NodesPerRack: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Zone a has node-0, 1, and 2, and zone b has node-3 and 4.
+	for i, rack := range []string{"a-rack-0", "a-rack-0", "a-rack-1", "b-rack-0", "b-rack-0"} {
+		labels := nodes[i].Metadata.Labels
+		if labels[util.LabelTopologyRack] != rack || labels[util.LabelTopologyZone] != rack[:1] ||
+			labels[util.LabelTopologyRegion] != "r" || labels[v1.LabelZoneFailureDomain] != rack[:1] {
+			t.Errorf("node-%d: got: %v\nwant: region r, zone %s, rack %s", i, labels, rack[:1], rack)
+		}
+	}
+
+	_, err = ExpandNodeGroup(NodeGroupConfig{Name: "node", Count: 2})
+	assert.EqualError(t, err, "node group \"node\" must contain {index} in its name")
+
+	_, err = ExpandNodeGroup(NodeGroupConfig{Name: "node-{index}", Count: 1, Labels: map[string]LabelGenerator{
+		"zone": {RoundRobin: []string{"a"}, Pattern: "b"},
+	}})
+	assert.EqualError(t, err,
+		"label \"zone\" of node group \"node-{index}\": exactly one of roundRobin, blocks, and pattern must be set")
+}
+
+func TestInclude(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"base.yaml": "tick: 10\nreport:\n  dest: base.json\n  recordsDest: base.log\n" +
+			"cluster:\n- metadata: {name: node-0}\n",
+		"gpu.yaml":  "include: [base.yaml]\nnodeGroups:\n- name: gpu-{index}\n  count: 2\n",
+		"loop.yaml": "include: [loop.yaml]\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	settings := map[string]interface{}{
+		"include":    []interface{}{"gpu.yaml"},
+		"report":     map[string]interface{}{"dest": "main.json"},
+		"cluster":    []interface{}{map[interface{}]interface{}{"metadata": map[interface{}]interface{}{"name": "node-1"}}},
+		"startclock": "2019-01-01T00:00:00Z",
+	}
+	actual, err := Include(settings, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual["tick"] != 10 {
+		t.Errorf("got: %v\nwant: tick included", actual["tick"])
+	}
+	expectedReport := map[string]interface{}{"dest": "main.json", "recordsdest": "base.log"}
+	if !reflect.DeepEqual(actual["report"], expectedReport) {
+		t.Errorf("got: %v\nwant: %v", actual["report"], expectedReport)
+	}
+	if n := len(actual["cluster"].([]interface{})); n != 2 {
+		t.Errorf("got: %d nodes\nwant: nodes concatenated", n)
+	}
+	if n := len(actual["nodegroups"].([]interface{})); n != 1 {
+		t.Errorf("got: %d node groups\nwant: 1 node group", n)
+	}
+	if _, ok := actual["include"]; ok {
+		t.Errorf("got: include in the settings\nwant: include removed")
+	}
+
+	_, err = Include(map[string]interface{}{"include": []interface{}{"loop.yaml"}}, dir)
+	assert.EqualError(t, err, "config "+filepath.Join(dir, "loop.yaml")+" includes itself")
+}
+
+func TestBuildAdmissionController(t *testing.T) {
+	conf := AdmissionConfig{
+		LocalQueues: []LocalQueueConfig{{Namespace: "default", Name: "a", ClusterQueue: "cq-a"}},
+		ClusterQueues: []ClusterQueueConfig{{
+			Name:             "cq-a",
+			QueueingStrategy: "StrictFIFO",
+			Flavors: []FlavorConfig{{
+				Name:      "default",
+				Resources: []FlavorResourceConfig{{Name: "cpu", NominalQuota: "4"}},
+			}},
+		}},
+	}
+	controller, err := BuildAdmissionController(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "pod-0",
+		Labels:    map[string]string{DefaultQueueLabel: "a"},
+	}}
+	if cq, ok := controller.Submit(clock.NewClock(time.Now()), pod); !ok || cq != "cq-a" {
+		t.Errorf("got: %q, %v\nwant: %q, true", cq, ok, "cq-a")
+	}
+
+	if actual, err := BuildAdmissionController(AdmissionConfig{}); actual != nil || err != nil {
+		t.Errorf("got: %+v, %v\nwant: nil, nil", actual, err)
+	}
+
+	conf.LocalQueues[0].ClusterQueue = "cq-b"
+	_, err = BuildAdmissionController(conf)
+	assert.EqualError(t, err, "local queue \"a\" refers to unknown cluster queue \"cq-b\"")
+
+	conf.ClusterQueues[0].QueueingStrategy = "LIFO"
+	_, err = BuildAdmissionController(conf)
+	assert.EqualError(t, err, "queueing strategy \"LIFO\" of cluster queue \"cq-a\" is not supported")
+
+	conf.ClusterQueues[0].QueueingStrategy = ""
+	conf.ClusterQueues[0].Flavors = nil
+	_, err = BuildAdmissionController(conf)
+	assert.EqualError(t, err, "cluster queue \"cq-a\" has no flavor")
+}
+
+func TestBuildPriorityClasses(t *testing.T) {
+	classes, err := BuildPriorityClasses([]PriorityClassConfig{
+		{Name: "batch", Value: 10, GlobalDefault: true, PreemptionPolicy: "Never"},
+		{Name: "service", Value: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-0"}}
+	if err := classes.Resolve(pod); err != nil {
+		t.Fatal(err)
+	}
+	if util.PodPriority(pod) != 10 || util.PodPreemptionPolicy(pod) != util.PreemptNever {
+		t.Errorf("got: %d, %q\nwant: 10, %q", util.PodPriority(pod), util.PodPreemptionPolicy(pod), util.PreemptNever)
+	}
+
+	_, err = BuildPriorityClasses([]PriorityClassConfig{{Name: "a", GlobalDefault: true}, {Name: "b", GlobalDefault: true}})
+	assert.EqualError(t, err, "priority classes \"a\" and \"b\" are both the global default")
+
+	_, err = BuildPriorityClasses([]PriorityClassConfig{{Name: "system-critical"}})
+	assert.EqualError(t, err, "priority class name \"system-critical\" must not start with \"system-\"")
+
+	_, err = BuildPriorityClasses([]PriorityClassConfig{{Name: "a", Value: 2000000000}})
+	assert.EqualError(t, err, "value of priority class \"a\" must not be greater than 1000000000")
+
+	_, err = BuildPriorityClasses([]PriorityClassConfig{{Name: "a", PreemptionPolicy: "Always"}})
+	assert.EqualError(t, err, "preemption policy \"Always\" of priority class \"a\" is not supported")
+}
+
+func TestBuildAdmissionChain(t *testing.T) {
+	conf := AdmissionChainConfig{
+		LimitRanges: []LimitRangeConfig{{
+			Namespace: "default",
+			Max:       map[v1.ResourceName]string{"cpu": "4"},
+			Default:   map[v1.ResourceName]string{"cpu": "1"},
+		}},
+		ResourceQuotas: []ResourceQuotaConfig{{
+			Namespace: "default",
+			Pods:      "1",
+			Requests:  map[v1.ResourceName]string{"cpu": "2"},
+		}},
+	}
+	chain, err := BuildAdmissionChain(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := clock.NewClock(time.Now())
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-0"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main"}}},
+	}
+	if err := chain.Admit(clk, pod); err != nil {
+		t.Fatal(err)
+	}
+	if actual := pod.Spec.Containers[0].Resources.Requests.Cpu(); actual.Cmp(resource.MustParse("1")) != 0 {
+		t.Errorf("got: %v\nwant: 1", actual)
+	}
+	pod = &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-1"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "main"}}},
+	}
+	assert.EqualError(t, chain.Admit(clk, pod),
+		"ResourceQuota: exceeded quota: requested: pods=1, used: pods=1, limited: pods=1")
+
+	conf.LimitRanges[0].Type = "Pod"
+	_, err = BuildAdmissionChain(conf)
+	assert.EqualError(t, err, "limit range of type Pod in namespace \"default\" must not have defaults")
+
+	conf.LimitRanges[0].Type = "Node"
+	_, err = BuildAdmissionChain(conf)
+	assert.EqualError(t, err, "limit range type \"Node\" is not supported")
+
+	conf.LimitRanges = nil
+	conf.ResourceQuotas = append(conf.ResourceQuotas, ResourceQuotaConfig{Namespace: "default"})
+	_, err = BuildAdmissionChain(conf)
+	assert.EqualError(t, err, "resource quota of namespace \"default\" is duplicated")
+}
+
+func TestBuildQueue(t *testing.T) {
+	for typ, expected := range map[string]string{
+		"":           "*queue.FIFOQueue",
+		"Priority":   "*queue.PriorityQueue",
+		"drf":        "*queue.DRFQueue",
+		"scheduling": "*queue.SchedulingQueue",
+	} {
+		q, err := BuildQueue(QueueConfig{Type: typ})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := fmt.Sprintf("%T", q); actual != expected {
+			t.Errorf("got: %v\nwant: %v", actual, expected)
+		}
+	}
+
+	_, err := BuildQueue(QueueConfig{Type: "DRF", Weights: map[string]float64{"a": 0}})
+	assert.EqualError(t, err, "weight of tenant \"a\" must be > 0, but got 0")
+
+	_, err = BuildQueue(QueueConfig{Type: "LIFO"})
+	assert.EqualError(t, err, "queue type \"LIFO\" is not supported")
+}
+
+func TestBuildScheduler(t *testing.T) {
+	for _, profile := range []string{"", "bestFit", "worstFit", "overSub"} {
+		if actual, err := BuildScheduler(SchedulerConfig{Profile: profile}); actual == nil || err != nil {
+			t.Errorf("got: %v, %v\nwant: scheduler of profile %q", actual, err, profile)
+		}
+	}
+
+	_, err := BuildScheduler(SchedulerConfig{
+		Predicates:   []string{"GeneralPredicates", "PodTopologySpread"},
+		Prioritizers: []PrioritizerConfig{{Name: "LeastTasksFromSameJob", Weight: 2}},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = BuildScheduler(SchedulerConfig{Profile: "fastest"})
+	assert.EqualError(t, err, "scheduler profile \"fastest\" is not supported")
+
+	_, err = BuildScheduler(SchedulerConfig{Predicates: []string{"Foo"}})
+	assert.EqualError(t, err, "predicate \"Foo\" is not supported")
+
+	_, err = BuildScheduler(SchedulerConfig{Prioritizers: []PrioritizerConfig{{Name: "LeastRequested", Weight: -1}}})
+	assert.EqualError(t, err, "weight of prioritizer \"LeastRequested\" must be >= 0, but got -1")
+}
+
+// nodeList is an algorithm.NodeLister of the nodes.
+type nodeList []*v1.Node
+
+func (l nodeList) List() ([]*v1.Node, error) { return l, nil }
+
+func TestBuildSchedulerTopologySpreadPrioritizer(t *testing.T) {
+	sched, err := BuildScheduler(SchedulerConfig{
+		Predicates:   []string{"PodFitsResources"},
+		Prioritizers: []PrioritizerConfig{{Name: "PodTopologySpread"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Zone b is empty, and zone a has 2 pods labelled app=foo.
+	nodes := nodeList{}
+	nodeInfoMap := map[string]*nodeinfo.NodeInfo{}
+	for i, zone := range []string{"b", "a"} {
+		node := &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   fmt.Sprintf("node-%d", i),
+				Labels: util.TopologyLabelsOf("", zone, ""),
+			},
+			Status: v1.NodeStatus{Allocatable: v1.ResourceList{v1.ResourcePods: resource.MustParse("10")}},
+		}
+		info := nodeinfo.NewNodeInfo()
+		if err := info.SetNode(node); err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; zone == "a" && j < 2; j++ {
+			info.AddPod(&v1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      fmt.Sprintf("pod-%d", j),
+				Labels:    map[string]string{"app": "foo"},
+			}})
+		}
+		nodes = append(nodes, node)
+		nodeInfoMap[node.Name] = info
+	}
+
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "pod",
+		Labels:    map[string]string{"app": "foo"},
+		Annotations: map[string]string{scheduler.TopologySpreadConstraintsAnnotation: fmt.Sprintf(
+			"- maxSkew: 1\n  topologyKey: %s\n  whenUnsatisfiable: %s\n  labelSelector: {matchLabels: {app: foo}}\n",
+			util.LabelTopologyZone, scheduler.ScheduleAnyway)},
+	}}
+	q := queue.NewFIFOQueue()
+	if err := q.Push(pod); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := sched.Schedule(clock.NewClock(time.Now()), q, nodes, nodeInfoMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got: %d events\nwant: 1", len(events))
+	}
+	bind, ok := events[0].(*scheduler.BindEvent)
+	if !ok {
+		t.Fatalf("got: %T\nwant: *scheduler.BindEvent", events[0])
+	}
+	if bind.ScheduleResult.SuggestedHost != "node-0" {
+		t.Errorf("got: %s\nwant: node-0 in the empty zone", bind.ScheduleResult.SuggestedHost)
+	}
+}
+
+func TestBuildEndClock(t *testing.T) {
+	start := "2019-01-01T00:00:00Z"
+
+	actual, err := BuildEndClock("2019-01-02T00:00:00Z", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.ToRFC3339() != "2019-01-02T00:00:00Z" {
+		t.Errorf("got: %v\nwant: 2019-01-02T00:00:00Z", actual.ToRFC3339())
+	}
+
+	actual, err = BuildEndClock("36h", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.ToRFC3339() != "2019-01-02T12:00:00Z" {
+		t.Errorf("got: %v\nwant: 2019-01-02T12:00:00Z", actual.ToRFC3339())
+	}
+
+	actual, err = BuildEndClock("", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual.Before(clock.NewClock(time.Now().AddDate(1000, 0, 0))) {
+		t.Errorf("got: %v\nwant: far future", actual.ToRFC3339())
+	}
+
+	_, err = BuildEndClock("tomorrow", start)
+	assert.EqualError(t, err, "end \"tomorrow\" is neither RFC3339 nor a duration")
+}
+
+func TestBuildWorkloadSubmitter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tasks := `{"id":"a","arrival":0,"request":{"cpu":"1"},"phases":[{"seconds":10,"usage":{"cpu":"1"}}]}
+{"id":"b","arrival":60,"request":{"cpu":"1"},"phases":[{"seconds":10,"usage":{"cpu":"1"}}]}
+`
+	gen := "seed: 1\nduration: 60\narrival: {type: poisson, rate: 1}\nclasses:\n" +
+		"- name: batch\n  weight: 1\n  duration: {type: constant, value: 10}\n"
+	for name, content := range map[string]string{"tasks.jsonl": tasks, "generator.yaml": gen} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sub, closer, err := BuildWorkloadSubmitter(WorkloadConfig{Trace: "tasks.jsonl", TimeScale: 0.5}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	start := clock.NewClock(time.Now())
+	for sec, expected := range []int{1, 2} {
+		events, err := sub.Submit(start.Add(time.Duration(sec*30)*time.Second), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != expected {
+			t.Errorf("got: %d events at %ds\nwant: %d", len(events), sec*30, expected)
+		}
+	}
+
+	if err := ValidateWorkload(WorkloadConfig{Generator: "generator.yaml"}, dir); err != nil {
+		t.Error(err)
+	}
+	if _, _, err := BuildWorkloadSubmitter(WorkloadConfig{Generator: "generator.yaml"}, dir); err != nil {
+		t.Error(err)
+	}
+
+	err = ValidateWorkload(WorkloadConfig{Generator: "generator.yaml", Trace: "tasks.jsonl"}, dir)
+	assert.EqualError(t, err, "workload must have either generator or trace, but not both")
+
+	err = ValidateWorkload(WorkloadConfig{Trace: "tasks.jsonl", LoadScale: -1}, dir)
+	assert.EqualError(t, err, "loadScale must be >= 0, but got -1")
+
+	if err := ValidateWorkload(WorkloadConfig{Trace: "missing.jsonl"}, dir); err == nil {
+		t.Errorf("got: no error\nwant: error")
+	}
+}
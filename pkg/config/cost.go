@@ -0,0 +1,133 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+)
+
+// CostConfig is the cost model of a node (see node.CostModel).
+type CostConfig struct {
+	// HourlyPrice is the price of the node per hour.
+	HourlyPrice float64
+	// Lifecycle is "on-demand" (default), "reserved", or "spot".
+	Lifecycle string
+}
+
+// SpotConfig configures the interruptions of spot nodes.
+type SpotConfig struct {
+	// HazardRate is the expected number of interruptions of each spot node per hour.
+	HazardRate float64
+	// Seed is the random seed of the interruptions at the hazard rate.
+	Seed int64
+	// Trace is the path to a file of scheduled interruptions, one JSON object per line, e.g.,
+	// {"clock": "2019-01-01T01:00:00+09:00", "node": "spot-0"}.
+	Trace string
+	// NoticeSeconds is the duration between the notice of an interruption and the removal of the
+	// node, during which no pod can be bound to it.
+	NoticeSeconds int
+}
+
+// BuildCostModel builds node.CostModel with the given CostConfig.
+// Returns nil if the cost is not modeled, or error if the config is invalid.
+func BuildCostModel(conf CostConfig) (*node.CostModel, error) {
+	if conf.HourlyPrice == 0 && conf.Lifecycle == "" {
+		return nil, nil
+	}
+	if conf.HourlyPrice < 0 {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("hourly price must be >= 0, but got %v", conf.HourlyPrice))
+	}
+
+	model := node.CostModel{HourlyPrice: conf.HourlyPrice, Lifecycle: conf.Lifecycle}
+	switch conf.Lifecycle {
+	case "":
+		model.Lifecycle = node.OnDemand
+	case node.OnDemand, node.Reserved, node.Spot:
+	default:
+		return nil, strongerrors.InvalidArgument(errors.Errorf("lifecycle %q is not supported", conf.Lifecycle))
+	}
+
+	return &model, nil
+}
+
+// BuildSpotInterrupter builds node.SpotInterrupter with the given SpotConfig.
+// Returns nil if spot nodes are never interrupted, or error if the config is invalid or failed to
+// read the trace.
+func BuildSpotInterrupter(conf SpotConfig) (*node.SpotInterrupter, error) {
+	if conf.HazardRate < 0 || conf.NoticeSeconds < 0 {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("hazardRate and noticeSeconds must be >= 0, but got %+v", conf))
+	}
+	if conf.HazardRate == 0 && conf.Trace == "" {
+		return nil, nil
+	}
+
+	var trace []node.Interruption
+	if conf.Trace != "" {
+		var err error
+		if trace, err = readInterruptions(conf.Trace); err != nil {
+			return nil, err
+		}
+	}
+
+	return node.NewSpotInterrupter(conf.HazardRate, conf.Seed, trace, time.Duration(conf.NoticeSeconds)*time.Second), nil
+}
+
+// readInterruptions reads the interruptions from the trace file at the given path.
+func readInterruptions(path string) ([]node.Interruption, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	trace := []node.Interruption{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		entry := struct {
+			Clock string `json:"clock"`
+			Node  string `json:"node"`
+		}{}
+		if err := json.Unmarshal([]byte(text), &entry); err != nil {
+			return nil, strongerrors.InvalidArgument(errors.Wrapf(err, "%s:%d", path, line))
+		}
+		clk, err := time.Parse(time.RFC3339, entry.Clock)
+		if err != nil {
+			return nil, strongerrors.InvalidArgument(errors.Wrapf(err, "%s:%d", path, line))
+		}
+		trace = append(trace, node.Interruption{Clock: clock.NewClock(clk), Node: entry.Node})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return trace, nil
+}
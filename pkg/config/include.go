@@ -0,0 +1,107 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// concatenatedSettings are the settings whose lists are concatenated, rather than overridden, over
+// included files, so that a cluster can be composed of nodes described in several files.
+var concatenatedSettings = map[string]bool{
+	"cluster":    true,
+	"nodegroups": true,
+}
+
+// Include overlays the settings read by viper on the config files listed in their "include"
+// setting, relative to dir.
+// The included files are overlaid in order, and can include other files in turn. The cluster and
+// node groups of all the files are concatenated, and the other settings of a file override those
+// of the files it includes.
+// Returns error if failed to read an included file or the files include each other.
+func Include(settings map[string]interface{}, dir string) (map[string]interface{}, error) {
+	return include(settings, dir, map[string]bool{})
+}
+
+func include(settings map[string]interface{}, dir string, including map[string]bool) (map[string]interface{}, error) {
+	paths := []string{}
+	if value, ok := settings["include"]; ok && value != nil {
+		var err error
+		if paths, err = cast.ToStringSliceE(value); err != nil {
+			return nil, strongerrors.InvalidArgument(errors.Wrap(err, "include must be a list of paths"))
+		}
+	}
+
+	merged := map[string]interface{}{}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if including[path] {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("config %s includes itself", path))
+		}
+
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, errors.Wrapf(err, "cannot read included config %s", path)
+		}
+
+		including[path] = true
+		included, err := include(v.AllSettings(), filepath.Dir(path), including)
+		delete(including, path)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeSettings(merged, included)
+	}
+
+	overlay := map[string]interface{}{}
+	for key, value := range settings {
+		if key != "include" {
+			overlay[key] = value
+		}
+	}
+	return mergeSettings(merged, overlay), nil
+}
+
+// mergeSettings returns the settings of base overridden by those of overlay.
+func mergeSettings(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overlay))
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range overlay {
+		switch v := value.(type) {
+		case []interface{}:
+			if b, ok := merged[key].([]interface{}); ok && concatenatedSettings[key] {
+				value = append(append([]interface{}{}, b...), v...)
+			}
+		case map[string]interface{}:
+			if b, ok := merged[key].(map[string]interface{}); ok {
+				value = mergeSettings(b, v)
+			}
+		}
+		merged[key] = value
+	}
+
+	return merged
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
+)
+
+// InterferenceConfig configures the interference between the pods co-located on a node.
+type InterferenceConfig struct {
+	// ClassLabel is the key of the pod label whose value is the class of a pod.
+	// Interference is not modeled if empty.
+	ClassLabel string
+	// Effect is how interference affects the pods: "slowdown" (default) slows down their
+	// execution, "usage" inflates their resource usage, and "both" does both.
+	Effect string
+	// MaxSlowdown caps the slowdown of a pod if > 0. Must be >= 1 if set.
+	MaxSlowdown float64
+	// Classes are the pod classes. A pod of one class is slowed down by each co-located pod of
+	// another (or the same) class by the product of the sensitivity of the former and the pressure
+	// of the latter.
+	Classes []InterferenceClassConfig
+	// Matrix overrides the sensitivity of a victim class to an aggressor class, e.g., to model the
+	// interference between a latency-sensitive class and a memory-bandwidth-heavy one.
+	Matrix []InterferencePairConfig
+}
+
+type InterferenceClassConfig struct {
+	// Name is the value of the class label. "" is the class of pods without the label.
+	Name string
+	// Sensitivity is how much a pod of the class is slowed down by co-located pods.
+	Sensitivity float64
+	// Pressure is how much a pod of the class slows down co-located pods.
+	Pressure float64
+}
+
+type InterferencePairConfig struct {
+	Victim    string
+	Aggressor string
+	// Sensitivity is the fraction by which a pod of the victim class is slowed down by each
+	// co-located pod of the aggressor class.
+	Sensitivity float64
+}
+
+// BuildInterferenceModel builds metrics.InterferenceModel with the given InterferenceConfig.
+// Returns nil if interference is not modeled, or error if the config is invalid.
+func BuildInterferenceModel(conf InterferenceConfig) (*metrics.InterferenceModel, error) {
+	if conf.ClassLabel == "" {
+		if len(conf.Classes) > 0 || len(conf.Matrix) > 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("interference class label must not be empty"))
+		}
+		return nil, nil
+	}
+
+	model := metrics.InterferenceModel{
+		ClassLabel:  conf.ClassLabel,
+		Sensitivity: map[string]map[string]float64{},
+		MaxSlowdown: conf.MaxSlowdown,
+	}
+
+	switch conf.Effect {
+	case "", "slowdown":
+		model.SlowDown = true
+	case "usage":
+		model.InflateUsage = true
+	case "both":
+		model.SlowDown = true
+		model.InflateUsage = true
+	default:
+		return nil, strongerrors.InvalidArgument(errors.Errorf("interference effect %q is not supported", conf.Effect))
+	}
+
+	if conf.MaxSlowdown != 0 && conf.MaxSlowdown < 1 {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("max slowdown must be >= 1, but got %v", conf.MaxSlowdown))
+	}
+
+	seen := map[string]bool{}
+	for _, class := range conf.Classes {
+		if seen[class.Name] {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("interference class %q is duplicated", class.Name))
+		}
+		seen[class.Name] = true
+		if class.Sensitivity < 0 || class.Pressure < 0 {
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("interference class %q must have sensitivity and pressure >= 0", class.Name))
+		}
+	}
+
+	set := func(victim, aggressor string, sensitivity float64) {
+		if _, ok := model.Sensitivity[victim]; !ok {
+			model.Sensitivity[victim] = map[string]float64{}
+		}
+		model.Sensitivity[victim][aggressor] = sensitivity
+	}
+
+	for _, victim := range conf.Classes {
+		for _, aggressor := range conf.Classes {
+			if s := victim.Sensitivity * aggressor.Pressure; s > 0 {
+				set(victim.Name, aggressor.Name, s)
+			}
+		}
+	}
+	for _, pair := range conf.Matrix {
+		if pair.Sensitivity < 0 {
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("sensitivity of %q to %q must be >= 0", pair.Victim, pair.Aggressor))
+		}
+		set(pair.Victim, pair.Aggressor, pair.Sensitivity)
+	}
+
+	return &model, nil
+}
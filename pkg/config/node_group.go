@@ -0,0 +1,189 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// NodeGroupConfig is a group of nodes expanded from a template.
+type NodeGroupConfig struct {
+	// Name is the name pattern of the nodes (see LabelGenerator.Pattern), e.g., "gpu-{index}".
+	Name string
+	// Count is the number of nodes in the group.
+	Count int
+	// Template is the config of every node in the group. Its name is ignored.
+	Template NodeConfig
+	// Labels maps label keys to the generators of their values for each node, in addition to the
+	// labels of the template.
+	Labels map[string]LabelGenerator
+	// Topology places the nodes in topology domains.
+	Topology TopologyConfig
+}
+
+// TopologyConfig places the nodes of a group in topology domains, labelled with the keys in
+// util.TopologyLabels.
+type TopologyConfig struct {
+	// Region is the region of all the nodes.
+	Region string
+	// Zones divides the group into len(Zones) contiguous blocks of nodes, and places the i-th
+	// block in Zones[i].
+	Zones []string
+	// NodesPerRack places every NodesPerRack consecutive nodes of a zone in a rack, named
+	// "<zone>-rack-<i>" (or "rack-<i>" without zones). Nodes are not placed in racks if 0.
+	NodesPerRack int
+}
+
+// LabelGenerator generates a label value from the index of a node in its group.
+// Exactly one of the fields must be set.
+type LabelGenerator struct {
+	// RoundRobin assigns RoundRobin[index % len(RoundRobin)], e.g., to spread nodes over zones.
+	RoundRobin []string
+	// Blocks divides the group into len(Blocks) contiguous blocks of nodes, and assigns Blocks[i]
+	// to the i-th block.
+	Blocks []string
+	// Pattern is the value in which "{index}", "{index/N}", and "{index%N}" are replaced with the
+	// index, the index divided by N, and the index modulo N, e.g., "rack-{index/16}".
+	Pattern string
+}
+
+var indexPattern = regexp.MustCompile(`\{index(?:([/%])([0-9]+))?\}`)
+
+// ExpandNodeGroup expands the NodeGroupConfig into the NodeConfig of each node.
+// Returns error if the config is invalid.
+func ExpandNodeGroup(conf NodeGroupConfig) ([]NodeConfig, error) {
+	if conf.Name == "" {
+		return nil, strongerrors.InvalidArgument(errors.New("node group name must not be empty"))
+	}
+	if conf.Count < 0 {
+		return nil, strongerrors.InvalidArgument(errors.Errorf("node group %q must have count >= 0", conf.Name))
+	}
+	if conf.Count > 1 && !strings.Contains(conf.Name, "{index}") {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("node group %q must contain {index} in its name", conf.Name))
+	}
+	if conf.Topology.NodesPerRack < 0 {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("node group %q must have nodesPerRack >= 0", conf.Name))
+	}
+
+	nodes := make([]NodeConfig, 0, conf.Count)
+	for i := 0; i < conf.Count; i++ {
+		name, err := formatIndex(conf.Name, i)
+		if err != nil {
+			return nil, err
+		}
+
+		node := NodeConfig{
+			Metadata: *conf.Template.Metadata.DeepCopy(),
+			Spec:     *conf.Template.Spec.DeepCopy(),
+			Status:   conf.Template.Status,
+			Power:    conf.Template.Power,
+			Cost:     conf.Template.Cost,
+		}
+		node.Metadata.Name = name
+
+		if node.Metadata.Labels == nil {
+			node.Metadata.Labels = map[string]string{}
+		}
+		for key, value := range conf.Topology.labels(i, conf.Count) {
+			node.Metadata.Labels[key] = value
+		}
+		for key, gen := range conf.Labels {
+			value, err := gen.generate(i, conf.Count)
+			if err != nil {
+				return nil, errors.Wrapf(err, "label %q of node group %q", key, conf.Name)
+			}
+			node.Metadata.Labels[key] = value
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// labels returns the topology labels of the node of the given index in a group of count nodes.
+func (topo TopologyConfig) labels(index, count int) map[string]string {
+	zone := ""
+	start := 0 // the index of the first node in the zone
+	if n := len(topo.Zones); n > 0 {
+		z := index * n / count
+		zone = topo.Zones[z]
+		start = (z*count + n - 1) / n
+	}
+
+	rack := ""
+	if topo.NodesPerRack > 0 {
+		rack = fmt.Sprintf("rack-%d", (index-start)/topo.NodesPerRack)
+		if zone != "" {
+			rack = zone + "-" + rack
+		}
+	}
+
+	return util.TopologyLabelsOf(topo.Region, zone, rack)
+}
+
+func (gen LabelGenerator) generate(index, count int) (string, error) {
+	set := 0
+	for _, ok := range []bool{len(gen.RoundRobin) > 0, len(gen.Blocks) > 0, gen.Pattern != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return "", strongerrors.InvalidArgument(
+			errors.New("exactly one of roundRobin, blocks, and pattern must be set"))
+	}
+
+	switch {
+	case len(gen.RoundRobin) > 0:
+		return gen.RoundRobin[index%len(gen.RoundRobin)], nil
+	case len(gen.Blocks) > 0:
+		return gen.Blocks[index*len(gen.Blocks)/count], nil
+	default:
+		return formatIndex(gen.Pattern, index)
+	}
+}
+
+// formatIndex replaces the index placeholders in the pattern with the given index.
+func formatIndex(pattern string, index int) (string, error) {
+	var err error
+	formatted := indexPattern.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		match := indexPattern.FindStringSubmatch(placeholder)
+		if match[1] == "" {
+			return strconv.Itoa(index)
+		}
+
+		n, _ := strconv.Atoi(match[2])
+		if n == 0 {
+			err = strongerrors.InvalidArgument(errors.Errorf("invalid placeholder %s in %q", placeholder, pattern))
+			return placeholder
+		}
+		if match[1] == "/" {
+			return strconv.Itoa(index / n)
+		}
+		return strconv.Itoa(index % n)
+	})
+	return formatted, err
+}
@@ -0,0 +1,105 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+)
+
+// PowerConfig is the power model of a node (see node.PowerModel).
+type PowerConfig struct {
+	// IdleWatts and PeakWatts are the power consumption at 0 and 100 % CPU utilization.
+	IdleWatts float64
+	PeakWatts float64
+	// Curve makes the power consumption piecewise linear in the CPU utilization between its points
+	// instead, e.g., as measured by SPECpower.
+	Curve []PowerPointConfig
+	// SleepWatts is the power consumption while the node is asleep.
+	SleepWatts float64
+	// PUE is the power usage effectiveness of the facility of the node. Defaults to 1.
+	PUE float64
+}
+
+type PowerPointConfig struct {
+	// Utilization is the CPU utilization in [0, 1].
+	Utilization float64
+	Watts       float64
+}
+
+// PowerManagementConfig configures the controller of the power states of the nodes.
+type PowerManagementConfig struct {
+	// SleepAfter is the duration in seconds for which a node must stay empty before it is put into
+	// sleep, while no pod is pending. Nodes never sleep if 0.
+	SleepAfter int
+	// WakeUpDelay is the duration in seconds after a sleeping node starts to wake up, and before
+	// pods can be bound to it. A node starts to wake up when more pods are pending than nodes are
+	// waking up.
+	WakeUpDelay int
+}
+
+// BuildPowerModel builds node.PowerModel with the given PowerConfig.
+// Returns nil if the power is not modeled, or error if the config is invalid.
+func BuildPowerModel(conf PowerConfig) (*node.PowerModel, error) {
+	if conf.IdleWatts == 0 && conf.PeakWatts == 0 && len(conf.Curve) == 0 {
+		return nil, nil
+	}
+
+	if conf.IdleWatts < 0 || conf.PeakWatts < conf.IdleWatts || conf.SleepWatts < 0 {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("power must be 0 <= idleWatts <= peakWatts and sleepWatts >= 0, but got %+v", conf))
+	}
+	if conf.PUE != 0 && conf.PUE < 1 {
+		return nil, strongerrors.InvalidArgument(errors.Errorf("PUE must be >= 1, but got %v", conf.PUE))
+	}
+
+	model := node.PowerModel{
+		IdleWatts:  conf.IdleWatts,
+		PeakWatts:  conf.PeakWatts,
+		SleepWatts: conf.SleepWatts,
+		PUE:        conf.PUE,
+	}
+	for i, point := range conf.Curve {
+		if point.Utilization < 0 || point.Utilization > 1 || point.Watts < 0 {
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("power curve point must have utilization in [0, 1] and watts >= 0, but got %+v", point))
+		}
+		if i > 0 && point.Utilization <= conf.Curve[i-1].Utilization {
+			return nil, strongerrors.InvalidArgument(errors.New("power curve must be sorted by utilization"))
+		}
+		model.Curve = append(model.Curve, node.PowerPoint{Utilization: point.Utilization, Watts: point.Watts})
+	}
+
+	return &model, nil
+}
+
+// BuildSleepController builds node.SleepController with the given PowerManagementConfig.
+// Returns nil if nodes never sleep, or error if the config is invalid.
+func BuildSleepController(conf PowerManagementConfig) (*node.SleepController, error) {
+	if conf.SleepAfter < 0 || conf.WakeUpDelay < 0 {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("sleepAfter and wakeUpDelay must be >= 0, but got %+v", conf))
+	}
+	if conf.SleepAfter == 0 {
+		return nil, nil
+	}
+
+	return node.NewSleepController(
+		time.Duration(conf.SleepAfter)*time.Second, time.Duration(conf.WakeUpDelay)*time.Second), nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	"k8s.io/kubernetes/pkg/apis/scheduling"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// PriorityClassConfig defines a priority class, to which pods refer by their priorityClassName.
+type PriorityClassConfig struct {
+	Name  string
+	Value int32
+	// GlobalDefault makes the class the default of pods with neither a priority class nor a
+	// priority. At most one class can be the global default.
+	GlobalDefault bool
+	// PreemptionPolicy is "PreemptLowerPriority" (default) or "Never".
+	PreemptionPolicy string
+}
+
+// BuildPriorityClasses builds util.PriorityClasses with the given PriorityClassConfigs, in addition
+// to the system priority classes.
+// Returns error if the config is invalid.
+func BuildPriorityClasses(confs []PriorityClassConfig) (*util.PriorityClasses, error) {
+	classes := make([]util.PriorityClass, 0, len(confs))
+	names := map[string]bool{}
+	globalDefault := ""
+	for _, conf := range confs {
+		if conf.Name == "" {
+			return nil, strongerrors.InvalidArgument(errors.New("priority class name must not be empty"))
+		}
+		if strings.HasPrefix(conf.Name, scheduling.SystemPriorityClassPrefix) {
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("priority class name %q must not start with %q", conf.Name, scheduling.SystemPriorityClassPrefix))
+		}
+		if names[conf.Name] {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("priority class %q is duplicated", conf.Name))
+		}
+		names[conf.Name] = true
+
+		if conf.Value > scheduling.HighestUserDefinablePriority {
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("value of priority class %q must not be greater than %d",
+					conf.Name, scheduling.HighestUserDefinablePriority))
+		}
+
+		if conf.GlobalDefault {
+			if globalDefault != "" {
+				return nil, strongerrors.InvalidArgument(
+					errors.Errorf("priority classes %q and %q are both the global default", globalDefault, conf.Name))
+			}
+			globalDefault = conf.Name
+		}
+
+		switch conf.PreemptionPolicy {
+		case "", util.PreemptLowerPriority, util.PreemptNever:
+		default:
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("preemption policy %q of priority class %q is not supported", conf.PreemptionPolicy, conf.Name))
+		}
+
+		classes = append(classes, util.PriorityClass{
+			Name:             conf.Name,
+			Value:            conf.Value,
+			GlobalDefault:    conf.GlobalDefault,
+			PreemptionPolicy: conf.PreemptionPolicy,
+		})
+	}
+
+	return util.NewPriorityClasses(classes), nil
+}
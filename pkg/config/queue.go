@@ -0,0 +1,179 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+// QueueConfig configures the order of the pending pods, and the pod queue built by BuildQueue.
+type QueueConfig struct {
+	// Type is the kind of the pod queue built by BuildQueue for the kubesim command: "FIFO"
+	// (default), "priority", "DRF", "capacity", or "scheduling". KubeSim uses the queue that it is
+	// given regardless of Type.
+	Type string
+	// Order is the chain of keys by which the pending pods are sorted; a key is compared only if
+	// pods are equal in the preceding keys. The queue is sorted by its own comparator if empty.
+	Order []QueueOrderConfig
+	// AgingRate is the priority added to a pending pod for each second it has waited, which is
+	// compared by the "priority" key.
+	AgingRate float64
+	// TenantLabel is the key of the pod label whose value is the tenant compared by the "fairShare"
+	// key, and of the DRF queue. The namespace is the tenant if empty or missing.
+	TenantLabel string
+	// Weights are the weights of the tenants of the DRF queue. Tenants not in it weigh 1.
+	Weights map[string]float64
+	// Capacity configures the capacity queue.
+	Capacity CapacityQueueConfig
+	// Backoff configures the scheduling queue.
+	Backoff BackoffConfig
+}
+
+type CapacityQueueConfig struct {
+	// QueueLabel is the key of the pod label naming the leaf queue of a pod.
+	QueueLabel string
+	// DefaultQueue is the leaf queue of the pods without the label.
+	DefaultQueue string
+	// Preemption preempts the pods of the queues above their guaranteed capacity.
+	Preemption bool
+	// Root is the root of the tree of queues.
+	Root queue.QueueCapacity
+}
+
+type BackoffConfig struct {
+	// Initial and Max are the backoff of a pod after its first failed attempt and its upper
+	// limit, in seconds.
+	// Optional (default: 1 and 10)
+	Initial float64
+	Max     float64
+	// UnschedulableTimeout is the duration in seconds for which a pod waits for cluster events
+	// after a failed attempt.
+	// Optional (default: 60)
+	UnschedulableTimeout float64
+}
+
+type QueueOrderConfig struct {
+	// Key is one of "priority", "priorityClass", "age", "shortestJob", "smallestRequest",
+	// "largestRequest", and "fairShare".
+	Key string
+	// Resource is the resource compared by "smallestRequest" and "largestRequest".
+	// Optional (default: cpu)
+	Resource string
+	// Annotation is the key of the pod annotation holding the duration compared by "shortestJob".
+	Annotation string
+	// Classes are the priority class names compared by "priorityClass", from the highest.
+	Classes []string
+}
+
+// BuildQueue builds the queue.PodQueue of the Type of the given QueueConfig, sorted by its own
+// comparator; KubeSim applies the order.
+// Returns error if the config is invalid.
+func BuildQueue(conf QueueConfig) (queue.PodQueue, error) {
+	switch strings.ToLower(conf.Type) {
+	case "", "fifo":
+		return queue.NewFIFOQueue(), nil
+	case "priority":
+		return queue.NewPriorityQueue(0), nil
+	case "drf":
+		for tenant, weight := range conf.Weights {
+			if weight <= 0 {
+				return nil, strongerrors.InvalidArgument(
+					errors.Errorf("weight of tenant %q must be > 0, but got %v", tenant, weight))
+			}
+		}
+		return queue.NewDRFQueue(conf.TenantLabel, conf.Weights), nil
+	case "capacity":
+		c := conf.Capacity
+		return queue.NewCapacityQueue(c.Root, c.QueueLabel, c.DefaultQueue, c.Preemption)
+	case "scheduling":
+		b := conf.Backoff
+		if b.Initial < 0 || b.Max < 0 || b.UnschedulableTimeout < 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("backoff must be >= 0"))
+		}
+		return queue.NewSchedulingQueue(
+			secondsOr(b.Initial, queue.DefaultInitialBackoff),
+			secondsOr(b.Max, queue.DefaultMaxBackoff),
+			secondsOr(b.UnschedulableTimeout, queue.DefaultUnschedulableTimeout)), nil
+	default:
+		return nil, strongerrors.InvalidArgument(errors.Errorf("queue type %q is not supported", conf.Type))
+	}
+}
+
+// secondsOr converts the seconds into a duration, or returns the default if 0.
+func secondsOr(seconds float64, def time.Duration) time.Duration {
+	if seconds == 0 {
+		return def
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// BuildQueueOrder builds queue.Order with the given QueueConfig.
+// Returns nil if no order is configured, or error if the config is invalid.
+func BuildQueueOrder(conf QueueConfig) (*queue.Order, error) {
+	if conf.AgingRate < 0 {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("aging rate must be >= 0, but got %v", conf.AgingRate))
+	}
+	if len(conf.Order) == 0 {
+		if conf.AgingRate > 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("aging requires the queue order"))
+		}
+		return nil, nil
+	}
+
+	order := queue.Order{
+		Keys:        make([]queue.OrderKey, 0, len(conf.Order)),
+		AgingRate:   conf.AgingRate,
+		TenantLabel: conf.TenantLabel,
+	}
+
+	for _, key := range conf.Order {
+		orderKey := queue.OrderKey{Name: key.Key}
+
+		switch key.Key {
+		case queue.OrderByPriority, queue.OrderByAge, queue.OrderByFairShare:
+		case queue.OrderByPriorityClass:
+			if len(key.Classes) == 0 {
+				return nil, strongerrors.InvalidArgument(
+					errors.Errorf("queue order %q requires priority classes", key.Key))
+			}
+			orderKey.Classes = key.Classes
+		case queue.OrderByShortestJob:
+			if key.Annotation == "" {
+				return nil, strongerrors.InvalidArgument(
+					errors.Errorf("queue order %q requires an annotation", key.Key))
+			}
+			orderKey.Annotation = key.Annotation
+		case queue.OrderBySmallestRequest, queue.OrderByLargestRequest:
+			orderKey.Resource = v1.ResourceCPU
+			if key.Resource != "" {
+				orderKey.Resource = v1.ResourceName(key.Resource)
+			}
+		default:
+			return nil, strongerrors.InvalidArgument(errors.Errorf("queue order %q is not supported", key.Key))
+		}
+
+		order.Keys = append(order.Keys, orderKey)
+	}
+
+	return &order, nil
+}
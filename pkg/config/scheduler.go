@@ -0,0 +1,161 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/priorities"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/scheduler"
+)
+
+// SchedulerConfig configures the scheduler built by BuildScheduler for the kubesim command.
+type SchedulerConfig struct {
+	// Profile is the set of plugins the scheduler starts with: "default" (GeneralPredicates,
+	// BalancedResourceAllocation and LeastRequested), "bestFit" (PodFitsResources and
+	// MostRequested), "worstFit" (PodFitsResources and LeastRequested), or "overSub"
+	// (PodFitsResourcesOverSub and LeastRequested).
+	// Optional (default: "default")
+	Profile string
+	// Preemption enables the preemption of lower-priority pods.
+	Preemption bool
+	// Predicates replaces the predicates of the profile if not empty.
+	Predicates []string
+	// Prioritizers replaces the prioritizers of the profile if not empty.
+	Prioritizers []PrioritizerConfig
+	// OverSubFactor is the factor by which PodFitsResourcesOverSub oversubscribes the allocatable
+	// resources of every node.
+	// Optional (default: 1)
+	OverSubFactor float64
+}
+
+type PrioritizerConfig struct {
+	Name string
+	// Optional (default: 1)
+	Weight int
+}
+
+// schedulerProfile is the plugins of a scheduler profile.
+type schedulerProfile struct {
+	predicates   []string
+	prioritizers []string
+}
+
+var schedulerProfiles = map[string]schedulerProfile{
+	"default": {
+		predicates:   []string{"GeneralPredicates"},
+		prioritizers: []string{"BalancedResourceAllocation", "LeastRequested"},
+	},
+	"bestfit": {
+		predicates:   []string{"PodFitsResources"},
+		prioritizers: []string{"MostRequested"},
+	},
+	"worstfit": {
+		predicates:   []string{"PodFitsResources"},
+		prioritizers: []string{"LeastRequested"},
+	},
+	"oversub": {
+		predicates:   []string{"PodFitsResourcesOverSub"},
+		prioritizers: []string{"LeastRequested"},
+	},
+}
+
+var fitPredicates = map[string]predicates.FitPredicate{
+	"GeneralPredicates":       predicates.GeneralPredicates,
+	"PodFitsResources":        predicates.PodFitsResources,
+	"PodFitsResourcesOverSub": predicates.PodFitsResourcesOverSub,
+	"PodTopologySpread":       scheduler.PodTopologySpreadPredicate,
+}
+
+var priorityConfigs = map[string]priorities.PriorityConfig{
+	"BalancedResourceAllocation": {Map: priorities.BalancedResourceAllocationMap},
+	"LeastRequested":             {Map: priorities.LeastRequestedPriorityMap},
+	"MostRequested":              {Map: priorities.MostRequestedPriorityMap},
+	"LeastTasksFromSameJob":      {Map: priorities.LeastTasksFromSameJobPriorityMap},
+	"PodTopologySpread": {
+		Map:    scheduler.PodTopologySpreadPriorityMap,
+		Reduce: scheduler.PodTopologySpreadPriorityReduce,
+	},
+}
+
+// BuildScheduler builds a scheduler.GenericScheduler with the plugins of the given
+// SchedulerConfig.
+// Returns error if the config is invalid.
+func BuildScheduler(conf SchedulerConfig) (scheduler.Scheduler, error) {
+	profileName := conf.Profile
+	if profileName == "" {
+		profileName = "default"
+	}
+	profile, ok := schedulerProfiles[strings.ToLower(profileName)]
+	if !ok {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("scheduler profile %q is not supported", conf.Profile))
+	}
+	if conf.OverSubFactor < 0 {
+		return nil, strongerrors.InvalidArgument(
+			errors.Errorf("overSubFactor must be >= 0, but got %v", conf.OverSubFactor))
+	}
+
+	prioritizers := make([]PrioritizerConfig, 0, len(profile.prioritizers))
+	for _, name := range profile.prioritizers {
+		prioritizers = append(prioritizers, PrioritizerConfig{Name: name})
+	}
+	predicateNames := profile.predicates
+	if len(conf.Predicates) > 0 {
+		predicateNames = conf.Predicates
+	}
+	if len(conf.Prioritizers) > 0 {
+		prioritizers = conf.Prioritizers
+	}
+
+	sched := scheduler.NewGenericScheduler(conf.Preemption)
+
+	for _, name := range predicateNames {
+		predicate, ok := fitPredicates[name]
+		if !ok {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("predicate %q is not supported", name))
+		}
+		sched.AddPredicate(name, predicate)
+		if name == "PodTopologySpread" {
+			sched.SetMetadataProducer(scheduler.TopologySpreadMetadata)
+		}
+	}
+
+	for _, p := range prioritizers {
+		prioritizer, ok := priorityConfigs[p.Name]
+		if !ok {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("prioritizer %q is not supported", p.Name))
+		}
+		if p.Weight < 0 {
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("weight of prioritizer %q must be >= 0, but got %d", p.Name, p.Weight))
+		}
+		prioritizer.Name = p.Name
+		prioritizer.Weight = p.Weight
+		if prioritizer.Weight == 0 {
+			prioritizer.Weight = 1
+		}
+		sched.AddPrioritizer(prioritizer)
+		if p.Name == "PodTopologySpread" {
+			sched.SetMetadataProducer(scheduler.TopologySpreadMetadata)
+		}
+	}
+
+	return &sched, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io"
+	"path/filepath"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/submitter"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/submitter/generator"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/trace"
+)
+
+// WorkloadConfig configures the workload submitted by the kubesim command, either generated or
+// replayed from a trace. Relative paths are relative to the directory of the config file given to
+// the command, even if set in an included file.
+type WorkloadConfig struct {
+	// Generator is the path of a generator config (see example/generator.yaml).
+	Generator string
+	// Trace is the path of a task trace in the JSON lines written by `kubesim convert-trace`,
+	// gzipped if its name ends with ".gz".
+	Trace string
+
+	// TimeScale multiplies the arrival times; 0.5 replays the workload twice as fast.
+	// Optional (default: 1)
+	TimeScale float64
+	// LoadScale scales the number of pods; 0.5 sub-samples half the pods and 2 duplicates every
+	// pod.
+	// Optional (default: 1)
+	LoadScale float64
+	// Offset shifts the arrivals later (or earlier if negative), in seconds.
+	Offset float64
+	// Seed of the random number generator used by LoadScale.
+	// Optional (default: the current time)
+	Seed int64
+	// MaxPods terminates the submission after the number of pods.
+	// Optional (default: no limit)
+	MaxPods uint64
+}
+
+// BuildWorkloadSubmitter builds a submitter.TraceSubmitter of the workload configured by the given
+// WorkloadConfig, resolving relative paths against dir. The returned io.Closer closes the trace
+// after the simulation.
+// Returns error if the config is invalid or the workload cannot be loaded.
+func BuildWorkloadSubmitter(conf WorkloadConfig, dir string) (*submitter.TraceSubmitter, io.Closer, error) {
+	if err := validateWorkloadOptions(conf); err != nil {
+		return nil, nil, err
+	}
+
+	var source submitter.TraceSource
+	var closer io.Closer = nopCloser{}
+	if conf.Generator != "" {
+		gen, err := buildGenerator(conf, dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		tasks, err := gen.Generate()
+		if err != nil {
+			return nil, nil, err
+		}
+		source = submitter.NewTaskSliceSource(tasks)
+	} else {
+		file, err := trace.Open(resolvePath(conf.Trace, dir))
+		if err != nil {
+			return nil, nil, err
+		}
+		source = submitter.NewTaskSource(file)
+		closer = file
+	}
+
+	return submitter.NewTraceSubmitter(source, submitter.TraceSubmitterOptions{
+		Offset:    time.Duration(conf.Offset * float64(time.Second)),
+		TimeScale: conf.TimeScale,
+		LoadScale: conf.LoadScale,
+		Seed:      conf.Seed,
+		MaxPods:   conf.MaxPods,
+	}), closer, nil
+}
+
+// ValidateWorkload validates the given WorkloadConfig, resolving relative paths against dir,
+// without generating or reading the workload.
+func ValidateWorkload(conf WorkloadConfig, dir string) error {
+	if err := validateWorkloadOptions(conf); err != nil {
+		return err
+	}
+
+	if conf.Generator != "" {
+		_, err := buildGenerator(conf, dir)
+		return err
+	}
+	file, err := trace.Open(resolvePath(conf.Trace, dir))
+	if err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+func validateWorkloadOptions(conf WorkloadConfig) error {
+	if (conf.Generator == "") == (conf.Trace == "") {
+		return strongerrors.InvalidArgument(errors.New("workload must have either generator or trace, but not both"))
+	}
+	if conf.TimeScale < 0 {
+		return strongerrors.InvalidArgument(
+			errors.Errorf("timeScale must be >= 0, but got %v", conf.TimeScale))
+	}
+	if conf.LoadScale < 0 {
+		return strongerrors.InvalidArgument(
+			errors.Errorf("loadScale must be >= 0, but got %v", conf.LoadScale))
+	}
+	return nil
+}
+
+func buildGenerator(conf WorkloadConfig, dir string) (*generator.Generator, error) {
+	genConf, err := generator.LoadConfig(resolvePath(conf.Generator, dir))
+	if err != nil {
+		return nil, err
+	}
+	return generator.New(*genConf)
+}
+
+// resolvePath resolves the path against dir if it is relative.
+func resolvePath(path, dir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
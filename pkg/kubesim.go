@@ -17,6 +17,8 @@ package kubesim
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"time"
@@ -27,9 +29,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
 
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/admission"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
 	l "github.com/pfnet-research/k8s-cluster-simulator/pkg/log"
@@ -42,6 +44,14 @@ import (
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
 )
 
+const (
+	// maxGenerateNameAttempts is the number of attempts to generate a unique name for a pod from
+	// its generateName.
+	maxGenerateNameAttempts = 8
+	// validationRejection is the key in the metrics of the pods rejected by validation.
+	validationRejection = "Validation"
+)
+
 // KubeSim represents a simulated kubernetes cluster.
 type KubeSim struct {
 	tick  time.Duration
@@ -51,13 +61,44 @@ type KubeSim struct {
 	nodeNames   []string //TanLe fixed randomly list nodes.conf
 	pendingPods queue.PodQueue
 	boundPods   map[string]*pod.Pod
+	// pendingKeys are the keys of the submitted pods that are pending, i.e. neither bound nor
+	// deleted, including those waiting for admission.
+	pendingKeys map[string]struct{}
+	// names generates the names and UIDs of the submitted pods.
+	names *util.NameGenerator
 
 	submitters map[string]submitter.Submitter
 	scheduler  scheduler.Scheduler
 
 	metricsWriters []metrics.Writer
+	withPods       bool
+	resourceModels metrics.ResourceModels
+	interference   *metrics.InterferenceModel
+	queueOrder     *queue.Order
 	metricsTick    time.Duration
 	endClock       clock.Clock
+
+	// sleepController controls the power states of the nodes, or is nil if they never sleep.
+	sleepController *node.SleepController
+	// spotInterrupter interrupts spot nodes, or is nil if they are never interrupted.
+	spotInterrupter *node.SpotInterrupter
+	// priorityClasses resolves the priority classes of the submitted pods.
+	priorityClasses *util.PriorityClasses
+	// admission admits the submitted pods to the queue by the quota of their cluster queues, or is
+	// nil if the pods are queued as soon as submitted.
+	admission *admission.Controller
+	// admissionChain admits or rejects the pods on their submission.
+	admissionChain *admission.Chain
+
+	// podsNum and schedulableNodesNum are the numbers of the bound pods and the schedulable nodes
+	// after the last scheduling, used to detect cluster events for queue.UnschedulableQueue.
+	podsNum             int64
+	schedulableNodesNum int
+
+	lifecycle    *metrics.LifecycleRecorder
+	reportConfig config.ReportConfig
+
+	schedulerMetrics metrics.SchedulerMetrics
 }
 
 // NewKubeSim creates a new KubeSim with the given config, queue, and scheduler.
@@ -93,11 +134,57 @@ func NewKubeSim(
 		metricsTick = conf.MetricsTick
 	}
 
-	metricsWriters, err := buildMetricsWriters(conf)
+	metricsWriters, withPods, err := buildMetricsWriters(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceModels, err := config.BuildResourceModels(conf.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	interference, err := config.BuildInterferenceModel(conf.Interference)
+	if err != nil {
+		return nil, err
+	}
+
+	queueOrder, err := config.BuildQueueOrder(conf.Queue)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyQueueOrder(queue, queueOrder); err != nil {
+		return nil, err
+	}
+
+	sleepController, err := config.BuildSleepController(conf.PowerManagement)
+	if err != nil {
+		return nil, err
+	}
+
+	spotInterrupter, err := config.BuildSpotInterrupter(conf.Spot)
+	if err != nil {
+		return nil, err
+	}
+
+	priorityClasses, err := config.BuildPriorityClasses(conf.PriorityClasses)
+	if err != nil {
+		return nil, err
+	}
+
+	admissionController, err := config.BuildAdmissionController(conf.Admission)
+	if err != nil {
+		return nil, err
+	}
+
+	admissionChain, err := config.BuildAdmissionChain(conf.AdmissionChain)
 	if err != nil {
 		return nil, err
 	}
 
+	lifecycle := metrics.NewLifecycleRecorder()
+	lifecycle.SetCostLabels(conf.Report.CostLabels)
+
 	return &KubeSim{
 		tick:  time.Duration(conf.Tick) * time.Second,
 		clock: clk,
@@ -106,23 +193,80 @@ func NewKubeSim(
 		nodeNames:   nodeNames, //TanLe fixed randomly list nodes.
 		pendingPods: queue,
 		boundPods:   map[string]*pod.Pod{},
+		pendingKeys: map[string]struct{}{},
+		names:       util.NewNameGenerator(0),
 
 		submitters: map[string]submitter.Submitter{},
 		scheduler:  sched,
 
 		metricsTick:    time.Duration(metricsTick) * time.Second,
 		metricsWriters: metricsWriters,
+		withPods:       withPods,
+		resourceModels: resourceModels,
+		interference:   interference,
+		queueOrder:     queueOrder,
 		endClock:       endClock,
+
+		sleepController: sleepController,
+		spotInterrupter: spotInterrupter,
+		priorityClasses: priorityClasses,
+		admission:       admissionController,
+		admissionChain:  admissionChain,
+
+		lifecycle:    lifecycle,
+		reportConfig: conf.Report,
 	}, nil
 }
 
-// NewKubeSimFromConfigPath creates a new KubeSim with config from confPath (excluding file
+// ValidateConfig validates the given config as NewKubeSim does, without configuring logging or
+// creating the metrics files.
+// Returns error if the config is invalid.
+func ValidateConfig(conf *config.Config) error {
+	if _, err := log.ParseLevel(conf.LogLevel); err != nil {
+		return strongerrors.InvalidArgument(
+			errors.Errorf("Log level %q not supported: %s", conf.LogLevel, err.Error()))
+	}
+	if _, err := buildClock(conf.StartClock); err != nil {
+		return err
+	}
+	if _, err := buildCluster(conf); err != nil {
+		return err
+	}
+	if err := config.ValidateMetricsLogger(conf.MetricsLogger); err != nil {
+		return err
+	}
+	if _, err := config.BuildResourceModels(conf.Resources); err != nil {
+		return err
+	}
+	if _, err := config.BuildInterferenceModel(conf.Interference); err != nil {
+		return err
+	}
+	if _, err := config.BuildQueueOrder(conf.Queue); err != nil {
+		return err
+	}
+	if _, err := config.BuildSleepController(conf.PowerManagement); err != nil {
+		return err
+	}
+	if _, err := config.BuildSpotInterrupter(conf.Spot); err != nil {
+		return err
+	}
+	if _, err := config.BuildPriorityClasses(conf.PriorityClasses); err != nil {
+		return err
+	}
+	if _, err := config.BuildAdmissionController(conf.Admission); err != nil {
+		return err
+	}
+	_, err := config.BuildAdmissionChain(conf.AdmissionChain)
+	return err
+}
+
+// NewKubeSimFromConfigPath creates a new KubeSim with config from confPath (with or without file
 // extension), queue, and scheduler.
 // Returns error if the configuration failed.
 func NewKubeSimFromConfigPath(
 	confPath string, queue queue.PodQueue, sched scheduler.Scheduler, endClock clock.Clock) (*KubeSim, error) {
 
-	conf, err := readConfig(confPath)
+	conf, err := ReadConfig(confPath)
 	if err != nil {
 		return nil, errors.Errorf("Error reading config: %s", err.Error())
 	}
@@ -130,8 +274,8 @@ func NewKubeSimFromConfigPath(
 	return NewKubeSim(conf, queue, sched, endClock)
 }
 
-// NewKubeSimFromConfigPathOrDie creates a new KubeSim with config from confPath (excluding file
-// extension), queue, and scheduler.
+// NewKubeSimFromConfigPathOrDie creates a new KubeSim with config from confPath (with or without
+// file extension), queue, and scheduler.
 // If an error occurs during the initialization, it panics and stops the execution.
 func NewKubeSimFromConfigPathOrDie(
 	confPath string, queue queue.PodQueue, sched scheduler.Scheduler, endClock clock.Clock) *KubeSim {
@@ -149,13 +293,25 @@ func (k *KubeSim) AddSubmitter(name string, submitter submitter.Submitter) {
 	k.submitters[name] = submitter
 }
 
+// AddMutatingAdmissionHook adds the mutating hook to the admission chain run on the submission of
+// each pod. Mutating hooks are called in the order that they are added, after LimitRange defaulting.
+func (k *KubeSim) AddMutatingAdmissionHook(name string, hook admission.Hook) {
+	k.admissionChain.AddMutatingHook(name, hook)
+}
+
+// AddValidatingAdmissionHook adds the validating hook to the admission chain run on the submission
+// of each pod. Validating hooks are called in the order that they are added, after LimitRange
+// validation and before ResourceQuota enforcement.
+func (k *KubeSim) AddValidatingAdmissionHook(name string, hook admission.Hook) {
+	k.admissionChain.AddValidatingHook(name, hook)
+}
+
 // Run executes the main loop, which invokes submitters and the scheduler, and binds pods to the
 // selected nodes.
 // This method blocks until ctx is done or this KubeSim finishes processing all pods.
 func (k *KubeSim) Run(ctx context.Context) error {
 	preMetricsClock := k.clock
-	met, err := metrics.BuildMetrics(k.clock, k.nodes, k.pendingPods, scheduler.PredictionPenalty)
-
+	met, err := k.buildMetrics()
 	if err != nil {
 		return err
 	}
@@ -186,10 +342,19 @@ func (k *KubeSim) Run(ctx context.Context) error {
 				log.L.Infof("Simulation is running @ %v", k.clock.ToRFC3339())
 			}
 
+			if err := k.interruptSpotNodes(); err != nil {
+				return err
+			}
+
+			k.releaseResourceQuota()
 			if k.submit(met) != nil {
 				return err
 			}
 
+			if err := k.admit(); err != nil {
+				return err
+			}
+
 			start := time.Now()
 			if k.schedule() != nil {
 				return err
@@ -204,7 +369,7 @@ func (k *KubeSim) Run(ctx context.Context) error {
 
 			// Rebuild metrics every tick for submitters to use.
 			start = time.Now()
-			met, err = metrics.BuildMetrics(k.clock, k.nodes, k.pendingPods, scheduler.PredictionPenalty)
+			met, err = k.buildMetrics()
 			if err != nil {
 				return err
 			}
@@ -217,6 +382,12 @@ func (k *KubeSim) Run(ctx context.Context) error {
 			// run GC manually
 			runtime.GC()
 
+			k.lifecycle.Observe(k.clock, met.Nodes())
+
+			if k.sleepController != nil {
+				k.sleepController.Control(k.clock, k.nodes, k.nodeNames, met.Queue().PendingPodsNum)
+			}
+
 			scheduler.GlobalMetrics = met
 			scheduler.NodeMetricsCache = scheduler.Estimate(k.nodeNames)
 			start = time.Now()
@@ -241,38 +412,78 @@ func (k *KubeSim) Run(ctx context.Context) error {
 	lapseKube := time.Since(startKube)
 	scheduler.TimingMap["kubesim"] = lapseKube.Microseconds()
 
+	// Observe the pods terminated in the last tick, which the loop exited without observing.
+	met, err = k.buildMetrics()
+	if err != nil {
+		return err
+	}
+	k.lifecycle.Observe(k.clock, met.Nodes())
+
 	log.L.Infof("TimingMap : %v", scheduler.TimingMap)
 
-	return nil
+	return k.writeReport()
 }
 
 // List implements "k8s.io/pkg/scheduler/algorithm".NodeLister interface.
+// Nodes asleep or waking up are not listed.
 // Never returns an error.
 func (k *KubeSim) List() ([]*v1.Node, error) {
 	//TanLe fixed randomly list nodes
 	nodes := make([]*v1.Node, 0, len(k.nodes))
 	for _, name := range k.nodeNames {
-		nodes = append(nodes, k.nodes[name].ToV1())
+		if k.nodes[name].IsSchedulable(k.clock) {
+			nodes = append(nodes, k.nodes[name].ToV1())
+		}
 	}
 	return nodes, nil
 }
 
-// readConfig reads and parses a config from the path (excluding file extension).
-func readConfig(path string) (*config.Config, error) {
-	viper.SetConfigName(path)
-	viper.AddConfigPath(".")
+// applyQueueOrder sorts the queue by the order, if any.
+// Returns error if the queue cannot be sorted.
+func applyQueueOrder(q queue.PodQueue, order *queue.Order) error {
+	if order == nil {
+		return nil
+	}
+
+	ordered, ok := q.(queue.OrderedQueue)
+	if !ok {
+		return strongerrors.InvalidArgument(errors.Errorf("queue %T does not support ordering", q))
+	}
+	ordered.SetComparator(order.Compare)
+
+	return nil
+}
+
+// ReadConfig reads and parses a config from the path of the config file, with or without its file
+// extension.
+func ReadConfig(path string) (*config.Config, error) {
+	file := viper.New()
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		file.SetConfigFile(path)
+	} else {
+		file.SetConfigName(filepath.Base(path))
+		file.AddConfigPath(filepath.Dir(path))
+	}
 
-	if err := viper.ReadInConfig(); err != nil {
+	if err := file.ReadInConfig(); err != nil {
 		return nil, err
 	}
-	log.G(context.TODO()).Debugf("Config file %s", viper.ConfigFileUsed())
+	log.G(context.TODO()).Debugf("Config file %s", file.ConfigFileUsed())
 
 	var conf = config.Config{
 		LogLevel: "info",
 		Tick:     10,
 	}
 
-	if err := viper.Unmarshal(&conf); err != nil {
+	settings, err := config.Include(file.AllSettings(), filepath.Dir(file.ConfigFileUsed()))
+	if err != nil {
+		return nil, err
+	}
+	v := viper.New()
+	if err := v.MergeConfigMap(settings); err != nil {
+		return nil, err
+	}
+	if err := v.Unmarshal(&conf); err != nil {
 		return nil, err
 	}
 
@@ -308,14 +519,38 @@ func buildClock(startClock string) (clock.Clock, error) {
 }
 
 func buildCluster(conf *config.Config) (map[string]*node.Node, error) {
+	nodeConfs := append([]config.NodeConfig{}, conf.Cluster...)
+	for _, group := range conf.NodeGroups {
+		groupConfs, err := config.ExpandNodeGroup(group)
+		if err != nil {
+			return nil, err
+		}
+		nodeConfs = append(nodeConfs, groupConfs...)
+	}
+
 	nodes := map[string]*node.Node{}
-	for _, nodeConf := range conf.Cluster {
+	for _, nodeConf := range nodeConfs {
 		nodeV1, err := config.BuildNode(nodeConf, conf.StartClock)
 		if err != nil {
 			return nil, err
 		}
+		if _, ok := nodes[nodeV1.Name]; ok {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("node %q is duplicated", nodeV1.Name))
+		}
+
+		power, err := config.BuildPowerModel(nodeConf.Power)
+		if err != nil {
+			return nil, errors.Wrapf(err, "power of node %q", nodeV1.Name)
+		}
+
+		cost, err := config.BuildCostModel(nodeConf.Cost)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cost of node %q", nodeV1.Name)
+		}
 
 		nodeSim := node.NewNode(nodeV1)
+		nodeSim.SetPowerModel(power)
+		nodeSim.SetCostModel(cost)
 		nodes[nodeV1.Name] = &nodeSim
 
 		log.L.Debugf("Node %s created: %v", nodeV1.Name, nodeV1)
@@ -324,27 +559,35 @@ func buildCluster(conf *config.Config) (map[string]*node.Node, error) {
 	return nodes, nil
 }
 
-func buildMetricsWriters(conf *config.Config) ([]metrics.Writer, error) {
+// buildMetricsWriters builds metrics writers with the given config, and returns whether any of them
+// writes pod metrics.
+func buildMetricsWriters(conf *config.Config) ([]metrics.Writer, bool, error) {
 	writers := []metrics.Writer{}
+	withPods := false
 
 	fileWriters, err := config.BuildMetricsLogger(conf.MetricsLogger)
 	if err != nil {
-		return []metrics.Writer{}, err
+		return []metrics.Writer{}, false, err
 	}
 
 	for _, writer := range fileWriters {
 		log.L.Infof("Metrics and log written to %s", writer.FileName())
 		writers = append(writers, writer)
+		withPods = withPods || writer.WantsPods()
 	}
 
-	return writers, nil
+	return writers, withPods, nil
 }
 
 // toTerminate determines whether the main loop of this KubeSim can be terminated,
 // because all submitters are terminated, no pods are running on the cluster, and there are no
-// pending pods in the queue.
+// pending pods in the queue or waiting for admission.
 func (k *KubeSim) toTerminate(submitterAddedEver bool) bool {
-	if _, err := k.pendingPods.Front(); err == queue.ErrEmptyQueue { // queue is empty
+	if k.admission != nil && k.admission.PendingWorkloadsNum() > 0 {
+		return false
+	}
+
+	if k.queueIsEmpty() {
 		for _, node := range k.nodes { // cluster is empty
 			if node.PodsNum(k.clock) > 0 {
 				return false
@@ -359,6 +602,19 @@ func (k *KubeSim) toTerminate(submitterAddedEver bool) bool {
 	return false
 }
 
+// queueIsEmpty returns true if no pod is pending, including the pods set aside by a
+// queue.UnschedulableQueue.
+func (k *KubeSim) queueIsEmpty() bool {
+	if _, ok := k.pendingPods.(queue.UnschedulableQueue); ok {
+		if lister, ok := k.pendingPods.(queue.PendingPodLister); ok {
+			return len(lister.PendingPods()) == 0
+		}
+	}
+
+	_, err := k.pendingPods.Front()
+	return err == queue.ErrEmptyQueue
+}
+
 func (k *KubeSim) submit(metrics metrics.Metrics) error {
 	for name, subm := range k.submitters {
 		events, err := subm.Submit(k.clock, k, metrics)
@@ -369,30 +625,52 @@ func (k *KubeSim) submit(metrics metrics.Metrics) error {
 		for _, e := range events {
 			if submitted, ok := e.(*submitter.SubmitEvent); ok {
 				pod := submitted.Pod
-				pod.UID = types.UID(pod.Name) // FIXME
+				if err := k.validate(pod); err != nil {
+					k.reject(name, subm, pod, err)
+					continue
+				}
+				pod.UID = k.names.UID()
 				pod.CreationTimestamp = k.clock.ToMetaV1()
 				pod.Status.Phase = v1.PodPending
+				if err := k.priorityClasses.Resolve(pod); err != nil {
+					k.reject(name, subm, pod, err)
+					continue
+				}
+				if err := k.admissionChain.Admit(k.clock, pod); err != nil {
+					k.reject(name, subm, pod, err)
+					continue
+				}
 
 				log.L.Tracef("Submitter %s: Submit %v", name, pod)
 
+				key := util.PodKeyFromNames(pod.Namespace, pod.Name)
 				if l.IsDebugEnabled() {
-					key, err := util.PodKey(pod)
-					if err != nil {
-						return err
-					}
 					log.L.Debugf("Submitter %s: Submit %s", name, key)
 				}
 
-				err := k.pendingPods.Push(pod)
-				if err != nil {
+				if err := k.enqueue(pod); err != nil {
 					return err
 				}
+				k.pendingKeys[key] = struct{}{}
+
+				if handler, ok := subm.(submitter.AcceptanceHandler); ok {
+					handler.Accepted(k.clock, pod)
+				}
 			} else if del, ok := e.(*submitter.DeleteEvent); ok {
-				log.L.Debugf("Submitter %s: Delete %s",
-					name, util.PodKeyFromNames(del.PodNamespace, del.PodName))
+				key := util.PodKeyFromNames(del.PodNamespace, del.PodName)
+				log.L.Debugf("Submitter %s: Delete %s", name, key)
+
+				if _, ok := k.pendingKeys[key]; ok {
+					delete(k.pendingKeys, key)
+					k.admissionChain.Release(del.PodNamespace, del.PodName)
+				}
 
-				if delFromQ := k.pendingPods.Delete(del.PodNamespace, del.PodName); !delFromQ {
+				if k.admission != nil && k.admission.Delete(del.PodNamespace, del.PodName) {
+					k.lifecycle.RecordDelete(k.clock, del.PodNamespace, del.PodName)
+				} else if delFromQ := k.pendingPods.Delete(del.PodNamespace, del.PodName); !delFromQ {
 					k.deletePodFromNode(del.PodNamespace, del.PodName)
+				} else {
+					k.lifecycle.RecordDelete(k.clock, del.PodNamespace, del.PodName)
 				}
 			} else if up, ok := e.(*submitter.UpdateEvent); ok {
 				log.L.Tracef("Submitter %s: Update %s to %v",
@@ -419,10 +697,133 @@ func (k *KubeSim) submit(metrics metrics.Metrics) error {
 	return nil
 }
 
+// validate validates the submitted pod, expanding its generateName into a unique name.
+// Returns error if the pod lacks its namespace or name, another pending or running pod has the
+// same name, or the execution phases of the pod are invalid.
+func (k *KubeSim) validate(v1Pod *v1.Pod) error {
+	if v1Pod.Name == "" && v1Pod.GenerateName != "" {
+		for i := 0; i < maxGenerateNameAttempts && v1Pod.Name == ""; i++ {
+			if name := k.names.GenerateName(v1Pod.GenerateName); !k.podExists(util.PodKeyFromNames(v1Pod.Namespace, name)) {
+				v1Pod.Name = name
+			}
+		}
+		if v1Pod.Name == "" {
+			return strongerrors.AlreadyExists(
+				errors.Errorf("failed to generate a unique name from %q", v1Pod.GenerateName))
+		}
+	}
+
+	key, err := util.PodKey(v1Pod)
+	if err != nil {
+		return err
+	}
+	if k.podExists(key) {
+		return strongerrors.AlreadyExists(errors.Errorf("pod %s already exists", key))
+	}
+
+	return pod.Validate(v1Pod)
+}
+
+// podExists returns true if the pod with the key is pending, running, or terminating.
+func (k *KubeSim) podExists(key string) bool {
+	if _, ok := k.pendingKeys[key]; ok {
+		return true
+	}
+	bound, ok := k.boundPods[key]
+	return ok && (bound.IsRunning(k.clock) || bound.IsTerminating(k.clock))
+}
+
+// enqueue submits the pod to its cluster queue if it needs admission, or pushes it to the pending
+// queue otherwise.
+func (k *KubeSim) enqueue(pod *v1.Pod) error {
+	if k.admission != nil {
+		if clusterQueue, ok := k.admission.Submit(k.clock, pod); ok {
+			k.lifecycle.RecordSubmit(k.clock, pod)
+			k.lifecycle.RecordEnqueue(pod.Namespace, pod.Name, clusterQueue)
+			return nil
+		}
+	}
+
+	if err := k.pendingPods.Push(pod); err != nil {
+		return err
+	}
+	k.lifecycle.RecordSubmit(k.clock, pod)
+	return nil
+}
+
+// reject counts the pod rejected on submission, and reports it to the submitter if it implements
+// submitter.RejectionHandler.
+func (k *KubeSim) reject(name string, subm submitter.Submitter, pod *v1.Pod, reason error) {
+	log.L.Debugf("Submitter %s: Pod %s/%s rejected: %s", name, pod.Namespace, pod.Name, reason.Error())
+
+	plugin := validationRejection
+	if rej, ok := reason.(*admission.RejectionError); ok {
+		plugin = rej.Plugin
+	}
+	k.schedulerMetrics.RejectedPodsNum++
+	if k.schedulerMetrics.Rejections == nil {
+		k.schedulerMetrics.Rejections = map[string]int{}
+	}
+	k.schedulerMetrics.Rejections[plugin]++
+	k.lifecycle.RecordReject()
+
+	if handler, ok := subm.(submitter.RejectionHandler); ok {
+		handler.Rejected(k.clock, pod, reason)
+	}
+}
+
+// releaseResourceQuota releases the ResourceQuota usage charged for the bound pods that have
+// terminated.
+func (k *KubeSim) releaseResourceQuota() {
+	for _, key := range k.admissionChain.Charged() {
+		if pod, ok := k.boundPods[key]; ok && !pod.IsRunning(k.clock) && !pod.IsTerminating(k.clock) {
+			v1Pod := pod.ToV1()
+			k.admissionChain.Release(v1Pod.Namespace, v1Pod.Name)
+		}
+	}
+}
+
+// admit releases the quota of the admitted pods that have terminated, and pushes the pods admitted
+// by their cluster queues to the pending queue. The pods preempted to make room for them are
+// removed from the pending queue or evicted from their nodes, and wait for admission again.
+func (k *KubeSim) admit() error {
+	if k.admission == nil {
+		return nil
+	}
+
+	for _, key := range k.admission.Admitted() {
+		if pod, ok := k.boundPods[key]; ok && !pod.IsRunning(k.clock) && !pod.IsTerminating(k.clock) {
+			k.admission.Finish(key)
+		}
+	}
+
+	admitted, preempted := k.admission.Admit(k.clock)
+
+	for _, pod := range preempted {
+		if !k.pendingPods.Delete(pod.Namespace, pod.Name) {
+			k.evictPod(pod.Namespace, pod.Name)
+		}
+		k.lifecycle.RecordPreempt(k.clock, pod.Namespace, pod.Name)
+		log.L.Debugf("Pod %s/%s: preempted", pod.Namespace, pod.Name)
+	}
+
+	for _, pod := range admitted {
+		if err := k.pendingPods.Push(pod); err != nil {
+			return err
+		}
+		k.lifecycle.RecordAdmit(k.clock, pod.Namespace, pod.Name)
+	}
+
+	return nil
+}
+
 func (k *KubeSim) schedule() error {
-	// Build up-to-date NodeInfo.
+	// Build up-to-date NodeInfo of the nodes to which pods can be bound.
 	nodeInfoMap := make(map[string]*nodeinfo.NodeInfo, len(k.nodes))
 	for name, node := range k.nodes {
+		if !node.IsSchedulable(k.clock) {
+			continue
+		}
 		info, err := node.ToNodeInfo(k.clock)
 		if err != nil {
 			return err
@@ -430,6 +831,21 @@ func (k *KubeSim) schedule() error {
 		nodeInfoMap[name] = info
 	}
 
+	// Queues aware of the cluster (e.g., DRFQueue) order pods by the up-to-date state.
+	k.observeCluster()
+
+	if err := k.reclaim(); err != nil {
+		return err
+	}
+
+	uq, setAside := k.pendingPods.(queue.UnschedulableQueue)
+	if setAside {
+		if k.clusterEventOccurred() {
+			uq.MoveAllToActive(k.clock)
+		}
+		uq.Flush(k.clock)
+	}
+
 	// The scheduler makes scheduling decision.
 	events, err := k.scheduler.Schedule(k.clock, k.pendingPods, k, nodeInfoMap)
 	if err != nil {
@@ -446,26 +862,188 @@ func (k *KubeSim) schedule() error {
 			}
 			bind.Pod.Spec.NodeName = nodeName
 
-			pod, err := node.BindPod(k.clock, bind.Pod)
+			key, err := util.PodKey(bind.Pod)
 			if err != nil {
 				return err
 			}
+			delete(k.pendingKeys, key)
 
-			key, err := util.PodKey(bind.Pod)
+			pod, err := node.BindPod(k.clock, bind.Pod)
 			if err != nil {
-				return err
+				k.dropUnbindablePod(bind.Pod, err)
+				continue
 			}
 			k.boundPods[key] = pod
+			k.lifecycle.RecordBind(k.clock, pod)
+			k.schedulerMetrics.BoundPodsNum++
 		} else if del, ok := e.(*scheduler.DeleteEvent); ok {
 			k.deletePodFromNode(del.PodNamespace, del.PodName)
+			k.schedulerMetrics.DeletedPodsNum++
+		} else if failed, ok := e.(*scheduler.FailedSchedulingEvent); ok {
+			k.lifecycle.RecordAttempt(k.clock, failed.Pod)
+			k.schedulerMetrics.FailedAttemptsNum++
 		} else {
 			log.L.Panic("Unknown scheduler event")
 		}
 	}
 
+	if setAside {
+		k.podsNum, k.schedulableNodesNum = k.countPodsAndSchedulableNodes()
+	}
+
 	return nil
 }
 
+// dropUnbindablePod drops the pod that failed to be bound to the node selected by the scheduler,
+// as if it were deleted, so that the simulation goes on.
+func (k *KubeSim) dropUnbindablePod(pod *v1.Pod, err error) {
+	log.L.Warnf("Pod %s/%s: failed to bind to node %s; dropped: %s",
+		pod.Namespace, pod.Name, pod.Spec.NodeName, err.Error())
+
+	k.schedulerMetrics.FailedBindsNum++
+	k.lifecycle.RecordDelete(k.clock, pod.Namespace, pod.Name)
+	k.releaseQuota(pod.Namespace, pod.Name)
+}
+
+// releaseQuota releases the ResourceQuota charge and the admitted quota of the pod.
+func (k *KubeSim) releaseQuota(podNamespace, podName string) {
+	k.admissionChain.Release(podNamespace, podName)
+	if k.admission != nil {
+		k.admission.Finish(util.PodKeyFromNames(podNamespace, podName))
+	}
+}
+
+// reclaim evicts the bound pods preempted by the queue if it implements queue.Reclaimer, and pushes
+// them back to the queue.
+func (k *KubeSim) reclaim() error {
+	reclaimer, ok := k.pendingPods.(queue.Reclaimer)
+	if !ok {
+		return nil
+	}
+
+	victims := reclaimer.Reclaim()
+	for _, victim := range victims {
+		evicted := k.evictPod(victim.Namespace, victim.Name)
+		if evicted == nil {
+			continue
+		}
+		if err := k.pendingPods.Push(evicted); err != nil {
+			return err
+		}
+		log.L.Debugf("Pod %s/%s: preempted to reclaim capacity", victim.Namespace, victim.Name)
+	}
+
+	// The queue charges the evicted pods until it observes the cluster again.
+	if len(victims) > 0 {
+		k.observeCluster()
+	}
+
+	return nil
+}
+
+// evictPod starts deleting the bound pod from its node, and returns a copy of the pod to be pending
+// again. Returns nil if the pod is not bound.
+func (k *KubeSim) evictPod(podNamespace, podName string) *v1.Pod {
+	key := util.PodKeyFromNames(podNamespace, podName)
+	bound, ok := k.boundPods[key]
+	if !ok {
+		return nil
+	}
+
+	k.nodes[bound.NodeName()].DeletePod(k.clock, podNamespace, podName)
+	delete(k.boundPods, key)
+	k.pendingKeys[key] = struct{}{}
+	k.lifecycle.RecordEvict(k.clock, podNamespace, podName)
+	k.schedulerMetrics.EvictedPodsNum++
+
+	evicted := bound.ToV1().DeepCopy()
+	evicted.Spec.NodeName = ""
+	evicted.DeletionTimestamp = nil
+	evicted.Status = v1.PodStatus{Phase: v1.PodPending}
+
+	return evicted
+}
+
+// clusterEventOccurred returns true if any bound pod has been terminated, deleted, or evicted, or
+// any node has become schedulable since the last scheduling.
+func (k *KubeSim) clusterEventOccurred() bool {
+	podsNum, schedulableNodesNum := k.countPodsAndSchedulableNodes()
+	return podsNum < k.podsNum || schedulableNodesNum > k.schedulableNodesNum
+}
+
+// countPodsAndSchedulableNodes returns the number of the running or terminating pods and the
+// number of the schedulable nodes at the current clock.
+func (k *KubeSim) countPodsAndSchedulableNodes() (int64, int) {
+	podsNum := int64(0)
+	schedulableNodesNum := 0
+	for _, node := range k.nodes {
+		podsNum += node.PodsNum(k.clock)
+		if node.IsSchedulable(k.clock) {
+			schedulableNodesNum++
+		}
+	}
+
+	return podsNum, schedulableNodesNum
+}
+
+// buildMetrics builds the metrics of the cluster, the queue, and the scheduler at the current clock.
+func (k *KubeSim) buildMetrics() (metrics.Metrics, error) {
+	k.observeCluster()
+	met, err := metrics.BuildMetrics(k.clock, k.nodes, k.pendingPods, scheduler.PredictionPenalty, k.resourceModels, k.interference, k.withPods)
+	if err != nil {
+		return nil, err
+	}
+
+	schedMet := k.schedulerMetrics
+	schedMet.Timing = make(map[string]int64, len(scheduler.TimingMap))
+	for key, lapse := range scheduler.TimingMap {
+		schedMet.Timing[key] = lapse
+	}
+	met[metrics.SchedulerMetricsKey] = schedMet
+
+	if k.admission != nil {
+		met[metrics.AdmissionMetricsKey] = k.admission.Metrics()
+	}
+
+	return met, nil
+}
+
+// observeCluster lets the queue and its order observe the cluster if they implement
+// queue.ClusterObserver interface.
+func (k *KubeSim) observeCluster() {
+	observers := []queue.ClusterObserver{}
+	if observer, ok := k.pendingPods.(queue.ClusterObserver); ok {
+		observers = append(observers, observer)
+	}
+	if k.queueOrder != nil && k.queueOrder.ObservesCluster() {
+		observers = append(observers, k.queueOrder)
+	}
+	if len(observers) == 0 {
+		return
+	}
+
+	allocatable := v1.ResourceList{}
+	boundPods := []*v1.Pod{}
+	for _, name := range k.nodeNames {
+		node := k.nodes[name]
+		allocatable = util.ResourceListSum(allocatable, node.ToV1().Status.Allocatable)
+		for _, pod := range node.PodList() {
+			if pod.IsRunning(k.clock) || pod.IsTerminating(k.clock) {
+				boundPods = append(boundPods, pod.ToV1())
+			}
+		}
+	}
+
+	for _, observer := range observers {
+		observer.ObserveCluster(allocatable, boundPods)
+	}
+
+	// The order may have changed with the shares of the tenants.
+	if k.queueOrder != nil && k.queueOrder.ObservesCluster() {
+		k.pendingPods.(queue.OrderedQueue).SetComparator(k.queueOrder.Compare)
+	}
+}
+
 func (k *KubeSim) writeMetrics(met *metrics.Metrics) error {
 	for _, writer := range k.metricsWriters {
 		if err := writer.Write(met); err != nil {
@@ -476,6 +1054,84 @@ func (k *KubeSim) writeMetrics(met *metrics.Metrics) error {
 	return nil
 }
 
+// writeReport writes the KPI report and the lifecycle records of all pods to the configured
+// destinations, if any.
+func (k *KubeSim) writeReport() error {
+	if k.reportConfig.Dest != "" {
+		if err := k.lifecycle.WriteReport(k.clock, k.reportConfig.Dest); err != nil {
+			return err
+		}
+		log.L.Infof("KPI report written to %s", k.reportConfig.Dest)
+	}
+
+	if k.reportConfig.RecordsDest != "" {
+		if err := k.lifecycle.WriteRecords(k.reportConfig.RecordsDest); err != nil {
+			return err
+		}
+		log.L.Infof("Pod lifecycle records written to %s", k.reportConfig.RecordsDest)
+	}
+
+	return nil
+}
+
+// interruptSpotNodes interrupts the spot nodes to be interrupted at the clock, and removes the
+// interrupted nodes whose notice period has passed.
+func (k *KubeSim) interruptSpotNodes() error {
+	if k.spotInterrupter != nil {
+		for _, name := range k.spotInterrupter.Interrupt(k.clock, k.tick, k.nodes, k.nodeNames) {
+			log.L.Infof("Node %s: interrupted", name)
+		}
+	}
+
+	names := make([]string, 0, len(k.nodeNames))
+	for _, name := range k.nodeNames {
+		if interrupted, removeAt := k.nodes[name].IsInterrupted(); interrupted && !k.clock.Before(removeAt) {
+			if err := k.removeNode(name); err != nil {
+				return err
+			}
+			continue
+		}
+		names = append(names, name)
+	}
+	k.nodeNames = names
+
+	return nil
+}
+
+// removeNode removes the node from the cluster, and evicts the running pods on it to the pending
+// queue.
+func (k *KubeSim) removeNode(name string) error {
+	for _, simPod := range k.nodes[name].PodList() {
+		if !simPod.IsRunning(k.clock) {
+			continue
+		}
+
+		v1Pod := simPod.ToV1()
+		evicted := k.evictPod(v1Pod.Namespace, v1Pod.Name)
+		if evicted == nil {
+			continue
+		}
+		if err := k.pendingPods.Push(evicted); err != nil {
+			return err
+		}
+		log.L.Debugf("Node %s: Pod %s/%s evicted", name, v1Pod.Namespace, v1Pod.Name)
+	}
+
+	// The terminated and terminating pods left on the node release their quota, which is released
+	// only while they are bound otherwise.
+	for key, simPod := range k.boundPods {
+		if simPod.NodeName() == name {
+			v1Pod := simPod.ToV1()
+			k.releaseQuota(v1Pod.Namespace, v1Pod.Name)
+			delete(k.boundPods, key)
+		}
+	}
+	delete(k.nodes, name)
+	log.L.Infof("Node %s: removed", name)
+
+	return nil
+}
+
 func (k *KubeSim) gcTerminatedPodsInNodes() {
 	for _, node := range k.nodes {
 		node.GCTerminatedPods(k.clock)
@@ -490,6 +1146,7 @@ func (k *KubeSim) deletePodFromNode(podNamespace, podName string) {
 		return
 	}
 	k.boundPods[key].Delete(k.clock)
+	k.lifecycle.RecordDelete(k.clock, podNamespace, podName)
 
 	nodeName := k.boundPods[key].ToV1().Spec.NodeName
 	deletedFromNode := k.nodes[nodeName].DeletePod(k.clock, podNamespace, podName) // nolint
@@ -0,0 +1,199 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubesim
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/submitter"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/trace"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		LogLevel:   "error",
+		Tick:       10,
+		StartClock: "2019-01-01T00:00:00Z",
+		Cluster: []config.NodeConfig{{
+			Metadata: metav1.ObjectMeta{Name: "node-0"},
+			Status: config.NodeStatus{
+				Allocatable: map[v1.ResourceName]string{"cpu": "2", "memory": "4Gi", "pods": "10"},
+			},
+		}},
+	}
+}
+
+func newTestKubeSim(t *testing.T, conf *config.Config, tasks []*trace.Task) *KubeSim {
+	sched, err := config.BuildScheduler(config.SchedulerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	endClock, err := config.BuildEndClock("", conf.StartClock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := NewKubeSim(conf, queue.NewFIFOQueue(), sched, endClock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.AddSubmitter("Trace", submitter.NewTraceSubmitter(
+		submitter.NewTaskSliceSource(tasks), submitter.TraceSubmitterOptions{Seed: 1}))
+	return k
+}
+
+// podsSubmitter submits the pods at the first call of Submit.
+type podsSubmitter struct {
+	pods []*v1.Pod
+}
+
+func (s *podsSubmitter) Submit(
+	clock clock.Clock,
+	nodeLister algorithm.NodeLister,
+	metrics metrics.Metrics) ([]submitter.Event, error) {
+
+	events := make([]submitter.Event, 0, len(s.pods))
+	for _, pod := range s.pods {
+		events = append(events, &submitter.SubmitEvent{Pod: pod})
+	}
+	s.pods = nil
+	return events, nil
+}
+
+func TestRunRecordsEveryPodTerminated(t *testing.T) {
+	tasks := []*trace.Task{}
+	for i := 0; i < 8; i++ {
+		cpu := resource.MustParse("1")
+		tasks = append(tasks, &trace.Task{
+			ID:      fmt.Sprintf("task-%d", i),
+			Arrival: int64(i * 5),
+			Request: v1.ResourceList{v1.ResourceCPU: cpu},
+			// The last pods finish in the last tick of the simulation.
+			Phases: []trace.Phase{{Seconds: int32(15 + i*7), Usage: v1.ResourceList{v1.ResourceCPU: cpu}}},
+		})
+	}
+	k := newTestKubeSim(t, newTestConfig(), tasks)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := k.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	records := k.lifecycle.Records()
+	if len(records) != len(tasks) {
+		t.Fatalf("got: %d records\nwant: %d", len(records), len(tasks))
+	}
+	for _, record := range records {
+		terminated := 0
+		for _, at := range []*clock.Clock{record.FinishedAt, record.KilledAt, record.DeletedAt} {
+			if at != nil {
+				terminated++
+			}
+		}
+		if terminated != 1 {
+			t.Errorf("got: %d terminal states of pod %s\nwant: 1", terminated, record.Name)
+		}
+	}
+
+	report := k.lifecycle.BuildReport(k.clock)
+	expected := metrics.Report{PodsNum: len(tasks), BoundPodsNum: len(tasks), FinishedPodsNum: len(tasks)}
+	if report.PodsNum != expected.PodsNum || report.BoundPodsNum != expected.BoundPodsNum ||
+		report.FinishedPodsNum != expected.FinishedPodsNum {
+		t.Errorf("got: %d pods, %d bound, %d finished\nwant: %d pods, %d bound, %d finished",
+			report.PodsNum, report.BoundPodsNum, report.FinishedPodsNum,
+			expected.PodsNum, expected.BoundPodsNum, expected.FinishedPodsNum)
+	}
+}
+
+func TestRemoveNodeReleasesQuota(t *testing.T) {
+	conf := newTestConfig()
+	conf.AdmissionChain.ResourceQuotas = []config.ResourceQuotaConfig{{Namespace: "default", Pods: "10"}}
+	k := newTestKubeSim(t, conf, nil)
+
+	newPod := func(name string, seconds int) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        name,
+				Annotations: map[string]string{"simSpec": fmt.Sprintf("- seconds: %d\n  resourceUsage: {cpu: 1}\n", seconds)},
+			},
+			Spec: v1.PodSpec{Containers: []v1.Container{{Name: "main"}}},
+		}
+	}
+	for _, v1Pod := range []*v1.Pod{newPod("finished", 10), newPod("running", 100)} {
+		if err := k.admissionChain.Admit(k.clock, v1Pod); err != nil {
+			t.Fatal(err)
+		}
+		simPod, err := k.nodes["node-0"].BindPod(k.clock, v1Pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k.boundPods[util.PodKeyFromNames(v1Pod.Namespace, v1Pod.Name)] = simPod
+	}
+
+	k.clock = k.clock.Add(30 * time.Second)
+	if err := k.removeNode("node-0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The running pod is pending again and stays charged.
+	if expected := []string{"default/running"}; !reflect.DeepEqual(k.admissionChain.Charged(), expected) {
+		t.Errorf("got: %v charged\nwant: %v", k.admissionChain.Charged(), expected)
+	}
+	if pending, _ := k.pendingPods.Front(); pending == nil || pending.Name != "running" {
+		t.Errorf("got: %v pending\nwant: running pod pending", pending)
+	}
+	if len(k.boundPods) != 0 || len(k.nodes) != 0 {
+		t.Errorf("got: %d bound pods on %d nodes\nwant: none", len(k.boundPods), len(k.nodes))
+	}
+}
+
+func TestSubmitRejectsUnknownPriorityClass(t *testing.T) {
+	k := newTestKubeSim(t, newTestConfig(), nil)
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "pod",
+			Annotations: map[string]string{"simSpec": "- seconds: 10\n  resourceUsage: {cpu: 1}\n"},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{Name: "main"}}, PriorityClassName: "unknown"},
+	}
+	k.AddSubmitter("Pods", &podsSubmitter{pods: []*v1.Pod{pod}})
+
+	if err := k.submit(metrics.Metrics{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if pending, _ := k.pendingPods.Front(); pending != nil {
+		t.Errorf("got: %s/%s pending\nwant: none", pending.Namespace, pending.Name)
+	}
+	if expected := map[string]int{validationRejection: 1}; !reflect.DeepEqual(k.schedulerMetrics.Rejections, expected) {
+		t.Errorf("got: %v\nwant: %v", k.schedulerMetrics.Rejections, expected)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// maxShapesNum is the maximum number of request shapes of which fragmentation is reported.
+const maxShapesNum = 10
+
+// ClusterMetrics is a metrics of the whole cluster at one time point, aggregated from the metrics
+// of all nodes.
+type ClusterMetrics struct {
+	Allocatable      v1.ResourceList
+	FreeResource     v1.ResourceList
+	StrandedResource v1.ResourceList
+	// StrandedRatio is the ratio of the stranded resource to the allocatable resource.
+	StrandedRatio map[v1.ResourceName]float64
+
+	// Fragmentation is reported for the most common request shapes of pending pods.
+	Fragmentation []ShapeFragmentation
+
+	// Topology maps each topology key that labels any node to how the running pods are spread
+	// among its domains.
+	Topology map[string]TopologyMetrics `json:",omitempty"`
+
+	// SleepingNodesNum is the number of nodes asleep or waking up.
+	SleepingNodesNum int `json:",omitempty"`
+	// Power, Energy, and FacilityEnergy are the sums of those of the nodes.
+	Power          float64 `json:",omitempty"`
+	Energy         float64 `json:",omitempty"`
+	FacilityEnergy float64 `json:",omitempty"`
+}
+
+// ShapeFragmentation represents how fragmented the free resource of the cluster is for pods of one
+// request shape.
+type ShapeFragmentation struct {
+	Shape          v1.ResourceList
+	PendingPodsNum int
+	// PlaceableNum is the number of pods of this shape that can be placed on the nodes at once.
+	PlaceableNum int64
+	// Index is 1 - PlaceableNum / (the number of pods of this shape that could be placed if the
+	// free resource of all nodes were pooled in one node).
+	// It is 0 if the free resource is not fragmented at all, and 1 if no pod can be placed even
+	// though the pooled free resource would suffice.
+	Index float64
+}
+
+// pendingPodsOf returns the pending pods of the given queue, or nil if it cannot list them.
+func pendingPodsOf(q queue.PodQueue) []*v1.Pod {
+	if lister, ok := q.(queue.PendingPodLister); ok {
+		return lister.PendingPods()
+	}
+	return nil
+}
+
+func buildClusterMetrics(nodesMetrics map[string]node.Metrics, pendingPods []*v1.Pod) ClusterMetrics {
+	met := ClusterMetrics{
+		Allocatable:      v1.ResourceList{},
+		FreeResource:     v1.ResourceList{},
+		StrandedResource: v1.ResourceList{},
+		StrandedRatio:    map[v1.ResourceName]float64{},
+		Fragmentation:    []ShapeFragmentation{},
+	}
+
+	for _, nodeMet := range nodesMetrics {
+		met.Allocatable = util.ResourceListSum(met.Allocatable, nodeMet.Allocatable)
+		met.FreeResource = util.ResourceListSum(met.FreeResource, nodeMet.FreeResource)
+		met.StrandedResource = util.ResourceListSum(met.StrandedResource, nodeMet.StrandedResource)
+
+		if nodeMet.PowerState == node.Asleep.String() || nodeMet.PowerState == node.Waking.String() {
+			met.SleepingNodesNum++
+		}
+		met.Power += nodeMet.Power
+		met.Energy += nodeMet.Energy
+		met.FacilityEnergy += nodeMet.FacilityEnergy
+	}
+
+	for rsrc, stranded := range met.StrandedResource {
+		alloc := met.Allocatable[rsrc]
+		if alloc.IsZero() {
+			continue
+		}
+		met.StrandedRatio[rsrc] = float64(stranded.MilliValue()) / float64(alloc.MilliValue())
+	}
+
+	for _, shape := range commonShapes(pendingPods, maxShapesNum) {
+		placeable := int64(0)
+		for _, nodeMet := range nodesMetrics {
+			placeable += placeableNum(nodeMet.FreeResource, shape.Shape)
+		}
+		shape.PlaceableNum = placeable
+
+		if pooled := placeableNum(met.FreeResource, shape.Shape); pooled > 0 {
+			shape.Index = 1 - float64(placeable)/float64(pooled)
+		}
+
+		met.Fragmentation = append(met.Fragmentation, shape)
+	}
+
+	return met
+}
+
+// commonShapes returns at most maxNum request shapes of the given pods, in descending order of
+// the number of pods.
+// Pods requesting no resource are ignored.
+func commonShapes(pods []*v1.Pod, maxNum int) []ShapeFragmentation {
+	shapes := map[string]*ShapeFragmentation{}
+	for _, pod := range pods {
+		req := util.PodTotalResourceRequests(pod)
+		if len(req) == 0 {
+			continue
+		}
+
+		key := shapeKey(req)
+		if _, ok := shapes[key]; !ok {
+			shapes[key] = &ShapeFragmentation{Shape: req}
+		}
+		shapes[key].PendingPodsNum++
+	}
+
+	keys := make([]string, 0, len(shapes))
+	for key := range shapes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if ni, nj := shapes[keys[i]].PendingPodsNum, shapes[keys[j]].PendingPodsNum; ni != nj {
+			return ni > nj
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > maxNum {
+		keys = keys[:maxNum]
+	}
+
+	result := make([]ShapeFragmentation, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, *shapes[key])
+	}
+
+	return result
+}
+
+func shapeKey(shape v1.ResourceList) string {
+	names := make([]string, 0, len(shape))
+	for rsrc := range shape {
+		names = append(names, string(rsrc))
+	}
+	sort.Strings(names)
+
+	key := ""
+	for _, name := range names {
+		q := shape[v1.ResourceName(name)]
+		key += name + "=" + q.String() + ","
+	}
+
+	return key
+}
+
+// placeableNum returns the number of pods of the given request shape that fit in the free
+// resource.
+func placeableNum(free, shape v1.ResourceList) int64 {
+	num := int64(-1)
+	if pods, ok := free[v1.ResourcePods]; ok {
+		num = pods.Value()
+	}
+
+	for rsrc, req := range shape {
+		if req.IsZero() {
+			continue
+		}
+		f := free[rsrc]
+		n := f.MilliValue() / req.MilliValue()
+		if num < 0 || n < num {
+			num = n
+		}
+	}
+
+	if num < 0 {
+		return 0
+	}
+	return num
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+func TestBuildClusterMetrics(t *testing.T) {
+	rl := func(cpu, mem string) v1.ResourceList {
+		return v1.ResourceList{"cpu": resource.MustParse(cpu), "memory": resource.MustParse(mem)}
+	}
+	newPod := func(name, cpu, mem string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1.PodSpec{Containers: []v1.Container{
+				{Resources: v1.ResourceRequirements{Requests: rl(cpu, mem)}},
+			}},
+		}
+	}
+
+	// node-0 has 2 free CPUs stranded by its exhausted memory, and node-1 and node-2 have 1 free
+	// CPU and 2Gi free memory each.
+	nodesMet := map[string]node.Metrics{
+		"node-0": {
+			Allocatable:      rl("4", "8Gi"),
+			FreeResource:     rl("2", "0"),
+			StrandedResource: rl("2", "0"),
+		},
+		"node-1": {
+			Allocatable:      rl("4", "8Gi"),
+			FreeResource:     rl("1", "2Gi"),
+			LargestPlaceable: rl("1", "2Gi"),
+		},
+		"node-2": {
+			Allocatable:      rl("4", "8Gi"),
+			FreeResource:     rl("1", "2Gi"),
+			LargestPlaceable: rl("1", "2Gi"),
+		},
+	}
+	pending := []*v1.Pod{
+		newPod("pod-0", "2", "2Gi"),
+		newPod("pod-1", "2", "2Gi"),
+		newPod("pod-2", "1", "1Gi"),
+	}
+
+	met := buildClusterMetrics(nodesMet, pending)
+
+	if ratio := met.StrandedRatio["cpu"]; ratio != 2.0/12 {
+		t.Errorf("got: %v\nwant: %v", ratio, 2.0/12)
+	}
+	if len(met.Fragmentation) != 2 {
+		t.Fatalf("got: %+v\nwant: 2 shapes", met.Fragmentation)
+	}
+
+	// The pooled 4 CPUs and 4Gi memory would fit two pods of 2 CPUs and 2Gi, but none fits a node.
+	large := met.Fragmentation[0]
+	if large.PendingPodsNum != 2 || large.PlaceableNum != 0 || large.Index != 1 {
+		t.Errorf("got: %+v\nwant: 2 pending, 0 placeable, index 1", large)
+	}
+	// The pooled resource would fit four pods of 1 CPU and 1Gi, but only two fit the nodes.
+	small := met.Fragmentation[1]
+	if small.PendingPodsNum != 1 || small.PlaceableNum != 2 || small.Index != 0.5 {
+		t.Errorf("got: %+v\nwant: 1 pending, 2 placeable, index 0.5", small)
+	}
+}
+
+func TestBuildTopologyMetrics(t *testing.T) {
+	nodes := map[string]*node.Node{}
+	nodesMet := map[string]node.Metrics{}
+	for i, zone := range []string{"a", "a", "b", ""} {
+		name := fmt.Sprintf("node-%d", i)
+		n := node.NewNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: util.TopologyLabelsOf("", zone, ""),
+		}})
+		nodes[name] = &n
+		nodesMet[name] = node.Metrics{RunningPodsNum: int64(i + 1)}
+	}
+
+	topology := buildTopologyMetrics(nodes, nodesMet)
+
+	if _, ok := topology[util.LabelTopologyRack]; ok || len(topology) != 1 {
+		t.Errorf("got: %v\nwant: only %s", topology, util.LabelTopologyZone)
+	}
+	zones := topology[util.LabelTopologyZone]
+	expected := TopologyMetrics{
+		Domains: map[string]DomainMetrics{
+			"a": {NodesNum: 2, RunningPodsNum: 3, Skew: 0},
+			"b": {NodesNum: 1, RunningPodsNum: 3, Skew: 0},
+		},
+		Skew: 0,
+	}
+	if !reflect.DeepEqual(zones, expected) {
+		t.Errorf("got: %+v\nwant: %+v", zones, expected)
+	}
+
+	nodesMet["node-0"] = node.Metrics{RunningPodsNum: 5}
+	zones = buildTopologyMetrics(nodes, nodesMet)[util.LabelTopologyZone]
+	if zones.Skew != 4 || zones.Domains["a"].Skew != 4 || zones.Domains["b"].Skew != 0 {
+		t.Errorf("got: %+v\nwant: skew 4 in zone a", zones)
+	}
+}
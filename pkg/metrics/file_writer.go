@@ -23,6 +23,7 @@ import (
 type FileWriter struct {
 	file      *os.File
 	formatter Formatter
+	filter    *Filter
 }
 
 // NewFileWriter creates a new FileWriter with an output device or file at the given path, and the formatter that
@@ -32,17 +33,9 @@ type FileWriter struct {
 // Otherwise, the file of a given path is set and it will be truncated if it exists.
 // Returns error if failed to create a file.
 func NewFileWriter(dest string, formatter Formatter) (*FileWriter, error) {
-	var file *os.File
-	if dest == "/dev/stdout" || strings.ToLower(dest) == "stdout" {
-		file = os.Stdout
-	} else if dest == "/dev/stderr" || strings.ToLower(dest) == "stderr" {
-		file = os.Stderr
-	} else {
-		f, err := os.Create(dest)
-		if err != nil {
-			return nil, err
-		}
-		file = f
+	file, err := openDest(dest)
+	if err != nil {
+		return nil, err
 	}
 
 	return &FileWriter{
@@ -51,12 +44,49 @@ func NewFileWriter(dest string, formatter Formatter) (*FileWriter, error) {
 	}, nil
 }
 
+// NewFileWriterWithFilter creates a new FileWriter in the same way as NewFileWriter, which writes
+// only the part of metrics selected by the given filter.
+func NewFileWriterWithFilter(dest string, formatter Formatter, filter *Filter) (*FileWriter, error) {
+	writer, err := NewFileWriter(dest, formatter)
+	if err != nil {
+		return nil, err
+	}
+	writer.filter = filter
+
+	return writer, nil
+}
+
+// openDest opens the output device or file at the given path, in the same way as NewFileWriter.
+func openDest(dest string) (*os.File, error) {
+	if dest == "/dev/stdout" || strings.ToLower(dest) == "stdout" {
+		return os.Stdout, nil
+	} else if dest == "/dev/stderr" || strings.ToLower(dest) == "stderr" {
+		return os.Stderr, nil
+	}
+
+	return os.Create(dest)
+}
+
+// closeDest closes the file opened by openDest, unless it is the standard out or error.
+func closeDest(file *os.File) {
+	if file != os.Stdout && file != os.Stderr {
+		file.Close()
+	}
+}
+
 // FileName returns the name of file underlying this FileWriter.
 func (w *FileWriter) FileName() string { return w.file.Name() }
 
+// WantsPods returns whether this FileWriter writes pod metrics.
+func (w *FileWriter) WantsPods() bool { return w.filter == nil || w.filter.Pods }
+
 // Write implements Writer interface.
 // Returns error if failed to format with the underlying formatter.
 func (w *FileWriter) Write(metrics *Metrics) error {
+	if w.filter != nil {
+		metrics = w.filter.Apply(metrics)
+	}
+
 	str, err := w.formatter.Format(metrics)
 	if err != nil {
 		return err
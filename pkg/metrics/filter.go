@@ -0,0 +1,162 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"hash/fnv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/conversion"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+// semantic compares metrics by the values of quantities and clocks rather than by their internal
+// representations.
+var semantic = conversion.EqualitiesOrDie(
+	func(a, b resource.Quantity) bool { return a.Cmp(b) == 0 },
+	func(a, b clock.Clock) bool { return a.Sub(b) == 0 },
+)
+
+// Filter selects the part of a Metrics that a Writer writes.
+type Filter struct {
+	// Nodes, Pods, and Queue select which entity types are written.
+	// Metrics of an unselected entity type are written as empty, so that formatters can handle
+	// filtered metrics in the same way as unfiltered ones.
+	Nodes bool
+	Pods  bool
+	Queue bool
+
+	// Namespaces selects pods in any of the namespaces. All namespaces are selected if empty.
+	Namespaces []string
+	// LabelSelector selects pods whose labels match it. All pods are selected if nil.
+	LabelSelector labels.Selector
+	// PodSamplingRate is the fraction of pods selected, in (0, 1].
+	// The same pods are selected at every time point, so that they can be traced through the whole
+	// simulation. All pods are selected if zero.
+	PodSamplingRate float64
+
+	// Deltas selects only nodes and pods whose metrics have changed since the last time point
+	// written through this Filter.
+	Deltas bool
+
+	lastNodes map[string]node.Metrics
+	lastPods  map[string]pod.Metrics
+}
+
+// Apply returns a new Metrics that consists of the part of the given metrics selected by this
+// Filter.
+func (f *Filter) Apply(metrics *Metrics) *Metrics {
+	filtered := Metrics{}
+	for key, value := range *metrics {
+		filtered[key] = value
+	}
+
+	nodesMet := map[string]node.Metrics{}
+	if f.Nodes {
+		if met, ok := (*metrics)[NodesMetricsKey].(map[string]node.Metrics); ok {
+			nodesMet = f.nodesDelta(met)
+		}
+	}
+	filtered[NodesMetricsKey] = nodesMet
+
+	podsMet := map[string]pod.Metrics{}
+	if f.Pods {
+		if met, ok := (*metrics)[PodsMetricsKey].(map[string]pod.Metrics); ok {
+			for key, podMet := range met {
+				if f.selectsPod(key, podMet) {
+					podsMet[key] = podMet
+				}
+			}
+			podsMet = f.podsDelta(podsMet)
+		}
+	}
+	filtered[PodsMetricsKey] = podsMet
+
+	if !f.Queue {
+		filtered[QueueMetricsKey] = queue.Metrics{}
+	}
+
+	return &filtered
+}
+
+// selectsPod returns whether the pod with the given key ("namespace/name") and metrics is selected
+// by the namespaces, label selector, and sampling rate of this Filter.
+func (f *Filter) selectsPod(key string, met pod.Metrics) bool {
+	if len(f.Namespaces) > 0 {
+		ns := strings.SplitN(key, "/", 2)[0]
+		found := false
+		for _, n := range f.Namespaces {
+			if n == ns {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.LabelSelector != nil && !f.LabelSelector.Matches(labels.Set(met.Labels)) {
+		return false
+	}
+
+	if f.PodSamplingRate > 0 && f.PodSamplingRate < 1 {
+		h := fnv.New32a()
+		h.Write([]byte(key)) // nolint
+		if float64(h.Sum32())/float64(^uint32(0)) >= f.PodSamplingRate {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f *Filter) nodesDelta(met map[string]node.Metrics) map[string]node.Metrics {
+	if !f.Deltas {
+		return met
+	}
+
+	delta := map[string]node.Metrics{}
+	for name, nodeMet := range met {
+		if last, ok := f.lastNodes[name]; !ok || !semantic.DeepEqual(last, nodeMet) {
+			delta[name] = nodeMet
+		}
+	}
+	f.lastNodes = met
+
+	return delta
+}
+
+func (f *Filter) podsDelta(met map[string]pod.Metrics) map[string]pod.Metrics {
+	if !f.Deltas {
+		return met
+	}
+
+	delta := map[string]pod.Metrics{}
+	for key, podMet := range met {
+		if last, ok := f.lastPods[key]; !ok || !semantic.DeepEqual(last, podMet) {
+			delta[key] = podMet
+		}
+	}
+	f.lastPods = met
+
+	return delta
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+func TestFilterApply(t *testing.T) {
+	podsMet := map[string]pod.Metrics{
+		"default/pod-0": {Labels: map[string]string{"app": "foo"}},
+		"default/pod-1": {Labels: map[string]string{"app": "bar"}},
+		"other/pod-2":   {Labels: map[string]string{"app": "foo"}},
+	}
+	met := Metrics{
+		ClockKey:        "2019-01-01T00:00:00+09:00",
+		NodesMetricsKey: map[string]node.Metrics{"node-0": {RunningPodsNum: 1}},
+		PodsMetricsKey:  podsMet,
+		QueueMetricsKey: queue.Metrics{PendingPodsNum: 1},
+	}
+
+	filter := Filter{
+		Pods:          true,
+		Namespaces:    []string{"default"},
+		LabelSelector: labels.SelectorFromSet(labels.Set{"app": "foo"}),
+		Deltas:        true,
+	}
+
+	filtered := filter.Apply(&met)
+	if err := validateMetrics(filtered); err != nil {
+		t.Fatal(err)
+	}
+	if n := len((*filtered)[NodesMetricsKey].(map[string]node.Metrics)); n != 0 {
+		t.Errorf("got: %d nodes\nwant: 0 nodes", n)
+	}
+	if q := (*filtered)[QueueMetricsKey].(queue.Metrics); q.PendingPodsNum != 0 {
+		t.Errorf("got: %+v\nwant: empty queue metrics", q)
+	}
+	pods := (*filtered)[PodsMetricsKey].(map[string]pod.Metrics)
+	if _, ok := pods["default/pod-0"]; !ok || len(pods) != 1 {
+		t.Errorf("got: %+v\nwant: only default/pod-0", pods)
+	}
+
+	// Nothing has changed since the last record.
+	filtered = filter.Apply(&met)
+	if pods := (*filtered)[PodsMetricsKey].(map[string]pod.Metrics); len(pods) != 0 {
+		t.Errorf("got: %+v\nwant: no pods", pods)
+	}
+}
+
+func TestFilterPodSampling(t *testing.T) {
+	podsMet := map[string]pod.Metrics{}
+	for i := 0; i < 1000; i++ {
+		podsMet[fmt.Sprintf("default/pod-%d", i)] = pod.Metrics{}
+	}
+	met := Metrics{PodsMetricsKey: podsMet}
+
+	filter := Filter{Pods: true, PodSamplingRate: 0.1}
+	sampled := (*filter.Apply(&met))[PodsMetricsKey].(map[string]pod.Metrics)
+	if len(sampled) < 50 || 150 < len(sampled) {
+		t.Errorf("got: %d pods\nwant: about 100 pods", len(sampled))
+	}
+
+	// The same pods are sampled every time.
+	again := (*filter.Apply(&met))[PodsMetricsKey].(map[string]pod.Metrics)
+	for key := range sampled {
+		if _, ok := again[key]; !ok {
+			t.Errorf("pod %s is not sampled again", key)
+		}
+	}
+}
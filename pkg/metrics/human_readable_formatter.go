@@ -16,10 +16,13 @@ package metrics
 
 import (
 	"fmt"
+	"sort"
 
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/admission"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
 )
 
 // HumanReadableFormatter is a Foramtter that formats metrics in a human-readable style.
@@ -28,32 +31,107 @@ type HumanReadableFormatter struct{}
 // Format implements Formatter interface.
 // Returns error if the given metrics does not have valid structure.
 func (h *HumanReadableFormatter) Format(metrics *Metrics) (string, error) {
-	if err := validateMetrics(metrics); err != nil {
+	snapshot, err := metrics.Snapshot()
+	if err != nil {
 		return "", err
 	}
 
 	// Clock
-	clk := (*metrics)[ClockKey].(string)
-	str := "Metrics " + clk + "\n"
+	str := "Metrics " + snapshot.Clock + "\n"
 
 	// Nodes
 	str += "  Nodes\n"
-	nodesMet := (*metrics)[NodesMetricsKey].(map[string]node.Metrics)
-	str += h.formatNodesMetrics(nodesMet)
+	str += h.formatNodesMetrics(snapshot.Nodes)
 
 	// Pods
 	str += "  Pods\n"
-	podsMet := (*metrics)[PodsMetricsKey].(map[string]pod.Metrics)
-	str += h.formatPodsMetrics(podsMet)
+	str += h.formatPodsMetrics(snapshot.Pods)
 
 	// Queue
 	str += "  Queue\n"
-	queueMet := (*metrics)[QueueMetricsKey].(queue.Metrics)
-	str += h.formatQueueMetrics(queueMet)
+	str += h.formatQueueMetrics(snapshot.Queue)
+
+	// Cluster
+	if _, ok := (*metrics)[ClusterMetricsKey]; ok {
+		str += "  Cluster\n"
+		str += h.formatClusterMetrics(snapshot.Cluster)
+	}
+
+	// Admission
+	if len(snapshot.Admission) > 0 {
+		str += "  Admission\n"
+		str += h.formatAdmissionMetrics(snapshot.Admission)
+	}
 
 	return str, nil
 }
 
+func (h *HumanReadableFormatter) formatAdmissionMetrics(metrics map[string]admission.Metrics) string {
+	str := ""
+
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		met := metrics[name]
+		str += fmt.Sprintf("    %s: Pending %d, Admitted %d, Preempted %d",
+			name, met.PendingWorkloadsNum, met.AdmittedWorkloadsNum, met.PreemptedWorkloadsNum)
+
+		flavors := make([]string, 0, len(met.Utilization))
+		for flavor := range met.Utilization {
+			flavors = append(flavors, flavor)
+		}
+		sort.Strings(flavors)
+		for _, flavor := range flavors {
+			for rsrc, ratio := range met.Utilization[flavor] {
+				str += fmt.Sprintf(", %s/%s %.1f%%", flavor, rsrc, ratio*100)
+			}
+		}
+		str += "\n"
+	}
+
+	return str
+}
+
+func (h *HumanReadableFormatter) formatClusterMetrics(metrics ClusterMetrics) string {
+	str := "    Stranded"
+	for rsrc, ratio := range metrics.StrandedRatio {
+		str += fmt.Sprintf(", %s %.1f%%", rsrc, ratio*100)
+	}
+	str += "\n"
+
+	for _, frag := range metrics.Fragmentation {
+		str += fmt.Sprintf("    Shape %v: pending %d, placeable %d, fragmentation %.2f\n",
+			frag.Shape, frag.PendingPodsNum, frag.PlaceableNum, frag.Index)
+	}
+
+	for _, key := range util.TopologyLabels {
+		topo, ok := metrics.Topology[key]
+		if !ok {
+			continue
+		}
+		str += fmt.Sprintf("    Topology %s: skew %d", key, topo.Skew)
+		domains := make([]string, 0, len(topo.Domains))
+		for domain := range topo.Domains {
+			domains = append(domains, domain)
+		}
+		sort.Strings(domains)
+		for _, domain := range domains {
+			str += fmt.Sprintf(", %s %d", domain, topo.Domains[domain].RunningPodsNum)
+		}
+		str += "\n"
+	}
+
+	if metrics.Power > 0 || metrics.Energy > 0 || metrics.SleepingNodesNum > 0 {
+		str += fmt.Sprintf("    Power %.0f W, energy %.0f J (facility %.0f J), sleeping nodes %d\n",
+			metrics.Power, metrics.Energy, metrics.FacilityEnergy, metrics.SleepingNodesNum)
+	}
+
+	return str
+}
+
 func validateMetrics(metrics *Metrics) error {
 	keys := []string{ClockKey, NodesMetricsKey, PodsMetricsKey, QueueMetricsKey}
 	for _, key := range keys {
@@ -99,7 +177,14 @@ func (h *HumanReadableFormatter) formatNodesMetrics(metrics map[string]node.Metr
 			}
 		}
 
-		str += fmt.Sprintf(", Failed %d\n", met.FailedPodsNum)
+		str += fmt.Sprintf(", Failed %d", met.FailedPodsNum)
+		if met.PowerState != "" && met.PowerState != node.Active.String() {
+			str += ", " + met.PowerState
+		}
+		if met.Power > 0 {
+			str += fmt.Sprintf(", %.0f W", met.Power)
+		}
+		str += "\n"
 	}
 
 	return str
@@ -111,6 +196,9 @@ func (h *HumanReadableFormatter) formatPodsMetrics(metrics map[string]pod.Metric
 	for name, met := range metrics {
 		str += fmt.Sprintf("    %s: prio %d, bound at %s on %s, status %s, elapsed %d s",
 			name, met.Priority, met.BoundAt.ToRFC3339(), met.Node, met.Status, met.ExecutedSeconds)
+		if met.Slowdown > 1 {
+			str += fmt.Sprintf(", slowdown %.2f", met.Slowdown)
+		}
 
 		for rsrc, req := range met.ResourceRequest {
 			lim := met.ResourceLimit[rsrc] // !ok -> usage == 0
@@ -131,7 +219,45 @@ func (h *HumanReadableFormatter) formatPodsMetrics(metrics map[string]pod.Metric
 }
 
 func (h *HumanReadableFormatter) formatQueueMetrics(metrics queue.Metrics) string {
-	return fmt.Sprintf("    PendingPods %d\n", metrics.PendingPodsNum)
+	str := fmt.Sprintf("    PendingPods %d", metrics.PendingPodsNum)
+	if metrics.ActivePodsNum+metrics.BackoffPodsNum+metrics.UnschedulablePodsNum > 0 {
+		str += fmt.Sprintf(" (Active %d, Backoff %d, Unschedulable %d)",
+			metrics.ActivePodsNum, metrics.BackoffPodsNum, metrics.UnschedulablePodsNum)
+	}
+	str += "\n"
+
+	tenants := make([]string, 0, len(metrics.TenantShares))
+	for tenant := range metrics.TenantShares {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	for _, tenant := range tenants {
+		str += fmt.Sprintf("    Tenant %s: DominantShare %.3f\n", tenant, metrics.TenantShares[tenant])
+	}
+
+	queues := make([]string, 0, len(metrics.Capacity))
+	for name := range metrics.Capacity {
+		queues = append(queues, name)
+	}
+	sort.Strings(queues)
+	for _, name := range queues {
+		met := metrics.Capacity[name]
+		str += fmt.Sprintf("    Queue %s: Used %.1f%% (Capacity %.1f%%, Max %.1f%%), Pending %d\n",
+			name, met.Used*100, met.Capacity*100, met.MaxCapacity*100, met.PendingPodsNum)
+	}
+
+	classes := make([]string, 0, len(metrics.Starvation))
+	for class := range metrics.Starvation {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		met := metrics.Starvation[class]
+		str += fmt.Sprintf("    Class %s: Pending %d, LongestWait %s %.0fs\n",
+			class, met.PendingPodsNum, met.Pod, met.WaitSeconds)
+	}
+
+	return str
 }
 
 var _ = Formatter(&HumanReadableFormatter{})
@@ -0,0 +1,131 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"math"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// InterferenceModel models the interference between the pods co-located on a node (e.g., a
+// latency-sensitive service next to a memory-bandwidth-heavy batch job), which the fair-share
+// allocation of the node capacity does not capture.
+// Each pod is classified by a label, and suffers from each of the other running pods on its node
+// a fraction of slowdown given by the sensitivity of its class to the class of the other pod.
+type InterferenceModel struct {
+	// ClassLabel is the key of the pod label whose value is the class of the pod.
+	// Pods without the label are of the class "".
+	ClassLabel string
+	// Sensitivity maps a victim class and an aggressor class to the fraction by which a pod of the
+	// victim class is slowed down by each co-located pod of the aggressor class.
+	// Pairs not in the map do not interfere.
+	Sensitivity map[string]map[string]float64
+	// MaxSlowdown caps the slowdown of a pod if > 0.
+	MaxSlowdown float64
+
+	// SlowDown specifies whether interference slows down the execution of the pods.
+	SlowDown bool
+	// InflateUsage specifies whether interference inflates the resource usage of the pods (e.g.,
+	// extra CPU cycles stalled on memory), which is then allocated as usual.
+	InflateUsage bool
+}
+
+// apply applies the interference among the running pods on a node at the clock: the pods are
+// slowed down from the clock on, and/or their usage in their metrics is inflated.
+// Returns the total usage of the pods on the node after inflation, given the one before.
+func (m *InterferenceModel) apply(clock clock.Clock, runningPodKeys []string, runningPods map[string]*pod.Pod,
+	usage v1.ResourceList, podsMetrics map[string]pod.Metrics, podsMetricsMutex *sync.RWMutex) v1.ResourceList {
+
+	// Pods that have failed to start do not interfere.
+	keys := make([]string, 0, len(runningPodKeys))
+	for _, key := range runningPodKeys {
+		if p := runningPods[key]; p.IsRunning(clock) || p.IsTerminating(clock) {
+			keys = append(keys, key)
+		}
+	}
+
+	podsMetricsMutex.RLock()
+	slowdowns := m.slowdowns(keys, podsMetrics)
+	podsMetricsMutex.RUnlock()
+
+	for _, key := range keys {
+		slowdown := slowdowns[key]
+		if m.SlowDown {
+			runningPods[key].SetSlowdown(clock, slowdown)
+		}
+
+		podsMetricsMutex.Lock()
+		podMetrics := podsMetrics[key]
+		podMetrics.Slowdown = slowdown
+		if m.InflateUsage && slowdown > 1 {
+			inflated := inflate(podMetrics.ResourceUsage, slowdown)
+			usage = util.ResourceListSum(usage, util.ResourceListSub(inflated, podMetrics.ResourceUsage))
+			podMetrics.ResourceUsage = inflated
+			podMetrics.ResourceAllocation = inflated
+		}
+		podsMetrics[key] = podMetrics
+		podsMetricsMutex.Unlock()
+	}
+
+	return usage
+}
+
+// slowdowns returns the slowdown factor (>= 1) of each of the given running pods on a node.
+func (m *InterferenceModel) slowdowns(runningPodKeys []string, podsMetrics map[string]pod.Metrics) map[string]float64 {
+	classes := make(map[string]string, len(runningPodKeys))
+	counts := map[string]int{}
+	for _, key := range runningPodKeys {
+		class := podsMetrics[key].Labels[m.ClassLabel]
+		classes[key] = class
+		counts[class]++
+	}
+
+	slowdowns := make(map[string]float64, len(runningPodKeys))
+	for _, key := range runningPodKeys {
+		victim := classes[key]
+		interference := 0.0
+		for aggressor, sensitivity := range m.Sensitivity[victim] {
+			n := counts[aggressor]
+			if aggressor == victim {
+				n-- // the pod does not interfere with itself
+			}
+			interference += sensitivity * float64(n)
+		}
+
+		slowdown := 1 + interference
+		if m.MaxSlowdown > 0 {
+			slowdown = math.Min(slowdown, m.MaxSlowdown)
+		}
+		slowdowns[key] = math.Max(slowdown, 1)
+	}
+
+	return slowdowns
+}
+
+// inflate returns the usage multiplied by the given factor.
+func inflate(usage v1.ResourceList, factor float64) v1.ResourceList {
+	inflated := make(v1.ResourceList, len(usage))
+	for rsrc, q := range usage {
+		a := float64(amount(rsrc, q)) * factor
+		inflated[rsrc] = quantity(rsrc, int64(math.Ceil(a)), q.Format)
+	}
+	return inflated
+}
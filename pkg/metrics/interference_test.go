@@ -0,0 +1,98 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+func TestBuildMetricsWithInterference(t *testing.T) {
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	newNode := func() *node.Node {
+		n := node.NewNode(&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+			Status:     v1.NodeStatus{Allocatable: v1.ResourceList{"cpu": resource.MustParse("8"), "pods": resource.MustParse("10")}},
+		})
+		newPod := func(name, class, seconds string) *v1.Pod {
+			return &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:   "default",
+					Name:        name,
+					Labels:      map[string]string{"class": class},
+					Annotations: map[string]string{"simSpec": "- seconds: " + seconds + "\n  resourceUsage:\n    cpu: 1\n"},
+				},
+				Spec: v1.PodSpec{Containers: []v1.Container{{Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{"cpu": resource.MustParse("1")},
+				}}}},
+			}
+		}
+		for _, p := range []*v1.Pod{newPod("svc", "service", "100"), newPod("batch-0", "batch", "1000"), newPod("batch-1", "batch", "1000")} {
+			if _, err := n.BindPod(start, p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return &n
+	}
+	model := InterferenceModel{
+		ClassLabel:  "class",
+		Sensitivity: map[string]map[string]float64{"service": {"batch": 0.5}},
+		SlowDown:    true,
+	}
+
+	// The service is slowed down twice by the two batch pods, which are not slowed down.
+	n := newNode()
+	met, err := BuildMetrics(start, map[string]*node.Node{"node-0": n}, queue.NewFIFOQueue(), 1, nil, &model, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string]float64{"default/svc": 2, "default/batch-0": 1} {
+		if actual := met.Pods()[key].Slowdown; actual != expected {
+			t.Errorf("%s: got: %v\nwant: %v", key, actual, expected)
+		}
+	}
+	svc := n.Pod("default", "svc")
+	if actual := svc.Metrics(start.Add(50 * time.Second)).ExecutedSeconds; actual != 25 {
+		t.Errorf("got: %d s executed\nwant: 25 s executed", actual)
+	}
+	if actual, expected := svc.FinishAt(), start.Add(200*time.Second); actual.Sub(expected) != 0 {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	// The usage of the service is inflated instead.
+	model.SlowDown, model.InflateUsage = false, true
+	n = newNode()
+	met, err = BuildMetrics(start, map[string]*node.Node{"node-0": n}, queue.NewFIFOQueue(), 1, nil, &model, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpu := met.Pods()["default/svc"].ResourceUsage[v1.ResourceCPU]; cpu.MilliValue() != 2000 {
+		t.Errorf("got: %s CPUs\nwant: 2 CPUs", cpu.String())
+	}
+	if cpu := met.Nodes()["node-0"].TotalResourceUsage[v1.ResourceCPU]; cpu.MilliValue() != 4000 {
+		t.Errorf("got: %s CPUs\nwant: 4 CPUs", cpu.String())
+	}
+	if !n.Pod("default", "svc").IsTerminated(start.Add(100 * time.Second)) {
+		t.Errorf("got: pod running\nwant: pod terminated at 100s")
+	}
+}
@@ -23,10 +23,16 @@ type JSONFormatter struct {
 }
 
 // Format implements Formatter interface.
-// It formats the given metrics to a single JSON string, without newline at the end.
-// Returns error if failed to marshal.
+// It formats the Snapshot of the given metrics to a single JSON string, without newline at the
+// end.
+// Returns error if the given metrics does not have valid structure or failed to marshal.
 func (j *JSONFormatter) Format(metrics *Metrics) (string, error) {
-	bytes, err := json.Marshal(metrics)
+	snapshot, err := metrics.Snapshot()
+	if err != nil {
+		return "", err
+	}
+
+	bytes, err := json.Marshal(snapshot)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,390 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// PodLifecycle is a record of the life of one pod in the simulated cluster.
+// Fields of events that have not happened (yet) are nil.
+type PodLifecycle struct {
+	Namespace string
+	Name      string
+	Priority  int32
+	Request   v1.ResourceList
+
+	SubmittedAt    clock.Clock
+	FirstAttemptAt *clock.Clock `json:",omitempty"`
+	BoundAt        *clock.Clock `json:",omitempty"`
+	FinishedAt     *clock.Clock `json:",omitempty"`
+	KilledAt       *clock.Clock `json:",omitempty"`
+	DeletedAt      *clock.Clock `json:",omitempty"`
+	// EvictedAt is the clock at which the pod was last evicted from an interrupted node, after
+	// which it waits for binding again.
+	EvictedAt *clock.Clock `json:",omitempty"`
+	// AdmittedAt is the clock at which the pod was last admitted by its cluster queue, if it is
+	// subject to quota-based admission.
+	AdmittedAt *clock.Clock `json:",omitempty"`
+
+	Node     string `json:",omitempty"`
+	Attempts int
+	// ExecutionSeconds is the execution duration of the pod without slowdown, known once it is
+	// bound.
+	ExecutionSeconds int32 `json:",omitempty"`
+	Evictions        int   `json:",omitempty"`
+	// ClusterQueue is the cluster queue to which the pod was submitted, if any.
+	ClusterQueue string `json:",omitempty"`
+	Preemptions  int    `json:",omitempty"`
+}
+
+// LifecycleRecorder records the lifecycle of every submitted pod, and samples the cluster-wide
+// resource utilization, so that scheduling KPIs can be reported at the end of a simulation.
+type LifecycleRecorder struct {
+	records map[string]*PodLifecycle
+	// replaced holds the records of the pods whose namespace/name was re-submitted later.
+	replaced []*PodLifecycle
+	// running holds bound pods that have neither finished nor been deleted yet.
+	running map[string]*pod.Pod
+
+	firstSubmit *clock.Clock
+	lastFinish  *clock.Clock
+	rejectedNum int
+
+	utilSamples   int
+	usageRatioAcc map[v1.ResourceName]float64
+	reqRatioAcc   map[v1.ResourceName]float64
+
+	// lastObserved is the clock of the last observation, since which the cost is accrued.
+	lastObserved *clock.Clock
+	costLabels   []string
+	cost         CostReport
+}
+
+// NewLifecycleRecorder creates a new empty LifecycleRecorder.
+func NewLifecycleRecorder() *LifecycleRecorder {
+	return &LifecycleRecorder{
+		records:       map[string]*PodLifecycle{},
+		running:       map[string]*pod.Pod{},
+		usageRatioAcc: map[v1.ResourceName]float64{},
+		reqRatioAcc:   map[v1.ResourceName]float64{},
+		cost: CostReport{
+			ByLifecycle: map[string]float64{},
+			ByNamespace: map[string]float64{},
+			ByLabel:     map[string]map[string]float64{},
+		},
+	}
+}
+
+// SetCostLabels sets the keys of the pod labels by whose values the cost is broken down.
+func (r *LifecycleRecorder) SetCostLabels(keys []string) {
+	r.costLabels = keys
+}
+
+// RecordSubmit records that the given pod was submitted at the clock.
+// A pod re-submitted with the same namespace/name starts a new record, and the record of the
+// previous pod is kept with its termination, if not observed yet.
+func (r *LifecycleRecorder) RecordSubmit(clk clock.Clock, v1Pod *v1.Pod) {
+	key, err := util.PodKey(v1Pod)
+	if err != nil {
+		return
+	}
+
+	if prev, ok := r.records[key]; ok {
+		if simPod, ok := r.running[key]; ok {
+			r.observePod(clk, key, simPod)
+			delete(r.running, key)
+		}
+		r.replaced = append(r.replaced, prev)
+	}
+
+	r.records[key] = &PodLifecycle{
+		Namespace:   v1Pod.Namespace,
+		Name:        v1Pod.Name,
+		Priority:    util.PodPriority(v1Pod),
+		Request:     util.PodTotalResourceRequests(v1Pod),
+		SubmittedAt: clk,
+	}
+
+	if r.firstSubmit == nil {
+		r.firstSubmit = &clk
+	}
+}
+
+// RecordReject records that a pod was rejected on submission. The pod has no record.
+func (r *LifecycleRecorder) RecordReject() {
+	r.rejectedNum++
+}
+
+// RecordAttempt records that the scheduler tried to schedule the given pod at the clock.
+func (r *LifecycleRecorder) RecordAttempt(clk clock.Clock, v1Pod *v1.Pod) {
+	rec := r.record(v1Pod.Namespace, v1Pod.Name)
+	if rec == nil {
+		return
+	}
+
+	rec.Attempts++
+	if rec.FirstAttemptAt == nil {
+		rec.FirstAttemptAt = &clk
+	}
+}
+
+// RecordBind records that the given pod was bound to a node at the clock.
+// Binding counts as the last scheduling attempt of the pod.
+func (r *LifecycleRecorder) RecordBind(clk clock.Clock, simPod *pod.Pod) {
+	v1Pod := simPod.ToV1()
+	key, err := util.PodKey(v1Pod)
+	if err != nil {
+		return
+	}
+	rec := r.records[key]
+	if rec == nil {
+		return
+	}
+
+	r.RecordAttempt(clk, v1Pod)
+	rec.BoundAt = &clk
+	rec.Node = v1Pod.Spec.NodeName
+	rec.ExecutionSeconds = simPod.TotalSeconds()
+	r.running[key] = simPod
+}
+
+// RecordDelete records that the given pod was deleted at the clock, whether it was pending or
+// bound.
+func (r *LifecycleRecorder) RecordDelete(clk clock.Clock, podNamespace, podName string) {
+	rec := r.record(podNamespace, podName)
+	if rec == nil || rec.FinishedAt != nil || rec.KilledAt != nil || rec.DeletedAt != nil {
+		return
+	}
+
+	rec.DeletedAt = &clk
+	delete(r.running, util.PodKeyFromNames(podNamespace, podName))
+}
+
+// RecordEvict records that the given bound pod was evicted from its node at the clock, and is
+// pending again.
+func (r *LifecycleRecorder) RecordEvict(clk clock.Clock, podNamespace, podName string) {
+	rec := r.record(podNamespace, podName)
+	if rec == nil || rec.BoundAt == nil {
+		return
+	}
+
+	rec.Evictions++
+	rec.EvictedAt = &clk
+	rec.BoundAt = nil
+	rec.Node = ""
+	rec.ExecutionSeconds = 0
+	delete(r.running, util.PodKeyFromNames(podNamespace, podName))
+}
+
+// RecordEnqueue records that the given pod was submitted to the cluster queue for admission.
+func (r *LifecycleRecorder) RecordEnqueue(podNamespace, podName, clusterQueue string) {
+	if rec := r.record(podNamespace, podName); rec != nil {
+		rec.ClusterQueue = clusterQueue
+	}
+}
+
+// RecordAdmit records that the given pod was admitted by its cluster queue at the clock.
+func (r *LifecycleRecorder) RecordAdmit(clk clock.Clock, podNamespace, podName string) {
+	if rec := r.record(podNamespace, podName); rec != nil {
+		rec.AdmittedAt = &clk
+	}
+}
+
+// RecordPreempt records that the admission of the given pod was preempted at the clock, and it
+// waits for admission again. A bound pod must also be recorded as evicted.
+func (r *LifecycleRecorder) RecordPreempt(clk clock.Clock, podNamespace, podName string) {
+	rec := r.record(podNamespace, podName)
+	if rec == nil || rec.AdmittedAt == nil {
+		return
+	}
+
+	rec.Preemptions++
+	rec.AdmittedAt = nil
+}
+
+// Observe checks the bound pods for spontaneous termination and killing, and samples the resource utilization
+// of the given nodes at the clock.
+// The cost of the nodes since the last observation is accrued.
+func (r *LifecycleRecorder) Observe(clk clock.Clock, nodesMetrics map[string]node.Metrics) {
+	for key, simPod := range r.running {
+		if r.observePod(clk, key, simPod) {
+			delete(r.running, key)
+		}
+	}
+
+	allocatable := v1.ResourceList{}
+	usage := v1.ResourceList{}
+	request := v1.ResourceList{}
+	for _, met := range nodesMetrics {
+		allocatable = util.ResourceListSum(allocatable, met.Allocatable)
+		usage = util.ResourceListSum(usage, met.TotalResourceUsage)
+		request = util.ResourceListSum(request, met.TotalResourceRequest)
+	}
+
+	for rsrc, alloc := range allocatable {
+		if rsrc == v1.ResourcePods || alloc.IsZero() {
+			continue
+		}
+		u := usage[rsrc]
+		q := request[rsrc]
+		r.usageRatioAcc[rsrc] += float64(u.MilliValue()) / float64(alloc.MilliValue())
+		r.reqRatioAcc[rsrc] += float64(q.MilliValue()) / float64(alloc.MilliValue())
+	}
+	r.utilSamples++
+
+	if r.lastObserved != nil {
+		r.accrueCost(clk, clk.Sub(*r.lastObserved).Hours(), nodesMetrics)
+	}
+	r.lastObserved = &clk
+}
+
+// observePod records the termination of the given running pod, if it has been killed or has
+// finished by the clock.
+// Returns true if the pod has terminated.
+func (r *LifecycleRecorder) observePod(clk clock.Clock, key string, simPod *pod.Pod) bool {
+	if simPod.IsKilled() {
+		killedAt := simPod.KilledAt()
+		r.records[key].KilledAt = &killedAt
+		return true
+	}
+	if simPod.IsTerminated(clk) {
+		finishedAt := simPod.FinishAt()
+		r.records[key].FinishedAt = &finishedAt
+		if r.lastFinish == nil || r.lastFinish.Before(finishedAt) {
+			r.lastFinish = &finishedAt
+		}
+		return true
+	}
+	return false
+}
+
+// accrueCost accrues the cost of the given nodes for the given hours, and attributes the cost of
+// each node to the pods running on it at the clock in proportion to their dominant share of its
+// allocatable resource. The rest is idle.
+func (r *LifecycleRecorder) accrueCost(clk clock.Clock, hours float64, nodesMetrics map[string]node.Metrics) {
+	podsByNode := map[string][]*pod.Pod{}
+	keys := make([]string, 0, len(r.running))
+	for key := range r.running {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		simPod := r.running[key]
+		if simPod.IsRunning(clk) || simPod.IsTerminating(clk) {
+			name := simPod.NodeName()
+			podsByNode[name] = append(podsByNode[name], simPod)
+		}
+	}
+
+	names := make([]string, 0, len(nodesMetrics))
+	for name := range nodesMetrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		met := nodesMetrics[name]
+		if met.HourlyPrice == 0 {
+			continue
+		}
+		cost := met.HourlyPrice * hours
+		r.cost.Total += cost
+		r.cost.ByLifecycle[met.Lifecycle] += cost
+
+		pods := podsByNode[name]
+		shares := make([]float64, len(pods))
+		sum := 0.0
+		for i, simPod := range pods {
+			shares[i] = dominantShare(simPod.TotalResourceRequests(), met.Allocatable)
+			sum += shares[i]
+		}
+		if sum > 1 {
+			// Over-subscribed; the whole cost is attributed to the pods.
+			for i := range shares {
+				shares[i] /= sum
+			}
+			sum = 1
+		}
+
+		for i, simPod := range pods {
+			c := cost * shares[i]
+			v1Pod := simPod.ToV1()
+			r.cost.ByNamespace[v1Pod.Namespace] += c
+			for _, key := range r.costLabels {
+				value, ok := v1Pod.Labels[key]
+				if !ok {
+					continue
+				}
+				if _, ok := r.cost.ByLabel[key]; !ok {
+					r.cost.ByLabel[key] = map[string]float64{}
+				}
+				r.cost.ByLabel[key][value] += c
+			}
+		}
+		r.cost.Idle += cost * (1 - sum)
+	}
+}
+
+// dominantShare returns the largest ratio of the request to the allocatable resource among the
+// resources except the number of pods.
+func dominantShare(request, allocatable v1.ResourceList) float64 {
+	share := 0.0
+	for rsrc, req := range request {
+		alloc, ok := allocatable[rsrc]
+		if rsrc == v1.ResourcePods || !ok || alloc.IsZero() {
+			continue
+		}
+		if s := float64(req.MilliValue()) / float64(alloc.MilliValue()); s > share {
+			share = s
+		}
+	}
+	return share
+}
+
+// Records returns all lifecycle records sorted by their submission clock and key.
+func (r *LifecycleRecorder) Records() []*PodLifecycle {
+	recs := r.allRecords()
+	sort.Slice(recs, func(i, j int) bool {
+		if d := recs[i].SubmittedAt.Sub(recs[j].SubmittedAt); d != 0 {
+			return d < 0
+		}
+		return util.PodKeyFromNames(recs[i].Namespace, recs[i].Name) <
+			util.PodKeyFromNames(recs[j].Namespace, recs[j].Name)
+	})
+
+	return recs
+}
+
+// allRecords returns all lifecycle records, including the replaced ones, in no particular order.
+func (r *LifecycleRecorder) allRecords() []*PodLifecycle {
+	recs := make([]*PodLifecycle, 0, len(r.replaced)+len(r.records))
+	recs = append(recs, r.replaced...)
+	for _, rec := range r.records {
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func (r *LifecycleRecorder) record(podNamespace, podName string) *PodLifecycle {
+	return r.records[util.PodKeyFromNames(podNamespace, podName)]
+}
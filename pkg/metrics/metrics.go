@@ -25,7 +25,6 @@ import (
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/workqueue"
-	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
 )
 
 // Metrics represents a metrics at one time point, in the following structure.
@@ -33,6 +32,10 @@ import (
 //   Metrics[NodesMetricsKey] = map from node name to node.Metrics
 //   Metrics[PodsMetricsKey] = map from pod name to pod.Metrics
 // 	 Metrics[QueueMetricsKey] = queue.Metrics
+//   Metrics[ClusterMetricsKey] = ClusterMetrics
+//   Metrics[SchedulerMetricsKey] = SchedulerMetrics
+//   Metrics[AdmissionMetricsKey] = map from cluster queue name to admission.Metrics
+// Use the typed accessors (e.g., Nodes()) or Snapshot() rather than type-asserting the values.
 type Metrics map[string]interface{}
 
 const (
@@ -44,18 +47,14 @@ const (
 	PodsMetricsKey = "Pods"
 	// QueueMetricsKey is the key associated to a queue.Metrics.
 	QueueMetricsKey = "Queue"
+	// ClusterMetricsKey is the key associated to a ClusterMetrics.
+	ClusterMetricsKey = "Cluster"
+	// SchedulerMetricsKey is the key associated to a SchedulerMetrics.
+	SchedulerMetricsKey = "Scheduler"
+	// AdmissionMetricsKey is the key associated to a map of admission.Metrics.
+	AdmissionMetricsKey = "Admission"
 )
 
-func whichSharePolicy(demand, request, capacity int64) int {
-	res := 0 // allocaton = demand. (demand <= capacity.)
-	if demand > capacity && request <= capacity {
-		res = 1
-	} else if demand > capacity && request > capacity {
-		res = 2
-	}
-	return res
-}
-
 func max(a, b int64) int64 {
 	if a > b {
 		return a
@@ -70,180 +69,17 @@ func min(a, b int64) int64 {
 	return b
 }
 
-func minFloat32(a, b float32) float32 {
-	if a < b {
-		return a
-	}
-	return b
-}
-
-func allocate(clock clock.Clock, runingPodKeys []string, capacity, demand, request *nodeinfo.Resource, podsMetrics map[string]pod.Metrics, podsMetricsMutex *sync.RWMutex) (float32, float32) {
-	cpuFairSharePolicy := whichSharePolicy(demand.MilliCPU, request.MilliCPU, capacity.MilliCPU)
-	memFairSharePolicy := whichSharePolicy(demand.Memory, request.Memory, capacity.Memory)
-
-	numRunningPods := float32(len(runingPodKeys))
-	if numRunningPods == 0 {
-		return 0, 0
-	}
-	qos := float32(0)
-	c := capacity.MilliCPU
-	d := demand.MilliCPU
-	r := request.MilliCPU
-	// cpu
-	if cpuFairSharePolicy > 0 {
-		// guarantee
-		// fairShare := float32(c) / float32(d)
-		if d > 0 {
-			C := c
-			R := r
-			for _, key := range runingPodKeys {
-				podsMetricsMutex.Lock()
-				currMetrics := podsMetrics[key]
-				podsMetricsMutex.Unlock()
-
-				pRequest := nodeinfo.NewResource(currMetrics.ResourceRequest)
-				pUsage := nodeinfo.NewResource(currMetrics.ResourceUsage)
-
-				guarantee := int64(0)
-
-				if R > 0 {
-					guarantee = min(C*pRequest.MilliCPU/R, pRequest.MilliCPU)
-					guarantee = min(pUsage.MilliCPU, guarantee)
-				}
-				c -= guarantee
-				d -= guarantee
-				pAllocation := nodeinfo.NewResource(currMetrics.ResourceAllocation)
-				pAllocation.MilliCPU = int64(guarantee)
-				currMetrics.ResourceAllocation = pAllocation.ResourceList()
-				podsMetricsMutex.Lock()
-				podsMetrics[key] = currMetrics
-				podsMetricsMutex.Unlock()
-			}
-		}
-
-		// fairShare = float32(c) / float32(d)
-		if d > 0 && c > 0 {
-			C := c
-			D := d
-			for _, key := range runingPodKeys {
-				podsMetricsMutex.Lock()
-				currMetrics := podsMetrics[key]
-				podsMetricsMutex.Unlock()
-
-				pUsage := nodeinfo.NewResource(currMetrics.ResourceUsage)
-				pAllocation := nodeinfo.NewResource(currMetrics.ResourceAllocation)
-				extra := max(pUsage.MilliCPU-pAllocation.MilliCPU, 0)
-				pAllocation.MilliCPU += min(int64(C*extra/D), extra)
-				currMetrics.ResourceAllocation = pAllocation.ResourceList()
-
-				podsMetricsMutex.Lock()
-				podsMetrics[key] = currMetrics
-				podsMetricsMutex.Unlock()
-			}
-		}
-	}
-
-	c = capacity.Memory
-	d = demand.Memory
-	r = request.Memory
-	// memory
-	if memFairSharePolicy > 0 {
-		// guarantee
-		// fairShare := float32(c) / float32(d)
-		if d > 0 {
-			C := c
-			R := r
-			for _, key := range runingPodKeys {
-				podsMetricsMutex.Lock()
-				currMetrics := podsMetrics[key]
-				podsMetricsMutex.Unlock()
-
-				pRequest := nodeinfo.NewResource(currMetrics.ResourceRequest)
-				pUsage := nodeinfo.NewResource(currMetrics.ResourceUsage)
-				guarantee := int64(0)
-				if R > 0 {
-					guarantee = min(C*pRequest.Memory/R, pRequest.Memory)
-					guarantee = min(pUsage.Memory, guarantee)
-				}
-				c -= guarantee
-				d -= guarantee
-				pAllocation := nodeinfo.NewResource(currMetrics.ResourceAllocation)
-				pAllocation.Memory = int64(guarantee)
-				currMetrics.ResourceAllocation = pAllocation.ResourceList()
-
-				podsMetricsMutex.Lock()
-				podsMetrics[key] = currMetrics
-				podsMetricsMutex.Unlock()
-			}
-		}
-
-		// fairShare = float32(c) / float32(d)
-		if d > 0 && c > 0 {
-			C := c
-			D := d
-			for _, key := range runingPodKeys {
-				podsMetricsMutex.Lock()
-				currMetrics := podsMetrics[key]
-				podsMetricsMutex.Unlock()
-				pUsage := nodeinfo.NewResource(currMetrics.ResourceUsage)
-				pAllocation := nodeinfo.NewResource(currMetrics.ResourceAllocation)
-				extra := max(pUsage.Memory-pAllocation.Memory, 0)
-				extra = min(int64(float64(C)/float64(D)*float64(extra)), extra)
-				pAllocation.Memory += extra
-				currMetrics.ResourceAllocation = pAllocation.ResourceList()
-
-				podsMetricsMutex.Lock()
-				podsMetrics[key] = currMetrics
-				podsMetricsMutex.Unlock()
-			}
-		}
-	}
-
-	for _, key := range runingPodKeys {
-		podsMetricsMutex.Lock()
-		currMetrics := podsMetrics[key]
-		podsMetricsMutex.Unlock()
-
-		pUsage := nodeinfo.NewResource(currMetrics.ResourceUsage)
-		pAllocation := nodeinfo.NewResource(currMetrics.ResourceAllocation)
-		pRequest := nodeinfo.NewResource(currMetrics.ResourceRequest)
-
-		binaryQoS := true
-
-		if !binaryQoS {
-			if (pUsage.MilliCPU <= pAllocation.MilliCPU) &&
-				(pUsage.Memory <= pAllocation.Memory) {
-				// guaranteed
-				qos += 1
-			} else if pRequest.MilliCPU < pUsage.MilliCPU || pRequest.Memory < pUsage.Memory {
-				// best effort
-				c := float32(1)
-				m := float32(1)
-				if pUsage.MilliCPU != 0 {
-					c = float32(pAllocation.MilliCPU) / float32(pUsage.MilliCPU)
-				}
-				if pUsage.Memory != 0 {
-					m = float32(pAllocation.Memory) / float32(pUsage.Memory)
-				}
-				qos += minFloat32(c, m)
-			}
-		} else {
-			if (pUsage.MilliCPU <= pAllocation.MilliCPU || pAllocation.MilliCPU >= pRequest.MilliCPU) &&
-				(pUsage.Memory <= pAllocation.Memory || pAllocation.Memory >= pRequest.Memory) {
-				// guaranteed
-				qos += 1
-			}
-		}
-	}
-
-	return qos, numRunningPods
-}
-
-// BuildMetrics builds a Metrics at the given clock.
 const parralel = true
 const workerNum = 16
 
-func BuildMetrics(clock clock.Clock, nodes map[string]*node.Node, queue queue.PodQueue, predictionPenalty float32) (Metrics, error) {
+// BuildMetrics builds a Metrics at the given clock.
+// The capacity of each node is allocated to its running pods according to the resource models, and
+// pods are killed if the node is short of an incompressible resource.
+// The energy consumption of each node is integrated until the clock.
+// Co-located pods interfere with each other according to the interference model, if not nil.
+// Metrics of running pods are included only if withPods is true, since they are costly to keep in
+// memory and write for a large cluster.
+func BuildMetrics(clock clock.Clock, nodes map[string]*node.Node, queue queue.PodQueue, predictionPenalty float32, models ResourceModels, interference *InterferenceModel, withPods bool) (Metrics, error) {
 	isTinyMetrics := false
 	metrics := make(map[string]interface{})
 	metrics[ClockKey] = clock.ToRFC3339()
@@ -269,11 +105,9 @@ func BuildMetrics(clock clock.Clock, nodes map[string]*node.Node, queue queue.Po
 		nodeMetrics := node.Metrics(clock)
 		resourceAllocation := v1.ResourceList{}
 		if !isTinyMetrics {
-			capacity := nodeinfo.NewResource(nodeMetrics.Allocatable)
-			demand := nodeinfo.NewResource(nodeMetrics.TotalResourceUsage)
-			request := nodeinfo.NewResource(nodeMetrics.TotalResourceRequest)
 			pods := node.PodList()
 			runingPodKeys := make([]string, 0, len(pods))
+			runningPods := make(map[string]*pod.Pod, len(pods))
 			for _, pod := range pods {
 				if !pod.IsTerminated(clock) {
 					key, err := util.PodKey(pod.ToV1())
@@ -285,9 +119,23 @@ func BuildMetrics(clock clock.Clock, nodes map[string]*node.Node, queue queue.Po
 					podsMetrics[key] = podMetrics
 					podsMetricsMutex.Unlock()
 					runingPodKeys = append(runingPodKeys, key)
+					runningPods[key] = pod
 				}
 			}
-			qos, podNum := allocate(clock, runingPodKeys, capacity, demand, request, podsMetrics, &podsMetricsMutex)
+			if interference != nil {
+				nodeMetrics.TotalResourceUsage = interference.apply(clock, runingPodKeys, runningPods,
+					nodeMetrics.TotalResourceUsage, podsMetrics, &podsMetricsMutex)
+			}
+			qos, podNum, killed := allocate(runingPodKeys, nodeMetrics.Allocatable,
+				nodeMetrics.TotalResourceUsage, nodeMetrics.TotalResourceRequest, models, podsMetrics, &podsMetricsMutex)
+			for key, rsrc := range killed {
+				runningPods[key].Kill(clock, rsrc)
+				podsMetricsMutex.Lock()
+				podMetrics := podsMetrics[key]
+				podMetrics.Status = pod.Killed
+				podsMetrics[key] = podMetrics
+				podsMetricsMutex.Unlock()
+			}
 			// compute the real resource allocated (usage)
 			for _, key := range runingPodKeys {
 				podsMetricsMutex.Lock()
@@ -302,6 +150,9 @@ func BuildMetrics(clock clock.Clock, nodes map[string]*node.Node, queue queue.Po
 			podNumMutex.Unlock()
 		}
 		nodeMetrics.TotalResourceAllocation = resourceAllocation
+		nodeMetrics.Power = node.UpdateEnergy(clock, nodeMetrics.TotalResourceUsage)
+		nodeMetrics.Energy = node.Energy()
+		nodeMetrics.FacilityEnergy = node.FacilityEnergy()
 		nodesMetricsMutex.Lock()
 		nodesMetrics[name] = nodeMetrics
 		nodesMetricsMutex.Unlock()
@@ -313,8 +164,18 @@ func BuildMetrics(clock clock.Clock, nodes map[string]*node.Node, queue queue.Po
 	}
 
 	metrics[NodesMetricsKey] = nodesMetrics
-	metrics[PodsMetricsKey] = make(map[string]pod.Metrics) //podsMetrics
-	metrics[QueueMetricsKey] = queue.Metrics(QualityOfService, predictionPenalty, podQoses, numPods)
+	if withPods {
+		metrics[PodsMetricsKey] = podsMetrics
+	} else {
+		metrics[PodsMetricsKey] = make(map[string]pod.Metrics)
+	}
+	pendingPods := pendingPodsOf(queue)
+	queueMetrics := queue.Metrics(QualityOfService, predictionPenalty, podQoses, numPods)
+	queueMetrics.Starvation = buildStarvationMetrics(clock, pendingPods)
+	metrics[QueueMetricsKey] = queueMetrics
+	clusterMetrics := buildClusterMetrics(nodesMetrics, pendingPods)
+	clusterMetrics.Topology = buildTopologyMetrics(nodes, nodesMetrics)
+	metrics[ClusterMetricsKey] = clusterMetrics
 
 	return metrics, nil
 }
@@ -0,0 +1,95 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reader loads metrics logs written with the JSON formatter back into typed snapshots, for
+// analysis tools.
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
+)
+
+// Reader reads metrics.Snapshots from a metrics log, which has one JSON object per line.
+type Reader struct {
+	r    *bufio.Reader
+	line int
+}
+
+// NewReader creates a new Reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Next reads the next snapshot.
+// Logs written before the schema was versioned are read as well, while logs of a newer schema
+// version than metrics.SchemaVersion are rejected.
+// Returns io.EOF if there is no more snapshot, or error if failed to read or decode a line.
+func (r *Reader) Next() (*metrics.Snapshot, error) {
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return nil, err
+		}
+		r.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		snapshot := metrics.Snapshot{}
+		if err := json.Unmarshal(line, &snapshot); err != nil {
+			return nil, errors.Wrapf(err, "line %d", r.line)
+		}
+		if snapshot.SchemaVersion > metrics.SchemaVersion {
+			return nil, errors.Errorf("line %d: schema version %d is newer than the supported version %d",
+				r.line, snapshot.SchemaVersion, metrics.SchemaVersion)
+		}
+
+		return &snapshot, nil
+	}
+}
+
+// ReadAll reads all remaining snapshots.
+func (r *Reader) ReadAll() ([]*metrics.Snapshot, error) {
+	snapshots := []*metrics.Snapshot{}
+	for {
+		snapshot, err := r.Next()
+		if err == io.EOF {
+			return snapshots, nil
+		} else if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+}
+
+// ReadFile reads all snapshots from the metrics log at the given path.
+func ReadFile(path string) ([]*metrics.Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return NewReader(file).ReadAll()
+}
@@ -0,0 +1,91 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reader
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+func TestReaderRoundTrip(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2019-01-01T00:00:00+09:00")
+	clk := clock.NewClock(start)
+
+	met := metrics.Metrics{
+		metrics.ClockKey: clk.ToRFC3339(),
+		metrics.NodesMetricsKey: map[string]node.Metrics{
+			"node-0": {
+				Allocatable:    v1.ResourceList{"cpu": resource.MustParse("4")},
+				RunningPodsNum: 1,
+			},
+		},
+		metrics.PodsMetricsKey: map[string]pod.Metrics{
+			"default/pod-0": {BoundAt: clk, Node: "node-0", Status: pod.Deleted},
+		},
+		metrics.QueueMetricsKey:     queue.Metrics{PendingPodsNum: 2},
+		metrics.SchedulerMetricsKey: metrics.SchedulerMetrics{BoundPodsNum: 1},
+	}
+
+	formatter := metrics.JSONFormatter{}
+	line, err := formatter.Format(&met)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReader(strings.NewReader(line + "\n\n" + line))
+	snapshots, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("got: %d snapshots\nwant: 2 snapshots", len(snapshots))
+	}
+
+	snapshot := snapshots[0]
+	if snapshot.SchemaVersion != metrics.SchemaVersion || snapshot.Clock != clk.ToRFC3339() {
+		t.Errorf("got: %+v\nwant: version %d at %s", snapshot, metrics.SchemaVersion, clk.ToRFC3339())
+	}
+	cpu := snapshot.Nodes["node-0"].Allocatable["cpu"]
+	if cpu.Value() != 4 {
+		t.Errorf("got: %v\nwant: 4", cpu.Value())
+	}
+	podMet := snapshot.Pods["default/pod-0"]
+	if podMet.BoundAt != clk || podMet.Status != pod.Deleted {
+		t.Errorf("got: %+v\nwant: bound at %s and deleted", podMet, clk)
+	}
+	if snapshot.Queue.PendingPodsNum != 2 || snapshot.Scheduler.BoundPodsNum != 1 {
+		t.Errorf("got: %+v, %+v\nwant: 2 pending and 1 bound", snapshot.Queue, snapshot.Scheduler)
+	}
+
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReaderNewerSchema(t *testing.T) {
+	_, err := NewReader(strings.NewReader(`{"SchemaVersion": 1000}`)).Next()
+	assert.Error(t, err)
+}
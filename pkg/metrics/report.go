@@ -0,0 +1,336 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+)
+
+// Report summarizes the scheduling KPIs of a whole simulation.
+// All durations are in seconds.
+type Report struct {
+	Clock string
+
+	PodsNum         int
+	BoundPodsNum    int
+	FinishedPodsNum int
+	KilledPodsNum   int
+	DeletedPodsNum  int
+	PendingPodsNum  int
+	// EvictionsNum is the number of evictions of pods from interrupted nodes.
+	EvictionsNum int
+	// RejectedPodsNum is the number of pods rejected on submission, which are not counted in
+	// PodsNum.
+	RejectedPodsNum int `json:",omitempty"`
+
+	// Makespan is the duration from the first submission to the last spontaneous termination.
+	Makespan float64
+	// Throughput is the number of finished pods per hour of the makespan.
+	Throughput float64
+
+	// UsageUtilization is the time-averaged ratio of the total resource usage to the total
+	// allocatable resource of the cluster.
+	UsageUtilization map[v1.ResourceName]float64
+	// RequestUtilization is the time-averaged ratio of the total resource request to the total
+	// allocatable resource of the cluster.
+	RequestUtilization map[v1.ResourceName]float64
+
+	Summary     ReportBreakdown
+	ByPriority  map[string]*ReportBreakdown
+	ByNamespace map[string]*ReportBreakdown
+
+	// Cost is the cost of the nodes, or nil if no node is priced.
+	Cost *CostReport `json:",omitempty"`
+	// Admission summarizes the admission of the pods of each cluster queue, if any.
+	Admission map[string]*AdmissionReport `json:",omitempty"`
+}
+
+// AdmissionReport summarizes the quota-based admission of the pods of a cluster queue.
+type AdmissionReport struct {
+	PodsNum         int
+	AdmittedPodsNum int
+	Preemptions     int
+	// AdmissionWait is the duration from the submission to the last admission of each admitted
+	// pod.
+	AdmissionWait Distribution
+}
+
+// CostReport is the cost of the nodes accrued during a simulation, broken down for chargeback.
+type CostReport struct {
+	Total       float64
+	ByLifecycle map[string]float64
+	// ByNamespace and ByLabel (keyed by label key and value) attribute the cost of each node to the
+	// pods running on it, in proportion to their dominant share of its allocatable resource.
+	// Idle is the cost not attributed to any pod.
+	ByNamespace map[string]float64
+	ByLabel     map[string]map[string]float64
+	Idle        float64
+}
+
+// ReportBreakdown summarizes the KPIs of a subset of pods.
+type ReportBreakdown struct {
+	PodsNum         int
+	FinishedPodsNum int
+
+	// WaitTime is the duration from the submission to the binding of each bound pod.
+	WaitTime Distribution
+	// CompletionTime is the duration from the submission to the termination of each finished pod.
+	CompletionTime Distribution
+	// Slowdown is the ratio of CompletionTime to the execution duration of each finished pod.
+	Slowdown Distribution
+	// InterferenceSlowdown is the ratio of the execution duration of each finished pod to that
+	// without slowdown by the pods co-located with it.
+	InterferenceSlowdown Distribution
+}
+
+// Distribution summarizes a set of samples.
+type Distribution struct {
+	Count int
+	Mean  float64
+	P50   float64
+	P90   float64
+	P95   float64
+	P99   float64
+	Max   float64
+}
+
+// BuildReport builds a Report from the records and utilization samples collected until the given
+// clock.
+func (r *LifecycleRecorder) BuildReport(clk clock.Clock) Report {
+	report := Report{
+		Clock:              clk.ToRFC3339(),
+		RejectedPodsNum:    r.rejectedNum,
+		UsageUtilization:   map[v1.ResourceName]float64{},
+		RequestUtilization: map[v1.ResourceName]float64{},
+		ByPriority:         map[string]*ReportBreakdown{},
+		ByNamespace:        map[string]*ReportBreakdown{},
+	}
+
+	all := samples{}
+	byPrio := map[string]*samples{}
+	byNs := map[string]*samples{}
+
+	recs := r.allRecords()
+	for _, rec := range recs {
+		report.PodsNum++
+		switch {
+		case rec.FinishedAt != nil:
+			report.FinishedPodsNum++
+		case rec.KilledAt != nil:
+			report.KilledPodsNum++
+		case rec.DeletedAt != nil:
+			report.DeletedPodsNum++
+		case rec.BoundAt == nil:
+			report.PendingPodsNum++
+		}
+		if rec.BoundAt != nil {
+			report.BoundPodsNum++
+		}
+		report.EvictionsNum += rec.Evictions
+
+		prio := strconv.Itoa(int(rec.Priority))
+		if _, ok := byPrio[prio]; !ok {
+			byPrio[prio] = &samples{}
+		}
+		if _, ok := byNs[rec.Namespace]; !ok {
+			byNs[rec.Namespace] = &samples{}
+		}
+
+		all.add(rec)
+		byPrio[prio].add(rec)
+		byNs[rec.Namespace].add(rec)
+	}
+
+	admissionWait := map[string][]float64{}
+	for _, rec := range recs {
+		if rec.ClusterQueue == "" {
+			continue
+		}
+		if report.Admission == nil {
+			report.Admission = map[string]*AdmissionReport{}
+		}
+		adm, ok := report.Admission[rec.ClusterQueue]
+		if !ok {
+			adm = &AdmissionReport{}
+			report.Admission[rec.ClusterQueue] = adm
+		}
+		adm.PodsNum++
+		adm.Preemptions += rec.Preemptions
+		if rec.AdmittedAt != nil {
+			adm.AdmittedPodsNum++
+			admissionWait[rec.ClusterQueue] = append(admissionWait[rec.ClusterQueue],
+				rec.AdmittedAt.Sub(rec.SubmittedAt).Seconds())
+		}
+	}
+	for cq, adm := range report.Admission {
+		adm.AdmissionWait = newDistribution(admissionWait[cq])
+	}
+
+	report.Summary = all.breakdown()
+	for prio, s := range byPrio {
+		b := s.breakdown()
+		report.ByPriority[prio] = &b
+	}
+	for ns, s := range byNs {
+		b := s.breakdown()
+		report.ByNamespace[ns] = &b
+	}
+
+	if r.firstSubmit != nil && r.lastFinish != nil {
+		report.Makespan = r.lastFinish.Sub(*r.firstSubmit).Seconds()
+		if report.Makespan > 0 {
+			report.Throughput = float64(report.FinishedPodsNum) / (report.Makespan / 3600)
+		}
+	}
+
+	if r.cost.Total > 0 {
+		cost := r.cost
+		report.Cost = &cost
+	}
+
+	if r.utilSamples > 0 {
+		for rsrc, acc := range r.usageRatioAcc {
+			report.UsageUtilization[rsrc] = acc / float64(r.utilSamples)
+		}
+		for rsrc, acc := range r.reqRatioAcc {
+			report.RequestUtilization[rsrc] = acc / float64(r.utilSamples)
+		}
+	}
+
+	return report
+}
+
+// WriteReport writes the Report built at the given clock to dest, in the indented JSON format.
+// dest is interpreted in the same way as NewFileWriter.
+func (r *LifecycleRecorder) WriteReport(clk clock.Clock, dest string) error {
+	file, err := openDest(dest)
+	if err != nil {
+		return err
+	}
+	defer closeDest(file)
+
+	bytes, err := json.MarshalIndent(r.BuildReport(clk), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(bytes, '\n'))
+
+	return err
+}
+
+// WriteRecords writes all lifecycle records to dest, one JSON object per line.
+// dest is interpreted in the same way as NewFileWriter.
+func (r *LifecycleRecorder) WriteRecords(dest string) error {
+	file, err := openDest(dest)
+	if err != nil {
+		return err
+	}
+	defer closeDest(file)
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, rec := range r.Records() {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// samples collects per-pod KPI samples of a subset of pods.
+type samples struct {
+	podsNum        int
+	waitTime       []float64
+	completionTime []float64
+	slowdown       []float64
+	interference   []float64
+}
+
+func (s *samples) add(rec *PodLifecycle) {
+	s.podsNum++
+
+	if rec.BoundAt == nil {
+		return
+	}
+	s.waitTime = append(s.waitTime, rec.BoundAt.Sub(rec.SubmittedAt).Seconds())
+
+	if rec.FinishedAt == nil {
+		return
+	}
+	jct := rec.FinishedAt.Sub(rec.SubmittedAt).Seconds()
+	s.completionTime = append(s.completionTime, jct)
+	if exec := rec.FinishedAt.Sub(*rec.BoundAt).Seconds(); exec > 0 {
+		s.slowdown = append(s.slowdown, jct/exec)
+		if rec.ExecutionSeconds > 0 {
+			s.interference = append(s.interference, exec/float64(rec.ExecutionSeconds))
+		}
+	}
+}
+
+func (s *samples) breakdown() ReportBreakdown {
+	return ReportBreakdown{
+		PodsNum:         s.podsNum,
+		FinishedPodsNum: len(s.completionTime),
+		WaitTime:        newDistribution(s.waitTime),
+		CompletionTime:  newDistribution(s.completionTime),
+		Slowdown:        newDistribution(s.slowdown),
+
+		InterferenceSlowdown: newDistribution(s.interference),
+	}
+}
+
+// newDistribution summarizes the given samples with nearest-rank percentiles.
+func newDistribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+
+	return Distribution{
+		Count: len(sorted),
+		Mean:  sum / float64(len(sorted)),
+		P50:   percentile(sorted, 50),
+		P90:   percentile(sorted, 90),
+		P95:   percentile(sorted, 95),
+		P99:   percentile(sorted, 99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the p-th percentile of the sorted values with the nearest-rank method.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
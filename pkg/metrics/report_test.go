@@ -0,0 +1,244 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+)
+
+func TestNewDistribution(t *testing.T) {
+	values := []float64{}
+	for i := 100; i >= 1; i-- {
+		values = append(values, float64(i))
+	}
+
+	actual := newDistribution(values)
+	expected := Distribution{
+		Count: 100,
+		Mean:  50.5,
+		P50:   50,
+		P90:   90,
+		P95:   95,
+		P99:   99,
+		Max:   100,
+	}
+	if actual != expected {
+		t.Errorf("got: %+v\nwant: %+v", actual, expected)
+	}
+
+	if empty := newDistribution([]float64{}); empty != (Distribution{}) {
+		t.Errorf("got: %+v\nwant: %+v", empty, Distribution{})
+	}
+}
+
+func TestLifecycleRecorderBuildReport(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2019-01-01T00:00:00+09:00")
+	clk := clock.NewClock(start)
+	at := func(sec int) *clock.Clock {
+		c := clk.Add(time.Duration(sec) * time.Second)
+		return &c
+	}
+
+	prio := int32(1)
+	newPod := func(namespace, name string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec:       v1.PodSpec{Priority: &prio},
+		}
+	}
+
+	rec := NewLifecycleRecorder()
+	rec.RecordSubmit(clk, newPod("default", "pod-0"))
+	rec.RecordSubmit(clk, newPod("default", "pod-1"))
+	rec.RecordSubmit(*at(10), newPod("other", "pod-2"))
+	rec.RecordSubmit(*at(10), newPod("other", "pod-3"))
+
+	// pod-0 waits 10s and runs 10s, pod-1 waits 20s and runs 20s, pod-2 is deleted while pending,
+	// and pod-3 keeps pending.
+	rec.RecordAttempt(clk, newPod("default", "pod-1"))
+	r0 := rec.records["default/pod-0"]
+	r0.BoundAt, r0.FinishedAt, r0.Attempts = at(10), at(20), 1
+	r1 := rec.records["default/pod-1"]
+	r1.BoundAt, r1.FinishedAt, r1.Attempts = at(20), at(40), 2
+	rec.RecordDelete(*at(30), "other", "pod-2")
+	rec.firstSubmit, rec.lastFinish = at(0), at(40)
+
+	rec.Observe(clk, map[string]node.Metrics{
+		"node-0": {
+			Allocatable:          v1.ResourceList{"cpu": resource.MustParse("4")},
+			TotalResourceUsage:   v1.ResourceList{"cpu": resource.MustParse("1")},
+			TotalResourceRequest: v1.ResourceList{"cpu": resource.MustParse("2")},
+		},
+	})
+
+	report := rec.BuildReport(*at(40))
+
+	if report.PodsNum != 4 || report.BoundPodsNum != 2 || report.FinishedPodsNum != 2 ||
+		report.DeletedPodsNum != 1 || report.PendingPodsNum != 1 {
+		t.Errorf("unexpected pod counts: %+v", report)
+	}
+	if report.Makespan != 40 || report.Throughput != 180 {
+		t.Errorf("got: makespan %v, throughput %v\nwant: makespan 40, throughput 180",
+			report.Makespan, report.Throughput)
+	}
+
+	expectedUsage := map[v1.ResourceName]float64{"cpu": 0.25}
+	if !reflect.DeepEqual(report.UsageUtilization, expectedUsage) {
+		t.Errorf("got: %+v\nwant: %+v", report.UsageUtilization, expectedUsage)
+	}
+	expectedRequest := map[v1.ResourceName]float64{"cpu": 0.5}
+	if !reflect.DeepEqual(report.RequestUtilization, expectedRequest) {
+		t.Errorf("got: %+v\nwant: %+v", report.RequestUtilization, expectedRequest)
+	}
+
+	def := report.ByNamespace["default"]
+	if def.WaitTime.Mean != 15 || def.CompletionTime.Max != 40 || def.Slowdown.Mean != 2 {
+		t.Errorf("unexpected breakdown of namespace default: %+v", def)
+	}
+	other := report.ByNamespace["other"]
+	if other.PodsNum != 2 || other.WaitTime.Count != 0 {
+		t.Errorf("unexpected breakdown of namespace other: %+v", other)
+	}
+	if report.ByPriority["1"].PodsNum != 4 {
+		t.Errorf("got: %d\nwant: 4", report.ByPriority["1"].PodsNum)
+	}
+}
+
+func TestLifecycleRecorderCost(t *testing.T) {
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	n := node.NewNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+		Status:     v1.NodeStatus{Allocatable: v1.ResourceList{"cpu": resource.MustParse("4"), "pods": resource.MustParse("10")}},
+	})
+	newPod := func(namespace, team, cpu string) *v1.Pod {
+		labels := map[string]string{}
+		if team != "" {
+			labels["team"] = team
+		}
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        "pod",
+				Labels:      labels,
+				Annotations: map[string]string{"simSpec": "- seconds: 7200\n  resourceUsage:\n    cpu: 1\n"},
+			},
+			Spec: v1.PodSpec{Containers: []v1.Container{{Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{"cpu": resource.MustParse(cpu)},
+			}}}},
+		}
+	}
+
+	rec := NewLifecycleRecorder()
+	rec.SetCostLabels([]string{"team"})
+	for _, v1Pod := range []*v1.Pod{newPod("ns-a", "x", "2"), newPod("ns-b", "", "1")} {
+		rec.RecordSubmit(start, v1Pod)
+		simPod, err := n.BindPod(start, v1Pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec.RecordBind(start, simPod)
+	}
+
+	nodesMetrics := map[string]node.Metrics{
+		"node-0": {
+			Allocatable: v1.ResourceList{"cpu": resource.MustParse("4")},
+			Lifecycle:   node.OnDemand,
+			HourlyPrice: 2,
+		},
+	}
+	rec.Observe(start, nodesMetrics)
+	rec.Observe(start.Add(time.Hour), nodesMetrics)
+	rec.RecordEvict(start.Add(time.Hour), "ns-a", "pod")
+
+	report := rec.BuildReport(start.Add(time.Hour))
+	expected := &CostReport{
+		Total:       2,
+		ByLifecycle: map[string]float64{node.OnDemand: 2},
+		ByNamespace: map[string]float64{"ns-a": 1, "ns-b": 0.5},
+		ByLabel:     map[string]map[string]float64{"team": {"x": 1}},
+		Idle:        0.5,
+	}
+	if !reflect.DeepEqual(report.Cost, expected) {
+		t.Errorf("got: %+v\nwant: %+v", report.Cost, expected)
+	}
+
+	if report.EvictionsNum != 1 || report.BoundPodsNum != 1 {
+		t.Errorf("got: %d evictions, %d bound pods\nwant: 1 eviction, 1 bound pod", report.EvictionsNum, report.BoundPodsNum)
+	}
+}
+
+func TestLifecycleRecorderResubmit(t *testing.T) {
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	n := node.NewNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+		Status:     v1.NodeStatus{Allocatable: v1.ResourceList{"cpu": resource.MustParse("4"), "pods": resource.MustParse("10")}},
+	})
+	newPod := func(seconds int) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        "pod",
+				Annotations: map[string]string{"simSpec": fmt.Sprintf("- seconds: %d\n  resourceUsage:\n    cpu: 1\n", seconds)},
+			},
+			Spec: v1.PodSpec{Containers: []v1.Container{{}}},
+		}
+	}
+
+	rec := NewLifecycleRecorder()
+	rec.RecordSubmit(start, newPod(10))
+	simPod, err := n.BindPod(start, newPod(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.RecordBind(start, simPod)
+
+	// The pod is re-submitted in the tick in which the previous one finished, before Observe.
+	resubmitted := start.Add(10 * time.Second)
+	rec.RecordSubmit(resubmitted, newPod(100))
+	rec.Observe(resubmitted, map[string]node.Metrics{})
+
+	// Both pods are reported, the previous one finished.
+	records := rec.Records()
+	if len(records) != 2 {
+		t.Fatalf("got: %d records\nwant: 2", len(records))
+	}
+	if prev := records[0]; prev.SubmittedAt != start || prev.FinishedAt == nil || *prev.FinishedAt != resubmitted {
+		t.Errorf("got: submitted at %v, finished at %v\nwant: submitted at %v, finished at %v",
+			prev.SubmittedAt, prev.FinishedAt, start, resubmitted)
+	}
+	if r := records[1]; r.SubmittedAt != resubmitted || r.FinishedAt != nil {
+		t.Errorf("got: submitted at %v, finished at %v\nwant: submitted at %v, not finished",
+			r.SubmittedAt, r.FinishedAt, resubmitted)
+	}
+	if rec.lastFinish == nil || *rec.lastFinish != resubmitted {
+		t.Errorf("got: last finish %v\nwant: %v", rec.lastFinish, resubmitted)
+	}
+
+	report := rec.BuildReport(resubmitted)
+	if report.PodsNum != 2 || report.FinishedPodsNum != 1 || report.PendingPodsNum != 1 {
+		t.Errorf("got: %d pods, %d finished, %d pending\nwant: 2 pods, 1 finished, 1 pending",
+			report.PodsNum, report.FinishedPodsNum, report.PendingPodsNum)
+	}
+}
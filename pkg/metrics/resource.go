@@ -0,0 +1,242 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
+)
+
+// ResourceModel declares how a resource is shared among the pods on a node when their total usage
+// exceeds the capacity of the node.
+type ResourceModel struct {
+	// Compressible resources (e.g., CPU and bandwidth) are throttled: each pod is guaranteed its
+	// share of the capacity in proportion to its request, and the rest is shared in proportion to
+	// the excess usage.
+	// Incompressible resources (e.g., memory and GPU) cannot be throttled: pods are killed, those
+	// using more than their request and of lower priority first, until the usage fits.
+	Compressible bool
+}
+
+// ResourceModels maps resource names to their models.
+// Resources not in the map are compressible, as all resources were before models were introduced.
+type ResourceModels map[v1.ResourceName]ResourceModel
+
+// IsCompressible returns whether the given resource is compressible.
+func (m ResourceModels) IsCompressible(rsrc v1.ResourceName) bool {
+	model, ok := m[rsrc]
+	return !ok || model.Compressible
+}
+
+// amount returns the amount of the given resource in q, in millicores for CPU and in the base unit
+// for the others.
+func amount(rsrc v1.ResourceName, q resource.Quantity) int64 {
+	if rsrc == v1.ResourceCPU {
+		return q.MilliValue()
+	}
+	return q.Value()
+}
+
+// quantity is the inverse of amount.
+func quantity(rsrc v1.ResourceName, a int64, format resource.Format) resource.Quantity {
+	if rsrc == v1.ResourceCPU {
+		return *resource.NewMilliQuantity(a, format)
+	}
+	return *resource.NewQuantity(a, format)
+}
+
+// allocate allocates the capacity of a node to its running pods, given the total demand (usage)
+// and request of the pods, and stores the allocation in their metrics.
+// Returns the sum of the QoS of the pods, the number of the pods, and the pods to be killed for
+// an incompressible resource.
+func allocate(runningPodKeys []string, capacity, demand, request v1.ResourceList, models ResourceModels,
+	podsMetrics map[string]pod.Metrics, podsMetricsMutex *sync.RWMutex) (float32, float32, map[string]v1.ResourceName) {
+
+	numRunningPods := float32(len(runningPodKeys))
+	if numRunningPods == 0 {
+		return 0, 0, nil
+	}
+
+	// Take a snapshot of the metrics of the running pods, to be written back at last.
+	// Each pod is allocated its usage unless the node is short of a resource.
+	pods := make([]pod.Metrics, 0, len(runningPodKeys))
+	podsMetricsMutex.RLock()
+	for _, key := range runningPodKeys {
+		met := podsMetrics[key]
+		if met.ResourceAllocation == nil {
+			met.ResourceAllocation = met.ResourceUsage.DeepCopy()
+		}
+		pods = append(pods, met)
+	}
+	podsMetricsMutex.RUnlock()
+
+	// Resources are processed in sorted order, so that the same pods are killed for the same
+	// resources in every run.
+	rsrcs := make([]v1.ResourceName, 0, len(capacity))
+	for rsrc := range capacity {
+		rsrcs = append(rsrcs, rsrc)
+	}
+	sort.Slice(rsrcs, func(i, j int) bool { return rsrcs[i] < rsrcs[j] })
+
+	killed := map[string]v1.ResourceName{}
+	for _, rsrc := range rsrcs {
+		if rsrc == v1.ResourcePods {
+			continue
+		}
+		c := amount(rsrc, capacity[rsrc])
+		d := amount(rsrc, demand[rsrc])
+		if d <= c {
+			// Every pod is allocated its usage.
+			continue
+		}
+
+		if models.IsCompressible(rsrc) {
+			shareCompressible(rsrc, c, d, amount(rsrc, request[rsrc]), pods)
+		} else {
+			// Pods already killed for another resource release this one as well.
+			alive := make([]bool, len(pods))
+			for i, key := range runningPodKeys {
+				if _, ok := killed[key]; ok {
+					d -= amount(rsrc, pods[i].ResourceUsage[rsrc])
+				} else {
+					alive[i] = true
+				}
+			}
+			for _, i := range victims(rsrc, c, d, pods, alive) {
+				killed[runningPodKeys[i]] = rsrc
+			}
+		}
+	}
+
+	qos := float32(0)
+	for i, key := range runningPodKeys {
+		if _, ok := killed[key]; ok {
+			pods[i].ResourceAllocation = v1.ResourceList{}
+			continue
+		}
+		if isGuaranteed(pods[i]) {
+			qos++
+		}
+	}
+
+	podsMetricsMutex.Lock()
+	for i, key := range runningPodKeys {
+		podsMetrics[key] = pods[i]
+	}
+	podsMetricsMutex.Unlock()
+
+	return qos, numRunningPods, killed
+}
+
+// shareCompressible shares the capacity c of a compressible resource among the pods demanding d in
+// total, whose total request is r.
+// Each pod is first guaranteed its share of the capacity in proportion to its request (but no more
+// than its usage), and then the rest of the capacity is shared in proportion to the excess usage.
+func shareCompressible(rsrc v1.ResourceName, c, d, r int64, pods []pod.Metrics) {
+	alloc := make([]int64, len(pods))
+	usage := make([]int64, len(pods))
+
+	C, R := float64(c), float64(r)
+	for i, met := range pods {
+		usage[i] = amount(rsrc, met.ResourceUsage[rsrc])
+		if r > 0 {
+			req := amount(rsrc, met.ResourceRequest[rsrc])
+			guarantee := int64(math.Min(C*float64(req)/R, float64(req)))
+			alloc[i] = min(usage[i], guarantee)
+		}
+		c -= alloc[i]
+		d -= alloc[i]
+	}
+
+	if d > 0 && c > 0 {
+		C, D := float64(c), float64(d)
+		for i := range pods {
+			extra := max(usage[i]-alloc[i], 0)
+			alloc[i] += min(int64(C/D*float64(extra)), extra)
+		}
+	}
+
+	for i := range pods {
+		allocation := pods[i].ResourceAllocation.DeepCopy()
+		allocation[rsrc] = quantity(rsrc, alloc[i], pods[i].ResourceUsage[rsrc].Format)
+		pods[i].ResourceAllocation = allocation
+	}
+}
+
+// victims returns the indices of the pods to be killed so that the demand d of an incompressible
+// resource fits in the capacity c: pods using more than their request first, and among them, those
+// of lower priority and then those using more first. Only the alive pods are candidates.
+func victims(rsrc v1.ResourceName, c, d int64, pods []pod.Metrics, alive []bool) []int {
+	type candidate struct {
+		idx      int
+		overuse  bool
+		priority int32
+		usage    int64
+	}
+
+	candidates := make([]candidate, 0, len(pods))
+	for i, met := range pods {
+		usage := amount(rsrc, met.ResourceUsage[rsrc])
+		if !alive[i] || usage == 0 {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			idx:      i,
+			overuse:  usage > amount(rsrc, met.ResourceRequest[rsrc]),
+			priority: met.Priority,
+			usage:    usage,
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		if ci.overuse != cj.overuse {
+			return ci.overuse
+		}
+		if ci.priority != cj.priority {
+			return ci.priority < cj.priority
+		}
+		return ci.usage > cj.usage
+	})
+
+	idxs := []int{}
+	for _, cand := range candidates {
+		if d <= c {
+			break
+		}
+		idxs = append(idxs, cand.idx)
+		d -= cand.usage
+	}
+	return idxs
+}
+
+// isGuaranteed returns whether the pod is allocated, for every resource, either its usage or at
+// least its request.
+func isGuaranteed(met pod.Metrics) bool {
+	for rsrc, usage := range met.ResourceUsage {
+		alloc := met.ResourceAllocation[rsrc]
+		req := met.ResourceRequest[rsrc]
+		if usage.Cmp(alloc) > 0 && alloc.Cmp(req) < 0 {
+			return false
+		}
+	}
+	return true
+}
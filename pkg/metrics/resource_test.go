@@ -0,0 +1,153 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+const gpu = v1.ResourceName("nvidia.com/gpu")
+
+func gpuList(cpu, gpus string) v1.ResourceList {
+	return v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), gpu: resource.MustParse(gpus)}
+}
+
+func TestAllocate(t *testing.T) {
+	podsMetrics := map[string]pod.Metrics{
+		// Uses more than its request of both resources.
+		"default/pod-0": {ResourceRequest: gpuList("2", "1"), ResourceUsage: gpuList("6", "2"), Priority: 1},
+		"default/pod-1": {ResourceRequest: gpuList("2", "1"), ResourceUsage: gpuList("2", "1"), Priority: 0},
+	}
+	keys := []string{"default/pod-0", "default/pod-1"}
+
+	capacity := gpuList("4", "2")
+	demand := gpuList("8", "3")
+	request := gpuList("4", "2")
+	models := ResourceModels{gpu: {Compressible: false}}
+
+	qos, num, killed := allocate(keys, capacity, demand, request, models, podsMetrics, &sync.RWMutex{})
+
+	// The GPU is incompressible; pod-0 uses more than its request, so it is killed first despite
+	// its higher priority.
+	if len(killed) != 1 || killed["default/pod-0"] != gpu {
+		t.Errorf("got: %v\nwant: pod-0 killed for %s", killed, gpu)
+	}
+	if alloc := podsMetrics["default/pod-0"].ResourceAllocation; len(alloc) != 0 {
+		t.Errorf("got: %v\nwant: nothing allocated to the killed pod", alloc)
+	}
+	// The CPU is compressible by default; pod-1 is guaranteed its request.
+	if cpu := podsMetrics["default/pod-1"].ResourceAllocation[v1.ResourceCPU]; cpu.MilliValue() != 2000 {
+		t.Errorf("got: %s CPUs\nwant: 2 CPUs", cpu.String())
+	}
+	if qos != 1 || num != 2 {
+		t.Errorf("got: qos %v of %v pods\nwant: qos 1 of 2 pods", qos, num)
+	}
+}
+
+func TestAllocateKillsInResourceOrder(t *testing.T) {
+	list := func(mem, gpus string) v1.ResourceList {
+		return v1.ResourceList{v1.ResourceMemory: resource.MustParse(mem), gpu: resource.MustParse(gpus)}
+	}
+	keys := []string{"default/pod-0", "default/pod-1"}
+	models := ResourceModels{v1.ResourceMemory: {Compressible: false}, gpu: {Compressible: false}}
+
+	// Killing either pod relieves the node of both resources. Memory is processed first, for
+	// which pod-0 uses more than its request.
+	for i := 0; i < 20; i++ {
+		podsMetrics := map[string]pod.Metrics{
+			"default/pod-0": {ResourceRequest: list("1Gi", "1"), ResourceUsage: list("3Gi", "1")},
+			"default/pod-1": {ResourceRequest: list("1Gi", "1"), ResourceUsage: list("1Gi", "2")},
+		}
+		_, _, killed := allocate(
+			keys, list("3Gi", "2"), list("4Gi", "3"), list("2Gi", "2"), models, podsMetrics, &sync.RWMutex{})
+		if len(killed) != 1 || killed["default/pod-0"] != v1.ResourceMemory {
+			t.Fatalf("got: %v\nwant: pod-0 killed for memory", killed)
+		}
+	}
+}
+
+func TestBuildMetricsKillsPods(t *testing.T) {
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	n := node.NewNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+		Status:     v1.NodeStatus{Allocatable: v1.ResourceList{"cpu": resource.MustParse("4"), "memory": resource.MustParse("4Gi"), "pods": resource.MustParse("10")}},
+	})
+	newPod := func(name string, prio int32, mem string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Annotations: map[string]string{
+					"simSpec": "- seconds: 100\n  resourceUsage:\n    cpu: 1\n    memory: " + mem + "\n",
+				},
+			},
+			Spec: v1.PodSpec{
+				Priority: &prio,
+				Containers: []v1.Container{{Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{"cpu": resource.MustParse("1"), "memory": resource.MustParse("2Gi")},
+				}}},
+			},
+		}
+	}
+	for _, p := range []*v1.Pod{newPod("pod-0", 0, "2Gi"), newPod("pod-1", 0, "3Gi"), newPod("pod-2", 1, "2Gi")} {
+		if _, err := n.BindPod(start, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nodes := map[string]*node.Node{"node-0": &n}
+
+	// By default, memory is compressible and no pod is killed.
+	now := start.Add(10 * time.Second)
+	if _, err := BuildMetrics(now, nodes, queue.NewFIFOQueue(), 1, nil, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if n.Pod("default", "pod-1").IsKilled() {
+		t.Errorf("got: pod-1 killed\nwant: no pod killed")
+	}
+
+	models := ResourceModels{v1.ResourceMemory: {Compressible: false}}
+	met, err := BuildMetrics(now, nodes, queue.NewFIFOQueue(), 1, models, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string]bool{"pod-0": false, "pod-1": true, "pod-2": false} {
+		if actual := n.Pod("default", name).IsKilled(); actual != expected {
+			t.Errorf("%s: got: killed %v\nwant: killed %v", name, actual, expected)
+		}
+	}
+	if status := met.Pods()["default/pod-1"].Status; status != pod.Killed {
+		t.Errorf("got: %v\nwant: %v", status, pod.Killed)
+	}
+
+	killed := n.Pod("default", "pod-1")
+	if status := killed.BuildStatus(now); status.Phase != v1.PodFailed ||
+		status.ContainerStatuses[0].State.Terminated.Reason != "OOMKilled" {
+		t.Errorf("got: %+v\nwant: failed with OOMKilled", status)
+	}
+	if killed.IsRunning(now.Add(time.Second)) || len(killed.ResourceUsage(now.Add(time.Second))) != 0 {
+		t.Errorf("got: killed pod running\nwant: killed pod not running")
+	}
+}
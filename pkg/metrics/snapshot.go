@@ -0,0 +1,120 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/admission"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/pod"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+// SchemaVersion is the version of the Snapshot schema.
+// It is incremented whenever a field of Snapshot (or of its sections) is renamed, removed, or
+// changes its meaning, so that readers can reject logs they cannot interpret.
+// Version 2 counts the pods rejected by validation in SchedulerMetrics.RejectedPodsNum.
+const SchemaVersion = 2
+
+// Snapshot is the typed representation of a Metrics, which is written in the JSON format.
+type Snapshot struct {
+	SchemaVersion int
+	Clock         string
+	Cluster       ClusterMetrics
+	Nodes         map[string]node.Metrics
+	Pods          map[string]pod.Metrics
+	Queue         queue.Metrics
+	Scheduler     SchedulerMetrics
+	// Admission is the metrics of each cluster queue, if quota-based admission is configured.
+	Admission map[string]admission.Metrics `json:",omitempty"`
+}
+
+// SchedulerMetrics is a metrics of the scheduler, accumulated from the start of the simulation.
+type SchedulerMetrics struct {
+	BoundPodsNum      int
+	DeletedPodsNum    int
+	FailedAttemptsNum int
+	// EvictedPodsNum is the number of pods evicted from interrupted nodes and requeued.
+	EvictedPodsNum int `json:",omitempty"`
+	// RejectedPodsNum is the number of pods rejected on submission, and Rejections breaks it down
+	// by the rejecting admission plugin, or "Validation".
+	RejectedPodsNum int            `json:",omitempty"`
+	Rejections      map[string]int `json:",omitempty"`
+	// FailedBindsNum is the number of pods dropped since they failed to be bound.
+	FailedBindsNum int `json:",omitempty"`
+	// Timing is the wall time spent in each part of the simulator, in microseconds.
+	Timing map[string]int64 `json:",omitempty"`
+}
+
+// Snapshot converts this Metrics to a Snapshot.
+// Sections other than clock, nodes, pods, and queue are optional and left empty if missing.
+// Returns error if this Metrics does not have valid structure.
+func (m Metrics) Snapshot() (*Snapshot, error) {
+	if err := validateMetrics(&m); err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		SchemaVersion: SchemaVersion,
+		Clock:         m.Clock(),
+		Cluster:       m.Cluster(),
+		Nodes:         m.Nodes(),
+		Pods:          m.Pods(),
+		Queue:         m.Queue(),
+		Scheduler:     m.Scheduler(),
+		Admission:     m.Admission(),
+	}, nil
+}
+
+// Clock returns the formatted clock of this Metrics, or an empty string if missing.
+func (m Metrics) Clock() string {
+	clk, _ := m[ClockKey].(string)
+	return clk
+}
+
+// Nodes returns the node metrics of this Metrics, or nil if missing.
+func (m Metrics) Nodes() map[string]node.Metrics {
+	met, _ := m[NodesMetricsKey].(map[string]node.Metrics)
+	return met
+}
+
+// Pods returns the pod metrics of this Metrics, or nil if missing.
+func (m Metrics) Pods() map[string]pod.Metrics {
+	met, _ := m[PodsMetricsKey].(map[string]pod.Metrics)
+	return met
+}
+
+// Queue returns the queue metrics of this Metrics, or the zero value if missing.
+func (m Metrics) Queue() queue.Metrics {
+	met, _ := m[QueueMetricsKey].(queue.Metrics)
+	return met
+}
+
+// Cluster returns the cluster metrics of this Metrics, or the zero value if missing.
+func (m Metrics) Cluster() ClusterMetrics {
+	met, _ := m[ClusterMetricsKey].(ClusterMetrics)
+	return met
+}
+
+// Scheduler returns the scheduler metrics of this Metrics, or the zero value if missing.
+func (m Metrics) Scheduler() SchedulerMetrics {
+	met, _ := m[SchedulerMetricsKey].(SchedulerMetrics)
+	return met
+}
+
+// Admission returns the metrics of the cluster queues of this Metrics, or nil if missing.
+func (m Metrics) Admission() map[string]admission.Metrics {
+	met, _ := m[AdmissionMetricsKey].(map[string]admission.Metrics)
+	return met
+}
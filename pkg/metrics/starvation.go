@@ -0,0 +1,62 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// buildStarvationMetrics finds the longest-waiting pod of each class among the pending pods.
+// Returns nil if no pod is pending.
+func buildStarvationMetrics(clk clock.Clock, pendingPods []*v1.Pod) map[string]queue.StarvationMetrics {
+	if len(pendingPods) == 0 {
+		return nil
+	}
+
+	starvation := map[string]queue.StarvationMetrics{}
+	for _, pod := range pendingPods {
+		key, err := util.PodKey(pod)
+		if err != nil {
+			continue
+		}
+
+		class := starvationClass(pod)
+		wait := clk.Sub(clock.NewClockWithMetaV1(pod.CreationTimestamp)).Seconds()
+		met, ok := starvation[class]
+		if !ok || wait > met.WaitSeconds || (wait == met.WaitSeconds && key < met.Pod) {
+			met.Pod = key
+			met.WaitSeconds = wait
+		}
+		met.PendingPodsNum++
+		starvation[class] = met
+	}
+
+	return starvation
+}
+
+// starvationClass returns the priority class name of the pod, or its priority if it has no
+// priority class.
+func starvationClass(pod *v1.Pod) string {
+	if pod.Spec.PriorityClassName != "" {
+		return pod.Spec.PriorityClassName
+	}
+	return strconv.Itoa(int(util.PodPriority(pod)))
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+func TestBuildStarvationMetrics(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	newPod := func(name, class string, prio int32, submittedSec int) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "default",
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(start.Add(time.Duration(submittedSec) * time.Second)),
+			},
+			Spec: v1.PodSpec{PriorityClassName: class, Priority: &prio},
+		}
+	}
+
+	pods := []*v1.Pod{
+		newPod("batch-0", "batch", 0, 30),
+		newPod("batch-1", "batch", 0, 10),
+		newPod("service-0", "service", 100, 50),
+		newPod("none-0", "", 5, 0),
+	}
+
+	actual := buildStarvationMetrics(clock.NewClock(start.Add(100*time.Second)), pods)
+	expected := map[string]queue.StarvationMetrics{
+		"batch":   {Pod: "default/batch-1", WaitSeconds: 90, PendingPodsNum: 2},
+		"service": {Pod: "default/service-0", WaitSeconds: 50, PendingPodsNum: 1},
+		"5":       {Pod: "default/none-0", WaitSeconds: 100, PendingPodsNum: 1},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %+v\nwant: %+v", actual, expected)
+	}
+
+	if actual := buildStarvationMetrics(clock.NewClock(start), nil); actual != nil {
+		t.Errorf("got: %+v\nwant: nil", actual)
+	}
+}
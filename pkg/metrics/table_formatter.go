@@ -31,26 +31,23 @@ type TableFormatter struct{}
 // Format implements Formatter interface.
 // Returns error if the given metrics does not have valid structure.
 func (t *TableFormatter) Format(metrics *Metrics) (string, error) {
-	if err := validateMetrics(metrics); err != nil {
+	snapshot, err := metrics.Snapshot()
+	if err != nil {
 		return "", err
 	}
 
 	// Clock
-	clk := (*metrics)[ClockKey].(string)
-	str := clk + "\n\n"
+	str := snapshot.Clock + "\n\n"
 
 	// Nodes
-	nodesMet := (*metrics)[NodesMetricsKey].(map[string]node.Metrics)
-	s, resourceTypes := t.formatNodesMetrics(nodesMet)
+	s, resourceTypes := t.formatNodesMetrics(snapshot.Nodes)
 	str += s + "\n"
 
 	// Pods
-	podsMet := (*metrics)[PodsMetricsKey].(map[string]pod.Metrics)
-	str += t.formatPodsMetrics(podsMet, resourceTypes) + "\n"
+	str += t.formatPodsMetrics(snapshot.Pods, resourceTypes) + "\n"
 
 	// Queue
-	queueMet := (*metrics)[QueueMetricsKey].(queue.Metrics)
-	str += t.formatQueueMetrics(queueMet) + "\n"
+	str += t.formatQueueMetrics(snapshot.Queue) + "\n"
 
 	return str, nil
 }
@@ -0,0 +1,80 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// TopologyMetrics is a metrics of how the running pods are spread among the domains of one
+// topology key (e.g., zones).
+type TopologyMetrics struct {
+	Domains map[string]DomainMetrics
+	// Skew is the difference between the largest and the smallest number of running pods in any
+	// domain.
+	Skew int64
+}
+
+// DomainMetrics is a metrics of one topology domain.
+type DomainMetrics struct {
+	NodesNum       int
+	RunningPodsNum int64
+	// Skew is the number of running pods in this domain minus the smallest number in any domain.
+	Skew int64
+}
+
+// buildTopologyMetrics builds the TopologyMetrics of each of util.TopologyLabels that labels any
+// node.
+func buildTopologyMetrics(nodes map[string]*node.Node, nodesMetrics map[string]node.Metrics) map[string]TopologyMetrics {
+	topology := map[string]TopologyMetrics{}
+
+	for _, key := range util.TopologyLabels {
+		domains := map[string]DomainMetrics{}
+		for name, n := range nodes {
+			domain, ok := n.ToV1().Labels[key]
+			if !ok {
+				continue
+			}
+			met := domains[domain]
+			met.NodesNum++
+			met.RunningPodsNum += nodesMetrics[name].RunningPodsNum
+			domains[domain] = met
+		}
+		if len(domains) == 0 {
+			continue
+		}
+
+		first := true
+		minPods, maxPods := int64(0), int64(0)
+		for _, met := range domains {
+			if first || met.RunningPodsNum < minPods {
+				minPods = met.RunningPodsNum
+			}
+			if first || met.RunningPodsNum > maxPods {
+				maxPods = met.RunningPodsNum
+			}
+			first = false
+		}
+		for domain, met := range domains {
+			met.Skew = met.RunningPodsNum - minPods
+			domains[domain] = met
+		}
+
+		topology[key] = TopologyMetrics{Domains: domains, Skew: maxPods - minPods}
+	}
+
+	return topology
+}
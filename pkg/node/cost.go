@@ -0,0 +1,133 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+)
+
+// Lifecycle classes of nodes, after those of cloud instances.
+const (
+	OnDemand = "on-demand"
+	Reserved = "reserved"
+	Spot     = "spot"
+)
+
+// CostModel is the price of a node.
+type CostModel struct {
+	// HourlyPrice is the price of the node per hour, in any currency.
+	HourlyPrice float64
+	// Lifecycle is the lifecycle class of the node: OnDemand, Reserved, or Spot.
+	// Only Spot nodes are interrupted.
+	Lifecycle string
+}
+
+// SetCostModel sets the cost model of this Node. The cost is not modeled if nil.
+func (node *Node) SetCostModel(model *CostModel) {
+	node.cost = model
+}
+
+// CostModel returns the cost model of this Node, or nil if the cost is not modeled.
+func (node *Node) CostModel() *CostModel {
+	return node.cost
+}
+
+// IsSpot returns whether this Node is a spot node.
+func (node *Node) IsSpot() bool {
+	return node.cost != nil && node.cost.Lifecycle == Spot
+}
+
+// Interrupt notices this Node that it will be removed at the given clock.
+// Pods can no longer be bound to it.
+func (node *Node) Interrupt(removeAt clock.Clock) {
+	node.removeAt = &removeAt
+}
+
+// IsInterrupted returns whether this Node has been noticed of an interruption, and the clock at
+// which it will be removed.
+func (node *Node) IsInterrupted() (bool, clock.Clock) {
+	if node.removeAt == nil {
+		return false, clock.Clock{}
+	}
+	return true, *node.removeAt
+}
+
+// Interruption is an interruption of a spot node in a trace.
+type Interruption struct {
+	// Clock is the clock at which the interruption is noticed.
+	Clock clock.Clock
+	Node  string
+}
+
+// SpotInterrupter interrupts spot nodes at random at a hazard rate, or as scheduled in a trace.
+// An interrupted node is noticed a notice period before it is removed.
+type SpotInterrupter struct {
+	// hazardRate is the expected number of interruptions per node per hour.
+	hazardRate float64
+	rng        *rand.Rand
+	notice     time.Duration
+	// trace is the interruptions not noticed yet, sorted by clock.
+	trace []Interruption
+}
+
+// NewSpotInterrupter creates a new SpotInterrupter, which interrupts each spot node at the given
+// hazard rate (per hour) with the random seed, and as scheduled in the trace, noticing the given
+// duration before the removal.
+func NewSpotInterrupter(hazardRate float64, seed int64, trace []Interruption, notice time.Duration) *SpotInterrupter {
+	sorted := append([]Interruption{}, trace...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Clock.Before(sorted[j].Clock) })
+
+	return &SpotInterrupter{
+		hazardRate: hazardRate,
+		rng:        rand.New(rand.NewSource(seed)),
+		notice:     notice,
+		trace:      sorted,
+	}
+}
+
+// Interrupt interrupts the spot nodes to be interrupted in the tick ending at the given clock, in
+// the order of the names, and returns their names.
+func (s *SpotInterrupter) Interrupt(clock clock.Clock, tick time.Duration, nodes map[string]*Node, names []string) []string {
+	interrupt := map[string]bool{}
+	for len(s.trace) > 0 && !clock.Before(s.trace[0].Clock) {
+		interrupt[s.trace[0].Node] = true
+		s.trace = s.trace[1:]
+	}
+
+	// The probability that a node is interrupted in a tick under the constant hazard rate.
+	prob := 1 - math.Exp(-s.hazardRate*tick.Hours())
+
+	interrupted := []string{}
+	for _, name := range names {
+		node, ok := nodes[name]
+		if !ok || !node.IsSpot() {
+			continue
+		}
+		if ok, _ := node.IsInterrupted(); ok {
+			continue
+		}
+		if interrupt[name] || (prob > 0 && s.rng.Float64() < prob) {
+			node.Interrupt(clock.Add(s.notice))
+			interrupted = append(interrupted, name)
+		}
+	}
+
+	return interrupted
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+)
+
+func TestSpotInterrupter(t *testing.T) {
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	nodes := map[string]*Node{}
+	names := []string{"od-0", "spot-0", "spot-1"}
+	for _, name := range names {
+		n := NewNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		lifecycle := Spot
+		if name == "od-0" {
+			lifecycle = OnDemand
+		}
+		n.SetCostModel(&CostModel{HourlyPrice: 1, Lifecycle: lifecycle})
+		nodes[name] = &n
+	}
+
+	trace := []Interruption{
+		{Clock: start.Add(30 * time.Second), Node: "spot-1"},
+		{Clock: start.Add(10 * time.Second), Node: "od-0"},
+	}
+	s := NewSpotInterrupter(0, 0, trace, 120*time.Second)
+
+	if actual := s.Interrupt(start.Add(20*time.Second), 10*time.Second, nodes, names); len(actual) != 0 {
+		t.Errorf("got: %v\nwant: on-demand node not interrupted", actual)
+	}
+	actual := s.Interrupt(start.Add(30*time.Second), 10*time.Second, nodes, names)
+	if expected := []string{"spot-1"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+	interrupted, removeAt := nodes["spot-1"].IsInterrupted()
+	if !interrupted || removeAt.Sub(start) != 150*time.Second {
+		t.Errorf("got: %v, %v\nwant: removed at 150 s", interrupted, removeAt)
+	}
+	if nodes["spot-1"].IsSchedulable(start.Add(30 * time.Second)) {
+		t.Errorf("got: schedulable\nwant: not schedulable after interrupted")
+	}
+
+	// At a hazard rate of 1e6 per hour, every spot node is interrupted in one tick.
+	s = NewSpotInterrupter(1e6, 42, nil, 0)
+	actual = s.Interrupt(start.Add(40*time.Second), 10*time.Second, nodes, names)
+	if expected := []string{"spot-0"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
@@ -28,6 +28,22 @@ import (
 type Node struct {
 	v1   *v1.Node
 	pods map[string]*pod.Pod
+
+	powerState PowerState
+	// wakeAt is the clock at which this Node becomes active while waking up.
+	wakeAt clock.Clock
+
+	// power is the power model of this Node, or nil if the power is not modeled.
+	power *PowerModel
+	// watts is the power consumption since energyAt, until which the energy is integrated.
+	watts    float64
+	energy   float64
+	energyAt *clock.Clock
+
+	// cost is the cost model of this Node, or nil if the cost is not modeled.
+	cost *CostModel
+	// removeAt is the clock at which this Node will be removed after an interruption, or nil.
+	removeAt *clock.Clock
 }
 
 // Metrics is a metrics of a Node at one point of time.
@@ -39,6 +55,31 @@ type Metrics struct {
 	TotalResourceRequest    v1.ResourceList
 	TotalResourceUsage      v1.ResourceList
 	TotalResourceAllocation v1.ResourceList
+
+	// FreeResource is the allocatable resource not requested by the running and terminating pods,
+	// including the number of pods that can still be placed.
+	FreeResource v1.ResourceList
+	// LargestPlaceable is the largest resource request of a pod that can still be placed on this
+	// Node, or empty if no more pod can be placed.
+	LargestPlaceable v1.ResourceList
+	// StrandedResource is the free resource that no pod can use because another resource of this
+	// Node has been exhausted (e.g., free CPU on a node with no free memory).
+	StrandedResource v1.ResourceList
+
+	// PowerState is the power state of this Node.
+	PowerState string `json:",omitempty"`
+	// Power is the power consumption of this Node in watts, Energy is its energy consumption in
+	// joules since the start, and FacilityEnergy is Energy multiplied by the PUE.
+	// All are 0 if the power is not modeled.
+	Power          float64 `json:",omitempty"`
+	Energy         float64 `json:",omitempty"`
+	FacilityEnergy float64 `json:",omitempty"`
+
+	// Lifecycle and HourlyPrice are those of the cost model of this Node, if any.
+	Lifecycle   string  `json:",omitempty"`
+	HourlyPrice float64 `json:",omitempty"`
+	// Interrupted is whether this Node has been noticed of an interruption.
+	Interrupted bool `json:",omitempty"`
 }
 
 // NewNode creates a new Node with the given v1.Node.
@@ -67,14 +108,60 @@ func (node *Node) ToNodeInfo(clock clock.Clock) (*nodeinfo.NodeInfo, error) {
 
 // Metrics returns the Metrics of this Node at the given clock.
 func (node *Node) Metrics(clock clock.Clock) Metrics {
-	return Metrics{
+	met := Metrics{
 		Allocatable:          node.ToV1().Status.Allocatable,
 		RunningPodsNum:       node.runningPodsNum(clock),
 		TerminatingPodsNum:   node.terminatingPodsNum(clock),
 		FailedPodsNum:        node.bindingFailedPodsNum(),
 		TotalResourceRequest: node.totalResourceRequest(clock),
 		TotalResourceUsage:   node.totalResourceUsage(clock),
+		PowerState:           node.PowerState(clock).String(),
+	}
+	if node.cost != nil {
+		met.Lifecycle = node.cost.Lifecycle
+		met.HourlyPrice = node.cost.HourlyPrice
+	}
+	met.Interrupted, _ = node.IsInterrupted()
+	met.FreeResource, met.LargestPlaceable, met.StrandedResource = fragmentation(
+		met.Allocatable, met.TotalResourceRequest, met.RunningPodsNum+met.TerminatingPodsNum)
+
+	return met
+}
+
+// fragmentation returns the free, largest placeable, and stranded resources of a node with the
+// given allocatable resource, total request, and number of pods.
+// A resource of which the node has nothing allocatable never strands the others.
+func fragmentation(allocatable, request v1.ResourceList, podsNum int64) (free, largest, stranded v1.ResourceList) {
+	free = v1.ResourceList{}
+	exhausted := false
+	for rsrc, alloc := range allocatable {
+		f := alloc.DeepCopy()
+		if rsrc == v1.ResourcePods {
+			f.Set(alloc.Value() - podsNum)
+		} else if req, ok := request[rsrc]; ok {
+			f.Sub(req)
+		}
+		if f.Sign() <= 0 {
+			f.Set(0)
+			exhausted = exhausted || !alloc.IsZero()
+		}
+		free[rsrc] = f
 	}
+
+	largest = v1.ResourceList{}
+	stranded = v1.ResourceList{}
+	for rsrc, f := range free {
+		if rsrc == v1.ResourcePods {
+			continue
+		}
+		if exhausted {
+			stranded[rsrc] = f
+		} else {
+			largest[rsrc] = f
+		}
+	}
+
+	return free, largest, stranded
 }
 
 // BindPod accepts the given pod and try to start it.
@@ -154,10 +241,10 @@ func (node *Node) PodsNum(clock clock.Clock) int64 {
 	return node.runningPodsNum(clock) + node.terminatingPodsNum(clock)
 }
 
-// GCTerminatedPods deletes terminated or deleted pods at the given clock from this Node.
+// GCTerminatedPods deletes terminated, killed, or deleted pods at the given clock from this Node.
 func (node *Node) GCTerminatedPods(clock clock.Clock) {
 	for name, pod := range node.pods {
-		if pod.IsTerminated(clock) || pod.IsDeleted(clock) {
+		if pod.IsTerminated(clock) || pod.IsKilled() || pod.IsDeleted(clock) {
 			delete(node.pods, name)
 		}
 	}
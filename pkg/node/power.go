@@ -0,0 +1,247 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"math"
+	"time"
+
+	"github.com/containerd/containerd/log"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+)
+
+// PowerModel is the power consumption of a node as a function of its CPU utilization.
+type PowerModel struct {
+	// IdleWatts and PeakWatts are the power consumption at 0 and 100 % CPU utilization, between
+	// which it is linear.
+	IdleWatts float64
+	PeakWatts float64
+	// Curve, if not empty, makes the power consumption piecewise linear between its points instead,
+	// and constant outside them. The points must be sorted by utilization.
+	Curve []PowerPoint
+	// SleepWatts is the power consumption while the node is asleep.
+	SleepWatts float64
+	// PUE (power usage effectiveness) is the ratio of the facility energy, including cooling and
+	// power distribution, to the energy consumed by the node. 1 if 0.
+	PUE float64
+}
+
+// PowerPoint is a point of PowerModel.Curve.
+type PowerPoint struct {
+	Utilization float64
+	Watts       float64
+}
+
+// Watts returns the power consumption in watts at the given CPU utilization in [0, 1].
+func (m *PowerModel) Watts(utilization float64) float64 {
+	if len(m.Curve) == 0 {
+		return m.IdleWatts + (m.PeakWatts-m.IdleWatts)*utilization
+	}
+
+	if utilization <= m.Curve[0].Utilization {
+		return m.Curve[0].Watts
+	}
+	for i := 1; i < len(m.Curve); i++ {
+		lo, hi := m.Curve[i-1], m.Curve[i]
+		if utilization <= hi.Utilization {
+			return lo.Watts + (hi.Watts-lo.Watts)*(utilization-lo.Utilization)/(hi.Utilization-lo.Utilization)
+		}
+	}
+	return m.Curve[len(m.Curve)-1].Watts
+}
+
+// pue returns the PUE of this model.
+func (m *PowerModel) pue() float64 {
+	if m.PUE == 0 {
+		return 1
+	}
+	return m.PUE
+}
+
+// PowerState represents the power state of a Node.
+type PowerState int
+
+const (
+	// Active indicates that the node is awake and pods can be bound to it.
+	Active PowerState = iota
+	// Asleep indicates that the node is asleep and no pod can be bound to it.
+	Asleep
+	// Waking indicates that the node is waking up, and pods can be bound to it after its wake-up
+	// delay.
+	Waking
+)
+
+// String implements Stringer interface.
+func (state PowerState) String() string {
+	switch state {
+	case Active:
+		return "Active"
+	case Asleep:
+		return "Asleep"
+	case Waking:
+		return "Waking"
+	default:
+		log.L.Panic("Unknown node.PowerState")
+		return ""
+	}
+}
+
+// SetPowerModel sets the power model of this Node. The power is not modeled if nil.
+func (node *Node) SetPowerModel(model *PowerModel) {
+	node.power = model
+}
+
+// PowerState returns the power state of this Node at the given clock.
+func (node *Node) PowerState(clock clock.Clock) PowerState {
+	if node.powerState == Waking && !clock.Before(node.wakeAt) {
+		node.powerState = Active
+	}
+	return node.powerState
+}
+
+// IsSchedulable returns whether pods can be bound to this Node at the given clock, i.e., it is
+// awake and has not been interrupted.
+func (node *Node) IsSchedulable(clock clock.Clock) bool {
+	return node.PowerState(clock) == Active && node.removeAt == nil
+}
+
+// Sleep puts this Node into sleep at the given clock.
+// Returns false if it is not awake or any pod is running or terminating on it.
+func (node *Node) Sleep(clock clock.Clock) bool {
+	if node.PowerState(clock) != Active || node.PodsNum(clock) > 0 {
+		return false
+	}
+	node.powerState = Asleep
+	return true
+}
+
+// WakeUp starts to wake up this Node at the given clock, which takes the given delay.
+// Does nothing if it is not asleep.
+func (node *Node) WakeUp(clock clock.Clock, delay time.Duration) {
+	if node.PowerState(clock) != Asleep {
+		return
+	}
+	node.powerState = Waking
+	node.wakeAt = clock.Add(delay)
+}
+
+// UpdateEnergy integrates the energy consumption of this Node until the given clock at the power
+// consumption since the last update, and then updates the power consumption from the given total
+// resource usage at the clock. A waking node consumes its idle power.
+// Returns the power consumption in watts, or 0 if the power is not modeled.
+func (node *Node) UpdateEnergy(clock clock.Clock, usage v1.ResourceList) float64 {
+	if node.power == nil {
+		return 0
+	}
+
+	if node.energyAt != nil {
+		node.energy += node.watts * clock.Sub(*node.energyAt).Seconds()
+	}
+	node.energyAt = &clock
+
+	switch node.PowerState(clock) {
+	case Active:
+		utilization := 0.0
+		alloc := node.ToV1().Status.Allocatable[v1.ResourceCPU]
+		if u, ok := usage[v1.ResourceCPU]; ok && !alloc.IsZero() {
+			utilization = math.Min(float64(u.MilliValue())/float64(alloc.MilliValue()), 1)
+		}
+		node.watts = node.power.Watts(utilization)
+	case Asleep:
+		node.watts = node.power.SleepWatts
+	case Waking:
+		node.watts = node.power.IdleWatts
+	}
+
+	return node.watts
+}
+
+// Energy returns the energy consumed by this Node until the last update, in joules.
+func (node *Node) Energy() float64 {
+	return node.energy
+}
+
+// FacilityEnergy returns the energy consumed by this Node multiplied by its PUE, in joules.
+func (node *Node) FacilityEnergy() float64 {
+	if node.power == nil {
+		return 0
+	}
+	return node.energy * node.power.pue()
+}
+
+// SleepController puts the nodes that have been empty for a while into sleep, and wakes them up
+// when pods are pending.
+type SleepController struct {
+	sleepAfter  time.Duration
+	wakeUpDelay time.Duration
+	// idleSince maps the names of the empty active nodes to the clock since which they are empty.
+	idleSince map[string]clock.Clock
+}
+
+// NewSleepController creates a new SleepController, which puts a node into sleep after it has been
+// empty for sleepAfter, and wakes it up in wakeUpDelay.
+func NewSleepController(sleepAfter, wakeUpDelay time.Duration) *SleepController {
+	return &SleepController{
+		sleepAfter:  sleepAfter,
+		wakeUpDelay: wakeUpDelay,
+		idleSince:   map[string]clock.Clock{},
+	}
+}
+
+// Control controls the power states of the given nodes at the clock, given the number of pending
+// pods.
+// Empty nodes are put into sleep only while no pod is pending, and a sleeping node starts to wake
+// up, in the order of the names, while more pods are pending than nodes are waking up.
+func (c *SleepController) Control(clock clock.Clock, nodes map[string]*Node, names []string, pendingPodsNum int) {
+	wakingNum := 0
+	for _, name := range names {
+		node := nodes[name]
+		if interrupted, _ := node.IsInterrupted(); interrupted {
+			continue
+		}
+		switch node.PowerState(clock) {
+		case Active:
+			if node.PodsNum(clock) > 0 {
+				delete(c.idleSince, name)
+				continue
+			}
+			since, ok := c.idleSince[name]
+			if !ok {
+				c.idleSince[name] = clock
+				since = clock
+			}
+			if pendingPodsNum == 0 && clock.Sub(since) >= c.sleepAfter && node.Sleep(clock) {
+				delete(c.idleSince, name)
+				log.L.Debugf("Node %s: asleep", name)
+			}
+		case Waking:
+			wakingNum++
+		}
+	}
+
+	if pendingPodsNum <= wakingNum {
+		return
+	}
+	for _, name := range names {
+		node := nodes[name]
+		if interrupted, _ := node.IsInterrupted(); !interrupted && node.PowerState(clock) == Asleep {
+			node.WakeUp(clock, c.wakeUpDelay)
+			log.L.Debugf("Node %s: waking up", name)
+			return
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package node
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+)
+
+func TestPowerModelWatts(t *testing.T) {
+	linear := PowerModel{IdleWatts: 100, PeakWatts: 300}
+	curve := PowerModel{Curve: []PowerPoint{{0.1, 100}, {0.5, 200}, {1, 220}}}
+
+	for _, tc := range []struct {
+		model       *PowerModel
+		utilization float64
+		expected    float64
+	}{
+		{&linear, 0, 100},
+		{&linear, 0.25, 150},
+		{&linear, 1, 300},
+		{&curve, 0, 100},
+		{&curve, 0.3, 150},
+		{&curve, 0.75, 210},
+		{&curve, 1, 220},
+	} {
+		if actual := tc.model.Watts(tc.utilization); actual != tc.expected {
+			t.Errorf("%+v at %v: got: %v\nwant: %v", tc.model.Curve, tc.utilization, actual, tc.expected)
+		}
+	}
+}
+
+func TestUpdateEnergy(t *testing.T) {
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	n := NewNode(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+		Status:     v1.NodeStatus{Allocatable: v1.ResourceList{"cpu": resource.MustParse("4")}},
+	})
+	n.SetPowerModel(&PowerModel{IdleWatts: 100, PeakWatts: 300, SleepWatts: 10, PUE: 1.5})
+
+	if watts := n.UpdateEnergy(start, v1.ResourceList{"cpu": resource.MustParse("2")}); watts != 200 {
+		t.Errorf("got: %v W\nwant: 200 W", watts)
+	}
+	// 200 W for 10 s, and then asleep.
+	n.Sleep(start.Add(10 * time.Second))
+	if watts := n.UpdateEnergy(start.Add(10*time.Second), v1.ResourceList{}); watts != 10 {
+		t.Errorf("got: %v W\nwant: 10 W", watts)
+	}
+	// 10 W for 100 s.
+	n.UpdateEnergy(start.Add(110*time.Second), v1.ResourceList{})
+	if energy, facility := n.Energy(), n.FacilityEnergy(); energy != 3000 || facility != 4500 {
+		t.Errorf("got: %v J, %v J\nwant: 3000 J, 4500 J", energy, facility)
+	}
+}
+
+func TestSleepController(t *testing.T) {
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	nodes := map[string]*Node{}
+	names := []string{"node-0", "node-1"}
+	for _, name := range names {
+		n := NewNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		nodes[name] = &n
+	}
+	c := NewSleepController(60*time.Second, 30*time.Second)
+
+	states := func(clk clock.Clock) []PowerState {
+		return []PowerState{nodes["node-0"].PowerState(clk), nodes["node-1"].PowerState(clk)}
+	}
+	expect := func(clk clock.Clock, expected ...PowerState) {
+		t.Helper()
+		if actual := states(clk); actual[0] != expected[0] || actual[1] != expected[1] {
+			t.Errorf("got: %v\nwant: %v", actual, expected)
+		}
+	}
+
+	// The empty nodes sleep after 60 s.
+	c.Control(start, nodes, names, 0)
+	c.Control(start.Add(50*time.Second), nodes, names, 0)
+	expect(start.Add(50*time.Second), Active, Active)
+	c.Control(start.Add(60*time.Second), nodes, names, 0)
+	expect(start.Add(60*time.Second), Asleep, Asleep)
+
+	// One node wakes up for a pending pod, and takes 30 s.
+	c.Control(start.Add(100*time.Second), nodes, names, 1)
+	c.Control(start.Add(110*time.Second), nodes, names, 1)
+	expect(start.Add(110*time.Second), Waking, Asleep)
+	if nodes["node-0"].IsSchedulable(start.Add(129 * time.Second)) {
+		t.Errorf("got: schedulable\nwant: not schedulable until woken up")
+	}
+	expect(start.Add(130*time.Second), Active, Asleep)
+}
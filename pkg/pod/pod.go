@@ -17,7 +17,7 @@ package pod
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"time"
 
 	"github.com/containerd/containerd/log"
@@ -29,15 +29,27 @@ import (
 
 // Pod represents a simulated pod.
 type Pod struct {
-	v1           *v1.Pod
-	spec         spec
-	boundAt      clock.Clock
-	status       Status
-	node         string
-	path         string // for loading more resource usages.
-	currentPhase int
-	numPhase     int
-	loadPhase    int
+	v1      *v1.Pod
+	spec    spec
+	boundAt clock.Clock
+	status  Status
+	node    string
+
+	// source is the source of the phases following spec, or nil if it is exhausted.
+	source UsageSource
+	// total is the total seconds of all the phases.
+	total int32
+	// specStart is the executed seconds at the start of spec[0], since past phases are dropped.
+	specStart int32
+
+	// progressed is the executed duration until progressedAt, after which the pod executes at the
+	// rate of 1/slowdown of the clock.
+	progressed   time.Duration
+	progressedAt clock.Clock
+	slowdown     float64
+
+	killedAt clock.Clock
+	killedBy v1.ResourceName
 }
 
 // Metrics is a metrics of a pod at one time point.
@@ -53,6 +65,11 @@ type Metrics struct {
 
 	Priority int32
 	Status   Status
+	// Slowdown is the factor by which the pod is slowed down or its usage is inflated by the pods
+	// co-located with it. 1 means no interference, and 0 that interference is not modeled.
+	Slowdown float64 `json:",omitempty"`
+
+	Labels map[string]string `json:",omitempty"`
 }
 
 // Status represents status of a Pod.
@@ -71,6 +88,10 @@ const (
 
 	// OverCapacity indicates that the pod failed to start due to over capacity.
 	OverCapacity
+
+	// Killed indicates that the pod was killed because its node was short of an incompressible
+	// resource.
+	Killed
 )
 
 // String implements Stringer interface.
@@ -82,6 +103,8 @@ func (status Status) String() string {
 		return "Deleted"
 	case OverCapacity:
 		return "OverCapacity"
+	case Killed:
+		return "Killed"
 	default:
 		log.L.Panic("Unknown pod.Status")
 		return ""
@@ -93,113 +116,50 @@ func (status Status) MarshalJSON() ([]byte, error) {
 	return json.Marshal(status.String())
 }
 
-// NewPod creates a pod with the given v1.Pod, the clock at which the pod was bound to a node, and
-// the pod's status.
-func NewPod(pod *v1.Pod, boundAt clock.Clock, status Status, node string) (*Pod, error) {
-	path := parsePath(pod)
-	// load specs from file.
-	if path == "" {
-		spec, numPhase, err := parseSpec(pod)
-		if err != nil {
-			return nil, err
-		}
-		return &Pod{
-			v1:           pod,
-			spec:         spec,
-			boundAt:      boundAt,
-			status:       status,
-			node:         node,
-			path:         path,
-			numPhase:     numPhase,
-			loadPhase:    numPhase,
-			currentPhase: 0,
-		}, nil
-	}
-	podFromFile, err := loadPodFromFile(path)
-	if err != nil {
-		return nil, err
-	}
-	pSpec, numPhase, err := parseSpec(podFromFile)
-	if err != nil {
-		return nil, err
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (status *Status) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
 	}
-	podFromFile = nil
 
-	newLoadPhase := clock.LOAD_PHASE_CACHE
-	if newLoadPhase >= numPhase {
-		newLoadPhase = numPhase
-		path = ""
+	for _, s := range []Status{Ok, Deleted, OverCapacity, Killed} {
+		if s.String() == str {
+			*status = s
+			return nil
+		}
 	}
-	newSpec := make(spec, 0, newLoadPhase)
-	newSpec = append(newSpec, pSpec[:newLoadPhase]...)
-	pSpec = nil
 
-	newPod := Pod{
-		v1:           pod,
-		spec:         newSpec,
-		boundAt:      boundAt,
-		status:       status,
-		node:         node,
-		path:         path,
-		numPhase:     numPhase,
-		loadPhase:    newLoadPhase,
-		currentPhase: 0,
-	}
-	return &newPod, nil
+	return fmt.Errorf("unknown pod status %q", str)
 }
 
-func loadPodFromFile(filePath string) (*v1.Pod, error) {
-	d, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		log.L.Errorf("cannot find file %s", filePath)
-		return nil, fmt.Errorf("cannot find file %s", filePath)
-	}
-
-	pod := v1.Pod{}
-
-	// err = yaml.Unmarshal(d, &pod)
-	// if err != nil {
-	// 	log.L.Errorf("cannot parse pod from file %s", filePath)
-	// 	return nil, fmt.Errorf("cannot parse pod from file %s", filePath)
-	// }
-
-	err = json.Unmarshal(d, &pod)
+// NewPod creates a pod with the given v1.Pod, the clock at which the pod was bound to a node, and
+// the pod's status.
+// The phases of the pod are consumed from the UsageSource selected by its annotations.
+func NewPod(pod *v1.Pod, boundAt clock.Clock, status Status, node string) (*Pod, error) {
+	source, err := newUsageSource(pod)
 	if err != nil {
-		log.L.Errorf("cannot parse pod from file %s", filePath)
-		return nil, fmt.Errorf("cannot parse pod from file %s", filePath)
+		return nil, err
 	}
-
-	// store filePath for loading more resource usages
-	pod.Annotations["path"] = filePath
-
-	return &pod, nil
+	return NewPodWithSource(pod, boundAt, status, node, source)
 }
 
-func UpdatePod(pod *v1.Pod, boundAt clock.Clock, status Status, node string, currentPhase, loadPhase int, numPhase int, currSpec spec, pSpec spec, path string) (*Pod, error) {
-
-	newLoadPhase := loadPhase + clock.LOAD_PHASE_CACHE
-	if newLoadPhase >= numPhase {
-		newLoadPhase = numPhase
-		path = ""
-	}
-
-	more := make(spec, 0, newLoadPhase-loadPhase)
-	more = append(more, pSpec[loadPhase:newLoadPhase]...)
-	currSpec = append(currSpec, more...)
-	pSpec = nil
-
+// NewPodWithSource creates a pod like NewPod, whose phases are consumed from the given source.
+func NewPodWithSource(pod *v1.Pod, boundAt clock.Clock, status Status, node string, source UsageSource) (*Pod, error) {
 	newPod := Pod{
-		v1:           pod,
-		spec:         currSpec,
-		boundAt:      boundAt,
-		status:       status,
-		node:         node,
-		path:         path,
-		numPhase:     numPhase,
-		loadPhase:    newLoadPhase,
-		currentPhase: currentPhase,
+		v1:      pod,
+		boundAt: boundAt,
+		status:  status,
+		node:    node,
+		source:  source,
+		total:   source.TotalSeconds(),
+
+		progressedAt: boundAt,
+		slowdown:     1,
+	}
+	if err := newPod.loadPhases(); err != nil && err != io.EOF {
+		return nil, err
 	}
-
 	return &newPod, nil
 }
 
@@ -208,6 +168,11 @@ func (pod *Pod) ToV1() *v1.Pod {
 	return pod.v1
 }
 
+// NodeName returns the name of the node to which this Pod is bound.
+func (pod *Pod) NodeName() string {
+	return pod.node
+}
+
 // Metrics returns the Metrics of this Pod at the given clock.
 func (pod *Pod) Metrics(clock clock.Clock) Metrics {
 	ResourceUsage := pod.ResourceUsage(clock)
@@ -223,6 +188,8 @@ func (pod *Pod) Metrics(clock clock.Clock) Metrics {
 
 		Priority: util.PodPriority(pod.ToV1()),
 		Status:   pod.status,
+
+		Labels: pod.ToV1().Labels,
 	}
 	return metrics
 }
@@ -234,11 +201,7 @@ func (pod *Pod) TotalResourceRequests() v1.ResourceList {
 
 // TotalResourceLimits extracts the total amount of resource limits of this Pod.
 func (pod *Pod) TotalResourceLimits() v1.ResourceList {
-	result := v1.ResourceList{}
-	for _, container := range pod.ToV1().Spec.Containers {
-		result = util.ResourceListSum(result, container.Resources.Limits)
-	}
-	return result
+	return util.PodTotalResourceLimits(pod.ToV1())
 }
 
 // ResourceUsage returns resource usage of this Pod at the given clock.
@@ -249,71 +212,78 @@ func (pod *Pod) ResourceUsage(clock clock.Clock) v1.ResourceList {
 	}
 
 	executedSeconds := int32(pod.executedDuration(clock).Seconds())
-	phaseDurationAcc := int32(0)
-	stop := -1
-	for i, phase := range pod.spec {
-		phaseDurationAcc += phase.seconds
-		if executedSeconds < phaseDurationAcc {
-			stop = i
-			break
-		}
-	}
-	//delete the past phases & load new phases
-	if stop >= 0 {
-		res := pod.spec[stop].resourceUsage
-		if stop > 0 {
-			pod.spec[stop].seconds = phaseDurationAcc
-			remainLen := len(pod.spec) - stop
-			remain := make(spec, 0, remainLen)
-			remain = append(remain, pod.spec[stop:]...)
-			pod.spec = remain
-			pod.currentPhase += stop
+	for {
+		// Drop the past phases.
+		for len(pod.spec) > 0 && executedSeconds >= pod.specStart+pod.spec[0].seconds {
+			pod.specStart += pod.spec[0].seconds
+			pod.spec = pod.spec[1:]
 		}
-		//loading more phases
-		if pod.currentPhase >= pod.loadPhase-1 && pod.loadPhase < pod.numPhase {
-			loadPod, _ := pod.loadPod()
-			pod.spec = loadPod.spec
-			pod.loadPhase = loadPod.loadPhase
-			pod.path = loadPod.path
+		if len(pod.spec) > 0 {
+			return pod.spec[0].usageAt(executedSeconds - pod.specStart)
 		}
 
-		return res
+		if err := pod.loadPhases(); err != nil {
+			if err != io.EOF {
+				log.L.Errorf("cannot load the phases of pod %s: %v", pod.v1.Name, err)
+			} else {
+				log.L.Errorf("pod %s has run out of phases", pod.v1.Name)
+			}
+			return v1.ResourceList{}
+		}
 	}
-
-	log.L.Panic("Unreachable code in pod.ResourceUsage()")
-	return v1.ResourceList{}
 }
 
-func (pod *Pod) loadPod() (*Pod, error) {
-	if pod.path == "" {
-		return pod, nil
+// loadPhases loads the next phases from the source.
+// Returns io.EOF if there is no more phase.
+func (pod *Pod) loadPhases() error {
+	if pod.source == nil {
+		return io.EOF
 	}
 
-	d, err := ioutil.ReadFile(pod.path)
+	phases, err := pod.source.Next()
 	if err != nil {
-		log.L.Errorf("cannot find file %s", pod.path)
-		return nil, fmt.Errorf("cannot find file %s", pod.path)
+		// The source is not consumed any more.
+		pod.source = nil
+		return err
 	}
-	v1Pod := v1.Pod{}
-	err = json.Unmarshal(d, &v1Pod)
-	if err != nil {
-		log.L.Errorf("cannot parse pod from file %s", pod.path)
-		return nil, fmt.Errorf("cannot parse pod from file %s", pod.path)
+
+	more := make(spec, 0, len(pod.spec)+len(phases))
+	more = append(more, pod.spec...)
+	for _, phase := range phases {
+		p := specPhase{seconds: phase.Seconds, resourceUsage: phase.ResourceUsage}
+		if phase.UsageAt != nil {
+			p.model = usageFunc(phase.UsageAt)
+		}
+		more = append(more, p)
 	}
-	// store filePath for loading more resource usages
-	spec, numPhase, err := parseSpec(&v1Pod)
-	if err != nil {
-		return nil, err
+	pod.spec = more
+	return nil
+}
+
+// SetSlowdown sets the factor by which this Pod is slowed down from the given clock on, e.g., by
+// interference with the pods co-located with it. The factor must be >= 1.
+func (pod *Pod) SetSlowdown(clock clock.Clock, slowdown float64) {
+	if pod.status != Ok || slowdown == pod.slowdown {
+		return
 	}
-	v1Pod.Annotations["simSpec"] = "" // reduce memory use.
-	newPod, err := UpdatePod(&v1Pod, pod.boundAt, pod.status, pod.node, pod.currentPhase, pod.loadPhase, numPhase, pod.spec, spec, pod.path)
-	spec = nil
-	if err != nil {
-		log.L.Errorf("cannot load pod %v", pod.v1.Name)
-		return nil, fmt.Errorf("cannot load pod %v", pod.v1.Name)
+	if slowdown < 1 {
+		slowdown = 1
 	}
 
-	return newPod, nil
+	pod.progressed = pod.executedDuration(clock)
+	pod.progressedAt = clock
+	pod.slowdown = slowdown
+}
+
+// Slowdown returns the factor by which this Pod is currently slowed down.
+func (pod *Pod) Slowdown() float64 {
+	return pod.slowdown
+}
+
+// TotalSeconds returns the total seconds of the phases of this Pod, i.e., its execution duration
+// without slowdown.
+func (pod *Pod) TotalSeconds() int32 {
+	return pod.total
 }
 
 // IsRunning returns whether this Pod is running at the given clock.
@@ -346,7 +316,7 @@ func (pod *Pod) IsDeleted(clk clock.Clock) bool {
 
 // Delete starts to delete this Pod.
 func (pod *Pod) Delete(clock clock.Clock) {
-	if pod.IsTerminated(clock) || pod.status == Deleted {
+	if pod.IsTerminated(clock) || pod.status == Deleted || pod.status == Killed {
 		return
 	}
 
@@ -357,6 +327,29 @@ func (pod *Pod) Delete(clock clock.Clock) {
 	pod.ToV1().DeletionTimestamp = &deletedAt
 }
 
+// Kill kills this Pod at the given clock, because its node is short of the given incompressible
+// resource.
+func (pod *Pod) Kill(clock clock.Clock, rsrc v1.ResourceName) {
+	if !pod.IsRunning(clock) {
+		return
+	}
+
+	pod.status = Killed
+	pod.killedAt = clock
+	pod.killedBy = rsrc
+}
+
+// IsKilled returns whether this Pod has been killed.
+func (pod *Pod) IsKilled() bool {
+	return pod.status == Killed
+}
+
+// KilledAt returns the clock at which this Pod was killed.
+// The returned value is meaningless unless IsKilled returns true.
+func (pod *Pod) KilledAt() clock.Clock {
+	return pod.killedAt
+}
+
 // HasFailedToStart returns whether this Pod has failed to start to a node.
 func (pod *Pod) HasFailedToStart() bool {
 	return pod.status == OverCapacity
@@ -373,6 +366,32 @@ func (pod *Pod) BuildStatus(clock clock.Clock) v1.PodStatus {
 		// status.Conditions =
 		status.Reason = "CapacityExceeded"
 		status.Message = "Pod cannot be started due to the requested resource exceeds the capacity"
+	case Killed:
+		startTime := pod.boundAt.ToMetaV1()
+		status.StartTime = &startTime
+		status.Phase = v1.PodFailed
+		status.Reason = "Killed"
+		status.Message = fmt.Sprintf("Pod was killed since the node was short of %s", pod.killedBy)
+
+		reason := "Killed"
+		if pod.killedBy == v1.ResourceMemory {
+			reason = "OOMKilled"
+		}
+		containerStatuses := make([]v1.ContainerStatus, 0, len(pod.ToV1().Spec.Containers))
+		for _, container := range pod.ToV1().Spec.Containers {
+			containerStatuses = append(containerStatuses, v1.ContainerStatus{
+				Name: container.Name,
+				State: v1.ContainerState{
+					Terminated: &v1.ContainerStateTerminated{
+						ExitCode:   137,
+						Reason:     reason,
+						StartedAt:  startTime,
+						FinishedAt: pod.killedAt.ToMetaV1(),
+					}},
+				Image: container.Image,
+			})
+		}
+		status.ContainerStatuses = containerStatuses
 	case Ok, Deleted:
 		startTime := pod.boundAt.ToMetaV1()
 		status.StartTime = &startTime
@@ -393,7 +412,7 @@ func (pod *Pod) BuildStatus(clock clock.Clock) v1.PodStatus {
 					Reason:     "Succeeded",
 					Message:    "All containers in the pod have voluntarily terminated",
 					StartedAt:  startTime,
-					FinishedAt: pod.finishAt().ToMetaV1(),
+					FinishedAt: pod.FinishAt().ToMetaV1(),
 					// ContainerID:
 				}}
 		}
@@ -429,34 +448,47 @@ func (pod *Pod) BuildStatus(clock clock.Clock) v1.PodStatus {
 	return status
 }
 
-// executedDuration returns the elapsed duration after this Pod started.
+// executedDuration returns the duration this Pod has executed after it started, which is shorter
+// than the elapsed duration if it has been slowed down.
 // Returns 0 if the pod failed to start.
-func (pod *Pod) executedDuration(clock clock.Clock) time.Duration {
+func (pod *Pod) executedDuration(clk clock.Clock) time.Duration {
 	switch pod.status {
 	case Ok:
-		elapsed := clock.Sub(pod.boundAt)
+		executed := pod.executedUntil(clk)
 		total := pod.totalExecutionDuration()
-		if elapsed < total {
-			return elapsed
+		if executed < total {
+			return executed
 		}
 		return total
 	case Deleted:
-		return pod.ToV1().DeletionTimestamp.Sub(pod.boundAt.ToMetaV1().Time)
+		return pod.executedUntil(clock.NewClockWithMetaV1(*pod.ToV1().DeletionTimestamp))
+	case Killed:
+		return pod.executedUntil(pod.killedAt)
 	default:
 		return 0
 	}
 }
 
+// executedUntil returns the executed duration at the given clock, not capped by the total.
+func (pod *Pod) executedUntil(clk clock.Clock) time.Duration {
+	elapsed := clk.Sub(pod.progressedAt)
+	if pod.slowdown > 1 {
+		elapsed = time.Duration(float64(elapsed) / pod.slowdown)
+	}
+	return pod.progressed + elapsed
+}
+
 // totalExecutionDuration returns the total execution duration of this Pod.
 func (pod *Pod) totalExecutionDuration() time.Duration {
-	phaseSecondsTotal := int32(0)
-	for _, phase := range pod.spec {
-		phaseSecondsTotal += phase.seconds
-	}
-	return time.Duration(phaseSecondsTotal) * time.Second
+	return time.Duration(pod.total) * time.Second
 }
 
-// finishAt returns the clock at which this Pod will finish spontaneously.
-func (pod *Pod) finishAt() clock.Clock {
-	return pod.boundAt.Add(pod.totalExecutionDuration())
+// FinishAt returns the clock at which this Pod will finish (or has finished) spontaneously.
+// The clock is estimated at the current slowdown if this Pod is running.
+func (pod *Pod) FinishAt() clock.Clock {
+	remaining := pod.totalExecutionDuration() - pod.progressed
+	if pod.slowdown > 1 {
+		remaining = time.Duration(float64(remaining) * pod.slowdown)
+	}
+	return pod.progressedAt.Add(remaining)
 }
@@ -15,6 +15,8 @@
 package pod
 
 import (
+	"hash/fnv"
+
 	"github.com/cpuguy83/strongerrors"
 	"github.com/pkg/errors"
 	yaml "gopkg.in/yaml.v2"
@@ -30,6 +32,16 @@ type spec []specPhase
 type specPhase struct {
 	seconds       int32
 	resourceUsage v1.ResourceList
+	// model models the usage varying within this phase, or nil if resourceUsage is constant.
+	model usageModel
+}
+
+// usageAt returns the resource usage at the given offset (in seconds) from the start of this phase.
+func (phase *specPhase) usageAt(offset int32) v1.ResourceList {
+	if phase.model == nil {
+		return phase.resourceUsage
+	}
+	return phase.model.usageAt(offset)
 }
 
 // parseSpec parses the pod's "simSpec" annotation into spec.
@@ -39,25 +51,31 @@ func parseSpec(pod *v1.Pod) (spec, int, error) {
 	if !ok {
 		return nil, 0, strongerrors.InvalidArgument(errors.Errorf("simSpec annotation not defined"))
 	}
-	s, err := parseSpecYAML(specAnnot)
+	s, err := parseSpecYAMLWithSeed(specAnnot, podSeed(pod))
 	return s, len(s), err
 }
 
-func parsePath(pod *v1.Pod) string {
-	path, ok := pod.ObjectMeta.Annotations["path"]
-	if !ok {
-		return ""
-	}
-
-	return path
+// podSeed returns the default seed of the random usage models of the pod.
+func podSeed(pod *v1.Pod) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(util.PodKeyFromNames(pod.Namespace, pod.Name))) // nolint
+	return int64(h.Sum64() >> 1)
 }
 
 // parseSpecYAML parses the YAML into spec.
 // Returns error if failed to parse.
 func parseSpecYAML(specYAML string) (spec, error) {
+	return parseSpecYAMLWithSeed(specYAML, 0)
+}
+
+// parseSpecYAMLWithSeed parses the YAML into spec, with the given default seed of random usage
+// models.
+// Returns error if failed to parse.
+func parseSpecYAMLWithSeed(specYAML string, seed int64) (spec, error) {
 	type specPhaseYAML struct {
-		Seconds       int32                      `yaml:"seconds"`
-		ResourceUsage map[v1.ResourceName]string `yaml:"resourceUsage"`
+		Seconds        int32                      `yaml:"seconds"`
+		ResourceUsage  map[v1.ResourceName]string `yaml:"resourceUsage"`
+		usageModelYAML `yaml:",inline"`
 	}
 
 	specUnmarshalled := []specPhaseYAML{}
@@ -66,18 +84,23 @@ func parseSpecYAML(specYAML string) (spec, error) {
 	}
 
 	spec := spec{}
-	for _, phase := range specUnmarshalled {
-		if phase.ResourceUsage == nil {
-			return nil, errors.New("Invalid spec.resoruceUsage field")
+	for i, phase := range specUnmarshalled {
+		var resourceUsage v1.ResourceList
+		if phase.ResourceUsage != nil {
+			var err error
+			if resourceUsage, err = util.BuildResourceList(phase.ResourceUsage); err != nil {
+				return nil, err
+			}
 		}
 
-		resourceUsage, err := util.BuildResourceList(phase.ResourceUsage)
+		model, err := buildUsageModel(phase.usageModelYAML, resourceUsage, seed+int64(i))
 		if err != nil {
 			return nil, err
 		}
 		spec = append(spec, specPhase{
 			seconds:       phase.Seconds,
 			resourceUsage: resourceUsage,
+			model:         model,
 		})
 	}
 
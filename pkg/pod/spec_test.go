@@ -17,11 +17,14 @@ package pod
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
 )
 
 func specPhaseNE(sp1, sp2 specPhase) bool {
@@ -38,7 +41,7 @@ func TestParseSpec(t *testing.T) {
 		},
 	}
 
-	_, err := parseSpec(pod)
+	_, _, err := parseSpec(pod)
 	assert.EqualError(t, err, "simSpec annotation not defined")
 
 	pod = &v1.Pod{
@@ -57,7 +60,7 @@ func TestParseSpec(t *testing.T) {
 		},
 	}
 
-	actual, err := parseSpec(pod)
+	actual, _, err := parseSpec(pod)
 	if err != nil {
 		t.Errorf("error %s", err.Error())
 	}
@@ -136,3 +139,116 @@ func TestParseSpecYAML(t *testing.T) {
 	_, err = parseSpecYAML(yamlStrInvalid)
 	assert.EqualError(t, err, "Invalid spec.resoruceUsage field")
 }
+
+func TestUsageModels(t *testing.T) {
+	actual, err := parseSpecYAMLWithSeed(`
+- seconds: 100
+  model: linear
+  points:
+  - at: 0
+    resourceUsage: {cpu: 1, memory: 1Gi}
+  - at: 100
+    resourceUsage: {cpu: 3, memory: 3Gi}
+- seconds: 100
+  model: noise
+  distribution: uniform
+  resourceUsage: {cpu: 2}
+  noise: {cpu: 1}
+  interval: 10
+- seconds: 100
+  model: periodic
+  resourceUsage: {cpu: 2}
+  amplitude: {cpu: 1}
+  period: 40
+- seconds: 100
+  model: markov
+  states:
+  - resourceUsage: {cpu: 1}
+  - resourceUsage: {cpu: 4}
+  transitions: [[0, 1], [1, 0]]
+  interval: 5
+`, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cpu := func(phase int, offset int32) int64 {
+		q := actual[phase].usageAt(offset)[v1.ResourceCPU]
+		return q.MilliValue()
+	}
+
+	if c := cpu(0, 50); c != 2000 {
+		t.Errorf("linear: got: %dm\nwant: 2000m", c)
+	}
+	if mem := actual[0].usageAt(25)[v1.ResourceMemory]; mem.Value() != 3<<29 {
+		t.Errorf("linear: got: %s\nwant: 1.5Gi", mem.String())
+	}
+	if c := cpu(0, 200); c != 3000 {
+		t.Errorf("linear: got: %dm\nwant: 3000m", c)
+	}
+
+	for offset := int32(0); offset < 100; offset++ {
+		c := cpu(1, offset)
+		if c < 1000 || 3000 < c || c != cpu(1, offset/10*10) {
+			t.Fatalf("noise: got: %dm at %d\nwant: in [1000m, 3000m], constant over 10 seconds", c, offset)
+		}
+	}
+
+	if c := cpu(2, 10); c != 3000 {
+		t.Errorf("periodic: got: %dm\nwant: 3000m", c)
+	}
+	if c := cpu(2, 30); c != 1000 {
+		t.Errorf("periodic: got: %dm\nwant: 1000m", c)
+	}
+
+	// The states alternate every 5 seconds, even if queried back in time.
+	for _, offset := range []int32{0, 7, 12, 3, 99} {
+		expected := []int64{1000, 4000}[(offset/5)%2]
+		if c := cpu(3, offset); c != expected {
+			t.Errorf("markov: got: %dm at %d\nwant: %dm", c, offset, expected)
+		}
+	}
+
+	_, err = parseSpecYAML(`
+- seconds: 10
+  model: markov
+  states:
+  - resourceUsage: {cpu: 1}
+  transitions: [[0.5]]
+`)
+	assert.EqualError(t, err, "markov transition row 0 must sum to 1")
+}
+
+func TestResourceUsageWithModels(t *testing.T) {
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	pod, err := NewPod(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: "default",
+			Annotations: map[string]string{"simSpec": `
+- seconds: 10
+  resourceUsage: {cpu: 1}
+- seconds: 100
+  model: linear
+  points:
+  - {at: 0, resourceUsage: {cpu: 1}}
+  - {at: 100, resourceUsage: {cpu: 11}}
+`},
+		},
+	}, start, Ok, "node")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The offsets within the linear phase are kept after the first phase is dropped.
+	for _, sec := range []int{5, 20, 60, 60} {
+		actual := pod.ResourceUsage(start.Add(time.Duration(sec) * time.Second))[v1.ResourceCPU]
+		expected := int64(1000)
+		if sec > 10 {
+			expected = int64(1000 + (sec-10)*100)
+		}
+		if actual.MilliValue() != expected {
+			t.Errorf("got: %dm at %ds\nwant: %dm", actual.MilliValue(), sec, expected)
+		}
+	}
+}
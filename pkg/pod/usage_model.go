@@ -0,0 +1,333 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// usageModel models the resource usage of a pod during one execution phase.
+// The usage at each offset must be deterministic, since it can be queried at any clock.
+type usageModel interface {
+	// usageAt returns the resource usage at the given offset (in seconds) from the start of the
+	// phase.
+	usageAt(offset int32) v1.ResourceList
+}
+
+// usageModelYAML is the YAML representation of the usage model of a phase, in addition to the
+// "seconds" and "resourceUsage" of a constant phase.
+type usageModelYAML struct {
+	// Model is one of "constant" (default), "linear", "noise", "periodic", and "markov".
+	Model string `yaml:"model"`
+
+	// Points of a linear model, between which the usage is linearly interpolated.
+	// The usage is constant before the first point and after the last.
+	Points []struct {
+		At            int32                      `yaml:"at"`
+		ResourceUsage map[v1.ResourceName]string `yaml:"resourceUsage"`
+	} `yaml:"points"`
+
+	// Noise of a noise model, added to resourceUsage: the standard deviation for the "normal"
+	// distribution (default), or the half width for the "uniform" distribution.
+	Distribution string                     `yaml:"distribution"`
+	Noise        map[v1.ResourceName]string `yaml:"noise"`
+
+	// Amplitude and Period (in seconds) of a periodic model, whose usage is
+	// resourceUsage + amplitude * sin(2π (offset + shift) / period).
+	Amplitude map[v1.ResourceName]string `yaml:"amplitude"`
+	Period    int32                      `yaml:"period"`
+	Shift     int32                      `yaml:"shift"`
+
+	// States of a Markov model, and Transitions[i][j], the probability of moving from state i to
+	// state j at every interval. The model starts in the first state.
+	States []struct {
+		ResourceUsage map[v1.ResourceName]string `yaml:"resourceUsage"`
+	} `yaml:"states"`
+	Transitions [][]float64 `yaml:"transitions"`
+
+	// Interval (in seconds, default 1) at which a noise model resamples its noise and a Markov
+	// model transitions.
+	Interval int32 `yaml:"interval"`
+	// Seed of the random numbers of a noise or Markov model. Defaults to a hash of the pod's key,
+	// so that pods sharing a spec do not share their noise.
+	Seed *int64 `yaml:"seed"`
+}
+
+// buildUsageModel builds a usageModel with the given YAML and the resource usage of the phase.
+// Returns nil for a constant model.
+func buildUsageModel(conf usageModelYAML, usage v1.ResourceList, defaultSeed int64) (usageModel, error) {
+	interval := conf.Interval
+	if interval == 0 {
+		interval = 1
+	}
+	if interval < 0 {
+		return nil, strongerrors.InvalidArgument(errors.New("interval must be > 0"))
+	}
+	seed := defaultSeed
+	if conf.Seed != nil {
+		seed = *conf.Seed
+	}
+
+	switch conf.Model {
+	case "", "constant":
+		if usage == nil {
+			return nil, errors.New("Invalid spec.resoruceUsage field")
+		}
+		return nil, nil
+
+	case "linear":
+		if len(conf.Points) == 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("linear model must have points"))
+		}
+		m := &linearModel{}
+		for i, point := range conf.Points {
+			if i > 0 && point.At <= conf.Points[i-1].At {
+				return nil, strongerrors.InvalidArgument(errors.New("linear model must have points in ascending order"))
+			}
+			u, err := util.BuildResourceList(point.ResourceUsage)
+			if err != nil {
+				return nil, err
+			}
+			m.at = append(m.at, point.At)
+			m.usage = append(m.usage, u)
+		}
+		return m, nil
+
+	case "noise":
+		if usage == nil {
+			return nil, strongerrors.InvalidArgument(errors.New("noise model must have resourceUsage"))
+		}
+		if conf.Distribution != "" && conf.Distribution != "normal" && conf.Distribution != "uniform" {
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("noise distribution %q is not supported", conf.Distribution))
+		}
+		noise, err := util.BuildResourceList(conf.Noise)
+		if err != nil {
+			return nil, err
+		}
+		return &noiseModel{
+			mean:     usage,
+			noise:    noise,
+			uniform:  conf.Distribution == "uniform",
+			interval: interval,
+			seed:     seed,
+		}, nil
+
+	case "periodic":
+		if usage == nil || conf.Period <= 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("periodic model must have resourceUsage and period > 0"))
+		}
+		amplitude, err := util.BuildResourceList(conf.Amplitude)
+		if err != nil {
+			return nil, err
+		}
+		return &periodicModel{base: usage, amplitude: amplitude, period: conf.Period, shift: conf.Shift}, nil
+
+	case "markov":
+		n := len(conf.States)
+		if n == 0 || len(conf.Transitions) != n {
+			return nil, strongerrors.InvalidArgument(errors.New("markov model must have states and a transition row per state"))
+		}
+		m := &markovModel{transitions: conf.Transitions, interval: interval, seed: seed}
+		for i, state := range conf.States {
+			u, err := util.BuildResourceList(state.ResourceUsage)
+			if err != nil {
+				return nil, err
+			}
+			m.states = append(m.states, u)
+
+			if len(conf.Transitions[i]) != n {
+				return nil, strongerrors.InvalidArgument(errors.Errorf("markov transition row %d must have %d probabilities", i, n))
+			}
+			sum := 0.0
+			for _, p := range conf.Transitions[i] {
+				if p < 0 {
+					return nil, strongerrors.InvalidArgument(errors.New("markov transition probabilities must be >= 0"))
+				}
+				sum += p
+			}
+			if math.Abs(sum-1) > 1e-6 {
+				return nil, strongerrors.InvalidArgument(errors.Errorf("markov transition row %d must sum to 1", i))
+			}
+		}
+		return m, nil
+
+	default:
+		return nil, strongerrors.InvalidArgument(errors.Errorf("usage model %q is not supported", conf.Model))
+	}
+}
+
+// linearModel interpolates the usage linearly between sample points.
+type linearModel struct {
+	at    []int32
+	usage []v1.ResourceList
+}
+
+func (m *linearModel) usageAt(offset int32) v1.ResourceList {
+	i := sort.Search(len(m.at), func(i int) bool { return m.at[i] > offset })
+	if i == 0 {
+		return m.usage[0]
+	}
+	if i == len(m.at) {
+		return m.usage[len(m.usage)-1]
+	}
+
+	ratio := float64(offset-m.at[i-1]) / float64(m.at[i]-m.at[i-1])
+	usage := v1.ResourceList{}
+	for rsrc := range unionKeys(m.usage[i-1], m.usage[i]) {
+		from, to := milli(m.usage[i-1], rsrc), milli(m.usage[i], rsrc)
+		usage[rsrc] = fromMilli(rsrc, from+(to-from)*ratio, m.usage[i-1], m.usage[i])
+	}
+	return usage
+}
+
+// noiseModel adds random noise, resampled at every interval, to the mean usage.
+type noiseModel struct {
+	mean     v1.ResourceList
+	noise    v1.ResourceList
+	uniform  bool
+	interval int32
+	seed     int64
+}
+
+func (m *noiseModel) usageAt(offset int32) v1.ResourceList {
+	// Seed by the interval so that the noise does not depend on the clocks queried before.
+	r := rand.New(rand.NewSource(m.seed*1000003 + int64(offset/m.interval)))
+
+	usage := v1.ResourceList{}
+	for _, rsrc := range sortedNames(m.mean) {
+		mean := milli(m.mean, rsrc)
+		var delta float64
+		if m.uniform {
+			delta = (2*r.Float64() - 1) * milli(m.noise, rsrc)
+		} else {
+			delta = r.NormFloat64() * milli(m.noise, rsrc)
+		}
+		usage[rsrc] = fromMilli(rsrc, mean+delta, m.mean)
+	}
+	return usage
+}
+
+// periodicModel oscillates sinusoidally around the base usage.
+type periodicModel struct {
+	base      v1.ResourceList
+	amplitude v1.ResourceList
+	period    int32
+	shift     int32
+}
+
+func (m *periodicModel) usageAt(offset int32) v1.ResourceList {
+	sin := math.Sin(2 * math.Pi * float64(offset+m.shift) / float64(m.period))
+
+	usage := v1.ResourceList{}
+	for rsrc := range m.base {
+		usage[rsrc] = fromMilli(rsrc, milli(m.base, rsrc)+milli(m.amplitude, rsrc)*sin, m.base)
+	}
+	return usage
+}
+
+// markovModel transitions among states of constant usage at every interval.
+type markovModel struct {
+	states      []v1.ResourceList
+	transitions [][]float64
+	interval    int32
+	seed        int64
+
+	// The state at step is cached, since the usage is mostly queried with increasing offsets.
+	rand  *rand.Rand
+	step  int32
+	state int
+}
+
+func (m *markovModel) usageAt(offset int32) v1.ResourceList {
+	target := offset / m.interval
+	if m.rand == nil || target < m.step {
+		m.rand = rand.New(rand.NewSource(m.seed))
+		m.step = 0
+		m.state = 0
+	}
+
+	for ; m.step < target; m.step++ {
+		p := m.rand.Float64()
+		next := len(m.states) - 1
+		for j, prob := range m.transitions[m.state] {
+			if p < prob {
+				next = j
+				break
+			}
+			p -= prob
+		}
+		m.state = next
+	}
+
+	return m.states[m.state]
+}
+
+// milli returns the amount of the resource in rl in milli-units.
+func milli(rl v1.ResourceList, rsrc v1.ResourceName) float64 {
+	q, ok := rl[rsrc]
+	if !ok {
+		return 0
+	}
+	return float64(q.MilliValue())
+}
+
+// fromMilli returns the quantity of the given amount in milli-units, clamped to be >= 0, in the
+// format of the resource in any of the given lists.
+func fromMilli(rsrc v1.ResourceName, amount float64, formats ...v1.ResourceList) resource.Quantity {
+	format := resource.DecimalSI
+	for _, rl := range formats {
+		if q, ok := rl[rsrc]; ok {
+			format = q.Format
+			break
+		}
+	}
+
+	amount = math.Max(amount, 0)
+	if rsrc == v1.ResourceCPU {
+		return *resource.NewMilliQuantity(int64(amount), format)
+	}
+	// Round to a whole unit, since fractional amounts of bytes or devices are meaningless.
+	return *resource.NewQuantity(int64(math.Round(amount/1000)), format)
+}
+
+func unionKeys(rls ...v1.ResourceList) map[v1.ResourceName]struct{} {
+	keys := map[v1.ResourceName]struct{}{}
+	for _, rl := range rls {
+		for rsrc := range rl {
+			keys[rsrc] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// sortedNames returns the resource names in rl in a deterministic order.
+func sortedNames(rl v1.ResourceList) []v1.ResourceName {
+	names := make([]v1.ResourceName, 0, len(rl))
+	for rsrc := range rl {
+		names = append(names, rsrc)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
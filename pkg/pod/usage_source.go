@@ -0,0 +1,425 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// UsageSourceAnnotation is the annotation naming the UsageSource, registered by
+	// RegisterUsageSource, from which a pod consumes its phases.
+	UsageSourceAnnotation = "kubesim.io/usage-source"
+	// PhaseCacheAnnotation is the annotation of the number of phases loaded at a time from the file
+	// of the "path" annotation, either phases in JSON lines or a pod manifest in JSON.
+	PhaseCacheAnnotation = "kubesim.io/phase-cache"
+	// DefaultPhaseCacheSize is the number of phases loaded at a time from a file by default.
+	DefaultPhaseCacheSize = 100
+)
+
+// Phase is an execution phase of a pod, provided by a UsageSource.
+type Phase struct {
+	Seconds       int32           `json:"seconds"`
+	ResourceUsage v1.ResourceList `json:"resourceUsage"`
+	// UsageAt returns the usage varying within the phase, at the given offset (in seconds) from the
+	// start of the phase. The usage is the constant ResourceUsage if UsageAt is nil.
+	UsageAt func(offset int32) v1.ResourceList `json:"-"`
+}
+
+// UsageSource is a source of the execution phases of a pod, which the pod consumes in order as it
+// runs, so that only the phases around the current one have to be kept in memory.
+type UsageSource interface {
+	// TotalSeconds returns the total seconds of all the phases.
+	TotalSeconds() int32
+	// Next returns the phases following those returned before. How many phases are returned at a
+	// time is up to the source.
+	// Returns io.EOF if there is no more phase.
+	Next() ([]Phase, error)
+}
+
+// UsageSourceFactory creates the UsageSource of the given pod.
+type UsageSourceFactory func(pod *v1.Pod) (UsageSource, error)
+
+var (
+	usageSourceFactories      = map[string]UsageSourceFactory{}
+	usageSourceFactoriesMutex = sync.RWMutex{}
+)
+
+// RegisterUsageSource registers the factory of UsageSource with the given name, which pods select
+// with the UsageSourceAnnotation.
+func RegisterUsageSource(name string, factory UsageSourceFactory) {
+	usageSourceFactoriesMutex.Lock()
+	defer usageSourceFactoriesMutex.Unlock()
+	usageSourceFactories[name] = factory
+}
+
+// newUsageSource creates the UsageSource of the pod: the registered one named by the
+// UsageSourceAnnotation, a FileSource or the pod manifest of the "path" annotation, or a SpecSource
+// of the "simSpec" annotation, in this order.
+func newUsageSource(pod *v1.Pod) (UsageSource, error) {
+	if name, ok := pod.Annotations[UsageSourceAnnotation]; ok {
+		usageSourceFactoriesMutex.RLock()
+		factory, ok := usageSourceFactories[name]
+		usageSourceFactoriesMutex.RUnlock()
+		if !ok {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("usage source %q is not registered", name))
+		}
+		return factory(pod)
+	}
+
+	if path := pod.Annotations["path"]; path != "" {
+		cacheSize, err := phaseCacheSize(pod)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(path, ".jsonl") {
+			return NewFileSource(path, cacheSize)
+		}
+		return newManifestSource(path, cacheSize)
+	}
+
+	return NewSpecSource(pod)
+}
+
+// Validate validates the source of the execution phases of the pod in the same order as
+// newUsageSource, so that an invalid pod can be rejected before it is bound.
+// The "simSpec" annotation is parsed, while a registered UsageSource is only checked to be
+// registered and the file of the "path" annotation to exist.
+func Validate(pod *v1.Pod) error {
+	if name, ok := pod.Annotations[UsageSourceAnnotation]; ok {
+		usageSourceFactoriesMutex.RLock()
+		_, ok := usageSourceFactories[name]
+		usageSourceFactoriesMutex.RUnlock()
+		if !ok {
+			return strongerrors.InvalidArgument(errors.Errorf("usage source %q is not registered", name))
+		}
+		return nil
+	}
+
+	if path := pod.Annotations["path"]; path != "" {
+		if _, err := phaseCacheSize(pod); err != nil {
+			return err
+		}
+		_, err := os.Stat(path)
+		return err
+	}
+
+	_, _, err := parseSpec(pod)
+	return err
+}
+
+// phaseCacheSize returns the number of phases loaded at a time from the file of the pod.
+// Returns error if the PhaseCacheAnnotation is invalid.
+func phaseCacheSize(pod *v1.Pod) (int, error) {
+	str, ok := pod.Annotations[PhaseCacheAnnotation]
+	if !ok {
+		return DefaultPhaseCacheSize, nil
+	}
+	cacheSize, err := strconv.Atoi(str)
+	if err != nil || cacheSize <= 0 {
+		return 0, strongerrors.InvalidArgument(errors.Errorf("invalid %s annotation %q", PhaseCacheAnnotation, str))
+	}
+	return cacheSize, nil
+}
+
+// SpecSource is a UsageSource of the phases in the "simSpec" annotation of a pod.
+type SpecSource struct {
+	spec  spec
+	total int32
+}
+
+// NewSpecSource creates a new SpecSource of the "simSpec" annotation of the given pod.
+// Returns error if the annotation does not exist or is invalid.
+func NewSpecSource(pod *v1.Pod) (*SpecSource, error) {
+	spec, _, err := parseSpec(pod)
+	if err != nil {
+		return nil, err
+	}
+	total := int32(0)
+	for _, phase := range spec {
+		total += phase.seconds
+	}
+	return &SpecSource{spec: spec, total: total}, nil
+}
+
+// TotalSeconds implements UsageSource interface.
+func (s *SpecSource) TotalSeconds() int32 {
+	return s.total
+}
+
+// Next implements UsageSource interface.
+// All the phases are returned at once, since they are already in memory.
+func (s *SpecSource) Next() ([]Phase, error) {
+	if s.spec == nil {
+		return nil, io.EOF
+	}
+
+	phases := s.spec.phases(0, len(s.spec))
+	s.spec = nil
+	return phases, nil
+}
+
+// phases converts the phases of this spec in [from, to) to Phases.
+func (s spec) phases(from, to int) []Phase {
+	phases := make([]Phase, 0, to-from)
+	for _, phase := range s[from:to] {
+		p := Phase{Seconds: phase.seconds, ResourceUsage: phase.resourceUsage}
+		if phase.model != nil {
+			p.UsageAt = phase.model.usageAt
+		}
+		phases = append(phases, p)
+	}
+	return phases
+}
+
+// FileSource is a UsageSource streaming the phases from a file written by WritePhases, one JSON
+// object per line.
+// The file is read from the offset where the previous Next stopped, and is not kept open between
+// calls, so that many pods can stream their phases at once.
+type FileSource struct {
+	path      string
+	cacheSize int
+	total     int32
+	offset    int64
+}
+
+// NewFileSource creates a new FileSource of the file at the given path, which returns at most
+// cacheSize phases at a time.
+// The file is scanned once to validate it and to sum up the seconds of its phases.
+func NewFileSource(path string, cacheSize int) (*FileSource, error) {
+	if cacheSize <= 0 {
+		cacheSize = DefaultPhaseCacheSize
+	}
+	s := &FileSource{path: path, cacheSize: cacheSize}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for {
+		phase, _, err := readPhase(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "invalid phases file %s", path)
+		}
+		s.total += phase.Seconds
+	}
+
+	return s, nil
+}
+
+// TotalSeconds implements UsageSource interface.
+func (s *FileSource) TotalSeconds() int32 {
+	return s.total
+}
+
+// Next implements UsageSource interface.
+func (s *FileSource) Next() ([]Phase, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(s.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(f)
+	phases := []Phase{}
+	for len(phases) < s.cacheSize {
+		phase, n, err := readPhase(r)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "invalid phases file %s", s.path)
+		}
+		s.offset += n
+		phases = append(phases, phase)
+	}
+
+	if len(phases) == 0 {
+		return nil, io.EOF
+	}
+	return phases, nil
+}
+
+// manifestSource is a UsageSource of the phases in the "simSpec" annotation of a pod manifest in
+// JSON, which returns at most cacheSize phases at a time.
+// The manifest is read and parsed only once, and its phases are kept in memory until returned.
+type manifestSource struct {
+	spec      spec
+	cacheSize int
+	total     int32
+}
+
+// newManifestSource creates a new manifestSource of the pod manifest at the given path.
+func newManifestSource(path string, cacheSize int) (*manifestSource, error) {
+	if cacheSize <= 0 {
+		cacheSize = DefaultPhaseCacheSize
+	}
+	spec, err := readManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &manifestSource{spec: spec, cacheSize: cacheSize}
+	for _, phase := range spec {
+		s.total += phase.seconds
+	}
+	return s, nil
+}
+
+// readManifest reads the spec of the pod manifest in JSON at the given path.
+func readManifest(path string) (spec, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pod := v1.Pod{}
+	if err := json.Unmarshal(data, &pod); err != nil {
+		return nil, strongerrors.InvalidArgument(errors.Wrapf(err, "invalid pod manifest %s", path))
+	}
+	spec, _, err := parseSpec(&pod)
+	return spec, err
+}
+
+// TotalSeconds implements UsageSource interface.
+func (s *manifestSource) TotalSeconds() int32 {
+	return s.total
+}
+
+// Next implements UsageSource interface.
+func (s *manifestSource) Next() ([]Phase, error) {
+	if len(s.spec) == 0 {
+		return nil, io.EOF
+	}
+
+	n := s.cacheSize
+	if n > len(s.spec) {
+		n = len(s.spec)
+	}
+	phases := s.spec.phases(0, n)
+	// Release the returned phases, which are still referenced by the underlying array.
+	for i := 0; i < n; i++ {
+		s.spec[i] = specPhase{}
+	}
+	s.spec = s.spec[n:]
+	return phases, nil
+}
+
+// readPhase reads the next phase from r, skipping blank lines.
+// Returns the phase and the number of bytes read, or io.EOF if there is no more phase.
+func readPhase(r *bufio.Reader) (Phase, int64, error) {
+	read := int64(0)
+	for {
+		line, err := r.ReadBytes('\n')
+		read += int64(len(line))
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return Phase{}, read, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			if err == io.EOF {
+				return Phase{}, read, io.EOF
+			}
+			continue
+		}
+
+		phase := Phase{}
+		if err := json.Unmarshal(line, &phase); err != nil {
+			return Phase{}, read, strongerrors.InvalidArgument(err)
+		}
+		return phase, read, nil
+	}
+}
+
+// WritePhases writes the phases to w in the format read by FileSource.
+// The UsageAt of the phases is not written.
+func WritePhases(w io.Writer, phases []Phase) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, phase := range phases {
+		if err := enc.Encode(phase); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// GeneratorSource is a UsageSource of phases generated on demand.
+type GeneratorSource struct {
+	numPhases int
+	cacheSize int
+	generate  func(i int) Phase
+	total     int32
+	next      int
+}
+
+// NewGeneratorSource creates a new GeneratorSource of numPhases phases, the i-th of which is
+// generated by generate(i), cacheSize phases at a time.
+// generate must be deterministic, since it is also called once for every phase to sum up their
+// seconds.
+func NewGeneratorSource(numPhases, cacheSize int, generate func(i int) Phase) *GeneratorSource {
+	if cacheSize <= 0 {
+		cacheSize = DefaultPhaseCacheSize
+	}
+	s := &GeneratorSource{numPhases: numPhases, cacheSize: cacheSize, generate: generate}
+	for i := 0; i < numPhases; i++ {
+		s.total += generate(i).Seconds
+	}
+	return s
+}
+
+// TotalSeconds implements UsageSource interface.
+func (s *GeneratorSource) TotalSeconds() int32 {
+	return s.total
+}
+
+// Next implements UsageSource interface.
+func (s *GeneratorSource) Next() ([]Phase, error) {
+	if s.next >= s.numPhases {
+		return nil, io.EOF
+	}
+
+	phases := []Phase{}
+	for ; s.next < s.numPhases && len(phases) < s.cacheSize; s.next++ {
+		phases = append(phases, s.generate(s.next))
+	}
+	return phases, nil
+}
+
+// usageFunc adapts the UsageAt of a Phase to usageModel.
+type usageFunc func(offset int32) v1.ResourceList
+
+func (f usageFunc) usageAt(offset int32) v1.ResourceList {
+	return f(offset)
+}
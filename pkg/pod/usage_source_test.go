@@ -0,0 +1,244 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+)
+
+func cpuPhase(seconds int32, cpu int64) Phase {
+	return Phase{
+		Seconds:       seconds,
+		ResourceUsage: v1.ResourceList{v1.ResourceCPU: *resource.NewQuantity(cpu, resource.DecimalSI)},
+	}
+}
+
+// cpuAt returns the CPU usage of the pod at each of the given seconds from its start.
+func cpuAt(pod *Pod, start clock.Clock, seconds ...int) []int64 {
+	usages := []int64{}
+	for _, sec := range seconds {
+		cpu := pod.ResourceUsage(start.Add(time.Duration(sec) * time.Second))[v1.ResourceCPU]
+		usages = append(usages, cpu.Value())
+	}
+	return usages
+}
+
+func TestFileSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "pod.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WritePhases(f, []Phase{cpuPhase(10, 1), cpuPhase(5, 2), cpuPhase(20, 3)}); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	source, err := NewFileSource(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := source.TotalSeconds(); total != 35 {
+		t.Errorf("got: %d\nwant: 35", total)
+	}
+
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	pod, err := NewPodWithSource(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod"}}, start, Ok, "node", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(pod.spec); n != 2 {
+		t.Errorf("got: %d phases loaded\nwant: 2 phases loaded", n)
+	}
+
+	actual := cpuAt(pod, start, 0, 9, 10, 14, 15, 34)
+	expected := []int64{1, 1, 2, 2, 3, 3}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("got: %v\nwant: %v", actual, expected)
+			break
+		}
+	}
+	if !pod.IsTerminated(start.Add(35 * time.Second)) {
+		t.Errorf("got: pod running\nwant: pod terminated at 35s")
+	}
+	if _, err := source.Next(); err != io.EOF {
+		t.Errorf("got: %v\nwant: %v", err, io.EOF)
+	}
+}
+
+func TestManifestSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "pod.json")
+	manifest := `{"metadata": {"name": "pod", "annotations": {"simSpec": "` +
+		`- {seconds: 1, resourceUsage: {cpu: 1}}\n- {seconds: 2, resourceUsage: {cpu: 2}}\n- {seconds: 3, resourceUsage: {cpu: 3}}\n"}}}`
+	if err := ioutil.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := newUsageSource(&v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "pod",
+		Annotations: map[string]string{"path": path, PhaseCacheAnnotation: "2"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := source.TotalSeconds(); total != 6 {
+		t.Errorf("got: %d\nwant: 6", total)
+	}
+
+	// The manifest is not read again.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// The phases are returned 2 at a time.
+	for _, expected := range [][]int32{{1, 2}, {3}} {
+		phases, err := source.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := []int32{}
+		for _, phase := range phases {
+			actual = append(actual, phase.Seconds)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("got: %v\nwant: %v", actual, expected)
+		}
+	}
+	if _, err := source.Next(); err != io.EOF {
+		t.Errorf("got: %v\nwant: %v", err, io.EOF)
+	}
+}
+
+func TestGeneratorSource(t *testing.T) {
+	called := 0
+	source := NewGeneratorSource(4, 1, func(i int) Phase {
+		called++
+		return cpuPhase(int32(i+1), int64(i))
+	})
+	if total := source.TotalSeconds(); total != 10 {
+		t.Errorf("got: %d\nwant: 10", total)
+	}
+
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	pod, err := NewPodWithSource(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod"}}, start, Ok, "node", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The phases are generated one at a time as the pod runs.
+	called = 0
+	actual := cpuAt(pod, start, 0, 2, 5)
+	expected := []int64{0, 1, 2}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("got: %v\nwant: %v", actual, expected)
+			break
+		}
+	}
+	if called != 2 {
+		t.Errorf("got: %d phases generated\nwant: 2 phases generated", called)
+	}
+}
+
+func TestNewUsageSource(t *testing.T) {
+	RegisterUsageSource("test", func(pod *v1.Pod) (UsageSource, error) {
+		return NewGeneratorSource(1, 0, func(int) Phase { return cpuPhase(42, 1) }), nil
+	})
+
+	newPod := func(annots map[string]string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Annotations: annots}}
+	}
+
+	source, err := newUsageSource(newPod(map[string]string{UsageSourceAnnotation: "test"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := source.TotalSeconds(); total != 42 {
+		t.Errorf("got: %d\nwant: 42", total)
+	}
+
+	source, err = newUsageSource(newPod(map[string]string{"simSpec": "- seconds: 7\n  resourceUsage:\n    cpu: 1\n"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := source.(*SpecSource); !ok || source.TotalSeconds() != 7 {
+		t.Errorf("got: %T of %d seconds\nwant: *SpecSource of 7 seconds", source, source.TotalSeconds())
+	}
+
+	for _, annots := range []map[string]string{
+		{UsageSourceAnnotation: "unknown"},
+		{"path": "pod.jsonl", PhaseCacheAnnotation: "0"},
+		{},
+	} {
+		if _, err := newUsageSource(newPod(annots)); err == nil {
+			t.Errorf("%v: got: no error\nwant: error", annots)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	RegisterUsageSource("test", func(pod *v1.Pod) (UsageSource, error) {
+		return NewGeneratorSource(1, 0, func(int) Phase { return cpuPhase(42, 1) }), nil
+	})
+
+	newPod := func(annots map[string]string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Annotations: annots}}
+	}
+
+	for _, annots := range []map[string]string{
+		{UsageSourceAnnotation: "test"},
+		{"simSpec": "- seconds: 7\n  resourceUsage:\n    cpu: 1\n"},
+	} {
+		if err := Validate(newPod(annots)); err != nil {
+			t.Errorf("%v: got: %v\nwant: no error", annots, err)
+		}
+	}
+
+	for _, annots := range []map[string]string{
+		{UsageSourceAnnotation: "unknown"},
+		{"path": "no-such-pod.jsonl"},
+		{"simSpec": "- seconds: 7\n  resourceUsage:\n    cpu: one\n"},
+		{},
+	} {
+		if err := Validate(newPod(annots)); err == nil {
+			t.Errorf("%v: got: no error\nwant: error", annots)
+		}
+	}
+}
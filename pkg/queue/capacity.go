@@ -0,0 +1,536 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// capacityEpsilon absorbs rounding errors in comparing shares of capacity.
+const capacityEpsilon = 1e-9
+
+// QueueCapacity specifies a queue in the tree of a CapacityQueue.
+type QueueCapacity struct {
+	// Name is the name of the queue, unique in the tree.
+	Name string
+	// Capacity is the share of the capacity of the parent queue guaranteed to this queue.
+	// Ignored for the root queue, which has the whole cluster.
+	Capacity float64
+	// MaxCapacity is the share of the capacity of the parent queue up to which this queue can use
+	// the capacity idle in its siblings. 0 means 1, i.e. the whole capacity of the parent.
+	MaxCapacity float64
+	// Children are the child queues; pods are queued only in the leaf queues.
+	Children []QueueCapacity
+}
+
+// CapacityMetrics is the metrics of a queue of a CapacityQueue. The capacities are the dominant
+// shares of the cluster allocatable.
+type CapacityMetrics struct {
+	Capacity    float64
+	MaxCapacity float64
+	// Used is the dominant share of the resources requested by the bound pods of the queue.
+	Used           float64
+	PendingPodsNum int
+}
+
+// Reclaimer is implemented by PodQueues that preempt bound pods to reclaim the capacity guaranteed
+// to their pending pods.
+// KubeSim calls Reclaim before every scheduling, after ObserveCluster, and evicts the returned pods
+// back to the PodQueue.
+type Reclaimer interface {
+	// Reclaim returns the bound pods to be preempted.
+	Reclaim() []*v1.Pod
+}
+
+// CapacityQueue stores pods in a tree of queues, each guaranteed a share of the capacity of its
+// parent, in the manner of the Capacity Scheduler of Hadoop YARN.
+// A pod is queued in the leaf queue named in its queue label, or in the default queue.
+// The queue furthest below its guaranteed capacity is served first at each level of the tree, and
+// a queue can use the capacity idle in its siblings up to its maximum capacity (elastic sharing).
+// Pods of the same leaf queue are sorted by the comparator.
+type CapacityQueue struct {
+	queueLabel   string
+	defaultQueue string
+	comparator   Compare
+	preemption   bool
+
+	root *capacityNode
+	// nodes are all queues in the depth-first order; leaves maps the name of a leaf queue to it.
+	nodes  []*capacityNode
+	leaves map[string]*capacityNode
+	// leafOf maps the key of a pending pod to its leaf queue.
+	leafOf map[string]*capacityNode
+
+	// allocatable and bound are updated by ObserveCluster.
+	// popped holds the pods popped since the last observation, which are charged to their leaf
+	// queues until they are observed as bound or pushed back.
+	allocatable v1.ResourceList
+	bound       []boundPod
+	popped      map[string]boundPod
+}
+
+type capacityNode struct {
+	name     string
+	parent   *capacityNode
+	children []*capacityNode
+
+	// capacity and maxCapacity are the absolute shares of the cluster.
+	capacity    float64
+	maxCapacity float64
+
+	// pods are the pending pods of a leaf queue.
+	pods *PriorityQueue
+	// usage is the total request of the bound and popped pods of the queue.
+	usage v1.ResourceList
+}
+
+type boundPod struct {
+	pod     *v1.Pod
+	leaf    *capacityNode
+	request v1.ResourceList
+}
+
+// NewCapacityQueue creates a new CapacityQueue with the tree of queues rooted at root.
+// Pods are queued in the leaf queue named in their queueLabel label, or in defaultQueue if the
+// label is missing or names no leaf queue. If preemption is true, the CapacityQueue implements
+// Reclaimer by preempting pods of the queues above their guaranteed capacity.
+// Pods of the same leaf queue are sorted with DefaultComparator.
+// Returns error if the tree is invalid.
+func NewCapacityQueue(root QueueCapacity, queueLabel, defaultQueue string, preemption bool) (*CapacityQueue, error) {
+	return NewCapacityQueueWithComparator(root, queueLabel, defaultQueue, preemption, DefaultComparator)
+}
+
+// NewCapacityQueueWithComparator creates a new CapacityQueue sorting pods of the same leaf queue
+// with the given comparator.
+func NewCapacityQueueWithComparator(
+	root QueueCapacity, queueLabel, defaultQueue string, preemption bool, comparator Compare) (*CapacityQueue, error) {
+
+	cq := &CapacityQueue{
+		queueLabel:   queueLabel,
+		defaultQueue: defaultQueue,
+		comparator:   comparator,
+		preemption:   preemption,
+
+		leaves: map[string]*capacityNode{},
+		leafOf: map[string]*capacityNode{},
+
+		allocatable: v1.ResourceList{},
+		popped:      map[string]boundPod{},
+	}
+
+	names := map[string]bool{}
+	var err error
+	if cq.root, err = cq.buildNode(root, nil, names); err != nil {
+		return nil, err
+	}
+	if _, ok := cq.leaves[defaultQueue]; !ok {
+		return nil, fmt.Errorf("default queue %q is not a leaf queue", defaultQueue)
+	}
+
+	return cq, nil
+}
+
+func (cq *CapacityQueue) buildNode(spec QueueCapacity, parent *capacityNode, names map[string]bool) (*capacityNode, error) {
+	if spec.Name == "" {
+		return nil, fmt.Errorf("queue name must not be empty")
+	}
+	if names[spec.Name] {
+		return nil, fmt.Errorf("queue %q is duplicated", spec.Name)
+	}
+	names[spec.Name] = true
+
+	node := &capacityNode{
+		name:        spec.Name,
+		parent:      parent,
+		capacity:    1,
+		maxCapacity: 1,
+		usage:       v1.ResourceList{},
+	}
+	if parent != nil {
+		maxCapacity := spec.MaxCapacity
+		if maxCapacity == 0 {
+			maxCapacity = 1
+		}
+		if spec.Capacity < 0 || spec.Capacity > maxCapacity || maxCapacity > 1 {
+			return nil, fmt.Errorf(
+				"capacity %v and max capacity %v of queue %q must satisfy 0 <= capacity <= max capacity <= 1",
+				spec.Capacity, spec.MaxCapacity, spec.Name)
+		}
+		node.capacity = parent.capacity * spec.Capacity
+		node.maxCapacity = parent.maxCapacity * maxCapacity
+	}
+	cq.nodes = append(cq.nodes, node)
+
+	if len(spec.Children) == 0 {
+		node.pods = NewPriorityQueueWithComparator(cq.comparator)
+		cq.leaves[spec.Name] = node
+		return node, nil
+	}
+
+	total := 0.0
+	for _, childSpec := range spec.Children {
+		child, err := cq.buildNode(childSpec, node, names)
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+		total += childSpec.Capacity
+	}
+	if total > 1+capacityEpsilon {
+		return nil, fmt.Errorf("capacities of the children of queue %q sum to %v, more than 1", spec.Name, total)
+	}
+
+	return node, nil
+}
+
+// ObserveCluster implements ClusterObserver interface.
+// It recomputes the usage of each queue from the requests of the bound pods.
+func (cq *CapacityQueue) ObserveCluster(allocatable v1.ResourceList, boundPods []*v1.Pod) {
+	cq.allocatable = allocatable
+	cq.bound = make([]boundPod, 0, len(boundPods))
+	cq.popped = map[string]boundPod{}
+	for _, node := range cq.nodes {
+		node.usage = v1.ResourceList{}
+	}
+
+	for _, pod := range boundPods {
+		bp := boundPod{pod: pod, leaf: cq.leaf(pod), request: util.PodTotalResourceRequests(pod)}
+		cq.bound = append(cq.bound, bp)
+		bp.leaf.charge(bp.request, true)
+	}
+}
+
+// SetComparator implements OrderedQueue interface.
+// The comparator sorts the pods of the same leaf queue.
+func (cq *CapacityQueue) SetComparator(comparator Compare) {
+	cq.comparator = comparator
+	for _, leaf := range cq.leaves {
+		leaf.pods.SetComparator(comparator)
+	}
+}
+
+func (cq *CapacityQueue) Push(pod *v1.Pod) error {
+	key, err := util.PodKey(pod)
+	if err != nil {
+		return err
+	}
+
+	leaf := cq.leaf(pod)
+	if err := leaf.pods.Push(pod); err != nil {
+		return err
+	}
+	cq.leafOf[key] = leaf
+
+	// A pod popped and pushed back (e.g., failed to be scheduled) no longer counts.
+	if popped, ok := cq.popped[key]; ok {
+		popped.leaf.charge(popped.request, false)
+		delete(cq.popped, key)
+	}
+
+	return nil
+}
+
+func (cq *CapacityQueue) Pop() (*v1.Pod, error) {
+	leaf := cq.nextLeaf(cq.root)
+	if leaf == nil {
+		return nil, ErrEmptyQueue
+	}
+
+	pod, err := leaf.pods.Pop()
+	if err != nil {
+		return nil, err
+	}
+	key, _ := util.PodKey(pod) // stored pod never have invalid key
+	delete(cq.leafOf, key)
+
+	// Charge the popped pod to the queue so that pods popped at the same clock are also limited by
+	// the capacities.
+	popped := boundPod{pod: pod, leaf: leaf, request: util.PodTotalResourceRequests(pod)}
+	leaf.charge(popped.request, true)
+	cq.popped[key] = popped
+
+	return pod, nil
+}
+
+// Front returns the pod to be popped next. Returns ErrEmptyQueue if no pending pod can be popped
+// without exceeding the maximum capacity of its queue.
+func (cq *CapacityQueue) Front() (*v1.Pod, error) {
+	leaf := cq.nextLeaf(cq.root)
+	if leaf == nil {
+		return nil, ErrEmptyQueue
+	}
+	return leaf.pods.Front()
+}
+
+func (cq *CapacityQueue) Delete(podNamespace, podName string) bool {
+	key := util.PodKeyFromNames(podNamespace, podName)
+	leaf, ok := cq.leafOf[key]
+	if !ok {
+		return false
+	}
+
+	delete(cq.leafOf, key)
+	return leaf.pods.Delete(podNamespace, podName)
+}
+
+func (cq *CapacityQueue) Update(podNamespace, podName string, newPod *v1.Pod) error {
+	keyOrig := util.PodKeyFromNames(podNamespace, podName)
+	keyNew, err := util.PodKey(newPod)
+	if err != nil {
+		return err
+	}
+	if keyOrig != keyNew {
+		return ErrDifferentNames
+	}
+
+	leaf, ok := cq.leafOf[keyOrig]
+	if !ok {
+		return &ErrNoMatchingPod{key: keyOrig}
+	}
+
+	// The new pod may belong to another queue if its label has changed.
+	if cq.leaf(newPod) != leaf {
+		cq.Delete(podNamespace, podName)
+		return cq.Push(newPod)
+	}
+
+	return leaf.pods.Update(podNamespace, podName, newPod)
+}
+
+func (cq *CapacityQueue) UpdateNominatedNode(pod *v1.Pod, nodeName string) error {
+	return cq.leaf(pod).pods.UpdateNominatedNode(pod, nodeName)
+}
+
+func (cq *CapacityQueue) RemoveNominatedNode(pod *v1.Pod) error {
+	return cq.leaf(pod).pods.RemoveNominatedNode(pod)
+}
+
+func (cq *CapacityQueue) NominatedPods(nodeName string) []*v1.Pod {
+	pods := []*v1.Pod{}
+	for _, node := range cq.nodes {
+		if node.pods != nil {
+			pods = append(pods, node.pods.NominatedPods(nodeName)...)
+		}
+	}
+
+	return pods
+}
+
+func (cq *CapacityQueue) Metrics(qualityOfService, predictionPenalty, podQoses, numPods float32) Metrics {
+	return Metrics{
+		PendingPodsNum:    len(cq.leafOf),
+		QualityOfService:  qualityOfService,
+		PredictionPenalty: predictionPenalty,
+		NumSatifisedPods:  podQoses,
+		NumPods:           numPods,
+		Capacity:          cq.Utilization(),
+	}
+}
+
+// PendingPods implements PendingPodLister interface.
+func (cq *CapacityQueue) PendingPods() []*v1.Pod {
+	pods := make([]*v1.Pod, 0, len(cq.leafOf))
+	for _, node := range cq.nodes {
+		if node.pods != nil {
+			pods = append(pods, node.pods.PendingPods()...)
+		}
+	}
+	return pods
+}
+
+// Utilization returns the capacities, the usage, and the number of the pending pods of each queue.
+func (cq *CapacityQueue) Utilization() map[string]CapacityMetrics {
+	metrics := make(map[string]CapacityMetrics, len(cq.nodes))
+	for _, node := range cq.nodes {
+		metrics[node.name] = CapacityMetrics{
+			Capacity:       node.capacity,
+			MaxCapacity:    node.maxCapacity,
+			Used:           cq.used(node),
+			PendingPodsNum: cq.pendingPodsNum(node),
+		}
+	}
+
+	return metrics
+}
+
+// Reclaim implements Reclaimer interface.
+// For each leaf queue whose pending pods (in the queue order, as far as they fit in its guaranteed
+// capacity) do not fit in the idle capacity of the cluster, it preempts the bound pods of the leaf
+// queues above their guaranteed capacity, in the ascending order of their priorities and then from
+// the most recently created ones.
+// Returns nil if preemption is disabled.
+func (cq *CapacityQueue) Reclaim() []*v1.Pod {
+	if !cq.preemption {
+		return nil
+	}
+
+	// Pods already being deleted will free their resources.
+	idle := cq.allocatable.DeepCopy()
+	candidates := []boundPod{}
+	for _, bp := range cq.bound {
+		if bp.pod.DeletionTimestamp == nil {
+			idle = util.ResourceListSub(idle, bp.request)
+			candidates = append(candidates, bp)
+		}
+	}
+	for _, popped := range cq.popped {
+		idle = util.ResourceListSub(idle, popped.request)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		pi, pj := util.PodPriority(candidates[i].pod), util.PodPriority(candidates[j].pod)
+		if pi != pj {
+			return pi < pj
+		}
+		return podTimestamp(candidates[j].pod).Before(podTimestamp(candidates[i].pod))
+	})
+
+	victims := []*v1.Pod{}
+	for _, node := range cq.nodes {
+		if node.pods == nil {
+			continue
+		}
+		demand := v1.ResourceList{}
+		node.pods.Range(func(pod *v1.Pod) bool {
+			request := util.ResourceListSum(demand, util.PodTotalResourceRequests(pod))
+			if !cq.fitsIn(node, request, node.capacity) {
+				return false
+			}
+			demand = request
+			return true
+		})
+		if len(demand) == 0 {
+			continue
+		}
+
+		remaining := candidates[:0]
+		for _, bp := range candidates {
+			if util.ResourceListGE(idle, demand) || bp.leaf == node || cq.used(bp.leaf) <= bp.leaf.capacity+capacityEpsilon {
+				remaining = append(remaining, bp)
+				continue
+			}
+			bp.leaf.charge(bp.request, false)
+			idle = util.ResourceListSum(idle, bp.request)
+			victims = append(victims, bp.pod)
+		}
+		candidates = remaining
+		// The idle capacity is reserved for the pending pods of this queue.
+		idle = util.ResourceListSub(idle, demand)
+	}
+
+	return victims
+}
+
+var _ = PodQueue(&CapacityQueue{})
+var _ = PendingPodLister(&CapacityQueue{})
+var _ = ClusterObserver(&CapacityQueue{})
+var _ = OrderedQueue(&CapacityQueue{})
+var _ = Reclaimer(&CapacityQueue{})
+
+// nextLeaf returns the leaf queue under the node whose front pod is to be popped next, or nil if
+// no pending pod can be popped without exceeding the maximum capacity of its queue.
+// Children are tried in the ascending order of the ratio of their usage to their guaranteed
+// capacity, and then in the order of their names.
+func (cq *CapacityQueue) nextLeaf(node *capacityNode) *capacityNode {
+	if node.pods != nil {
+		front, err := node.pods.Front()
+		if err != nil || !cq.fitsIn(node, util.PodTotalResourceRequests(front), node.maxCapacity) {
+			return nil
+		}
+		return node
+	}
+
+	children := make([]*capacityNode, len(node.children))
+	copy(children, node.children)
+	sort.SliceStable(children, func(i, j int) bool {
+		ri, rj := cq.usageRatio(children[i]), cq.usageRatio(children[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return children[i].name < children[j].name
+	})
+	for _, child := range children {
+		if leaf := cq.nextLeaf(child); leaf != nil {
+			return leaf
+		}
+	}
+
+	return nil
+}
+
+// fitsIn returns whether the request fits in the given share of the cluster in the node, and in
+// the maximum capacities of its ancestors (except for the root, which is the whole cluster).
+func (cq *CapacityQueue) fitsIn(node *capacityNode, request v1.ResourceList, share float64) bool {
+	if node.parent != nil && dominantShare(util.ResourceListSum(node.usage, request), cq.allocatable) > share+capacityEpsilon {
+		return false
+	}
+	for n := node.parent; n != nil && n.parent != nil; n = n.parent {
+		if dominantShare(util.ResourceListSum(n.usage, request), cq.allocatable) > n.maxCapacity+capacityEpsilon {
+			return false
+		}
+	}
+	return true
+}
+
+// usageRatio returns the ratio of the usage of the node to its guaranteed capacity.
+func (cq *CapacityQueue) usageRatio(node *capacityNode) float64 {
+	used := cq.used(node)
+	if node.capacity == 0 {
+		if used == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return used / node.capacity
+}
+
+func (cq *CapacityQueue) used(node *capacityNode) float64 {
+	return dominantShare(node.usage, cq.allocatable)
+}
+
+func (cq *CapacityQueue) pendingPodsNum(node *capacityNode) int {
+	if node.pods != nil {
+		return node.pods.Len()
+	}
+	num := 0
+	for _, child := range node.children {
+		num += cq.pendingPodsNum(child)
+	}
+	return num
+}
+
+// leaf returns the leaf queue named in the queue label of the pod, or the default queue.
+func (cq *CapacityQueue) leaf(pod *v1.Pod) *capacityNode {
+	if leaf, ok := cq.leaves[pod.Labels[cq.queueLabel]]; ok {
+		return leaf
+	}
+	return cq.leaves[cq.defaultQueue]
+}
+
+// charge adds the request to (or subtracts it from) the usage of the node and its ancestors.
+func (node *capacityNode) charge(request v1.ResourceList, add bool) {
+	for n := node; n != nil; n = n.parent {
+		if add {
+			n.usage = util.ResourceListSum(n.usage, request)
+		} else {
+			n.usage = util.ResourceListSub(n.usage, request)
+		}
+	}
+}
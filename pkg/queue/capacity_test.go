@@ -0,0 +1,135 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+const capacityLabel = "queue"
+
+// root (10 CPUs) -> eng (60%) -> ml (50%), web (50%)
+//
+//	-> ops (40%, up to 50%)
+var capacityTree = queue.QueueCapacity{
+	Name: "root",
+	Children: []queue.QueueCapacity{
+		{
+			Name:     "eng",
+			Capacity: 0.6,
+			Children: []queue.QueueCapacity{
+				{Name: "ml", Capacity: 0.5},
+				{Name: "web", Capacity: 0.5},
+			},
+		},
+		{Name: "ops", Capacity: 0.4, MaxCapacity: 0.5},
+	},
+}
+
+var tenCPUs = v1.ResourceList{v1.ResourceCPU: resource.MustParse("10"), v1.ResourceMemory: resource.MustParse("100Gi")}
+
+func newCapacityPod(leaf string, idx int, prio int32) *v1.Pod {
+	pod := newTenantPod("default", fmt.Sprintf("%s-%d", leaf, idx), "1", "1Gi")
+	pod.Labels = map[string]string{capacityLabel: leaf}
+	pod.Spec.Priority = &prio
+	pod.CreationTimestamp = metav1.NewTime(time.Unix(int64(idx), 0))
+	return pod
+}
+
+func TestCapacityQueueElasticSharing(t *testing.T) {
+	q, err := queue.NewCapacityQueue(capacityTree, capacityLabel, "web", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		q.Push(newCapacityPod("ops", i, 0))
+	}
+	q.Push(newCapacityPod("ml", 0, 0))
+	q.ObserveCluster(tenCPUs, nil)
+
+	// ops uses the capacity idle in eng beyond its guaranteed 40%, up to its maximum of 50%.
+	expected := []string{"ml-0", "ops-0", "ops-1", "ops-2", "ops-3", "ops-4"}
+	for _, exp := range expected {
+		pod, err := q.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pod.Name != exp {
+			t.Errorf("got: %q\nwant: %q", pod.Name, exp)
+		}
+	}
+	if _, err := q.Pop(); err != queue.ErrEmptyQueue {
+		t.Errorf("got: %v\nwant: %v", err, queue.ErrEmptyQueue)
+	}
+
+	met := q.Utilization()
+	assert.InDelta(t, 0.5, met["ops"].Used, 1e-9)
+	assert.Equal(t, 1, met["ops"].PendingPodsNum)
+	assert.InDelta(t, 0.3, met["ml"].Capacity, 1e-9)
+	assert.InDelta(t, 0.1, met["eng"].Used, 1e-9)
+}
+
+func TestCapacityQueueReclaim(t *testing.T) {
+	q, err := queue.NewCapacityQueue(capacityTree, capacityLabel, "web", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ops and web use 50% each, above their guaranteed 40% and 30%.
+	bound := []*v1.Pod{}
+	for i := 0; i < 5; i++ {
+		bound = append(bound, newCapacityPod("ops", i, 0), newCapacityPod("web", i, 1))
+	}
+	q.ObserveCluster(tenCPUs, bound)
+
+	pod := newCapacityPod("ml", 0, 0)
+	pod.Spec.Containers[0].Resources.Requests[v1.ResourceCPU] = resource.MustParse("2")
+	q.Push(pod)
+
+	// The lower-priority pods are preempted first, but ops keeps its guaranteed capacity.
+	victims := q.Reclaim()
+	names := []string{}
+	for _, victim := range victims {
+		names = append(names, victim.Name)
+	}
+	assert.Equal(t, []string{"ops-4", "web-4"}, names)
+}
+
+func TestNewCapacityQueueInvalid(t *testing.T) {
+	_, err := queue.NewCapacityQueue(capacityTree, capacityLabel, "eng", false)
+	assert.EqualError(t, err, "default queue \"eng\" is not a leaf queue")
+
+	_, err = queue.NewCapacityQueue(queue.QueueCapacity{
+		Name:     "root",
+		Children: []queue.QueueCapacity{{Name: "a", Capacity: 0.6}, {Name: "b", Capacity: 0.6}},
+	}, capacityLabel, "a", false)
+	assert.EqualError(t, err, "capacities of the children of queue \"root\" sum to 1.2, more than 1")
+
+	_, err = queue.NewCapacityQueue(queue.QueueCapacity{
+		Name:     "root",
+		Children: []queue.QueueCapacity{{Name: "a", Capacity: 0.6, MaxCapacity: 0.5}},
+	}, capacityLabel, "a", false)
+	assert.EqualError(t, err,
+		"capacity 0.6 and max capacity 0.5 of queue \"a\" must satisfy 0 <= capacity <= max capacity <= 1")
+}
@@ -0,0 +1,329 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// DRFQueue stores pods in per-tenant queues, and serves the tenant with the lowest dominant share
+// of the cluster allocatable first (Dominant Resource Fairness).
+// A tenant is identified by the value of the tenant label of a pod, or by its namespace if the
+// label is not configured or missing.
+// Pods of the same tenant are sorted by the comparator.
+type DRFQueue struct {
+	tenantLabel string
+	weights     map[string]float64
+	comparator  Compare
+
+	// tenants maps a tenant to the queue of its pending pods; tenantOf maps a pod key to its tenant.
+	tenants  map[string]*PriorityQueue
+	tenantOf map[string]string
+
+	// allocatable and allocated are updated by ObserveCluster.
+	// popped holds the requests of pods popped since the last observation, which are charged to
+	// their tenants until they are observed as bound or pushed back.
+	allocatable v1.ResourceList
+	allocated   map[string]v1.ResourceList
+	popped      map[string]v1.ResourceList
+}
+
+// NewDRFQueue creates a new DRFQueue grouping pods by the tenantLabel (by namespace if empty).
+// weights maps a tenant to its weight; a tenant with weight w is entitled to w times the share of a
+// tenant with weight 1. Tenants not in weights have weight 1.
+// Pods of the same tenant are sorted with DefaultComparator.
+func NewDRFQueue(tenantLabel string, weights map[string]float64) *DRFQueue {
+	return NewDRFQueueWithComparator(tenantLabel, weights, DefaultComparator)
+}
+
+// NewDRFQueueWithComparator creates a new DRFQueue sorting pods of the same tenant with the given
+// comparator.
+func NewDRFQueueWithComparator(tenantLabel string, weights map[string]float64, comparator Compare) *DRFQueue {
+	return &DRFQueue{
+		tenantLabel: tenantLabel,
+		weights:     weights,
+		comparator:  comparator,
+
+		tenants:  map[string]*PriorityQueue{},
+		tenantOf: map[string]string{},
+
+		allocatable: v1.ResourceList{},
+		allocated:   map[string]v1.ResourceList{},
+		popped:      map[string]v1.ResourceList{},
+	}
+}
+
+// ObserveCluster implements ClusterObserver interface.
+// It recomputes the resource allocated to each tenant from the requests of the bound pods.
+func (drf *DRFQueue) ObserveCluster(allocatable v1.ResourceList, boundPods []*v1.Pod) {
+	drf.allocatable = allocatable
+	drf.allocated = map[string]v1.ResourceList{}
+	drf.popped = map[string]v1.ResourceList{}
+
+	for _, pod := range boundPods {
+		drf.charge(drf.tenant(pod), util.PodTotalResourceRequests(pod))
+	}
+}
+
+// SetComparator implements OrderedQueue interface.
+// The comparator sorts the pods of the same tenant.
+func (drf *DRFQueue) SetComparator(comparator Compare) {
+	drf.comparator = comparator
+	for _, q := range drf.tenants {
+		q.SetComparator(comparator)
+	}
+}
+
+func (drf *DRFQueue) Push(pod *v1.Pod) error {
+	key, err := util.PodKey(pod)
+	if err != nil {
+		return err
+	}
+
+	tenant := drf.tenant(pod)
+	if _, ok := drf.tenants[tenant]; !ok {
+		drf.tenants[tenant] = NewPriorityQueueWithComparator(drf.comparator)
+	}
+	if err := drf.tenants[tenant].Push(pod); err != nil {
+		return err
+	}
+	drf.tenantOf[key] = tenant
+
+	// A pod popped and pushed back (e.g., failed to be scheduled) no longer counts.
+	if request, ok := drf.popped[key]; ok {
+		drf.allocated[tenant] = util.ResourceListSub(drf.allocated[tenant], request)
+		delete(drf.popped, key)
+	}
+
+	return nil
+}
+
+func (drf *DRFQueue) Pop() (*v1.Pod, error) {
+	tenant, ok := drf.nextTenant()
+	if !ok {
+		return nil, ErrEmptyQueue
+	}
+
+	pod, err := drf.tenants[tenant].Pop()
+	if err != nil {
+		return nil, err
+	}
+	key, _ := util.PodKey(pod) // stored pod never have invalid key
+	delete(drf.tenantOf, key)
+
+	// Charge the popped pod to the tenant so that pods popped at the same clock are also served
+	// fairly.
+	request := util.PodTotalResourceRequests(pod)
+	drf.charge(tenant, request)
+	drf.popped[key] = request
+
+	return pod, nil
+}
+
+func (drf *DRFQueue) Front() (*v1.Pod, error) {
+	tenant, ok := drf.nextTenant()
+	if !ok {
+		return nil, ErrEmptyQueue
+	}
+	return drf.tenants[tenant].Front()
+}
+
+func (drf *DRFQueue) Delete(podNamespace, podName string) bool {
+	key := util.PodKeyFromNames(podNamespace, podName)
+	tenant, ok := drf.tenantOf[key]
+	if !ok {
+		return false
+	}
+
+	delete(drf.tenantOf, key)
+	return drf.tenants[tenant].Delete(podNamespace, podName)
+}
+
+func (drf *DRFQueue) Update(podNamespace, podName string, newPod *v1.Pod) error {
+	keyOrig := util.PodKeyFromNames(podNamespace, podName)
+	keyNew, err := util.PodKey(newPod)
+	if err != nil {
+		return err
+	}
+	if keyOrig != keyNew {
+		return ErrDifferentNames
+	}
+
+	tenant, ok := drf.tenantOf[keyOrig]
+	if !ok {
+		return &ErrNoMatchingPod{key: keyOrig}
+	}
+
+	// The new pod may belong to another tenant if its label has changed.
+	if newTenant := drf.tenant(newPod); newTenant != tenant {
+		drf.Delete(podNamespace, podName)
+		return drf.Push(newPod)
+	}
+
+	return drf.tenants[tenant].Update(podNamespace, podName, newPod)
+}
+
+func (drf *DRFQueue) UpdateNominatedNode(pod *v1.Pod, nodeName string) error {
+	return drf.tenantQueue(pod).UpdateNominatedNode(pod, nodeName)
+}
+
+func (drf *DRFQueue) RemoveNominatedNode(pod *v1.Pod) error {
+	return drf.tenantQueue(pod).RemoveNominatedNode(pod)
+}
+
+func (drf *DRFQueue) NominatedPods(nodeName string) []*v1.Pod {
+	pods := []*v1.Pod{}
+	for _, tenant := range drf.sortedTenants() {
+		pods = append(pods, drf.tenants[tenant].NominatedPods(nodeName)...)
+	}
+
+	return pods
+}
+
+func (drf *DRFQueue) Metrics(qualityOfService, predictionPenalty, podQoses, numPods float32) Metrics {
+	return Metrics{
+		PendingPodsNum:    len(drf.tenantOf),
+		QualityOfService:  qualityOfService,
+		PredictionPenalty: predictionPenalty,
+		NumSatifisedPods:  podQoses,
+		NumPods:           numPods,
+		TenantShares:      drf.Shares(),
+	}
+}
+
+// PendingPods implements PendingPodLister interface.
+func (drf *DRFQueue) PendingPods() []*v1.Pod {
+	pods := make([]*v1.Pod, 0, len(drf.tenantOf))
+	for _, tenant := range drf.sortedTenants() {
+		pods = append(pods, drf.tenants[tenant].PendingPods()...)
+	}
+	return pods
+}
+
+// Shares returns the dominant share of each tenant that has pending or bound pods.
+// The shares are not weighted.
+func (drf *DRFQueue) Shares() map[string]float64 {
+	shares := map[string]float64{}
+	for tenant := range drf.tenants {
+		shares[tenant] = drf.dominantShare(tenant)
+	}
+	for tenant := range drf.allocated {
+		shares[tenant] = drf.dominantShare(tenant)
+	}
+
+	return shares
+}
+
+var _ = PodQueue(&DRFQueue{})
+var _ = PendingPodLister(&DRFQueue{})
+var _ = ClusterObserver(&DRFQueue{})
+var _ = OrderedQueue(&DRFQueue{})
+
+// nextTenant returns the tenant with pending pods whose weighted dominant share is the lowest.
+// Ties are broken by the comparator on the front pods of the tenants, then by the tenant names.
+// Returns false if no pod is pending.
+func (drf *DRFQueue) nextTenant() (string, bool) {
+	next := ""
+	var nextShare float64
+	var nextFront *v1.Pod
+
+	for _, tenant := range drf.sortedTenants() {
+		front, err := drf.tenants[tenant].Front()
+		if err != nil {
+			continue
+		}
+
+		share := drf.dominantShare(tenant) / drf.weight(tenant)
+		if nextFront == nil || share < nextShare ||
+			(share == nextShare && drf.comparator(front, nextFront)) {
+			next, nextShare, nextFront = tenant, share, front
+		}
+	}
+
+	return next, nextFront != nil
+}
+
+func (drf *DRFQueue) dominantShare(tenant string) float64 {
+	return dominantShare(drf.allocated[tenant], drf.allocatable)
+}
+
+// charge adds the request to the resource allocated to the tenant.
+func (drf *DRFQueue) charge(tenant string, request v1.ResourceList) {
+	if _, ok := drf.allocated[tenant]; !ok {
+		drf.allocated[tenant] = v1.ResourceList{}
+	}
+	drf.allocated[tenant] = util.ResourceListSum(drf.allocated[tenant], request)
+}
+
+func (drf *DRFQueue) weight(tenant string) float64 {
+	if w, ok := drf.weights[tenant]; ok && w > 0 {
+		return w
+	}
+	return 1
+}
+
+func (drf *DRFQueue) tenant(pod *v1.Pod) string {
+	return podTenant(pod, drf.tenantLabel)
+}
+
+func (drf *DRFQueue) tenantQueue(pod *v1.Pod) *PriorityQueue {
+	tenant := drf.tenant(pod)
+	if _, ok := drf.tenants[tenant]; !ok {
+		drf.tenants[tenant] = NewPriorityQueueWithComparator(drf.comparator)
+	}
+	return drf.tenants[tenant]
+}
+
+func (drf *DRFQueue) sortedTenants() []string {
+	tenants := make([]string, 0, len(drf.tenants))
+	for tenant := range drf.tenants {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	return tenants
+}
+
+// podTenant returns the value of the tenantLabel label of the pod, or its namespace if the label
+// is empty or missing.
+func podTenant(pod *v1.Pod, tenantLabel string) string {
+	if tenantLabel != "" {
+		if tenant, ok := pod.Labels[tenantLabel]; ok {
+			return tenant
+		}
+	}
+	return pod.Namespace
+}
+
+// dominantShare returns the maximum ratio of the allocated resource to the allocatable across all
+// resource types.
+func dominantShare(allocated, allocatable v1.ResourceList) float64 {
+	share := 0.0
+	for name, alloc := range allocated {
+		capacity, ok := allocatable[name]
+		if !ok || capacity.IsZero() {
+			continue
+		}
+		if s := float64(alloc.MilliValue()) / float64(capacity.MilliValue()); s > share {
+			share = s
+		}
+	}
+
+	return share
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue_test
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+func newTenantPod(namespace, name, cpu, memory string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{{
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{
+						v1.ResourceCPU:    resource.MustParse(cpu),
+						v1.ResourceMemory: resource.MustParse(memory),
+					},
+				},
+			}},
+		},
+	}
+}
+
+func TestDRFQueuePopLowestDominantShare(t *testing.T) {
+	q := queue.NewDRFQueue("", nil)
+
+	q.Push(newTenantPod("a", "a-0", "1", "1Gi"))
+	q.Push(newTenantPod("a", "a-1", "1", "1Gi"))
+	q.Push(newTenantPod("b", "b-0", "1", "1Gi"))
+
+	// Tenant a runs 4 of 10 CPUs (dominant share 0.4); tenant b runs 3 of 10 Gi memory (0.3).
+	q.ObserveCluster(
+		v1.ResourceList{v1.ResourceCPU: resource.MustParse("10"), v1.ResourceMemory: resource.MustParse("10Gi")},
+		[]*v1.Pod{newTenantPod("a", "a-bound", "4", "1Gi"), newTenantPod("b", "b-bound", "1", "3Gi")})
+
+	expected := []string{"b-0", "a-0", "a-1"}
+	for _, exp := range expected {
+		front, _ := q.Front()
+		pod, err := q.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pod.Name != exp || front.Name != exp {
+			t.Errorf("got: %q (front %q)\nwant: %q", pod.Name, front.Name, exp)
+		}
+	}
+
+	if _, err := q.Pop(); err != queue.ErrEmptyQueue {
+		t.Errorf("got: %v\nwant: %v", err, queue.ErrEmptyQueue)
+	}
+
+	shares := q.Shares()
+	if shares["a"] != 0.6 || shares["b"] != 0.4 {
+		t.Errorf("got: %v\nwant: map[a:0.6 b:0.4]", shares)
+	}
+}
+
+func TestDRFQueueWeightsAndTenantLabel(t *testing.T) {
+	q := queue.NewDRFQueue("tenant", map[string]float64{"heavy": 2})
+
+	light := newTenantPod("default", "light-0", "1", "1Gi")
+	light.Labels = map[string]string{"tenant": "light"}
+	heavy := newTenantPod("default", "heavy-0", "1", "1Gi")
+	heavy.Labels = map[string]string{"tenant": "heavy"}
+	q.Push(light)
+	q.Push(heavy)
+
+	boundLight := newTenantPod("default", "light-bound", "2", "1Gi")
+	boundLight.Labels = map[string]string{"tenant": "light"}
+	boundHeavy := newTenantPod("default", "heavy-bound", "3", "1Gi")
+	boundHeavy.Labels = map[string]string{"tenant": "heavy"}
+	q.ObserveCluster(v1.ResourceList{v1.ResourceCPU: resource.MustParse("10")}, []*v1.Pod{boundLight, boundHeavy})
+
+	// Weighted shares: light 0.2, heavy 0.3 / 2 = 0.15.
+	pod, _ := q.Pop()
+	if pod.Name != "heavy-0" {
+		t.Errorf("got: %q\nwant: %q", pod.Name, "heavy-0")
+	}
+
+	// Pushing the pod back uncharges it.
+	q.Push(pod)
+	if share := q.Shares()["heavy"]; share != 0.3 {
+		t.Errorf("got: %v\nwant: %v", share, 0.3)
+	}
+
+	if !q.Delete("default", "heavy-0") {
+		t.Errorf("got: false\nwant: true")
+	}
+	met := q.Metrics(0, 0, 0, 0)
+	if met.PendingPodsNum != 1 {
+		t.Errorf("got: %v\nwant: %v", met.PendingPodsNum, 1)
+	}
+}
@@ -119,7 +119,7 @@ func (fifo *FIFOQueue) NominatedPods(nodeName string) []*v1.Pod {
 
 func (fifo *FIFOQueue) Metrics(qualityOfService, predictionPenalty, podQoses, numPods float32) Metrics {
 	return Metrics{
-		PendingPodsNum:    len(fifo.queue),
+		PendingPodsNum:    fifo.Len(),
 		QualityOfService:  qualityOfService,
 		PredictionPenalty: predictionPenalty,
 		NumSatifisedPods:  podQoses,
@@ -127,8 +127,54 @@ func (fifo *FIFOQueue) Metrics(qualityOfService, predictionPenalty, podQoses, nu
 	}
 }
 
+// Len implements LookAheadQueue interface.
 func (fifo *FIFOQueue) Len() int {
-	return len(fifo.queue)
+	return len(fifo.pods)
+}
+
+// Range implements LookAheadQueue interface. The pods are ranged in the order of their submission.
+func (fifo *FIFOQueue) Range(f func(pod *v1.Pod) bool) {
+	// A key pushed again after being deleted appears twice in the slice; Pop returns the pod at
+	// the first one.
+	ranged := make(map[string]bool, len(fifo.pods))
+	for _, key := range fifo.queue {
+		pod, ok := fifo.pods[key]
+		if !ok || ranged[key] {
+			continue
+		}
+		ranged[key] = true
+		if !f(pod) {
+			return
+		}
+	}
+}
+
+// Filter implements LookAheadQueue interface.
+func (fifo *FIFOQueue) Filter(predicate PodPredicate) []*v1.Pod {
+	return filter(fifo, predicate)
+}
+
+// Remove implements LookAheadQueue interface.
+func (fifo *FIFOQueue) Remove(podNamespace, podName string) (*v1.Pod, error) {
+	key := util.PodKeyFromNames(podNamespace, podName)
+	pod, ok := fifo.pods[key]
+	if !ok {
+		return nil, &ErrNoMatchingPod{key: key}
+	}
+	delete(fifo.pods, key)
+
+	return pod, nil
+}
+
+// PendingPods implements PendingPodLister interface.
+func (fifo *FIFOQueue) PendingPods() []*v1.Pod {
+	pods := make([]*v1.Pod, 0, len(fifo.pods))
+	for _, pod := range fifo.pods {
+		pods = append(pods, pod)
+	}
+	return pods
 }
 
 var _ = PodQueue(&FIFOQueue{})
+var _ = PendingPodLister(&FIFOQueue{})
+var _ = LookAheadQueue(&FIFOQueue{})
@@ -163,3 +163,46 @@ func TestFIFOQueueUpdate(t *testing.T) {
 		t.Errorf("got: %+v\nwant: %+v", actual, expected)
 	}
 }
+
+func TestFIFOQueueLookAhead(t *testing.T) {
+	q := queue.NewFIFOQueue()
+
+	for _, name := range []string{"pod-0", "pod-1", "pod-2", "pod-3"} {
+		pod := newPod(name)
+		if name == "pod-1" || name == "pod-3" {
+			pod.Namespace = "batch"
+		}
+		q.Push(pod)
+	}
+	q.Delete("default", "pod-0")
+
+	names := func(pods []*v1.Pod) []string {
+		names := []string{}
+		for _, pod := range pods {
+			names = append(names, pod.Name)
+		}
+		return names
+	}
+
+	if q.Len() != 3 {
+		t.Errorf("got: %d\nwant: 3", q.Len())
+	}
+
+	ranged := []*v1.Pod{}
+	q.Range(func(pod *v1.Pod) bool {
+		ranged = append(ranged, pod)
+		return len(ranged) < 2
+	})
+	assert.Equal(t, []string{"pod-1", "pod-2"}, names(ranged))
+
+	assert.Equal(t, []string{"pod-1", "pod-3"}, names(q.Filter(queue.InNamespace("batch"))))
+
+	pod, err := q.Remove("batch", "pod-3")
+	if err != nil || pod.Name != "pod-3" {
+		t.Errorf("got: %v, %v\nwant: pod-3, nil", pod, err)
+	}
+	_, err = q.Remove("batch", "pod-3")
+	assert.EqualError(t, err, "No pod with key \"batch/pod-3\"")
+
+	assert.Equal(t, []string{"pod-1", "pod-2"}, names(q.Filter(func(*v1.Pod) bool { return true })))
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PodPredicate returns true if the pod is selected.
+type PodPredicate = func(pod *v1.Pod) bool
+
+// InNamespace returns a PodPredicate that selects the pods in the namespace.
+func InNamespace(namespace string) PodPredicate {
+	return func(pod *v1.Pod) bool {
+		return pod.Namespace == namespace
+	}
+}
+
+// MatchingLabels returns a PodPredicate that selects the pods whose labels match the selector.
+func MatchingLabels(selector labels.Selector) PodPredicate {
+	return func(pod *v1.Pod) bool {
+		return selector.Matches(labels.Set(pod.Labels))
+	}
+}
+
+// filter returns the pending pods of the queue that satisfy the predicate, in the order of Range.
+func filter(queue LookAheadQueue, predicate PodPredicate) []*v1.Pod {
+	pods := []*v1.Pod{}
+	queue.Range(func(pod *v1.Pod) bool {
+		if predicate(pod) {
+			pods = append(pods, pod)
+		}
+		return true
+	})
+
+	return pods
+}
@@ -0,0 +1,187 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// Keys of an Order.
+const (
+	// OrderByPriority sorts pods in the descending order of their effective priorities, i.e. their
+	// priorities raised by Order.AgingRate for each second they have waited.
+	OrderByPriority = "priority"
+	// OrderByPriorityClass sorts pods in the order of their priority classes in OrderKey.Classes.
+	// Pods of the other classes come last.
+	OrderByPriorityClass = "priorityClass"
+	// OrderByAge sorts pods in the order they were submitted (or last attempted).
+	OrderByAge = "age"
+	// OrderByShortestJob sorts pods in the ascending order of their durations in the
+	// OrderKey.Annotation annotation, in seconds or in a Go duration format (e.g., "1h30m").
+	// Pods without the annotation come last.
+	OrderByShortestJob = "shortestJob"
+	// OrderBySmallestRequest sorts pods in the ascending order of their requests of
+	// OrderKey.Resource.
+	OrderBySmallestRequest = "smallestRequest"
+	// OrderByLargestRequest sorts pods in the descending order of their requests of
+	// OrderKey.Resource.
+	OrderByLargestRequest = "largestRequest"
+	// OrderByFairShare sorts pods in the ascending order of the dominant shares of the cluster
+	// allocatable of their tenants (see DRFQueue).
+	OrderByFairShare = "fairShare"
+)
+
+// OrderKey is a key of an Order.
+type OrderKey struct {
+	// Name is one of OrderBy* constants.
+	Name string
+	// Resource is the resource compared by OrderBySmallestRequest and OrderByLargestRequest.
+	Resource v1.ResourceName
+	// Annotation is the key of the annotation compared by OrderByShortestJob.
+	Annotation string
+	// Classes are the priority classes in the order compared by OrderByPriorityClass.
+	Classes []string
+}
+
+// Order is a comparator that compares pods by a chain of keys; a key is compared only if the pods
+// are equal in the preceding keys.
+// Pods equal in all keys are sorted by age, then by namespace and name.
+type Order struct {
+	Keys []OrderKey
+	// AgingRate is the priority added to a pod for each second it has waited since its submission.
+	AgingRate float64
+	// TenantLabel is the key of the pod label whose value is the tenant compared by
+	// OrderByFairShare. The namespace is the tenant if empty or missing.
+	TenantLabel string
+
+	shares map[string]float64
+}
+
+// Compare returns true if pod0 precedes pod1 in this Order.
+// It can be passed to NewPriorityQueueWithComparator or OrderedQueue.SetComparator.
+func (o *Order) Compare(pod0, pod1 *v1.Pod) bool {
+	for _, key := range o.Keys {
+		if c := o.compareBy(key, pod0, pod1); c != 0 {
+			return c > 0
+		}
+	}
+
+	ts0 := podTimestamp(pod0)
+	ts1 := podTimestamp(pod1)
+	if !ts0.Before(ts1) && !ts1.Before(ts0) {
+		key0, _ := util.PodKey(pod0)
+		key1, _ := util.PodKey(pod1)
+		return key0 < key1
+	}
+	return ts0.Before(ts1)
+}
+
+// ObservesCluster returns true if this Order depends on the state of the cluster, i.e. it has
+// OrderByFairShare.
+func (o *Order) ObservesCluster() bool {
+	for _, key := range o.Keys {
+		if key.Name == OrderByFairShare {
+			return true
+		}
+	}
+	return false
+}
+
+// ObserveCluster implements ClusterObserver interface.
+// It recomputes the dominant share of each tenant from the requests of the bound pods.
+// Queues sorted by this Order must be sorted again (e.g., by OrderedQueue.SetComparator) after
+// this method is called.
+func (o *Order) ObserveCluster(allocatable v1.ResourceList, boundPods []*v1.Pod) {
+	allocated := map[string]v1.ResourceList{}
+	for _, pod := range boundPods {
+		tenant := podTenant(pod, o.TenantLabel)
+		if _, ok := allocated[tenant]; !ok {
+			allocated[tenant] = v1.ResourceList{}
+		}
+		allocated[tenant] = util.ResourceListSum(allocated[tenant], util.PodTotalResourceRequests(pod))
+	}
+
+	o.shares = make(map[string]float64, len(allocated))
+	for tenant, alloc := range allocated {
+		o.shares[tenant] = dominantShare(alloc, allocatable)
+	}
+}
+
+var _ = ClusterObserver(&Order{})
+
+// compareBy returns a positive value if pod0 precedes pod1 by the key, a negative value if pod1
+// precedes pod0, or 0 otherwise.
+func (o *Order) compareBy(key OrderKey, pod0, pod1 *v1.Pod) float64 {
+	switch key.Name {
+	case OrderByPriority:
+		// The difference of the effective priorities does not depend on the current clock.
+		waitDiff := pod1.CreationTimestamp.Sub(pod0.CreationTimestamp.Time)
+		return float64(util.PodPriority(pod0)-util.PodPriority(pod1)) + o.AgingRate*waitDiff.Seconds()
+	case OrderByPriorityClass:
+		return float64(classRank(pod1, key.Classes) - classRank(pod0, key.Classes))
+	case OrderByAge:
+		return podTimestamp(pod1).Sub(podTimestamp(pod0)).Seconds()
+	case OrderByShortestJob:
+		d0 := jobDuration(pod0, key.Annotation)
+		d1 := jobDuration(pod1, key.Annotation)
+		if d0 == d1 { // including both missing
+			return 0
+		}
+		return d1 - d0
+	case OrderBySmallestRequest, OrderByLargestRequest:
+		r0 := util.PodTotalResourceRequests(pod0)[key.Resource]
+		r1 := util.PodTotalResourceRequests(pod1)[key.Resource]
+		diff := float64(r1.MilliValue() - r0.MilliValue())
+		if key.Name == OrderByLargestRequest {
+			return -diff
+		}
+		return diff
+	case OrderByFairShare:
+		return o.shares[podTenant(pod1, o.TenantLabel)] - o.shares[podTenant(pod0, o.TenantLabel)]
+	default:
+		return 0
+	}
+}
+
+func classRank(pod *v1.Pod, classes []string) int {
+	for i, class := range classes {
+		if pod.Spec.PriorityClassName == class {
+			return i
+		}
+	}
+	return len(classes)
+}
+
+// jobDuration returns the duration of the pod in the annotation in seconds, or +Inf if missing
+// or invalid.
+func jobDuration(pod *v1.Pod, annotation string) float64 {
+	value, ok := pod.Annotations[annotation]
+	if !ok {
+		return math.Inf(1)
+	}
+	if sec, err := strconv.ParseFloat(value, 64); err == nil {
+		return sec
+	}
+	if dur, err := time.ParseDuration(value); err == nil {
+		return dur.Seconds()
+	}
+	return math.Inf(1)
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue_test
+
+import (
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+func TestOrderCompare(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	newOrderedPod := func(name string, prio int32, submittedSec int, duration string) *v1.Pod {
+		pod := newTenantPod("default", name, "1", "1Gi")
+		pod.Spec.Priority = &prio
+		pod.CreationTimestamp = metav1.NewTime(start.Add(time.Duration(submittedSec) * time.Second))
+		if duration != "" {
+			pod.Annotations = map[string]string{"duration": duration}
+		}
+		return pod
+	}
+
+	high := newOrderedPod("high", 10, 100, "")
+	old := newOrderedPod("old", 0, 0, "1h")
+	short := newOrderedPod("short", 0, 50, "30m")
+	short.Spec.Containers[0].Resources.Requests[v1.ResourceCPU] = resource.MustParse("4")
+
+	tests := []struct {
+		order    queue.Order
+		pod0     *v1.Pod
+		pod1     *v1.Pod
+		expected bool
+	}{
+		{queue.Order{Keys: []queue.OrderKey{{Name: queue.OrderByPriority}}}, high, old, true},
+		// old has waited 100 seconds longer than high, i.e. gained 20 priority by aging.
+		{queue.Order{Keys: []queue.OrderKey{{Name: queue.OrderByPriority}}, AgingRate: 0.2}, high, old, false},
+		// Ties in priority are broken by age.
+		{queue.Order{Keys: []queue.OrderKey{{Name: queue.OrderByPriority}}}, short, old, false},
+		{queue.Order{Keys: []queue.OrderKey{{Name: queue.OrderByShortestJob, Annotation: "duration"}}}, short, old, true},
+		// Pods without the annotation come last.
+		{queue.Order{Keys: []queue.OrderKey{{Name: queue.OrderByShortestJob, Annotation: "duration"}}}, high, old, false},
+		{queue.Order{Keys: []queue.OrderKey{{Name: queue.OrderBySmallestRequest, Resource: v1.ResourceCPU}}}, short, old, false},
+		{queue.Order{Keys: []queue.OrderKey{{Name: queue.OrderByLargestRequest, Resource: v1.ResourceCPU}}}, short, old, true},
+		// The first key decides if the pods are not equal in it.
+		{queue.Order{Keys: []queue.OrderKey{{Name: queue.OrderByAge}, {Name: queue.OrderByPriority}}}, high, old, false},
+	}
+
+	for i, test := range tests {
+		actual := test.order.Compare(test.pod0, test.pod1)
+		if actual != test.expected {
+			t.Errorf("test %d: got: %v\nwant: %v", i, actual, test.expected)
+		}
+	}
+}
+
+func TestOrderFairShare(t *testing.T) {
+	order := queue.Order{Keys: []queue.OrderKey{{Name: queue.OrderByFairShare}}}
+	q := queue.NewPriorityQueueWithComparator(order.Compare)
+
+	q.Push(newTenantPod("a", "a-0", "1", "1Gi"))
+	q.Push(newTenantPod("b", "b-0", "1", "1Gi"))
+
+	order.ObserveCluster(v1.ResourceList{v1.ResourceCPU: resource.MustParse("10")},
+		[]*v1.Pod{newTenantPod("a", "a-bound", "5", "1Gi"), newTenantPod("b", "b-bound", "1", "1Gi")})
+	q.SetComparator(order.Compare)
+	if pod, _ := q.Front(); pod.Name != "b-0" {
+		t.Errorf("got: %q\nwant: %q", pod.Name, "b-0")
+	}
+
+	order.ObserveCluster(v1.ResourceList{v1.ResourceCPU: resource.MustParse("10")},
+		[]*v1.Pod{newTenantPod("b", "b-bound", "5", "1Gi")})
+	q.SetComparator(order.Compare)
+	if pod, _ := q.Front(); pod.Name != "a-0" {
+		t.Errorf("got: %q\nwant: %q", pod.Name, "a-0")
+	}
+}
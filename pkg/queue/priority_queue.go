@@ -16,6 +16,7 @@ package queue
 
 import (
 	"container/heap"
+	"sort"
 
 	v1 "k8s.io/api/core/v1"
 
@@ -61,6 +62,12 @@ func (pq *PriorityQueue) Reorder(comparator Compare) *PriorityQueue {
 	return newWithItems(items, comparator)
 }
 
+// SetComparator implements OrderedQueue interface.
+func (pq *PriorityQueue) SetComparator(comparator Compare) {
+	pq.inner.comparator = comparator
+	heap.Init(&pq.inner)
+}
+
 func (pq *PriorityQueue) Push(pod *v1.Pod) error {
 	if _, err := util.PodKey(pod); err != nil {
 		return err
@@ -174,7 +181,48 @@ func (pq *PriorityQueue) Metrics(qualityOfService, predictionPenalty, podQoses,
 	}
 }
 
+// PendingPods implements PendingPodLister interface.
+func (pq *PriorityQueue) PendingPods() []*v1.Pod {
+	return pq.inner.pendingPods()
+}
+
+// Len implements LookAheadQueue interface.
+func (pq *PriorityQueue) Len() int {
+	return pq.inner.Len()
+}
+
+// Range implements LookAheadQueue interface. The pods are ranged in the order of the comparator,
+// and those equal to each other in the order of their keys.
+func (pq *PriorityQueue) Range(f func(pod *v1.Pod) bool) {
+	for _, pod := range pq.inner.sortedPods() {
+		if !f(pod) {
+			return
+		}
+	}
+}
+
+// Filter implements LookAheadQueue interface.
+func (pq *PriorityQueue) Filter(predicate PodPredicate) []*v1.Pod {
+	return filter(pq, predicate)
+}
+
+// Remove implements LookAheadQueue interface.
+// As with Pop, the node nomination of the pod is kept.
+func (pq *PriorityQueue) Remove(podNamespace, podName string) (*v1.Pod, error) {
+	key := util.PodKeyFromNames(podNamespace, podName)
+	item, ok := pq.inner.items[key]
+	if !ok {
+		return nil, &ErrNoMatchingPod{key: key}
+	}
+
+	heap.Remove(&pq.inner, item.index)
+	return item.pod, nil
+}
+
 var _ = PodQueue(&PriorityQueue{})
+var _ = PendingPodLister(&PriorityQueue{})
+var _ = OrderedQueue(&PriorityQueue{})
+var _ = LookAheadQueue(&PriorityQueue{})
 
 type item struct {
 	pod   *v1.Pod
@@ -243,6 +291,29 @@ func (pq *rawPriorityQueue) pendingPods() []*v1.Pod {
 	return pods
 }
 
+// sortedPods returns all pods in the order of the comparator, breaking ties by their keys.
+func (pq *rawPriorityQueue) sortedPods() []*v1.Pod {
+	keys := make([]string, len(pq.keys))
+	copy(keys, pq.keys)
+	sort.Slice(keys, func(i, j int) bool {
+		pod0 := pq.items[keys[i]].pod
+		pod1 := pq.items[keys[j]].pod
+		if pq.comparator(pod0, pod1) {
+			return true
+		}
+		if pq.comparator(pod1, pod0) {
+			return false
+		}
+		return keys[i] < keys[j]
+	})
+
+	pods := make([]*v1.Pod, 0, len(keys))
+	for _, key := range keys {
+		pods = append(pods, pq.items[key].pod)
+	}
+	return pods
+}
+
 // DefaultComparator returns true if pod0 has higher priority than pod1.
 // If the priorities are equal, it compares the timestamps and returns true if pod0 is older than
 // pod1.
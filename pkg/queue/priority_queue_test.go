@@ -22,6 +22,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
 )
@@ -76,7 +77,7 @@ func (pq *PriorityQueue) isSorted(num int) bool {
 
 func TestPriorityQueuePushAndPop(t *testing.T) {
 	now := metav1.Now()
-	q := NewPriorityQueue()
+	q := NewPriorityQueue(0)
 
 	q.Push(newPodWithPriority("pod-0", nil, now))
 
@@ -108,7 +109,7 @@ func TestPriorityQueuePushAndPop(t *testing.T) {
 
 func TestPriorityQueueIsSorted(t *testing.T) {
 	now := metav1.Now()
-	q := NewPriorityQueue()
+	q := NewPriorityQueue(0)
 
 	for prio := 9; prio >= 0; prio-- {
 		p := int32(prio)
@@ -142,7 +143,7 @@ func lowPriority(pod0, pod1 *v1.Pod) bool {
 
 func TestPriorityQueueFront(t *testing.T) {
 	now := metav1.Now()
-	q := NewPriorityQueue()
+	q := NewPriorityQueue(0)
 
 	q.Push(newPodWithPriority("pod-0", nil, now))
 
@@ -182,7 +183,7 @@ func TestPriorityQueueFront(t *testing.T) {
 
 func TestPriorityReorder(t *testing.T) {
 	now := metav1.Now()
-	q := NewPriorityQueue()
+	q := NewPriorityQueue(0)
 
 	q.Push(newPodWithPriority("pod-0", nil, now))
 
@@ -201,7 +202,7 @@ func TestPriorityReorder(t *testing.T) {
 
 func TestPriorityQueueDelete(t *testing.T) {
 	now := metav1.Now()
-	q := NewPriorityQueue()
+	q := NewPriorityQueue(0)
 
 	q.Push(newPodWithPriority("pod-0", nil, now))
 	q.Push(newPodWithPriority("pod-1", nil, now))
@@ -226,7 +227,7 @@ func TestPriorityQueueDelete(t *testing.T) {
 
 func TestPriorityQueueDeleteAndFront(t *testing.T) {
 	now := metav1.Now()
-	q := NewPriorityQueue()
+	q := NewPriorityQueue(0)
 
 	prio0 := int32(0)
 	q.Push(newPodWithPriority("pod-0", &prio0, now))
@@ -240,7 +241,7 @@ func TestPriorityQueueDeleteAndFront(t *testing.T) {
 
 func TestPriorityQueueUpdate(t *testing.T) {
 	now := metav1.Now()
-	q := NewPriorityQueue()
+	q := NewPriorityQueue(0)
 
 	prio0 := int32(0)
 	pod0 := newPodWithPriority("pod-0", &prio0, now)
@@ -272,7 +273,7 @@ func TestPriorityQueueUpdate(t *testing.T) {
 
 func TestPriorityQueueNomination(t *testing.T) {
 	now := metav1.Now()
-	q := NewPriorityQueue()
+	q := NewPriorityQueue(0)
 
 	pod0 := newPodWithPriority("pod-0", nil, now)
 
@@ -284,3 +285,51 @@ func TestPriorityQueueNomination(t *testing.T) {
 		t.Errorf("got: %v\nwant: [\"pod-0\"]", pods)
 	}
 }
+
+func TestPriorityQueueLookAhead(t *testing.T) {
+	now := metav1.Now()
+	q := NewPriorityQueue(0)
+
+	for i, prio := range []int32{1, 3, 2, 3} {
+		prio := prio
+		pod := newPodWithPriority(fmt.Sprintf("pod-%d", i), &prio, now)
+		pod.Labels = map[string]string{"high": fmt.Sprint(prio > 1)}
+		q.Push(pod)
+	}
+
+	names := func(pods []*v1.Pod) []string {
+		names := []string{}
+		for _, pod := range pods {
+			names = append(names, pod.Name)
+		}
+		return names
+	}
+
+	if q.Len() != 4 {
+		t.Errorf("got: %d\nwant: 4", q.Len())
+	}
+
+	ranged := []*v1.Pod{}
+	q.Range(func(pod *v1.Pod) bool {
+		ranged = append(ranged, pod)
+		return true
+	})
+	assert.Equal(t, []string{"pod-1", "pod-3", "pod-2", "pod-0"}, names(ranged))
+
+	selector := labels.SelectorFromSet(labels.Set{"high": "true"})
+	assert.Equal(t, []string{"pod-1", "pod-3", "pod-2"}, names(q.Filter(MatchingLabels(selector))))
+
+	pod, err := q.Remove("default", "pod-3")
+	if err != nil || pod.Name != "pod-3" {
+		t.Errorf("got: %v, %v\nwant: pod-3, nil", pod, err)
+	}
+	_, err = q.Remove("default", "pod-3")
+	assert.EqualError(t, err, "No pod with key \"default/pod-3\"")
+
+	for _, expected := range []string{"pod-1", "pod-2", "pod-0"} {
+		pod, _ := q.Pop()
+		if pod.Name != expected {
+			t.Errorf("got: %q\nwant: %q", pod.Name, expected)
+		}
+	}
+}
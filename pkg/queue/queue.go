@@ -19,6 +19,8 @@ import (
 	"fmt"
 
 	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
 )
 
 // Metrics represents a metrics of a PodQueue at one time point.
@@ -28,6 +30,29 @@ type Metrics struct {
 	PredictionPenalty float32
 	NumSatifisedPods  float32
 	NumPods           float32
+	// TenantShares is the dominant share of the cluster allocatable of each tenant, reported by
+	// queues that are aware of tenants (e.g., DRFQueue).
+	TenantShares map[string]float64 `json:",omitempty"`
+	// ActivePodsNum, BackoffPodsNum, and UnschedulablePodsNum are the numbers of pods in each
+	// internal queue of a SchedulingQueue.
+	ActivePodsNum        int `json:",omitempty"`
+	BackoffPodsNum       int `json:",omitempty"`
+	UnschedulablePodsNum int `json:",omitempty"`
+	// Starvation is the longest-waiting pending pod of each class, i.e. the priority class name of
+	// the pods, or their priority if they have no priority class.
+	Starvation map[string]StarvationMetrics `json:",omitempty"`
+	// Capacity is the capacity and the usage of each queue of a CapacityQueue.
+	Capacity map[string]CapacityMetrics `json:",omitempty"`
+}
+
+// StarvationMetrics is the metrics of the longest-waiting pending pod of a class.
+type StarvationMetrics struct {
+	// Pod is the key of the pod.
+	Pod string
+	// WaitSeconds is the time elapsed since the submission of the pod.
+	WaitSeconds float64
+	// PendingPodsNum is the number of the pending pods of the class.
+	PendingPodsNum int
 }
 
 var (
@@ -81,3 +106,62 @@ type PodQueue interface {
 	// Metrics returns a metrics of this PodQueue.
 	Metrics(QualityOfService, PredictionPenalty, podQoses, numPods float32) Metrics
 }
+
+// PendingPodLister is implemented by PodQueues that can list their pending pods without popping
+// them, e.g., to derive the request shapes for fragmentation metrics.
+type PendingPodLister interface {
+	// PendingPods returns all pods in this PodQueue, in no particular order.
+	PendingPods() []*v1.Pod
+}
+
+// LookAheadQueue is implemented by PodQueues that let schedulers examine the pending pods beyond
+// the front, e.g., to schedule them in batches or in a different order.
+type LookAheadQueue interface {
+	PodQueue
+
+	// Len returns the number of the pending pods.
+	Len() int
+
+	// Range calls f for each pending pod in the order in which this PodQueue serves them, until f
+	// returns false. f must not modify this PodQueue or the pods.
+	Range(f func(pod *v1.Pod) bool)
+
+	// Filter returns the pending pods that satisfy the predicate, in the order of Range.
+	Filter(predicate PodPredicate) []*v1.Pod
+
+	// Remove pops the pod with the given namespace and name wherever it is in this PodQueue.
+	// Returns ErrNoMatchingPod if the pod is not found.
+	Remove(podNamespace, podName string) (*v1.Pod, error)
+}
+
+// ClusterObserver is implemented by PodQueues whose order depends on the state of the cluster.
+// KubeSim calls ObserveCluster before every scheduling and metrics building.
+type ClusterObserver interface {
+	// ObserveCluster observes the total allocatable resource of the nodes and the pods bound to
+	// them that hold resources, i.e. running or terminating pods.
+	ObserveCluster(allocatable v1.ResourceList, boundPods []*v1.Pod)
+}
+
+// UnschedulableQueue is implemented by PodQueues that set pods failed to be scheduled aside and
+// retry them later, instead of keeping them at the front.
+// Schedulers pop such a pod and pass it to AddUnschedulable.
+type UnschedulableQueue interface {
+	PodQueue
+
+	// AddUnschedulable adds the pod, which has been popped and failed to be scheduled at the clock.
+	AddUnschedulable(clock clock.Clock, pod *v1.Pod) error
+
+	// Flush moves the pods set aside and ready to be retried at the clock back to the front.
+	// KubeSim calls this method before every scheduling.
+	Flush(clock clock.Clock)
+
+	// MoveAllToActive moves all pods set aside back to the front, as they may have become
+	// schedulable by a cluster event (e.g., pod deletion or node addition) at the clock.
+	MoveAllToActive(clock clock.Clock)
+}
+
+// OrderedQueue is implemented by PodQueues whose order can be configured, e.g., by an Order.
+type OrderedQueue interface {
+	// SetComparator sorts the pods again with the given comparator, which is used thereafter.
+	SetComparator(comparator Compare)
+}
@@ -0,0 +1,304 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue
+
+import (
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+const (
+	// DefaultInitialBackoff is the default backoff of a pod after its first failed attempt.
+	DefaultInitialBackoff = 1 * time.Second
+	// DefaultMaxBackoff is the default upper limit of the backoff of a pod.
+	DefaultMaxBackoff = 10 * time.Second
+	// DefaultUnschedulableTimeout is the default duration for which a pod stays in the
+	// unschedulable queue without cluster events.
+	DefaultUnschedulableTimeout = 60 * time.Second
+)
+
+// SchedulingQueue mirrors the scheduling queue of kube-scheduler.
+// It stores pods in three queues: activeQ, a priority queue of the pods to be scheduled, backoffQ,
+// the pods waiting for their backoff to complete, and unschedulableQ, the pods that failed to be
+// scheduled and are waiting for a cluster event.
+// Only pods in activeQ are visible from Front and Pop.
+// The backoff of a pod grows exponentially with the number of its failed attempts in simulated
+// time.
+type SchedulingQueue struct {
+	activeQ        *PriorityQueue
+	backoffQ       map[string]*queuedPod
+	unschedulableQ map[string]*queuedPod
+
+	// attempts maps the key of a pod in any of the queues to the number of its failed attempts.
+	// The attempts of the pod popped last are kept in popped, since AddUnschedulable is called
+	// after the scheduler pops the pod.
+	attempts map[string]int
+	popped   poppedPod
+
+	initialBackoff       time.Duration
+	maxBackoff           time.Duration
+	unschedulableTimeout time.Duration
+}
+
+type queuedPod struct {
+	pod *v1.Pod
+	// backoffUntil is the clock when the backoff of the pod completes.
+	backoffUntil clock.Clock
+	// unschedulableAt is the clock when the pod was added to unschedulableQ.
+	unschedulableAt clock.Clock
+}
+
+type poppedPod struct {
+	key      string
+	attempts int
+}
+
+// NewSchedulingQueue creates a new SchedulingQueue with DefaultComparator.
+// The backoff of a pod starts from initialBackoff and doubles for each failed attempt up to
+// maxBackoff. Pods in unschedulableQ are moved back after unschedulableTimeout even without
+// cluster events.
+func NewSchedulingQueue(initialBackoff, maxBackoff, unschedulableTimeout time.Duration) *SchedulingQueue {
+	return NewSchedulingQueueWithComparator(initialBackoff, maxBackoff, unschedulableTimeout, DefaultComparator)
+}
+
+// NewSchedulingQueueWithComparator creates a new SchedulingQueue whose activeQ is sorted with the
+// given comparator.
+func NewSchedulingQueueWithComparator(
+	initialBackoff, maxBackoff, unschedulableTimeout time.Duration, comparator Compare) *SchedulingQueue {
+
+	return &SchedulingQueue{
+		activeQ:        NewPriorityQueueWithComparator(comparator),
+		backoffQ:       map[string]*queuedPod{},
+		unschedulableQ: map[string]*queuedPod{},
+
+		attempts: map[string]int{},
+
+		initialBackoff:       initialBackoff,
+		maxBackoff:           maxBackoff,
+		unschedulableTimeout: unschedulableTimeout,
+	}
+}
+
+// SetComparator implements OrderedQueue interface.
+// The comparator sorts the pods in activeQ.
+func (sq *SchedulingQueue) SetComparator(comparator Compare) {
+	sq.activeQ.SetComparator(comparator)
+}
+
+// Push pushes the pod to activeQ, removing it from the other queues if exists.
+func (sq *SchedulingQueue) Push(pod *v1.Pod) error {
+	key, err := util.PodKey(pod)
+	if err != nil {
+		return err
+	}
+
+	delete(sq.backoffQ, key)
+	delete(sq.unschedulableQ, key)
+	if sq.activeQ.Update(pod.Namespace, pod.Name, pod) == nil {
+		return nil
+	}
+	if err := sq.activeQ.Push(pod); err != nil {
+		return err
+	}
+	if sq.popped.key == key {
+		sq.attempts[key] = sq.popped.attempts
+		sq.popped = poppedPod{}
+	}
+
+	return nil
+}
+
+func (sq *SchedulingQueue) Pop() (*v1.Pod, error) {
+	pod, err := sq.activeQ.Pop()
+	if err != nil {
+		return nil, err
+	}
+
+	key, _ := util.PodKey(pod) // stored pod never have invalid key
+	sq.popped = poppedPod{key: key, attempts: sq.attempts[key]}
+	delete(sq.attempts, key)
+
+	return pod, nil
+}
+
+func (sq *SchedulingQueue) Front() (*v1.Pod, error) {
+	return sq.activeQ.Front()
+}
+
+func (sq *SchedulingQueue) Delete(podNamespace, podName string) bool {
+	key := util.PodKeyFromNames(podNamespace, podName)
+	delete(sq.attempts, key)
+
+	if _, ok := sq.backoffQ[key]; ok {
+		delete(sq.backoffQ, key)
+		return true
+	}
+	if _, ok := sq.unschedulableQ[key]; ok {
+		delete(sq.unschedulableQ, key)
+		return true
+	}
+	return sq.activeQ.Delete(podNamespace, podName)
+}
+
+// Update updates the pod in any of the queues.
+// Since the update may make the pod schedulable, a pod in unschedulableQ is moved to backoffQ, from
+// which it is moved to activeQ once its backoff completes.
+func (sq *SchedulingQueue) Update(podNamespace, podName string, newPod *v1.Pod) error {
+	keyOrig := util.PodKeyFromNames(podNamespace, podName)
+	keyNew, err := util.PodKey(newPod)
+	if err != nil {
+		return err
+	}
+	if keyOrig != keyNew {
+		return ErrDifferentNames
+	}
+
+	if qp, ok := sq.backoffQ[keyOrig]; ok {
+		qp.pod = newPod
+		return nil
+	}
+	if qp, ok := sq.unschedulableQ[keyOrig]; ok {
+		qp.pod = newPod
+		delete(sq.unschedulableQ, keyOrig)
+		sq.backoffQ[keyOrig] = qp
+		return nil
+	}
+	return sq.activeQ.Update(podNamespace, podName, newPod)
+}
+
+func (sq *SchedulingQueue) UpdateNominatedNode(pod *v1.Pod, nodeName string) error {
+	return sq.activeQ.UpdateNominatedNode(pod, nodeName)
+}
+
+func (sq *SchedulingQueue) RemoveNominatedNode(pod *v1.Pod) error {
+	return sq.activeQ.RemoveNominatedNode(pod)
+}
+
+func (sq *SchedulingQueue) NominatedPods(nodeName string) []*v1.Pod {
+	return sq.activeQ.NominatedPods(nodeName)
+}
+
+func (sq *SchedulingQueue) Metrics(qualityOfService, predictionPenalty, podQoses, numPods float32) Metrics {
+	active := sq.activeQ.inner.Len()
+	return Metrics{
+		PendingPodsNum:       active + len(sq.backoffQ) + len(sq.unschedulableQ),
+		QualityOfService:     qualityOfService,
+		PredictionPenalty:    predictionPenalty,
+		NumSatifisedPods:     podQoses,
+		NumPods:              numPods,
+		ActivePodsNum:        active,
+		BackoffPodsNum:       len(sq.backoffQ),
+		UnschedulablePodsNum: len(sq.unschedulableQ),
+	}
+}
+
+// PendingPods implements PendingPodLister interface.
+func (sq *SchedulingQueue) PendingPods() []*v1.Pod {
+	pods := sq.activeQ.PendingPods()
+	for _, qp := range sq.backoffQ {
+		pods = append(pods, qp.pod)
+	}
+	for _, qp := range sq.unschedulableQ {
+		pods = append(pods, qp.pod)
+	}
+	return pods
+}
+
+// AddUnschedulable implements UnschedulableQueue interface.
+// The pod is added to unschedulableQ, and its backoff is extended.
+func (sq *SchedulingQueue) AddUnschedulable(clock clock.Clock, pod *v1.Pod) error {
+	key, err := util.PodKey(pod)
+	if err != nil {
+		return err
+	}
+
+	attempts := 1
+	if sq.popped.key == key {
+		attempts += sq.popped.attempts
+		sq.popped = poppedPod{}
+	}
+	sq.activeQ.Delete(pod.Namespace, pod.Name)
+	delete(sq.backoffQ, key)
+
+	sq.attempts[key] = attempts
+	sq.unschedulableQ[key] = &queuedPod{
+		pod:             pod,
+		backoffUntil:    clock.Add(sq.backoff(attempts)),
+		unschedulableAt: clock,
+	}
+
+	return nil
+}
+
+// Flush implements UnschedulableQueue interface.
+// Pods in backoffQ whose backoff has completed are moved to activeQ, and pods staying in
+// unschedulableQ longer than the timeout are moved to backoffQ or activeQ.
+func (sq *SchedulingQueue) Flush(clock clock.Clock) {
+	for _, key := range sortedKeys(sq.unschedulableQ) {
+		if qp := sq.unschedulableQ[key]; !clock.Before(qp.unschedulableAt.Add(sq.unschedulableTimeout)) {
+			delete(sq.unschedulableQ, key)
+			sq.backoffQ[key] = qp
+		}
+	}
+
+	for _, key := range sortedKeys(sq.backoffQ) {
+		if qp := sq.backoffQ[key]; !clock.Before(qp.backoffUntil) {
+			delete(sq.backoffQ, key)
+			_ = sq.activeQ.Push(qp.pod) // stored pod never have invalid key
+		}
+	}
+}
+
+// MoveAllToActive implements UnschedulableQueue interface.
+// All pods in unschedulableQ are moved to activeQ, or to backoffQ if they are still backing off.
+func (sq *SchedulingQueue) MoveAllToActive(clock clock.Clock) {
+	for _, key := range sortedKeys(sq.unschedulableQ) {
+		sq.backoffQ[key] = sq.unschedulableQ[key]
+		delete(sq.unschedulableQ, key)
+	}
+	sq.Flush(clock)
+}
+
+var _ = PodQueue(&SchedulingQueue{})
+var _ = PendingPodLister(&SchedulingQueue{})
+var _ = UnschedulableQueue(&SchedulingQueue{})
+var _ = OrderedQueue(&SchedulingQueue{})
+
+// backoff returns the backoff of a pod after the given number of failed attempts.
+func (sq *SchedulingQueue) backoff(attempts int) time.Duration {
+	backoff := sq.initialBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff > sq.maxBackoff {
+			return sq.maxBackoff
+		}
+	}
+	return backoff
+}
+
+func sortedKeys(pods map[string]*queuedPod) []string {
+	keys := make([]string, 0, len(pods))
+	for key := range pods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
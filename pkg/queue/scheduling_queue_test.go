@@ -0,0 +1,94 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queue_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+)
+
+func TestSchedulingQueueBackoff(t *testing.T) {
+	q := queue.NewSchedulingQueue(10*time.Second, 40*time.Second, 5*time.Minute)
+	clk := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	q.Push(newPod("pod-0"))
+	q.Push(newPod("pod-1"))
+
+	// pod-0 fails and is set aside; pod-1 is served next.
+	pod, _ := q.Pop()
+	if err := q.AddUnschedulable(clk, pod); err != nil {
+		t.Fatal(err)
+	}
+	front, _ := q.Front()
+	if front.Name != "pod-1" {
+		t.Errorf("got: %q\nwant: %q", front.Name, "pod-1")
+	}
+	q.Pop()
+
+	met := q.Metrics(0, 0, 0, 0)
+	if met.PendingPodsNum != 1 || met.UnschedulablePodsNum != 1 {
+		t.Errorf("got: %+v\nwant: 1 pending pod, 1 unschedulable pod", met)
+	}
+
+	// A cluster event before the backoff completes moves the pod only to backoffQ.
+	q.MoveAllToActive(clk.Add(5 * time.Second))
+	if _, err := q.Front(); err != queue.ErrEmptyQueue {
+		t.Errorf("got: %v\nwant: %v", err, queue.ErrEmptyQueue)
+	}
+	if met := q.Metrics(0, 0, 0, 0); met.BackoffPodsNum != 1 {
+		t.Errorf("got: %v\nwant: %v", met.BackoffPodsNum, 1)
+	}
+
+	q.Flush(clk.Add(10 * time.Second))
+	pod, err := q.Pop()
+	if err != nil || pod.Name != "pod-0" {
+		t.Errorf("got: %v, %v\nwant: pod-0", pod, err)
+	}
+
+	// The second failure doubles the backoff.
+	clk = clk.Add(10 * time.Second)
+	q.AddUnschedulable(clk, pod)
+	q.MoveAllToActive(clk.Add(15 * time.Second))
+	if _, err := q.Front(); err != queue.ErrEmptyQueue {
+		t.Errorf("got: %v\nwant: %v", err, queue.ErrEmptyQueue)
+	}
+	q.Flush(clk.Add(20 * time.Second))
+	pod, err = q.Pop()
+	if err != nil || pod.Name != "pod-0" {
+		t.Errorf("got: %v, %v\nwant: pod-0", pod, err)
+	}
+
+	// Without cluster events, the pod stays in unschedulableQ until the timeout.
+	clk = clk.Add(20 * time.Second)
+	q.AddUnschedulable(clk, pod)
+	q.Flush(clk.Add(time.Minute))
+	if _, err := q.Front(); err != queue.ErrEmptyQueue {
+		t.Errorf("got: %v\nwant: %v", err, queue.ErrEmptyQueue)
+	}
+	q.Flush(clk.Add(5 * time.Minute))
+	if front, _ := q.Front(); front == nil || front.Name != "pod-0" {
+		t.Errorf("got: %v\nwant: pod-0", front)
+	}
+
+	if !q.Delete("default", "pod-0") {
+		t.Errorf("got: false\nwant: true")
+	}
+	if met := q.Metrics(0, 0, 0, 0); met.PendingPodsNum != 0 {
+		t.Errorf("got: %v\nwant: %v", met.PendingPodsNum, 0)
+	}
+}
@@ -42,6 +42,8 @@ type GenericScheduler struct {
 	extenders    []Extender
 	predicates   map[string]predicates.FitPredicate
 	prioritizers []priorities.PriorityConfig
+	// metadataProducer produces the metadata passed to the plugins, or nil.
+	metadataProducer predicates.PredicateMetadataProducer
 
 	lastNodeIndex     uint64
 	preemptionEnabled bool
@@ -67,6 +69,22 @@ func (sched *GenericScheduler) AddPredicate(name string, predicate predicates.Fi
 	sched.predicates[name] = predicate
 }
 
+// SetMetadataProducer sets the producer of the metadata passed to the predicate and prioritizer
+// plugins, computed once for each pod from the whole cluster (e.g., TopologySpreadMetadata).
+func (sched *GenericScheduler) SetMetadataProducer(producer predicates.PredicateMetadataProducer) {
+	sched.metadataProducer = producer
+}
+
+// predicateMetadata produces the metadata of the pod passed to the plugins.
+func (sched *GenericScheduler) predicateMetadata(
+	pod *v1.Pod, nodeInfoMap map[string]*nodeinfo.NodeInfo) predicates.PredicateMetadata {
+
+	if sched.metadataProducer == nil {
+		return &dummyPredicateMetadata{}
+	}
+	return sched.metadataProducer(pod, nodeInfoMap)
+}
+
 // AddPrioritizer adds a prioritizer plugin to this GenericScheduler.
 func (sched *GenericScheduler) AddPrioritizer(prioritizer priorities.PriorityConfig) {
 	sched.prioritizers = append(sched.prioritizers, prioritizer)
@@ -111,8 +129,11 @@ func (sched *GenericScheduler) Schedule(
 		}
 
 		if err != nil {
+			results = append(results, &FailedSchedulingEvent{Pod: pod, Reason: err})
+
 			// queue failed pods to fail queue, and resubmit back the the queue later.
-			if KeepScheduling {
+			_, setAside := pendingPods.(queue.UnschedulableQueue)
+			if KeepScheduling && !setAside {
 				err = sched.failQueue.Push(pod)
 				if err != nil {
 					log.L.Errorf("Cannot push pod to failQueue: %v", err)
@@ -142,6 +163,15 @@ func (sched *GenericScheduler) Schedule(
 						results = append(results, delEvents...)
 					}
 
+					// If the queue can set the pod aside, try the next pod.
+					if uq, ok := pendingPods.(queue.UnschedulableQueue); ok {
+						pendingPods.Pop()
+						if err := uq.AddUnschedulable(clock, pod); err != nil {
+							return []Event{}, err
+						}
+						continue
+					}
+
 					// Else, stop the scheduling process at this clock.
 					break
 				} else {
@@ -153,8 +183,7 @@ func (sched *GenericScheduler) Schedule(
 			log.L.Debugf("Selected node %s", result.SuggestedHost)
 			if _, ok := NodeMetricsCache[result.SuggestedHost]; ok {
 				request := kutil.GetResourceRequest(pod)
-				NodeMetricsCache[result.SuggestedHost].Usage.MilliCPU += request.MilliCPU
-				NodeMetricsCache[result.SuggestedHost].Usage.Memory += request.Memory
+				NodeMetricsCache[result.SuggestedHost].Usage.Add(request.ResourceList())
 			} else {
 				NodeMetricsCache[result.SuggestedHost] = &NodeMetrics{
 					Usage:       *kutil.GetResourceRequest(pod),
@@ -215,10 +244,12 @@ func (sched *GenericScheduler) scheduleOne(
 		return result, core.ErrNoNodesAvailable
 	}
 
+	meta := sched.predicateMetadata(pod, nodeInfoMap)
+
 	// Filter out nodes that cannot accommodate the pod.
 	start := time.Now()
 	// set
-	nodesFiltered, failedPredicateMap, err := sched.filter(pod, nodes, nodeInfoMap, podQueue)
+	nodesFiltered, failedPredicateMap, err := sched.filter(pod, meta, nodes, nodeInfoMap, podQueue)
 
 	if err != nil {
 		return result, err
@@ -247,7 +278,7 @@ func (sched *GenericScheduler) scheduleOne(
 
 	// Prioritize nodes that have passed the filtering phase.
 	start = time.Now()
-	prios, err := sched.prioritize(pod, nodesFiltered, nodeInfoMap, podQueue)
+	prios, err := sched.prioritize(pod, meta, nodesFiltered, nodeInfoMap, podQueue)
 	lapse = time.Since(start)
 	if _, ok := TimingMap["sched.prioritize"]; !ok {
 		TimingMap["sched.prioritize"] = lapse.Microseconds()
@@ -271,6 +302,7 @@ func (sched *GenericScheduler) scheduleOne(
 
 func (sched *GenericScheduler) filter(
 	pod *v1.Pod,
+	meta predicates.PredicateMetadata,
 	nodes []*v1.Node,
 	nodeInfoMap map[string]*nodeinfo.NodeInfo,
 	podQueue queue.PodQueue,
@@ -285,7 +317,7 @@ func (sched *GenericScheduler) filter(
 	}
 
 	// In-process plugins
-	filtered, failedPredicateMap, err := filterWithPlugins(pod, sched.predicates, nodes, nodeInfoMap, podQueue)
+	filtered, failedPredicateMap, err := filterWithPlugins(pod, meta, sched.predicates, nodes, nodeInfoMap, podQueue)
 	if err != nil {
 		return []*v1.Node{}, core.FailedPredicateMap{}, err
 	}
@@ -337,6 +369,7 @@ func (sched *GenericScheduler) filter(
 
 func (sched *GenericScheduler) prioritize(
 	pod *v1.Pod,
+	meta interface{},
 	filteredNodes []*v1.Node,
 	nodeInfoMap map[string]*nodeinfo.NodeInfo,
 	podQueue queue.PodQueue) (api.HostPriorityList, error) {
@@ -371,7 +404,7 @@ func (sched *GenericScheduler) prioritize(
 	}
 
 	// In-process plugins
-	prioList, err := prioritizeWithPlugins(pod, sched.prioritizers, filteredNodes, nodeInfoMap, podQueue)
+	prioList, err := prioritizeWithPlugins(pod, meta, sched.prioritizers, filteredNodes, nodeInfoMap, podQueue)
 	if err != nil {
 		return api.HostPriorityList{}, err
 	}
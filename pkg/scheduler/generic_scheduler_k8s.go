@@ -85,6 +85,12 @@ func findMaxScores(priorities api.HostPriorityList) []int {
 }
 
 func podEligibleToPreemptOthers(preemptor *v1.Pod, nodeInfoMap map[string]*nodeinfo.NodeInfo) bool {
+	if util.PodPreemptionPolicy(preemptor) == util.PreemptNever {
+		log.L.Debugf("Pod %s/%s is not eligible for preemption because it has a preemptionPolicy of %s",
+			preemptor.Namespace, preemptor.Name, util.PreemptNever)
+		return false
+	}
+
 	nomNodeName := preemptor.Status.NominatedNodeName
 	if len(nomNodeName) > 0 {
 		if nodeInfo, ok := nodeInfoMap[nomNodeName]; ok {
@@ -151,9 +157,11 @@ func (sched *GenericScheduler) selectNodesForPreemption(
 	// pdbs []*policy.PodDisruptionBudget,
 ) (map[*v1.Node]*api.Victims, error) {
 	nodeToVictims := map[*v1.Node]*api.Victims{}
+	meta := sched.predicateMetadata(preemptor, nodeInfoMap)
 
 	for _, node := range potentialNodes {
-		pods, numPDBViolations, fits := sched.selectVictimsOnNode(preemptor, nodeInfoMap[node.Name], podQueue /* , pdbs */)
+		pods, numPDBViolations, fits := sched.selectVictimsOnNode(
+			preemptor, meta.ShallowCopy(), nodeInfoMap[node.Name], podQueue /* , pdbs */)
 		if fits {
 			nodeToVictims[node] = &api.Victims{
 				Pods:             pods,
@@ -167,6 +175,7 @@ func (sched *GenericScheduler) selectNodesForPreemption(
 
 func (sched *GenericScheduler) selectVictimsOnNode(
 	preemptor *v1.Pod,
+	meta predicates.PredicateMetadata,
 	nodeInfo *nodeinfo.NodeInfo,
 	podQueue queue.PodQueue,
 	// pdbs []*policy.PodDisruptionBudget,
@@ -180,10 +189,16 @@ func (sched *GenericScheduler) selectVictimsOnNode(
 
 	removePod := func(p *v1.Pod) {
 		nodeInfoCopy.RemovePod(p)
+		if err := meta.RemovePod(p); err != nil {
+			log.L.Warnf("Encountered error while removing pod %s/%s from metadata: %v", p.Namespace, p.Name, err)
+		}
 	}
 
 	addPod := func(p *v1.Pod) {
 		nodeInfoCopy.AddPod(p)
+		if err := meta.AddPod(p, nodeInfoCopy); err != nil {
+			log.L.Warnf("Encountered error while adding pod %s/%s to metadata: %v", p.Namespace, p.Name, err)
+		}
 	}
 
 	podPriority := util.PodPriority(preemptor)
@@ -195,7 +210,7 @@ func (sched *GenericScheduler) selectVictimsOnNode(
 	}
 	potentialVictims.Sort()
 
-	if fits, _, err := podFitsOnNode(preemptor, sched.predicates, nodeInfoCopy, podQueue); !fits {
+	if fits, _, err := podFitsOnNode(preemptor, meta, sched.predicates, nodeInfoCopy, podQueue); !fits {
 		if err != nil {
 			log.L.Warnf("Encountered error while selecting victims on node %s: %v", nodeInfoCopy.Node().Name, err)
 		}
@@ -216,7 +231,7 @@ func (sched *GenericScheduler) selectVictimsOnNode(
 
 	reprievePod := func(p *v1.Pod) bool {
 		addPod(p)
-		fits, _, _ := podFitsOnNode(preemptor, sched.predicates, nodeInfoCopy, podQueue)
+		fits, _, _ := podFitsOnNode(preemptor, meta, sched.predicates, nodeInfoCopy, podQueue)
 		if !fits {
 			removePod(p)
 			victims = append(victims, p)
@@ -250,6 +265,7 @@ func (sched *GenericScheduler) selectVictimsOnNode(
 
 func podFitsOnNode(
 	pod *v1.Pod,
+	meta predicates.PredicateMetadata,
 	preds map[string]predicates.FitPredicate,
 	nodeInfo *nodeinfo.NodeInfo,
 	podQueue queue.PodQueue,
@@ -266,7 +282,7 @@ func podFitsOnNode(
 		}
 
 		for _, pred := range preds {
-			fit, reasons, err := pred(pod, &dummyPredicateMetadata{}, nodeInfoToUse)
+			fit, reasons, err := pred(pod, meta, nodeInfoToUse)
 
 			if err != nil {
 				return false, []predicates.PredicateFailureReason{}, err
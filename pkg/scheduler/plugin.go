@@ -44,6 +44,7 @@ const workerNum = 32
 
 func filterWithPlugins(
 	pod *v1.Pod,
+	meta predicates.PredicateMetadata,
 	preds map[string]predicates.FitPredicate,
 	nodes []*v1.Node,
 	nodeInfoMap map[string]*nodeinfo.NodeInfo,
@@ -79,6 +80,7 @@ func filterWithPlugins(
 
 		fits, failedPredicates, err := podFitsOnNode(
 			pod,
+			meta,
 			preds,
 			nodeInfo,
 			podQueue,
@@ -129,6 +131,7 @@ func filterWithPlugins(
 
 func prioritizeWithPlugins(
 	pod *v1.Pod,
+	meta interface{},
 	prioritizers []priorities.PriorityConfig,
 	nodes []*v1.Node,
 	nodeInfoMap map[string]*nodeinfo.NodeInfo,
@@ -164,7 +167,7 @@ func prioritizeWithPlugins(
 			}
 
 			var err error
-			resultList[prioIdx][nodeIdx], err = prioritizers[prioIdx].Map(pod, &dummyPriorityMetadata{}, nodeInfo)
+			resultList[prioIdx][nodeIdx], err = prioritizers[prioIdx].Map(pod, meta, nodeInfo)
 			if err != nil {
 				appendError(err)
 				resultList[prioIdx][nodeIdx].Host = nodeName
@@ -182,7 +185,7 @@ func prioritizeWithPlugins(
 		wg.Add(1)
 		go func(prioIdx int) {
 			defer wg.Done()
-			if err := prioritizers[prioIdx].Reduce(pod, &dummyPriorityMetadata{}, nodeInfoMap, resultList[prioIdx]); err != nil {
+			if err := prioritizers[prioIdx].Reduce(pod, meta, nodeInfoMap, resultList[prioIdx]); err != nil {
 				appendError(err)
 			}
 		}(prioIdx)
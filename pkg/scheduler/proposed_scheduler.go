@@ -31,8 +31,6 @@ import (
 
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
 	l "github.com/pfnet-research/k8s-cluster-simulator/pkg/log"
-	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
-	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
 )
@@ -42,6 +40,8 @@ type ProposedScheduler struct {
 	extenders    []Extender
 	predicates   map[string]predicates.FitPredicate
 	prioritizers []priorities.PriorityConfig
+	// metadataProducer produces the metadata passed to the plugins, or nil.
+	metadataProducer predicates.PredicateMetadataProducer
 
 	lastNodeIndex     uint64
 	preemptionEnabled bool
@@ -67,6 +67,22 @@ func (sched *ProposedScheduler) AddPredicate(name string, predicate predicates.F
 	sched.predicates[name] = predicate
 }
 
+// SetMetadataProducer sets the producer of the metadata passed to the predicate plugins, computed
+// once for each pod from the whole cluster (e.g., TopologySpreadMetadata).
+func (sched *ProposedScheduler) SetMetadataProducer(producer predicates.PredicateMetadataProducer) {
+	sched.metadataProducer = producer
+}
+
+// predicateMetadata produces the metadata of the pod passed to the plugins.
+func (sched *ProposedScheduler) predicateMetadata(
+	pod *v1.Pod, nodeInfoMap map[string]*nodeinfo.NodeInfo) predicates.PredicateMetadata {
+
+	if sched.metadataProducer == nil {
+		return &dummyPredicateMetadata{}
+	}
+	return sched.metadataProducer(pod, nodeInfoMap)
+}
+
 // AddPrioritizer adds a prioritizer plugin to this ProposedScheduler.
 func (sched *ProposedScheduler) AddPrioritizer(prioritizer priorities.PriorityConfig) {
 	sched.prioritizers = append(sched.prioritizers, prioritizer)
@@ -82,7 +98,7 @@ func (sched *ProposedScheduler) Schedule(
 
 	// update NodesOverSubFactors
 	for nodeName, _ := range nodeInfoMap {
-		nodesMet := GlobalMetrics[metrics.NodesMetricsKey].(map[string]node.Metrics)
+		nodesMet := GlobalMetrics.Nodes()
 		usage := nodesMet[nodeName].TotalResourceUsage
 		allocatable := nodesMet[nodeName].Allocatable
 		request := nodesMet[nodeName].TotalResourceRequest
@@ -122,7 +138,10 @@ func (sched *ProposedScheduler) Schedule(
 		result, err := sched.scheduleOne(pod, nodeLister, nodeInfoMap, pendingPods)
 
 		if err != nil {
-			if KeepScheduling {
+			results = append(results, &FailedSchedulingEvent{Pod: pod, Reason: err})
+
+			_, setAside := pendingPods.(queue.UnschedulableQueue)
+			if KeepScheduling && !setAside {
 				err = sched.failQueue.Push(pod)
 				if err != nil {
 					log.L.Errorf("Cannot push pod to failQueue: %v", err)
@@ -153,6 +172,15 @@ func (sched *ProposedScheduler) Schedule(
 						results = append(results, delEvents...)
 					}
 
+					// If the queue can set the pod aside, try the next pod.
+					if uq, ok := pendingPods.(queue.UnschedulableQueue); ok {
+						pendingPods.Pop()
+						if err := uq.AddUnschedulable(clock, pod); err != nil {
+							return []Event{}, err
+						}
+						continue
+					}
+
 					// Else, stop the scheduling process at this clock.
 					break
 				} else {
@@ -220,8 +248,10 @@ func (sched *ProposedScheduler) scheduleOne(
 		return result, core.ErrNoNodesAvailable
 	}
 
+	meta := sched.predicateMetadata(pod, nodeInfoMap)
+
 	// Filter out nodes that cannot accommodate the pod.
-	nodesFiltered, failedPredicateMap, err := sched.filter(pod, nodes, nodeInfoMap, podQueue)
+	nodesFiltered, failedPredicateMap, err := sched.filter(pod, meta, nodes, nodeInfoMap, podQueue)
 	if err != nil {
 		return result, err
 	}
@@ -242,7 +272,7 @@ func (sched *ProposedScheduler) scheduleOne(
 	}
 
 	// Prioritize nodes that have passed the filtering phase.
-	prios, err := sched.prioritize(pod, nodesFiltered, nodeInfoMap, podQueue)
+	prios, err := sched.prioritize(pod, meta, nodesFiltered, nodeInfoMap, podQueue)
 	if err != nil {
 		return result, err
 	}
@@ -276,6 +306,7 @@ func (sched *ProposedScheduler) selectHost(priorities api.HostPriorityList) (str
 
 func (sched *ProposedScheduler) filter(
 	pod *v1.Pod,
+	meta predicates.PredicateMetadata,
 	nodes []*v1.Node,
 	nodeInfoMap map[string]*nodeinfo.NodeInfo,
 	podQueue queue.PodQueue,
@@ -290,7 +321,7 @@ func (sched *ProposedScheduler) filter(
 	}
 
 	// In-process plugins
-	filtered, failedPredicateMap, err := filterWithPlugins(pod, sched.predicates, nodes, nodeInfoMap, podQueue)
+	filtered, failedPredicateMap, err := filterWithPlugins(pod, meta, sched.predicates, nodes, nodeInfoMap, podQueue)
 	if err != nil {
 		return []*v1.Node{}, core.FailedPredicateMap{}, err
 	}
@@ -331,6 +362,7 @@ func (sched *ProposedScheduler) filter(
 
 func (sched *ProposedScheduler) prioritize(
 	pod *v1.Pod,
+	meta interface{},
 	filteredNodes []*v1.Node,
 	nodeInfoMap map[string]*nodeinfo.NodeInfo,
 	podQueue queue.PodQueue) (api.HostPriorityList, error) {
@@ -365,7 +397,7 @@ func (sched *ProposedScheduler) prioritize(
 	}
 
 	// In-process plugins
-	prioList, err := prioritizeWithPlugins(pod, sched.prioritizers, filteredNodes, nodeInfoMap, podQueue)
+	prioList, err := prioritizeWithPlugins(pod, meta, sched.prioritizers, filteredNodes, nodeInfoMap, podQueue)
 	if err != nil {
 		return api.HostPriorityList{}, err
 	}
@@ -532,9 +564,11 @@ func (sched *ProposedScheduler) selectNodesForPreemption(
 	// pdbs []*policy.PodDisruptionBudget,
 ) (map[*v1.Node]*api.Victims, error) {
 	nodeToVictims := map[*v1.Node]*api.Victims{}
+	meta := sched.predicateMetadata(preemptor, nodeInfoMap)
 
 	for _, node := range potentialNodes {
-		pods, numPDBViolations, fits := sched.selectVictimsOnNode(preemptor, nodeInfoMap[node.Name], podQueue /* , pdbs */)
+		pods, numPDBViolations, fits := sched.selectVictimsOnNode(
+			preemptor, meta.ShallowCopy(), nodeInfoMap[node.Name], podQueue /* , pdbs */)
 		if fits {
 			nodeToVictims[node] = &api.Victims{
 				Pods:             pods,
@@ -548,6 +582,7 @@ func (sched *ProposedScheduler) selectNodesForPreemption(
 
 func (sched *ProposedScheduler) selectVictimsOnNode(
 	preemptor *v1.Pod,
+	meta predicates.PredicateMetadata,
 	nodeInfo *nodeinfo.NodeInfo,
 	podQueue queue.PodQueue,
 	// pdbs []*policy.PodDisruptionBudget,
@@ -561,10 +596,16 @@ func (sched *ProposedScheduler) selectVictimsOnNode(
 
 	removePod := func(p *v1.Pod) {
 		nodeInfoCopy.RemovePod(p)
+		if err := meta.RemovePod(p); err != nil {
+			log.L.Warnf("Encountered error while removing pod %s/%s from metadata: %v", p.Namespace, p.Name, err)
+		}
 	}
 
 	addPod := func(p *v1.Pod) {
 		nodeInfoCopy.AddPod(p)
+		if err := meta.AddPod(p, nodeInfoCopy); err != nil {
+			log.L.Warnf("Encountered error while adding pod %s/%s to metadata: %v", p.Namespace, p.Name, err)
+		}
 	}
 
 	podPriority := util.PodPriority(preemptor)
@@ -576,7 +617,7 @@ func (sched *ProposedScheduler) selectVictimsOnNode(
 	}
 	potentialVictims.Sort()
 
-	if fits, _, err := podFitsOnNode(preemptor, sched.predicates, nodeInfoCopy, podQueue); !fits {
+	if fits, _, err := podFitsOnNode(preemptor, meta, sched.predicates, nodeInfoCopy, podQueue); !fits {
 		if err != nil {
 			log.L.Warnf("Encountered error while selecting victims on node %s: %v", nodeInfoCopy.Node().Name, err)
 		}
@@ -597,7 +638,7 @@ func (sched *ProposedScheduler) selectVictimsOnNode(
 
 	reprievePod := func(p *v1.Pod) bool {
 		addPod(p)
-		fits, _, _ := podFitsOnNode(preemptor, sched.predicates, nodeInfoCopy, podQueue)
+		fits, _, _ := podFitsOnNode(preemptor, meta, sched.predicates, nodeInfoCopy, podQueue)
 		if !fits {
 			removePod(p)
 			victims = append(victims, p)
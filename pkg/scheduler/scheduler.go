@@ -17,7 +17,6 @@ package scheduler
 import (
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
-	"github.com/pfnet-research/k8s-cluster-simulator/pkg/node"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/scheduler/algorithm"
@@ -75,8 +74,16 @@ type DeleteEvent struct {
 	NodeName     string
 }
 
-func (b *BindEvent) IsSchedulerEvent() bool   { return true }
-func (d *DeleteEvent) IsSchedulerEvent() bool { return true }
+// FailedSchedulingEvent represents an event of failing to find a node for a pending pod.
+// The pod remains pending.
+type FailedSchedulingEvent struct {
+	Pod    *v1.Pod
+	Reason error
+}
+
+func (b *BindEvent) IsSchedulerEvent() bool             { return true }
+func (d *DeleteEvent) IsSchedulerEvent() bool           { return true }
+func (f *FailedSchedulingEvent) IsSchedulerEvent() bool { return true }
 
 type NodeMetrics struct {
 	Usage       nodeinfo.Resource
@@ -106,8 +113,8 @@ func Estimate(nodeNames []string) map[string]*NodeMetrics {
 		//do nothing
 	} else if updatePenaltyRule == 1 {
 		// update min so prediction penalty will converge...
-		if GlobalMetrics[metrics.QueueMetricsKey].(queue.Metrics).PendingPodsNum > 0 {
-			qos := GlobalMetrics[metrics.QueueMetricsKey].(queue.Metrics).QualityOfService
+		if GlobalMetrics.Queue().PendingPodsNum > 0 {
+			qos := GlobalMetrics.Queue().QualityOfService
 			if qos < TargetQoS {
 				if penaltyUpdated {
 					MinPenalty = PredictionPenalty
@@ -121,8 +128,8 @@ func Estimate(nodeNames []string) map[string]*NodeMetrics {
 		}
 	} else if updatePenaltyRule == 2 {
 		// go from max to min.
-		if GlobalMetrics[metrics.QueueMetricsKey].(queue.Metrics).PendingPodsNum > 0 {
-			qos := GlobalMetrics[metrics.QueueMetricsKey].(queue.Metrics).QualityOfService
+		if GlobalMetrics.Queue().PendingPodsNum > 0 {
+			qos := GlobalMetrics.Queue().QualityOfService
 			if qos < TargetQoS {
 				PredictionPenalty = MaxPenalty
 			} else if qos > TargetQoS {
@@ -132,8 +139,8 @@ func Estimate(nodeNames []string) map[string]*NodeMetrics {
 	} else if updatePenaltyRule == 3 { // okay but it cannot deal with high demand when prediction penalty converge.
 		// we can start at 1.1
 		// update max & min so Prediction penalty will converge...
-		if GlobalMetrics[metrics.QueueMetricsKey].(queue.Metrics).PendingPodsNum > 0 {
-			qos := GlobalMetrics[metrics.QueueMetricsKey].(queue.Metrics).QualityOfService
+		if GlobalMetrics.Queue().PendingPodsNum > 0 {
+			qos := GlobalMetrics.Queue().QualityOfService
 			if qos < TargetQoS {
 				tmp := MaxPenalty
 				if penaltyUpdated {
@@ -151,8 +158,8 @@ func Estimate(nodeNames []string) map[string]*NodeMetrics {
 		MinPenalty = 1.1
 		PenaltyUpdate = 0.99
 		// update max & min so Prediction penalty will converge...
-		if GlobalMetrics[metrics.QueueMetricsKey].(queue.Metrics).PendingPodsNum > 0 {
-			qos := GlobalMetrics[metrics.QueueMetricsKey].(queue.Metrics).QualityOfService
+		if GlobalMetrics.Queue().PendingPodsNum > 0 {
+			qos := GlobalMetrics.Queue().QualityOfService
 			if qos < TargetQoS {
 				if penaltyUpdated {
 					PredictionPenalty += (PredictionPenalty - 1.0)
@@ -167,7 +174,7 @@ func Estimate(nodeNames []string) map[string]*NodeMetrics {
 		MinPenalty = 1.1
 		PenaltyUpdate = 0.99
 		// update max & min so Prediction penalty will converge...
-		qos := GlobalMetrics[metrics.QueueMetricsKey].(queue.Metrics).QualityOfService
+		qos := GlobalMetrics.Queue().QualityOfService
 		if qos < TargetQoS {
 			if penaltyUpdated || qos < (prevQoS*0.99) {
 				PredictionPenalty += (PredictionPenalty - 1.0)
@@ -184,10 +191,14 @@ func Estimate(nodeNames []string) map[string]*NodeMetrics {
 	nodeMetricsMap := make(map[string]*NodeMetrics)
 	// predict.
 	for _, nodeName := range nodeNames {
-		usage := *nodeinfo.NewResource(GlobalMetrics[metrics.NodesMetricsKey].(map[string]node.Metrics)[nodeName].TotalResourceUsage)
-		cap := *nodeinfo.NewResource(GlobalMetrics[metrics.NodesMetricsKey].(map[string]node.Metrics)[nodeName].Allocatable)
+		usage := *nodeinfo.NewResource(GlobalMetrics.Nodes()[nodeName].TotalResourceUsage)
+		cap := *nodeinfo.NewResource(GlobalMetrics.Nodes()[nodeName].Allocatable)
 		usage.MilliCPU = usage.MilliCPU * int64(PredictionPenalty*100) / 100
 		usage.Memory = usage.Memory * int64(PredictionPenalty*100) / 100
+		usage.EphemeralStorage = usage.EphemeralStorage * int64(PredictionPenalty*100) / 100
+		for rsrc, amount := range usage.ScalarResources {
+			usage.ScalarResources[rsrc] = amount * int64(PredictionPenalty*100) / 100
+		}
 		nodeMetricsMap[nodeName] = &NodeMetrics{
 			Usage:       usage,
 			Allocatable: cap,
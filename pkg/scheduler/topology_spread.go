@@ -0,0 +1,352 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/containerd/containerd/log"
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	"k8s.io/kubernetes/pkg/scheduler/api"
+	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+	"sigs.k8s.io/yaml"
+)
+
+// TopologySpreadConstraintsAnnotation is the annotation of the topology spread constraints of a
+// pod, in YAML or JSON, since v1.PodSpec of this Kubernetes version has no such field.
+//
+//	kubesim.io/topology-spread-constraints: |
+//	  - maxSkew: 1
+//	    topologyKey: topology.kubernetes.io/zone
+//	    whenUnsatisfiable: DoNotSchedule
+//	    labelSelector:
+//	      matchLabels:
+//	        app: foo
+const TopologySpreadConstraintsAnnotation = "kubesim.io/topology-spread-constraints"
+
+const (
+	// DoNotSchedule filters out the nodes on which the pod would violate the constraint.
+	DoNotSchedule = "DoNotSchedule"
+	// ScheduleAnyway prefers the nodes that minimize the skew, but does not filter out any node.
+	ScheduleAnyway = "ScheduleAnyway"
+)
+
+// TopologySpreadConstraint specifies how to spread matching pods among topology domains, as
+// v1.TopologySpreadConstraint of later Kubernetes versions does.
+type TopologySpreadConstraint struct {
+	// MaxSkew is the maximum difference between the number of matching pods in a domain and the
+	// minimum number of them in any domain.
+	MaxSkew int32 `json:"maxSkew"`
+	// TopologyKey is the key of the node labels whose values are the domains.
+	TopologyKey string `json:"topologyKey"`
+	// WhenUnsatisfiable is either DoNotSchedule (default) or ScheduleAnyway.
+	WhenUnsatisfiable string `json:"whenUnsatisfiable"`
+	// LabelSelector selects the pods, in the namespace of the pod, to be counted in each domain.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector"`
+}
+
+// PodTopologySpreadConstraints returns the topology spread constraints of the given pod.
+// Returns error if the annotation is invalid.
+func PodTopologySpreadConstraints(pod *v1.Pod) ([]TopologySpreadConstraint, error) {
+	annot, ok := pod.Annotations[TopologySpreadConstraintsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	constraints := []TopologySpreadConstraint{}
+	if err := yaml.Unmarshal([]byte(annot), &constraints); err != nil {
+		return nil, strongerrors.InvalidArgument(
+			errors.Wrapf(err, "invalid %s annotation", TopologySpreadConstraintsAnnotation))
+	}
+
+	for i := range constraints {
+		c := &constraints[i]
+		if c.MaxSkew <= 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("maxSkew must be > 0"))
+		}
+		if c.TopologyKey == "" {
+			return nil, strongerrors.InvalidArgument(errors.New("topologyKey must not be empty"))
+		}
+		switch c.WhenUnsatisfiable {
+		case "":
+			c.WhenUnsatisfiable = DoNotSchedule
+		case DoNotSchedule, ScheduleAnyway:
+		default:
+			return nil, strongerrors.InvalidArgument(
+				errors.Errorf("whenUnsatisfiable %q is not supported", c.WhenUnsatisfiable))
+		}
+	}
+
+	return constraints, nil
+}
+
+// topologySpreadMetadata is the number of matching pods in each domain of each constraint of a
+// pod, counted over the whole cluster once per pod, and updated as pods are added to or removed
+// from nodes (e.g., the victims of preemption).
+type topologySpreadMetadata struct {
+	namespace   string
+	constraints []topologySpreadConstraint
+}
+
+type topologySpreadConstraint struct {
+	TopologySpreadConstraint
+	selector labels.Selector
+	// selfMatch is 1 if the pod itself matches the selector, and 0 otherwise.
+	selfMatch int32
+	// counts maps each domain to the number of matching pods in it.
+	counts map[string]int32
+	// minCount is the minimum number of matching pods in any domain.
+	minCount int32
+	// domains maps the name of each node with the topology key to its domain.
+	domains map[string]string
+}
+
+var _ = predicates.PredicateMetadataProducer(TopologySpreadMetadata)
+
+// TopologySpreadMetadata is a predicates.PredicateMetadataProducer that counts the pods matching
+// each topology spread constraint of the pod in each domain.
+// It is to be set to a scheduler by SetMetadataProducer to use PodTopologySpreadPredicate
+// and PodTopologySpreadPriorityMap.
+// A pod with invalid constraints is treated as having none, and a warning is logged.
+func TopologySpreadMetadata(pod *v1.Pod, nodeInfoMap map[string]*nodeinfo.NodeInfo) predicates.PredicateMetadata {
+	meta := &topologySpreadMetadata{namespace: pod.Namespace}
+
+	constraints, err := PodTopologySpreadConstraints(pod)
+	if err != nil {
+		log.L.Warnf("Ignoring the topology spread constraints of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+		return meta
+	}
+
+	for _, c := range constraints {
+		selector, err := metav1.LabelSelectorAsSelector(c.LabelSelector)
+		if err != nil {
+			log.L.Warnf("Ignoring the topology spread constraints of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			return &topologySpreadMetadata{}
+		}
+		tc := topologySpreadConstraint{
+			TopologySpreadConstraint: c,
+			selector:                 selector,
+			counts:                   map[string]int32{},
+			domains:                  map[string]string{},
+		}
+		if selector.Matches(labels.Set(pod.Labels)) {
+			tc.selfMatch = 1
+		}
+
+		for _, info := range nodeInfoMap {
+			node := info.Node()
+			if node == nil {
+				continue
+			}
+			domain, ok := node.Labels[c.TopologyKey]
+			if !ok {
+				continue
+			}
+			tc.counts[domain] += countMatchingPods(pod.Namespace, selector, info)
+			tc.domains[node.Name] = domain
+		}
+		tc.updateMinCount()
+
+		meta.constraints = append(meta.constraints, tc)
+	}
+
+	return meta
+}
+
+func countMatchingPods(namespace string, selector labels.Selector, info *nodeinfo.NodeInfo) int32 {
+	count := int32(0)
+	for _, p := range info.Pods() {
+		if p.Namespace == namespace && p.DeletionTimestamp == nil && selector.Matches(labels.Set(p.Labels)) {
+			count++
+		}
+	}
+	return count
+}
+
+// updateMinCount sets minCount to the minimum of the counts.
+func (c *topologySpreadConstraint) updateMinCount() {
+	first := true
+	for _, count := range c.counts {
+		if first || count < c.minCount {
+			c.minCount = count
+			first = false
+		}
+	}
+}
+
+// ShallowCopy implements predicates.PredicateMetadata interface.
+// The counts are copied, so that the copy can be updated independently.
+func (meta *topologySpreadMetadata) ShallowCopy() predicates.PredicateMetadata {
+	copied := &topologySpreadMetadata{
+		namespace:   meta.namespace,
+		constraints: make([]topologySpreadConstraint, 0, len(meta.constraints)),
+	}
+	for _, c := range meta.constraints {
+		counts := make(map[string]int32, len(c.counts))
+		for domain, count := range c.counts {
+			counts[domain] = count
+		}
+		c.counts = counts
+		copied.constraints = append(copied.constraints, c)
+	}
+	return copied
+}
+
+// AddPod implements predicates.PredicateMetadata interface.
+func (meta *topologySpreadMetadata) AddPod(pod *v1.Pod, nodeInfo *nodeinfo.NodeInfo) error {
+	node := nodeInfo.Node()
+	if node == nil {
+		return fmt.Errorf("node not found")
+	}
+	meta.update(pod, node.Name, 1)
+	return nil
+}
+
+// RemovePod implements predicates.PredicateMetadata interface.
+// The pod is removed from the node in its spec.
+func (meta *topologySpreadMetadata) RemovePod(pod *v1.Pod) error {
+	meta.update(pod, pod.Spec.NodeName, -1)
+	return nil
+}
+
+// update adds delta to the counts of the domains of the node, if the pod matches the constraints.
+func (meta *topologySpreadMetadata) update(pod *v1.Pod, nodeName string, delta int32) {
+	if pod.Namespace != meta.namespace || pod.DeletionTimestamp != nil {
+		return
+	}
+	for i := range meta.constraints {
+		c := &meta.constraints[i]
+		domain, ok := c.domains[nodeName]
+		if !ok || !c.selector.Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		c.counts[domain] += delta
+		c.updateMinCount()
+	}
+}
+
+// ErrTopologySpreadConstraintsNotMatch is the failure reason of PodTopologySpreadPredicate.
+var ErrTopologySpreadConstraintsNotMatch = predicates.NewFailureReason(
+	"node(s) didn't match pod topology spread constraints")
+
+var _ = predicates.FitPredicate(PodTopologySpreadPredicate)
+
+// PodTopologySpreadPredicate is a predicates.FitPredicate that filters out the nodes on which the
+// pod would violate any of its DoNotSchedule topology spread constraints: the node lacks the
+// topology key, or the number of matching pods in its domain would exceed the minimum of any
+// domain by more than maxSkew.
+// Nodes are not filtered out unless the metadata is produced by TopologySpreadMetadata, set to the
+// scheduler by SetMetadataProducer.
+func PodTopologySpreadPredicate(
+	pod *v1.Pod,
+	meta predicates.PredicateMetadata,
+	nodeInfo *nodeinfo.NodeInfo) (bool, []predicates.PredicateFailureReason, error) {
+
+	topoMeta, ok := meta.(*topologySpreadMetadata)
+	if !ok {
+		return true, nil, nil
+	}
+	node := nodeInfo.Node()
+	if node == nil {
+		return false, nil, fmt.Errorf("node not found")
+	}
+
+	for _, c := range topoMeta.constraints {
+		if c.WhenUnsatisfiable != DoNotSchedule {
+			continue
+		}
+		domain, ok := node.Labels[c.TopologyKey]
+		if !ok {
+			return false, []predicates.PredicateFailureReason{ErrTopologySpreadConstraintsNotMatch}, nil
+		}
+		if c.counts[domain]+c.selfMatch-c.minCount > c.MaxSkew {
+			return false, []predicates.PredicateFailureReason{ErrTopologySpreadConstraintsNotMatch}, nil
+		}
+	}
+
+	return true, nil, nil
+}
+
+// PodTopologySpreadPriorityMap is a priorities.PriorityMapFunction that scores each node by the
+// number of pods matching the ScheduleAnyway topology spread constraints of the pod in its
+// domains. The fewer, the better after PodTopologySpreadPriorityReduce.
+func PodTopologySpreadPriorityMap(pod *v1.Pod, meta interface{}, nodeInfo *nodeinfo.NodeInfo) (api.HostPriority, error) {
+	node := nodeInfo.Node()
+	if node == nil {
+		return api.HostPriority{}, fmt.Errorf("node not found")
+	}
+	prio := api.HostPriority{Host: node.Name}
+
+	topoMeta, ok := meta.(*topologySpreadMetadata)
+	if !ok {
+		return prio, nil
+	}
+
+	for _, c := range topoMeta.constraints {
+		if c.WhenUnsatisfiable != ScheduleAnyway {
+			continue
+		}
+		domain, ok := node.Labels[c.TopologyKey]
+		if !ok {
+			// Nodes without the key are scored the lowest.
+			prio.Score = -1
+			return prio, nil
+		}
+		prio.Score += int(c.counts[domain])
+	}
+
+	return prio, nil
+}
+
+// PodTopologySpreadPriorityReduce is a priorities.PriorityReduceFunction that normalizes the
+// numbers of matching pods by PodTopologySpreadPriorityMap to scores in [0, api.MaxPriority], the
+// fewest pods the highest.
+func PodTopologySpreadPriorityReduce(
+	pod *v1.Pod,
+	meta interface{},
+	nodeInfoMap map[string]*nodeinfo.NodeInfo,
+	result api.HostPriorityList) error {
+
+	minCount, maxCount := -1, -1
+	for _, prio := range result {
+		if prio.Score < 0 {
+			continue
+		}
+		if minCount < 0 || prio.Score < minCount {
+			minCount = prio.Score
+		}
+		if prio.Score > maxCount {
+			maxCount = prio.Score
+		}
+	}
+
+	for i, prio := range result {
+		switch {
+		case prio.Score < 0:
+			result[i].Score = 0
+		case maxCount == minCount:
+			result[i].Score = api.MaxPriority
+		default:
+			result[i].Score = api.MaxPriority * (maxCount - prio.Score) / (maxCount - minCount)
+		}
+	}
+
+	return nil
+}
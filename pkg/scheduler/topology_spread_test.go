@@ -0,0 +1,207 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/priorities"
+	"k8s.io/kubernetes/pkg/scheduler/api"
+	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+// newZonedCluster creates nodes in the given zones, each running the given number of pods
+// labelled app=foo.
+func newZonedCluster(zones []string, pods []int) map[string]*nodeinfo.NodeInfo {
+	nodeInfoMap := map[string]*nodeinfo.NodeInfo{}
+	for i, zone := range zones {
+		name := fmt.Sprintf("node-%d", i)
+		info := nodeinfo.NewNodeInfo()
+		info.SetNode(&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: util.TopologyLabelsOf("", zone, "")}})
+		for j := 0; j < pods[i]; j++ {
+			info.AddPod(&v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      fmt.Sprintf("pod-%d-%d", i, j),
+					Labels:    map[string]string{"app": "foo"},
+				},
+				Spec: v1.PodSpec{NodeName: name},
+			})
+		}
+		nodeInfoMap[name] = info
+	}
+	return nodeInfoMap
+}
+
+func newSpreadPod(whenUnsatisfiable string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "pod",
+		Labels:    map[string]string{"app": "foo"},
+		Annotations: map[string]string{TopologySpreadConstraintsAnnotation: fmt.Sprintf(`
+- maxSkew: 1
+  topologyKey: %s
+  whenUnsatisfiable: %s
+  labelSelector:
+    matchLabels:
+      app: foo
+`, util.LabelTopologyZone, whenUnsatisfiable)},
+	}}
+}
+
+func TestPodTopologySpreadPredicate(t *testing.T) {
+	// Zone a has 2 pods, and zone b has 1 pod; node-3 has no zone.
+	nodeInfoMap := newZonedCluster([]string{"a", "a", "b", ""}, []int{1, 1, 1, 0})
+	pod := newSpreadPod(DoNotSchedule)
+	meta := TopologySpreadMetadata(pod, nodeInfoMap)
+
+	for name, expected := range map[string]bool{"node-0": false, "node-1": false, "node-2": true, "node-3": false} {
+		fits, _, err := PodTopologySpreadPredicate(pod, meta, nodeInfoMap[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fits != expected {
+			t.Errorf("%s: got: fits %v\nwant: fits %v", name, fits, expected)
+		}
+	}
+
+	// Without the metadata (e.g., without SetMetadataProducer), no node is filtered out.
+	if fits, _, _ := PodTopologySpreadPredicate(pod, &dummyPredicateMetadata{}, nodeInfoMap["node-0"]); !fits {
+		t.Errorf("got: not fits\nwant: fits without the metadata")
+	}
+}
+
+func TestTopologySpreadMetadataAddRemovePod(t *testing.T) {
+	// Zone a has 2 pods, and zone b has 1 pod.
+	nodeInfoMap := newZonedCluster([]string{"a", "a", "b"}, []int{1, 1, 1})
+	pod := newSpreadPod(DoNotSchedule)
+	meta := TopologySpreadMetadata(pod, nodeInfoMap)
+	victim := nodeInfoMap["node-0"].Pods()[0]
+
+	// Removing a pod from zone a (e.g., a victim of preemption) lets the pod fit in zone a.
+	copied := meta.ShallowCopy()
+	if err := copied.RemovePod(victim); err != nil {
+		t.Fatal(err)
+	}
+	if fits, _, _ := PodTopologySpreadPredicate(pod, copied, nodeInfoMap["node-0"]); !fits {
+		t.Errorf("got: not fits\nwant: fits after removing %s", victim.Name)
+	}
+
+	// The original metadata is not modified.
+	if fits, _, _ := PodTopologySpreadPredicate(pod, meta, nodeInfoMap["node-0"]); fits {
+		t.Errorf("got: fits\nwant: not fits in the original metadata")
+	}
+
+	if err := copied.AddPod(victim, nodeInfoMap["node-0"]); err != nil {
+		t.Fatal(err)
+	}
+	if fits, _, _ := PodTopologySpreadPredicate(pod, copied, nodeInfoMap["node-0"]); fits {
+		t.Errorf("got: fits\nwant: not fits after adding %s back", victim.Name)
+	}
+}
+
+func TestPodTopologySpreadPriority(t *testing.T) {
+	nodeInfoMap := newZonedCluster([]string{"a", "b", "c", ""}, []int{2, 1, 0, 0})
+	pod := newSpreadPod(ScheduleAnyway)
+	meta := TopologySpreadMetadata(pod, nodeInfoMap)
+
+	// ScheduleAnyway does not filter out any node.
+	if fits, _, _ := PodTopologySpreadPredicate(pod, meta, nodeInfoMap["node-0"]); !fits {
+		t.Errorf("got: not fits\nwant: fits")
+	}
+
+	result := api.HostPriorityList{}
+	for i := range nodeInfoMap {
+		prio, err := PodTopologySpreadPriorityMap(pod, meta, nodeInfoMap[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, prio)
+	}
+	if err := PodTopologySpreadPriorityReduce(pod, meta, nodeInfoMap, result); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"node-0": 0, "node-1": api.MaxPriority / 2, "node-2": api.MaxPriority, "node-3": 0}
+	for _, prio := range result {
+		if prio.Score != expected[prio.Host] {
+			t.Errorf("%s: got: %d\nwant: %d", prio.Host, prio.Score, expected[prio.Host])
+		}
+	}
+}
+
+// nodeList is an algorithm.NodeLister of the nodes.
+type nodeList []*v1.Node
+
+func (l nodeList) List() ([]*v1.Node, error) { return l, nil }
+
+func TestProposedSchedulerTopologySpreadPriority(t *testing.T) {
+	// Zone b is empty, and zone a has 2 pods.
+	nodeInfoMap := newZonedCluster([]string{"b", "a"}, []int{0, 2})
+	nodes := nodeList{nodeInfoMap["node-0"].Node(), nodeInfoMap["node-1"].Node()}
+
+	sched := NewProposedScheduler(false)
+	sched.AddPrioritizer(priorities.PriorityConfig{
+		Name:   "PodTopologySpread",
+		Map:    PodTopologySpreadPriorityMap,
+		Reduce: PodTopologySpreadPriorityReduce,
+		Weight: 1,
+	})
+	sched.SetMetadataProducer(TopologySpreadMetadata)
+
+	q := queue.NewFIFOQueue()
+	if err := q.Push(newSpreadPod(ScheduleAnyway)); err != nil {
+		t.Fatal(err)
+	}
+	events, err := sched.Schedule(clock.NewClock(time.Now()), q, nodes, nodeInfoMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got: %d events\nwant: 1", len(events))
+	}
+	bind, ok := events[0].(*BindEvent)
+	if !ok {
+		t.Fatalf("got: %T\nwant: *BindEvent", events[0])
+	}
+	if bind.ScheduleResult.SuggestedHost != "node-0" {
+		t.Errorf("got: %s\nwant: node-0 in the empty zone", bind.ScheduleResult.SuggestedHost)
+	}
+}
+
+func TestPodTopologySpreadConstraints(t *testing.T) {
+	pod := newSpreadPod("")
+	constraints, err := PodTopologySpreadConstraints(pod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(constraints) != 1 || constraints[0].WhenUnsatisfiable != DoNotSchedule ||
+		constraints[0].LabelSelector.MatchLabels["app"] != "foo" {
+		t.Errorf("got: %+v\nwant: a DoNotSchedule constraint on app=foo", constraints)
+	}
+
+	pod.Annotations[TopologySpreadConstraintsAnnotation] = "- maxSkew: 0\n  topologyKey: zone\n"
+	if _, err := PodTopologySpreadConstraints(pod); err == nil || err.Error() != "maxSkew must be > 0" {
+		t.Errorf("got: %v\nwant: maxSkew must be > 0", err)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+)
+
+// ArrivalProcess generates the arrival times of tasks.
+type ArrivalProcess interface {
+	// Next returns the arrival time following the given time, in seconds from the start of the
+	// workload, or +Inf if no more task arrives.
+	Next(r *rand.Rand, now float64) float64
+}
+
+// ArrivalConfig configures an ArrivalProcess. All rates are per second.
+type ArrivalConfig struct {
+	// Type is one of "poisson", "mmpp", "diurnal", and "replay".
+	Type string `yaml:"type"`
+
+	// Rate of a Poisson process, or the mean rate of a diurnal process.
+	Rate float64 `yaml:"rate"`
+
+	// Rates are the arrival rates in the states of a Markov-modulated Poisson process (MMPP), and
+	// SwitchRates are the rates of leaving the states, to the next state in a round-robin manner.
+	// For example, rates [0.1, 10] and switchRates [0.01, 0.1] model bursts of 10 s on average
+	// every 100 s on average.
+	Rates       []float64 `yaml:"rates"`
+	SwitchRates []float64 `yaml:"switchRates"`
+
+	// Amplitude (in [0, 1]), Period (in seconds, defaults to a day), and Phase (in seconds) of the
+	// sinusoidal rate of a diurnal process: rate * (1 + amplitude * sin(2π (t - phase) / period)).
+	Amplitude float64 `yaml:"amplitude"`
+	Period    float64 `yaml:"period"`
+	Phase     float64 `yaml:"phase"`
+
+	// Curve of a replayed rate curve, as a list of [time, rate] in ascending order of time.
+	// The rate is piecewise constant from each point to the next, and the last rate lasts forever.
+	Curve [][2]float64 `yaml:"curve"`
+}
+
+// NewArrivalProcess creates a new ArrivalProcess with the given config.
+// Returns error if the config is invalid.
+func NewArrivalProcess(conf ArrivalConfig) (ArrivalProcess, error) {
+	switch conf.Type {
+	case "poisson":
+		if conf.Rate <= 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("poisson arrival must have rate > 0"))
+		}
+		return &poisson{rate: conf.Rate}, nil
+
+	case "mmpp":
+		if len(conf.Rates) == 0 || len(conf.Rates) != len(conf.SwitchRates) {
+			return nil, strongerrors.InvalidArgument(
+				errors.New("mmpp arrival must have the same number of rates and switchRates"))
+		}
+		maxRate := 0.0
+		for i := range conf.Rates {
+			if conf.Rates[i] < 0 || conf.SwitchRates[i] <= 0 {
+				return nil, strongerrors.InvalidArgument(
+					errors.New("mmpp arrival must have rates >= 0 and switchRates > 0"))
+			}
+			maxRate = math.Max(maxRate, conf.Rates[i])
+		}
+		if maxRate == 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("mmpp arrival must have a rate > 0"))
+		}
+		return &mmpp{rates: conf.Rates, switchRates: conf.SwitchRates}, nil
+
+	case "diurnal":
+		if conf.Rate <= 0 || conf.Amplitude < 0 || conf.Amplitude > 1 {
+			return nil, strongerrors.InvalidArgument(
+				errors.New("diurnal arrival must have rate > 0 and amplitude in [0, 1]"))
+		}
+		period := conf.Period
+		if period == 0 {
+			period = 24 * 60 * 60
+		}
+		rate := func(t float64) float64 {
+			return conf.Rate * (1 + conf.Amplitude*math.Sin(2*math.Pi*(t-conf.Phase)/period))
+		}
+		return &thinning{rate: rate, maxRate: conf.Rate * (1 + conf.Amplitude)}, nil
+
+	case "replay":
+		if len(conf.Curve) == 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("replay arrival must have a curve"))
+		}
+		maxRate := 0.0
+		for i, point := range conf.Curve {
+			if point[1] < 0 || (i > 0 && point[0] < conf.Curve[i-1][0]) {
+				return nil, strongerrors.InvalidArgument(
+					errors.New("replay arrival must have a curve in ascending order of time with rates >= 0"))
+			}
+			maxRate = math.Max(maxRate, point[1])
+		}
+		return &thinning{rate: replayRate(conf.Curve), maxRate: maxRate, end: replayEnd(conf.Curve)}, nil
+
+	default:
+		return nil, strongerrors.InvalidArgument(errors.Errorf("arrival type %q is not supported", conf.Type))
+	}
+}
+
+type poisson struct{ rate float64 }
+
+func (p *poisson) Next(r *rand.Rand, now float64) float64 {
+	return now + r.ExpFloat64()/p.rate
+}
+
+// mmpp is a Markov-modulated Poisson process.
+type mmpp struct {
+	rates       []float64
+	switchRates []float64
+	state       int
+}
+
+func (m *mmpp) Next(r *rand.Rand, now float64) float64 {
+	for {
+		// Arrival and state transition compete as exponential clocks.
+		rate := m.rates[m.state]
+		total := rate + m.switchRates[m.state]
+		now += r.ExpFloat64() / total
+		if r.Float64()*total < rate {
+			return now
+		}
+		m.state = (m.state + 1) % len(m.rates)
+	}
+}
+
+// thinning is a non-homogeneous Poisson process, sampled by thinning a homogeneous one of maxRate.
+type thinning struct {
+	rate    func(t float64) float64
+	maxRate float64
+	// end is the time after which the rate is zero, or 0 if it never becomes zero.
+	end float64
+}
+
+func (t *thinning) Next(r *rand.Rand, now float64) float64 {
+	if t.maxRate == 0 {
+		return math.Inf(1)
+	}
+	for {
+		now += r.ExpFloat64() / t.maxRate
+		if t.end != 0 && now >= t.end {
+			return math.Inf(1)
+		}
+		if r.Float64()*t.maxRate < t.rate(now) {
+			return now
+		}
+	}
+}
+
+func replayRate(curve [][2]float64) func(t float64) float64 {
+	return func(t float64) float64 {
+		rate := 0.0
+		for _, point := range curve {
+			if point[0] > t {
+				break
+			}
+			rate = point[1]
+		}
+		return rate
+	}
+}
+
+// replayEnd returns the time after which the replayed rate is zero, or 0 if it is never.
+func replayEnd(curve [][2]float64) float64 {
+	last := curve[len(curve)-1]
+	if last[1] == 0 {
+		return last[0]
+	}
+	return 0
+}
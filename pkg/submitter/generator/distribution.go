@@ -0,0 +1,176 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+)
+
+// Distribution is a probability distribution of real values.
+type Distribution interface {
+	// Sample draws a value from this Distribution with r.
+	Sample(r *rand.Rand) float64
+}
+
+// DistributionConfig configures a Distribution.
+// Samples are clamped to [Min, Max], where a zero Max means no upper bound.
+type DistributionConfig struct {
+	// Type is one of "constant", "uniform", "normal", "lognormal", "pareto", and "empirical".
+	Type string `yaml:"type"`
+
+	// Value of a constant distribution.
+	Value float64 `yaml:"value"`
+	// Mean and Std of a normal distribution.
+	Mean float64 `yaml:"mean"`
+	Std  float64 `yaml:"std"`
+	// Mu and Sigma of the underlying normal distribution of a lognormal distribution.
+	Mu    float64 `yaml:"mu"`
+	Sigma float64 `yaml:"sigma"`
+	// Scale (the minimum value) and Shape (alpha) of a Pareto distribution.
+	Scale float64 `yaml:"scale"`
+	Shape float64 `yaml:"shape"`
+	// CDF of an empirical distribution, as a list of [value, cumulative probability] in ascending
+	// order, ending with probability 1. Values are linearly interpolated between the points.
+	CDF [][2]float64 `yaml:"cdf"`
+
+	// Min and Max are also the bounds of a uniform distribution.
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+}
+
+// NewDistribution creates a new Distribution with the given config.
+// Returns error if the config is invalid.
+func NewDistribution(conf DistributionConfig) (Distribution, error) {
+	var dist Distribution
+
+	switch conf.Type {
+	case "constant":
+		dist = constant(conf.Value)
+	case "uniform":
+		if conf.Max < conf.Min {
+			return nil, strongerrors.InvalidArgument(errors.New("uniform distribution must have min <= max"))
+		}
+		return uniform{min: conf.Min, max: conf.Max}, nil
+	case "normal":
+		if conf.Std < 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("normal distribution must have std >= 0"))
+		}
+		dist = normal{mean: conf.Mean, std: conf.Std}
+	case "lognormal":
+		if conf.Sigma < 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("lognormal distribution must have sigma >= 0"))
+		}
+		dist = lognormal{mu: conf.Mu, sigma: conf.Sigma}
+	case "pareto":
+		if conf.Scale <= 0 || conf.Shape <= 0 {
+			return nil, strongerrors.InvalidArgument(errors.New("pareto distribution must have scale > 0 and shape > 0"))
+		}
+		dist = pareto{scale: conf.Scale, shape: conf.Shape}
+	case "empirical":
+		e, err := newEmpirical(conf.CDF)
+		if err != nil {
+			return nil, err
+		}
+		dist = e
+	default:
+		return nil, strongerrors.InvalidArgument(errors.Errorf("distribution type %q is not supported", conf.Type))
+	}
+
+	if conf.Max != 0 && conf.Max < conf.Min {
+		return nil, strongerrors.InvalidArgument(errors.New("distribution must have min <= max"))
+	}
+	return clamped{inner: dist, min: conf.Min, max: conf.Max}, nil
+}
+
+type constant float64
+
+func (c constant) Sample(r *rand.Rand) float64 { return float64(c) }
+
+type uniform struct{ min, max float64 }
+
+func (u uniform) Sample(r *rand.Rand) float64 { return u.min + r.Float64()*(u.max-u.min) }
+
+type normal struct{ mean, std float64 }
+
+func (n normal) Sample(r *rand.Rand) float64 { return n.mean + r.NormFloat64()*n.std }
+
+type lognormal struct{ mu, sigma float64 }
+
+func (l lognormal) Sample(r *rand.Rand) float64 { return math.Exp(l.mu + r.NormFloat64()*l.sigma) }
+
+type pareto struct{ scale, shape float64 }
+
+func (p pareto) Sample(r *rand.Rand) float64 {
+	// Inverse transform sampling; 1 - Float64() is in (0, 1].
+	return p.scale / math.Pow(1-r.Float64(), 1/p.shape)
+}
+
+type empirical struct {
+	values []float64
+	probs  []float64
+}
+
+func newEmpirical(cdf [][2]float64) (*empirical, error) {
+	if len(cdf) == 0 {
+		return nil, strongerrors.InvalidArgument(errors.New("empirical distribution must have a cdf"))
+	}
+
+	e := &empirical{}
+	for i, point := range cdf {
+		if i > 0 && (point[0] < cdf[i-1][0] || point[1] < cdf[i-1][1]) {
+			return nil, strongerrors.InvalidArgument(errors.New("empirical cdf must be in ascending order"))
+		}
+		e.values = append(e.values, point[0])
+		e.probs = append(e.probs, point[1])
+	}
+	if e.probs[len(e.probs)-1] != 1 {
+		return nil, strongerrors.InvalidArgument(errors.New("empirical cdf must end with probability 1"))
+	}
+
+	return e, nil
+}
+
+func (e *empirical) Sample(r *rand.Rand) float64 {
+	p := r.Float64()
+	i := sort.SearchFloat64s(e.probs, p)
+	if i == 0 {
+		return e.values[0]
+	}
+
+	// Interpolate linearly between the (i-1)-th and i-th points.
+	p0, p1 := e.probs[i-1], e.probs[i]
+	if p1 == p0 {
+		return e.values[i]
+	}
+	return e.values[i-1] + (e.values[i]-e.values[i-1])*(p-p0)/(p1-p0)
+}
+
+type clamped struct {
+	inner    Distribution
+	min, max float64
+}
+
+func (c clamped) Sample(r *rand.Rand) float64 {
+	v := math.Max(c.inner.Sample(r), c.min)
+	if c.max != 0 {
+		v = math.Min(v, c.max)
+	}
+	return v
+}
@@ -0,0 +1,297 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package generator generates synthetic workloads, configured in YAML, as tasks of the trace
+// package.
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/trace"
+)
+
+// Config configures a workload.
+type Config struct {
+	// Seed of the random number generator. The current time is used if 0.
+	Seed int64 `yaml:"seed"`
+	// Duration is the period over which tasks arrive, in seconds.
+	Duration float64 `yaml:"duration"`
+	// MaxTasks limits the number of tasks. No limit if 0.
+	MaxTasks int `yaml:"maxTasks"`
+	// Namespace of the tasks. Defaults to "default".
+	Namespace string `yaml:"namespace"`
+	// Output is a file path in which the generated tasks are saved, for exact reuse through the
+	// trace package. The tasks are not saved if empty.
+	Output string `yaml:"output"`
+
+	Arrival ArrivalConfig `yaml:"arrival"`
+	// Classes is the mix of task classes. The class of each arriving task is chosen at random in
+	// proportion to the class weights.
+	Classes []ClassConfig `yaml:"classes"`
+}
+
+// ClassConfig configures a class of tasks.
+type ClassConfig struct {
+	Name     string  `yaml:"name"`
+	Weight   float64 `yaml:"weight"`
+	Priority int32   `yaml:"priority"`
+
+	// Duration is the distribution of the total execution duration of a task, in seconds.
+	Duration DistributionConfig `yaml:"duration"`
+	// Phases is the distribution of the number of phases of a task, rounded to an integer >= 1.
+	// A task has one phase if omitted.
+	Phases *DistributionConfig `yaml:"phases"`
+	// Request maps resource names to the distributions of the requested amounts.
+	Request map[v1.ResourceName]ResourceConfig `yaml:"request"`
+	// UsageRatio is the distribution of the ratio of the resource usage to the request, sampled
+	// for each phase and resource. A task uses as much as it requests if omitted.
+	UsageRatio *DistributionConfig `yaml:"usageRatio"`
+}
+
+// ResourceConfig configures the distribution of the amount of a resource, in Unit.
+type ResourceConfig struct {
+	DistributionConfig `yaml:",inline"`
+	// Unit is a quantity such as "1" or "1Gi". Defaults to "1".
+	Unit string `yaml:"unit"`
+}
+
+// LoadConfig loads a Config from the YAML file at the given path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := Config{}
+	if err := yaml.UnmarshalStrict(data, &conf); err != nil {
+		return nil, strongerrors.InvalidArgument(errors.Wrapf(err, "invalid generator config %s", path))
+	}
+	return &conf, nil
+}
+
+// Generator generates tasks.
+type Generator struct {
+	conf    Config
+	rand    *rand.Rand
+	arrival ArrivalProcess
+	classes []class
+	weights float64
+}
+
+type class struct {
+	conf       ClassConfig
+	duration   Distribution
+	phases     Distribution
+	request    map[v1.ResourceName]Distribution
+	units      map[v1.ResourceName]resource.Quantity
+	usageRatio Distribution
+	// names are the requested resources in sorted order, in which they are sampled so that the
+	// same seed generates the same workload.
+	names []v1.ResourceName
+}
+
+// New creates a new Generator with the given config.
+// Returns error if the config is invalid.
+func New(conf Config) (*Generator, error) {
+	if conf.Duration <= 0 {
+		return nil, strongerrors.InvalidArgument(errors.New("duration must be > 0"))
+	}
+	if len(conf.Classes) == 0 {
+		return nil, strongerrors.InvalidArgument(errors.New("at least one class must be configured"))
+	}
+
+	arrival, err := NewArrivalProcess(conf.Arrival)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := conf.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	g := &Generator{
+		conf:    conf,
+		rand:    rand.New(rand.NewSource(seed)),
+		arrival: arrival,
+	}
+
+	for _, classConf := range conf.Classes {
+		c, err := newClass(classConf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "class %q", classConf.Name)
+		}
+		if classConf.Weight < 0 {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("class %q must have weight >= 0", classConf.Name))
+		}
+		g.classes = append(g.classes, c)
+		g.weights += classConf.Weight
+	}
+	if g.weights == 0 {
+		return nil, strongerrors.InvalidArgument(errors.New("at least one class must have weight > 0"))
+	}
+
+	return g, nil
+}
+
+func newClass(conf ClassConfig) (class, error) {
+	c := class{
+		conf:    conf,
+		request: map[v1.ResourceName]Distribution{},
+		units:   map[v1.ResourceName]resource.Quantity{},
+	}
+
+	var err error
+	if c.duration, err = NewDistribution(conf.Duration); err != nil {
+		return c, errors.Wrap(err, "duration")
+	}
+
+	c.phases = constant(1)
+	if conf.Phases != nil {
+		if c.phases, err = NewDistribution(*conf.Phases); err != nil {
+			return c, errors.Wrap(err, "phases")
+		}
+	}
+
+	c.usageRatio = constant(1)
+	if conf.UsageRatio != nil {
+		if c.usageRatio, err = NewDistribution(*conf.UsageRatio); err != nil {
+			return c, errors.Wrap(err, "usageRatio")
+		}
+	}
+
+	for rsrc, rsrcConf := range conf.Request {
+		if c.request[rsrc], err = NewDistribution(rsrcConf.DistributionConfig); err != nil {
+			return c, errors.Wrapf(err, "request %s", rsrc)
+		}
+
+		unit := rsrcConf.Unit
+		if unit == "" {
+			unit = "1"
+		}
+		q, err := resource.ParseQuantity(unit)
+		if err != nil {
+			return c, strongerrors.InvalidArgument(errors.Wrapf(err, "request %s", rsrc))
+		}
+		c.units[rsrc] = q
+		c.names = append(c.names, rsrc)
+	}
+	sort.Slice(c.names, func(i, j int) bool { return c.names[i] < c.names[j] })
+
+	return c, nil
+}
+
+// Generate generates all tasks arriving within the configured duration, sorted by their arrival
+// time, and saves them to the configured output if any.
+func (g *Generator) Generate() ([]*trace.Task, error) {
+	tasks := []*trace.Task{}
+
+	for now := g.arrival.Next(g.rand, 0); now < g.conf.Duration; now = g.arrival.Next(g.rand, now) {
+		if g.conf.MaxTasks > 0 && len(tasks) >= g.conf.MaxTasks {
+			break
+		}
+		tasks = append(tasks, g.newTask(len(tasks), int64(now)))
+	}
+
+	if g.conf.Output != "" {
+		file, err := os.Create(g.conf.Output)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		if err := trace.WriteTasks(file, tasks); err != nil {
+			return nil, err
+		}
+	}
+
+	return tasks, nil
+}
+
+func (g *Generator) newTask(idx int, arrival int64) *trace.Task {
+	c := g.pickClass()
+
+	task := &trace.Task{
+		ID:        fmt.Sprintf("%s-%d", c.conf.Name, idx),
+		Namespace: g.conf.Namespace,
+		Arrival:   arrival,
+		Priority:  c.conf.Priority,
+		Request:   v1.ResourceList{},
+		Labels:    map[string]string{"class": c.conf.Name},
+	}
+
+	for _, rsrc := range c.names {
+		task.Request[rsrc] = scale(rsrc, c.units[rsrc], c.request[rsrc].Sample(g.rand))
+	}
+
+	duration := int32(math.Max(1, math.Round(c.duration.Sample(g.rand))))
+	phasesNum := int32(math.Max(1, math.Round(c.phases.Sample(g.rand))))
+	if phasesNum > duration {
+		phasesNum = duration
+	}
+
+	// Split the duration at random points into phases of at least one second.
+	remaining := duration - phasesNum
+	for i := int32(0); i < phasesNum; i++ {
+		seconds := int32(1)
+		if i == phasesNum-1 {
+			seconds += remaining
+		} else if remaining > 0 {
+			extra := g.rand.Int31n(remaining + 1)
+			seconds += extra
+			remaining -= extra
+		}
+
+		usage := v1.ResourceList{}
+		for _, rsrc := range c.names {
+			usage[rsrc] = scale(rsrc, task.Request[rsrc], c.usageRatio.Sample(g.rand))
+		}
+		task.Phases = append(task.Phases, trace.Phase{Seconds: seconds, Usage: usage})
+	}
+
+	return task
+}
+
+func (g *Generator) pickClass() *class {
+	w := g.rand.Float64() * g.weights
+	for i := range g.classes {
+		w -= g.classes[i].conf.Weight
+		if w < 0 {
+			return &g.classes[i]
+		}
+	}
+	return &g.classes[len(g.classes)-1]
+}
+
+// scale returns the quantity of factor times q of the resource, in millicores for cpu and rounded
+// to whole units (e.g., bytes) for the other resources.
+func scale(rsrc v1.ResourceName, q resource.Quantity, factor float64) resource.Quantity {
+	if rsrc == v1.ResourceCPU {
+		return *resource.NewMilliQuantity(int64(float64(q.MilliValue())*factor), q.Format)
+	}
+	return *resource.NewQuantity(int64(math.Round(float64(q.MilliValue())*factor/1000)), q.Format)
+}
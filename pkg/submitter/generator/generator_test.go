@@ -0,0 +1,236 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/trace"
+)
+
+func TestNewDistribution(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	pareto, _ := NewDistribution(DistributionConfig{Type: "pareto", Scale: 2, Shape: 1.5, Max: 10})
+	emp, _ := NewDistribution(DistributionConfig{Type: "empirical", CDF: [][2]float64{{0, 0}, {10, 1}}})
+	norm, _ := NewDistribution(DistributionConfig{Type: "normal", Mean: 5, Std: 10, Min: 0})
+
+	sum := 0.0
+	for i := 0; i < 10000; i++ {
+		if v := pareto.Sample(r); v < 2 || 10 < v {
+			t.Fatalf("got: %v\nwant: in [2, 10]", v)
+		}
+		if v := norm.Sample(r); v < 0 {
+			t.Fatalf("got: %v\nwant: >= 0", v)
+		}
+		sum += emp.Sample(r)
+	}
+	if mean := sum / 10000; math.Abs(mean-5) > 0.2 {
+		t.Errorf("got: mean %v\nwant: mean 5", mean)
+	}
+
+	_, err := NewDistribution(DistributionConfig{Type: "empirical", CDF: [][2]float64{{0, 0}, {10, 0.5}}})
+	assert.EqualError(t, err, "empirical cdf must end with probability 1")
+	_, err = NewDistribution(DistributionConfig{Type: "invalid"})
+	assert.EqualError(t, err, "distribution type \"invalid\" is not supported")
+}
+
+func TestArrivalProcesses(t *testing.T) {
+	count := func(conf ArrivalConfig, duration float64) int {
+		p, err := NewArrivalProcess(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := rand.New(rand.NewSource(1))
+		n := 0
+		for now := p.Next(r, 0); now < duration; now = p.Next(r, now) {
+			n++
+		}
+		return n
+	}
+
+	if n := count(ArrivalConfig{Type: "poisson", Rate: 2}, 1000); n < 1800 || 2200 < n {
+		t.Errorf("got: %d arrivals\nwant: about 2000 arrivals", n)
+	}
+	if n := count(ArrivalConfig{Type: "diurnal", Rate: 1, Amplitude: 1, Period: 100}, 1000); n < 900 || 1100 < n {
+		t.Errorf("got: %d arrivals\nwant: about 1000 arrivals", n)
+	}
+	// The rate is 1 for the first 100 seconds and 0 afterwards.
+	if n := count(ArrivalConfig{Type: "replay", Curve: [][2]float64{{0, 1}, {100, 0}}}, 1000); n < 70 || 130 < n {
+		t.Errorf("got: %d arrivals\nwant: about 100 arrivals", n)
+	}
+	// Half the time in each state on average.
+	mmpp := ArrivalConfig{Type: "mmpp", Rates: []float64{0, 2}, SwitchRates: []float64{0.1, 0.1}}
+	if n := count(mmpp, 10000); n < 8000 || 12000 < n {
+		t.Errorf("got: %d arrivals\nwant: about 10000 arrivals", n)
+	}
+
+	_, err := NewArrivalProcess(ArrivalConfig{Type: "mmpp", Rates: []float64{1}})
+	assert.Error(t, err)
+}
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	confPath := filepath.Join(dir, "generator.yaml")
+	output := filepath.Join(dir, "workload.jsonl")
+	confYAML := `
+seed: 1
+duration: 100
+maxTasks: 50
+output: ` + output + `
+arrival: {type: poisson, rate: 1}
+classes:
+- name: batch
+  weight: 1
+  priority: 2
+  duration: {type: uniform, min: 10, max: 100}
+  phases: {type: constant, value: 3}
+  request:
+    cpu: {type: constant, value: 2}
+    memory: {type: constant, value: 1, unit: 1Gi}
+  usageRatio: {type: constant, value: 0.5}
+`
+	if err := ioutil.WriteFile(confPath, []byte(confYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := LoadConfig(confPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen, _ := New(*conf)
+	tasks, err := gen.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) < 50 {
+		t.Fatalf("got: %d tasks\nwant: 50 tasks", len(tasks))
+	}
+
+	for _, task := range tasks {
+		if len(task.Phases) != 3 || task.Priority != 2 || task.Duration() < 10 || task.Duration() > 100 {
+			t.Fatalf("got: %+v\nwant: 3 phases of 10 to 100 seconds with priority 2", task)
+		}
+		cpu := task.Phases[0].Usage[v1.ResourceCPU]
+		mem := task.Phases[0].Usage[v1.ResourceMemory]
+		if cpu.MilliValue() != 1000 || mem.Value() != 1<<29 {
+			t.Fatalf("got: %+v\nwant: 1 CPU and 512Mi", task.Phases[0].Usage)
+		}
+	}
+
+	// The same seed generates the same workload, which has been saved to the output.
+	gen, _ = New(*conf)
+	again, _ := gen.Generate()
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	saved, err := trace.ReadTasks(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range tasks {
+		if tasks[i].Arrival != again[i].Arrival || tasks[i].Arrival != saved[i].Arrival ||
+			!reflect.DeepEqual(tasks[i].Labels, saved[i].Labels) {
+			t.Fatalf("task %d differs: %+v, %+v, %+v", i, tasks[i], again[i], saved[i])
+		}
+	}
+}
+
+// randomConfig returns a Config whose tasks request random amounts of several resources.
+func randomConfig() Config {
+	return Config{
+		Seed:     42,
+		Duration: 100,
+		Arrival:  ArrivalConfig{Type: "poisson", Rate: 1},
+		Classes: []ClassConfig{{
+			Name:     "batch",
+			Weight:   1,
+			Duration: DistributionConfig{Type: "uniform", Min: 10, Max: 100},
+			Request: map[v1.ResourceName]ResourceConfig{
+				"cpu":            {DistributionConfig: DistributionConfig{Type: "uniform", Min: 1, Max: 4}},
+				"memory":         {DistributionConfig: DistributionConfig{Type: "uniform", Min: 1, Max: 4}, Unit: "1Gi"},
+				"nvidia.com/gpu": {DistributionConfig: DistributionConfig{Type: "uniform", Min: 0, Max: 2}},
+			},
+			UsageRatio: &DistributionConfig{Type: "uniform", Min: 0.1, Max: 1},
+		}},
+	}
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	conf := randomConfig()
+
+	var expected bytes.Buffer
+	for i := 0; i < 5; i++ {
+		gen, err := New(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tasks, err := gen.Generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual bytes.Buffer
+		if err := trace.WriteTasks(&actual, tasks); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			expected = actual
+		} else if actual.String() != expected.String() {
+			t.Fatalf("got: a different workload in run %d\nwant: the same workload with the same seed", i)
+		}
+	}
+}
+
+func TestGenerateRoundsToWholeUnits(t *testing.T) {
+	gen, err := New(randomConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := gen.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, task := range tasks {
+		lists := []v1.ResourceList{task.Request}
+		for _, phase := range task.Phases {
+			lists = append(lists, phase.Usage)
+		}
+		for _, list := range lists {
+			for rsrc, q := range list {
+				if rsrc != v1.ResourceCPU && q.MilliValue()%1000 != 0 {
+					t.Fatalf("got: %s %s\nwant: whole units", q.String(), rsrc)
+				}
+			}
+		}
+	}
+}
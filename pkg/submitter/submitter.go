@@ -35,6 +35,23 @@ type Submitter interface {
 		metrics metrics.Metrics) ([]Event, error)
 }
 
+// AcceptanceHandler is optionally implemented by a Submitter to be notified of its pods accepted on
+// submission, i.e. queued to be scheduled.
+type AcceptanceHandler interface {
+	// Accepted is called with the pod accepted at the clock, whose name, UID, and resources may
+	// have been set on submission.
+	// This method must never block.
+	Accepted(clock clock.Clock, pod *v1.Pod)
+}
+
+// RejectionHandler is optionally implemented by a Submitter to be notified of its pods rejected on
+// submission, by validation or the admission chain. Rejected pods are neither queued nor scheduled.
+type RejectionHandler interface {
+	// Rejected is called with the pod rejected at the clock and the reason.
+	// This method must never block.
+	Rejected(clock clock.Clock, pod *v1.Pod, reason error)
+}
+
 // Event defines the interface of a submitter event.
 // Submit can returns any type in a list that implements this interface.
 type Event interface {
@@ -0,0 +1,365 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submitter
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/metrics"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/trace"
+)
+
+// TraceItem is a pod of a trace, loaded only when it is submitted.
+type TraceItem struct {
+	// Arrival is the arrival time of the pod from the origin of the trace.
+	Arrival time.Duration
+	// Load loads the pod.
+	Load func() (*v1.Pod, error)
+}
+
+// TraceSource streams the items of a trace in ascending order of arrival.
+type TraceSource interface {
+	// Next returns the next item.
+	// Returns io.EOF if there is no more item.
+	Next() (*TraceItem, error)
+}
+
+// TraceSubmitterOptions configures a TraceSubmitter.
+type TraceSubmitterOptions struct {
+	// Start is the simulated time of the origin of the trace.
+	// Defaults to the clock at which Submit is called first.
+	Start *clock.Clock
+	// Offset shifts the arrivals later (or earlier if negative).
+	Offset time.Duration
+	// TimeScale multiplies the arrival times from the origin; 0.5 replays the trace twice as fast.
+	// Defaults to 1.
+	TimeScale float64
+	// LoadScale scales the number of pods. Each pod is submitted floor(LoadScale) times, and once
+	// more with the probability of the fractional part, so that 0.5 sub-samples half the pods and
+	// 2 duplicates every pod. Defaults to 1.
+	LoadScale float64
+	// Seed of the random number generator used by LoadScale. The current time is used if 0.
+	Seed int64
+	// LookAhead is the number of items read ahead of the clock. Items out of order by less than
+	// LookAhead positions are still submitted in order of arrival. Defaults to 1024.
+	LookAhead int
+	// MaxPods terminates the submission after the number of pods. No limit if 0.
+	MaxPods uint64
+}
+
+// TraceSubmitter submits the pods of a trace, streamed from a TraceSource, as the simulated
+// clock reaches their arrival.
+// Every pod arriving at or before the current clock is submitted, so arrivals between ticks are
+// submitted at the following tick.
+type TraceSubmitter struct {
+	source TraceSource
+	opts   TraceSubmitterOptions
+	rand   *rand.Rand
+
+	buffer       traceBuffer
+	readNum      uint64
+	exhausted    bool
+	submittedNum uint64
+}
+
+// NewTraceSubmitter creates a new TraceSubmitter that submits the items of the given source.
+func NewTraceSubmitter(source TraceSource, opts TraceSubmitterOptions) *TraceSubmitter {
+	if opts.TimeScale == 0 {
+		opts.TimeScale = 1
+	}
+	if opts.LoadScale == 0 {
+		opts.LoadScale = 1
+	}
+	if opts.LookAhead <= 0 {
+		opts.LookAhead = 1024
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &TraceSubmitter{
+		source: source,
+		opts:   opts,
+		rand:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Submit implements Submitter interface.
+func (s *TraceSubmitter) Submit(
+	clock clock.Clock,
+	_ algorithm.NodeLister,
+	_ metrics.Metrics) ([]Event, error) {
+
+	if s.opts.Start == nil {
+		start := clock
+		s.opts.Start = &start
+	}
+
+	if err := s.fill(); err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	for len(s.buffer) > 0 && !clock.Before(s.arrival(s.buffer[0].item)) {
+		item := heap.Pop(&s.buffer).(bufferedItem).item
+
+		copies := int(s.opts.LoadScale)
+		if s.rand.Float64() < s.opts.LoadScale-float64(copies) {
+			copies++
+		}
+		for i := 0; i < copies; i++ {
+			pod, err := item.Load()
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				pod.Name = fmt.Sprintf("%s-%d", pod.Name, i)
+			}
+			events = append(events, &SubmitEvent{Pod: pod})
+
+			s.submittedNum++
+			if s.opts.MaxPods > 0 && s.submittedNum >= s.opts.MaxPods {
+				return append(events, &TerminateSubmitterEvent{}), nil
+			}
+		}
+
+		if err := s.fill(); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.exhausted && len(s.buffer) == 0 {
+		events = append(events, &TerminateSubmitterEvent{})
+	}
+	return events, nil
+}
+
+// fill reads items from the source until the look-ahead buffer is full.
+func (s *TraceSubmitter) fill() error {
+	for !s.exhausted && len(s.buffer) < s.opts.LookAhead {
+		item, err := s.source.Next()
+		if err == io.EOF {
+			s.exhausted = true
+		} else if err != nil {
+			return err
+		} else {
+			heap.Push(&s.buffer, bufferedItem{item: item, seq: s.readNum})
+			s.readNum++
+		}
+	}
+	return nil
+}
+
+func (s *TraceSubmitter) arrival(item *TraceItem) clock.Clock {
+	arrival := time.Duration(float64(item.Arrival) * s.opts.TimeScale)
+	return s.opts.Start.Add(s.opts.Offset + arrival)
+}
+
+type bufferedItem struct {
+	item *TraceItem
+	// seq is the order in which the item has been read, to keep the order of simultaneous items.
+	seq uint64
+}
+
+// traceBuffer is a min-heap of items by arrival.
+type traceBuffer []bufferedItem
+
+func (b traceBuffer) Len() int { return len(b) }
+func (b traceBuffer) Less(i, j int) bool {
+	if b[i].item.Arrival != b[j].item.Arrival {
+		return b[i].item.Arrival < b[j].item.Arrival
+	}
+	return b[i].seq < b[j].seq
+}
+func (b traceBuffer) Swap(i, j int)       { b[i], b[j] = b[j], b[i] }
+func (b *traceBuffer) Push(x interface{}) { *b = append(*b, x.(bufferedItem)) }
+func (b *traceBuffer) Pop() interface{} {
+	old := *b
+	item := old[len(old)-1]
+	*b = old[:len(old)-1]
+	return item
+}
+
+// TaskSource is a TraceSource of the tasks read by a trace.TaskReader.
+type TaskSource struct {
+	reader *trace.TaskReader
+}
+
+// NewTaskSource creates a new TaskSource that streams the tasks written by trace.WriteTasks to r.
+func NewTaskSource(r io.Reader) *TaskSource {
+	return &TaskSource{reader: trace.NewTaskReader(r)}
+}
+
+// Next implements TraceSource interface.
+func (s *TaskSource) Next() (*TraceItem, error) {
+	task, err := s.reader.Next()
+	if err != nil {
+		return nil, err
+	}
+	return &TraceItem{
+		Arrival: time.Duration(task.Arrival) * time.Second,
+		Load:    task.ToPod,
+	}, nil
+}
+
+// TaskSliceSource is a TraceSource of tasks in memory.
+type TaskSliceSource struct {
+	tasks []*trace.Task
+}
+
+// NewTaskSliceSource creates a new TaskSliceSource of the given tasks, sorted by their arrival.
+func NewTaskSliceSource(tasks []*trace.Task) *TaskSliceSource {
+	sorted := append([]*trace.Task{}, tasks...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Arrival < sorted[j].Arrival })
+	return &TaskSliceSource{tasks: sorted}
+}
+
+// Next implements TraceSource interface.
+func (s *TaskSliceSource) Next() (*TraceItem, error) {
+	if len(s.tasks) == 0 {
+		return nil, io.EOF
+	}
+	task := s.tasks[0]
+	s.tasks = s.tasks[1:]
+	return &TraceItem{
+		Arrival: time.Duration(task.Arrival) * time.Second,
+		Load:    task.ToPod,
+	}, nil
+}
+
+// WriteArrivalIndex walks the directory of pod manifests named "<RFC3339 arrival>@<name>.json"
+// and writes their arrival index to w, one "<seconds from origin>\t<path>" line per pod in
+// ascending order of arrival.
+// Returns the number of pods.
+func WriteArrivalIndex(w io.Writer, dir string, origin time.Time) (uint64, error) {
+	type entry struct {
+		arrival time.Duration
+		path    string
+	}
+	entries := []entry{}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return nil
+		}
+		name := filepath.Base(path)
+		at := strings.Index(name, "@")
+		if at < 0 {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, name[:at])
+		if err != nil {
+			return errors.Wrapf(err, "invalid arrival of %s", path)
+		}
+		entries = append(entries, entry{arrival: t.Sub(origin), path: path})
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].arrival < entries[j].arrival })
+
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		if _, err := fmt.Fprintf(bw, "%d\t%s\n", int64(e.arrival/time.Second), e.path); err != nil {
+			return 0, err
+		}
+	}
+	return uint64(len(entries)), bw.Flush()
+}
+
+// IndexSource is a TraceSource of the pod manifests in an arrival index written by
+// WriteArrivalIndex.
+type IndexSource struct {
+	scanner *bufio.Scanner
+	load    func(path string) (*v1.Pod, error)
+	line    int
+}
+
+// NewIndexSource creates a new IndexSource that streams the arrival index read from r.
+// Each pod is loaded by load from its path, or by LoadPodFile if load is nil.
+func NewIndexSource(r io.Reader, load func(path string) (*v1.Pod, error)) *IndexSource {
+	if load == nil {
+		load = LoadPodFile
+	}
+	return &IndexSource{scanner: bufio.NewScanner(r), load: load}
+}
+
+// Next implements TraceSource interface.
+func (s *IndexSource) Next() (*TraceItem, error) {
+	for s.scanner.Scan() {
+		s.line++
+		line := s.scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			return nil, strongerrors.InvalidArgument(errors.Errorf("invalid arrival index at line %d", s.line))
+		}
+		secs, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, strongerrors.InvalidArgument(errors.Wrapf(err, "invalid arrival index at line %d", s.line))
+		}
+
+		path := fields[1]
+		return &TraceItem{
+			Arrival: time.Duration(secs) * time.Second,
+			Load:    func() (*v1.Pod, error) { return s.load(path) },
+		}, nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// LoadPodFile loads the pod manifest in JSON at the given path.
+func LoadPodFile(path string) (*v1.Pod, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pod := v1.Pod{}
+	if err := json.Unmarshal(data, &pod); err != nil {
+		return nil, strongerrors.InvalidArgument(errors.Wrapf(err, "invalid pod manifest %s", path))
+	}
+	return &pod, nil
+}
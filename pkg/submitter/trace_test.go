@@ -0,0 +1,173 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package submitter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/trace"
+)
+
+func newTasks(arrivals ...int64) []*trace.Task {
+	tasks := []*trace.Task{}
+	for i, arrival := range arrivals {
+		tasks = append(tasks, &trace.Task{
+			ID:      string(rune('a' + i)),
+			Arrival: arrival,
+			Request: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")},
+			Phases:  []trace.Phase{{Seconds: 10, Usage: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}}},
+		})
+	}
+	return tasks
+}
+
+// submitAll submits at each tick of the given seconds until terminated, and returns the submitted
+// pod names per tick.
+func submitAll(t *testing.T, sub *TraceSubmitter, tick int) [][]string {
+	start := clock.NewClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	submitted := [][]string{}
+
+	for sec := 0; sec < 1000; sec += tick {
+		events, err := sub.Submit(start.Add(time.Duration(sec)*time.Second), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, e := range events {
+			switch e := e.(type) {
+			case *SubmitEvent:
+				names = append(names, e.Pod.Name)
+			case *TerminateSubmitterEvent:
+				return append(submitted, names)
+			}
+		}
+		submitted = append(submitted, names)
+	}
+
+	t.Fatal("not terminated")
+	return nil
+}
+
+func TestTraceSubmitterBetweenTicks(t *testing.T) {
+	sub := NewTraceSubmitter(NewTaskSliceSource(newTasks(0, 3, 1, 5)), TraceSubmitterOptions{})
+	actual := submitAll(t, sub, 2)
+	expected := [][]string{{"task-a"}, {"task-c"}, {"task-b"}, {"task-d"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+}
+
+func TestTraceSubmitterScaling(t *testing.T) {
+	sub := NewTraceSubmitter(NewTaskSliceSource(newTasks(0, 4)), TraceSubmitterOptions{
+		Offset:    2 * time.Second,
+		TimeScale: 0.5,
+		LoadScale: 2,
+	})
+	actual := submitAll(t, sub, 2)
+	expected := [][]string{{}, {"task-a", "task-a-1"}, {"task-b", "task-b-1"}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("got: %v\nwant: %v", actual, expected)
+	}
+
+	sub = NewTraceSubmitter(NewTaskSliceSource(newTasks(make([]int64, 1000)...)), TraceSubmitterOptions{
+		LoadScale: 0.5,
+		Seed:      1,
+	})
+	if n := len(submitAll(t, sub, 1)[0]); n < 400 || 600 < n {
+		t.Errorf("got: %d pods\nwant: about 500 pods", n)
+	}
+}
+
+func TestTraceSubmitterLookAhead(t *testing.T) {
+	for _, test := range []struct {
+		lookAhead int
+		expected  [][]string
+	}{
+		{3, [][]string{{"task-c"}, {"task-a"}, {"task-b"}}},
+		// The task "c" is out of order beyond the look-ahead, so it is submitted as soon as it is read.
+		{1, [][]string{{}, {"task-a"}, {"task-b", "task-c"}}},
+	} {
+		buf := bytes.Buffer{}
+		if err := trace.WriteTasks(&buf, newTasks(1, 2, 0)); err != nil {
+			t.Fatal(err)
+		}
+		sub := NewTraceSubmitter(NewTaskSource(&buf), TraceSubmitterOptions{LookAhead: test.lookAhead})
+		if actual := submitAll(t, sub, 1); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("lookAhead %d: got: %v\nwant: %v", test.lookAhead, actual, test.expected)
+		}
+	}
+
+	sub := NewTraceSubmitter(NewTaskSliceSource(newTasks(0, 0, 0)), TraceSubmitterOptions{MaxPods: 2})
+	if actual := submitAll(t, sub, 1); len(actual) != 1 || len(actual[0]) != 2 {
+		t.Errorf("got: %v\nwant: 2 pods", actual)
+	}
+}
+
+func TestArrivalIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "workload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	origin := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"pod-0", "pod-1", "pod-2"} {
+		pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		data, _ := json.Marshal(pod)
+		arrival := origin.Add(time.Duration(10-3*i) * time.Second).Format(time.RFC3339)
+		if err := ioutil.WriteFile(filepath.Join(dir, arrival+"@"+name+".json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index := bytes.Buffer{}
+	n, err := WriteArrivalIndex(&index, dir, origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("got: %d pods\nwant: 3 pods", n)
+	}
+
+	source := NewIndexSource(&index, nil)
+	for _, expected := range []struct {
+		arrival time.Duration
+		name    string
+	}{{4 * time.Second, "pod-2"}, {7 * time.Second, "pod-1"}, {10 * time.Second, "pod-0"}} {
+		item, err := source.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pod, err := item.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.Arrival != expected.arrival || pod.Name != expected.name {
+			t.Errorf("got: %v %s\nwant: %v %s", item.Arrival, pod.Name, expected.arrival, expected.name)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Priorities of the tasks imported from the Alibaba trace, which does not record priorities.
+// Containers run latency-critical online services, so they are prioritized over batch instances.
+const (
+	AlibabaBatchPriority     = int32(0)
+	AlibabaContainerPriority = int32(1)
+)
+
+// ImportAlibaba2018Batch imports the batch_task and batch_instance tables of the Alibaba
+// cluster-trace-v2018 (CSV without header).
+// Each instance becomes a task requesting the planned resources of its batch task, which uses its
+// average resources from its start to its end.
+// CPU amounts are in 1/100 cores, and memory amounts, normalized to [0, 100] by the machine, are
+// denormalized with opts.MachineMemory.
+func ImportAlibaba2018Batch(tasks, instances io.Reader, opts Options) ([]*Task, error) {
+	b := newTaskBuilder(opts)
+
+	// task_name, instance_num, job_name, task_type, status, start_time, end_time, plan_cpu,
+	// plan_mem
+	requests := map[string]v1.ResourceList{}
+	err := csvRecords(tasks, 9, func(record []string) error {
+		cpu, err := parseFloat(record[7])
+		if err != nil {
+			return err
+		}
+		mem, err := parseFloat(record[8])
+		if err != nil {
+			return err
+		}
+
+		requests[record[2]+"-"+record[0]] = v1.ResourceList{
+			v1.ResourceCPU:    cores(cpu / 100),
+			v1.ResourceMemory: b.opts.memory(mem / 100),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "batch_task")
+	}
+
+	// instance_name, task_name, job_name, task_type, status, start_time, end_time, machine_id,
+	// seq_no, total_seq_no, cpu_avg, cpu_max, mem_avg, mem_max
+	err = csvRecords(instances, 13, func(record []string) error {
+		start, err := parseInt(record[5])
+		if err != nil {
+			return err
+		}
+		end, err := parseInt(record[6])
+		if err != nil {
+			return err
+		}
+		cpu, err := parseFloat(record[10])
+		if err != nil {
+			return err
+		}
+		mem, err := parseFloat(record[12])
+		if err != nil {
+			return err
+		}
+
+		id := record[2] + "-" + record[1] + "-" + record[0] + "-" + record[8]
+		task := b.task(id)
+		task.Arrival = start
+		task.Priority = AlibabaBatchPriority
+		task.Labels = map[string]string{"job": record[2]}
+		if req, ok := requests[record[2]+"-"+record[1]]; ok {
+			task.Request = req
+		}
+		b.addSample(id, start, end, v1.ResourceList{
+			v1.ResourceCPU:    cores(cpu / 100),
+			v1.ResourceMemory: b.opts.memory(mem / 100),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "batch_instance")
+	}
+
+	return b.build(), nil
+}
+
+// ImportAlibaba2018Container imports the container_meta and container_usage tables of the Alibaba
+// cluster-trace-v2018 (CSV without header).
+// Each container becomes a task arriving at its first meta record, whose phases are taken from its
+// usage records; usage may be nil, in which case the container is assumed to use its requested
+// resources from its first to its last meta record.
+// cpu_request is in 1/100 cores, and cpu_util_percent is regarded as a percentage of it.
+// mem_size and mem_util_percent, normalized to [0, 100] by the machine, are denormalized with
+// opts.MachineMemory.
+func ImportAlibaba2018Container(meta, usage io.Reader, opts Options) ([]*Task, error) {
+	b := newTaskBuilder(opts)
+
+	// container_id, machine_id, time_stamp, app_du, status, cpu_request, cpu_limit, mem_size
+	lastSeen := map[string]int64{}
+	err := csvRecords(meta, 8, func(record []string) error {
+		t, err := parseInt(record[2])
+		if err != nil {
+			return err
+		}
+		cpu, err := parseFloat(record[5])
+		if err != nil {
+			return err
+		}
+		mem, err := parseFloat(record[7])
+		if err != nil {
+			return err
+		}
+
+		task := b.task(record[0])
+		if task.Arrival < 0 || t < task.Arrival {
+			task.Arrival = t
+		}
+		if t > lastSeen[record[0]] {
+			lastSeen[record[0]] = t
+		}
+		task.Priority = AlibabaContainerPriority
+		task.Labels = map[string]string{"app": record[3]}
+		task.Request = v1.ResourceList{
+			v1.ResourceCPU:    cores(cpu / 100),
+			v1.ResourceMemory: b.opts.memory(mem / 100),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "container_meta")
+	}
+
+	if usage == nil {
+		for id, task := range b.tasks {
+			b.addSample(id, task.Arrival, lastSeen[id], task.Request)
+		}
+		return b.build(), nil
+	}
+
+	// container_id, machine_id, time_stamp, cpu_util_percent, mem_util_percent, ...
+	points := map[string][]sample{}
+	err = csvRecords(usage, 5, func(record []string) error {
+		task, ok := b.tasks[record[0]]
+		if !ok {
+			return nil // container without meta
+		}
+
+		t, err := parseInt(record[2])
+		if err != nil {
+			return err
+		}
+		cpu, err := parseFloat(record[3])
+		if err != nil {
+			return err
+		}
+		mem, err := parseFloat(record[4])
+		if err != nil {
+			return err
+		}
+
+		reqCPU := task.Request[v1.ResourceCPU]
+		points[record[0]] = append(points[record[0]], sample{start: t, usage: v1.ResourceList{
+			v1.ResourceCPU:    cores(float64(reqCPU.MilliValue()) / 1000 * cpu / 100),
+			v1.ResourceMemory: b.opts.memory(mem / 100),
+		}})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "container_usage")
+	}
+
+	// Each usage record lasts until the next one, and the last one until the last meta record or for
+	// the preceding sampling interval.
+	for id, ps := range points {
+		sort.Slice(ps, func(i, j int) bool { return ps[i].start < ps[j].start })
+		for i, p := range ps {
+			end := lastSeen[id]
+			if i+1 < len(ps) {
+				end = ps[i+1].start
+			} else if i > 0 && end <= p.start {
+				end = p.start + (p.start - ps[i-1].start)
+			}
+			b.addSample(id, p.start, end, p.usage)
+		}
+	}
+
+	return b.build(), nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// AzurePriorities maps the VM categories of the Azure trace to task priorities.
+// VMs of unknown categories get priority 0.
+var AzurePriorities = map[string]int32{
+	"Interactive":       1,
+	"Delay-insensitive": 0,
+	"Unknown":           0,
+}
+
+// ImportAzure imports the vmtable and vm_cpu_readings tables of the Azure public dataset (CSV
+// without header).
+// Each VM becomes a task requesting its core count and memory, which arrives at its creation and
+// runs until its deletion.
+// CPU usage is taken from the average CPU readings (in percent of the VM); readings may be nil, in
+// which case the average CPU of the VM is used throughout its lifetime. Memory usage is not
+// recorded in the trace, so VMs are assumed to use all of their memory.
+// The buckets ">24" cores and ">64" GB are imported as 32 cores and 128 GB, respectively.
+func ImportAzure(vmtable, readings io.Reader, opts Options) ([]*Task, error) {
+	b := newTaskBuilder(opts)
+
+	// vm id, subscription id, deployment id, timestamp vm created, timestamp vm deleted, max cpu,
+	// avg cpu, p95 max cpu, vm category, vm virtual core count bucket, vm memory (GB) bucket
+	lifetimes := map[string][2]int64{}
+	err := csvRecords(vmtable, 11, func(record []string) error {
+		created, err := parseInt(record[3])
+		if err != nil {
+			return err
+		}
+		deleted, err := parseInt(record[4])
+		if err != nil {
+			return err
+		}
+		avgCPU, err := parseFloat(record[6])
+		if err != nil {
+			return err
+		}
+		coresNum, err := parseFloat(strings.Replace(record[9], ">24", "32", 1))
+		if err != nil {
+			return err
+		}
+		memGB, err := parseFloat(strings.Replace(record[10], ">64", "128", 1))
+		if err != nil {
+			return err
+		}
+
+		task := b.task(record[0])
+		task.Arrival = created
+		task.Priority = AzurePriorities[record[8]]
+		task.Labels = map[string]string{"category": record[8]}
+		task.Request = v1.ResourceList{
+			v1.ResourceCPU:    cores(coresNum),
+			v1.ResourceMemory: *resource.NewQuantity(int64(memGB*(1<<30)), resource.BinarySI),
+		}
+		lifetimes[record[0]] = [2]int64{created, deleted}
+
+		if readings == nil {
+			b.addSample(record[0], created, deleted, azureUsage(task, avgCPU))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "vmtable")
+	}
+
+	if readings == nil {
+		return b.build(), nil
+	}
+
+	// timestamp, vm id, min cpu, max cpu, avg cpu
+	points := map[string][]sample{}
+	err = csvRecords(readings, 5, func(record []string) error {
+		task, ok := b.tasks[record[1]]
+		if !ok {
+			return nil // VM without vmtable record
+		}
+
+		t, err := parseInt(record[0])
+		if err != nil {
+			return err
+		}
+		avgCPU, err := parseFloat(record[4])
+		if err != nil {
+			return err
+		}
+
+		points[record[1]] = append(points[record[1]], sample{start: t, usage: azureUsage(task, avgCPU)})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "vm_cpu_readings")
+	}
+
+	// Each reading lasts until the next one, and the last one until the deletion of the VM.
+	for id, ps := range points {
+		for _, p := range ps {
+			b.addSample(id, p.start, lifetimes[id][1], p.usage)
+		}
+	}
+
+	return b.build(), nil
+}
+
+func azureUsage(task *Task, cpuPercent float64) v1.ResourceList {
+	reqCPU := task.Request[v1.ResourceCPU]
+	return v1.ResourceList{
+		v1.ResourceCPU:    cores(float64(reqCPU.MilliValue()) / 1000 * cpuPercent / 100),
+		v1.ResourceMemory: task.Request[v1.ResourceMemory],
+	}
+}
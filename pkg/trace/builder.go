@@ -0,0 +1,213 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// sample is a resource usage measured from start to end, in seconds from the start of the trace.
+type sample struct {
+	start int64
+	end   int64
+	usage v1.ResourceList
+}
+
+// taskBuilder joins the records of tasks scattered over the tables of a trace.
+type taskBuilder struct {
+	opts    Options
+	tasks   map[string]*Task
+	samples map[string][]sample
+}
+
+func newTaskBuilder(opts Options) *taskBuilder {
+	return &taskBuilder{
+		opts:    opts.withDefaults(),
+		tasks:   map[string]*Task{},
+		samples: map[string][]sample{},
+	}
+}
+
+// task returns the task of the given ID, creating it if it does not exist yet.
+func (b *taskBuilder) task(id string) *Task {
+	task, ok := b.tasks[id]
+	if !ok {
+		task = &Task{
+			ID:        id,
+			Namespace: b.opts.Namespace,
+			Request:   v1.ResourceList{},
+			Arrival:   -1,
+		}
+		b.tasks[id] = task
+	}
+	return task
+}
+
+func (b *taskBuilder) addSample(id string, start, end int64, usage v1.ResourceList) {
+	if end <= start {
+		return
+	}
+	b.samples[id] = append(b.samples[id], sample{start: start, end: end, usage: usage})
+}
+
+// build returns the tasks with phases derived from their samples, sorted by their arrival time and
+// ID.
+// Tasks without any usage sample are dropped, since they never ran in the trace.
+// The arrival time of a task is the start of its first sample if it was not recorded.
+func (b *taskBuilder) build() []*Task {
+	tasks := make([]*Task, 0, len(b.tasks))
+	for id, task := range b.tasks {
+		samples := b.samples[id]
+		if len(samples) == 0 {
+			continue
+		}
+		sort.Slice(samples, func(i, j int) bool { return samples[i].start < samples[j].start })
+
+		if task.Arrival < 0 || task.Arrival > samples[0].start {
+			task.Arrival = samples[0].start
+		}
+		task.Phases = b.phases(samples)
+		tasks = append(tasks, task)
+	}
+
+	if len(tasks) > 0 {
+		origin := tasks[0].Arrival
+		for _, task := range tasks {
+			if task.Arrival < origin {
+				origin = task.Arrival
+			}
+		}
+		for _, task := range tasks {
+			task.Arrival -= origin
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Arrival != tasks[j].Arrival {
+			return tasks[i].Arrival < tasks[j].Arrival
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+
+	return tasks
+}
+
+// phases converts the sorted samples into phases, merging consecutive samples of the same usage.
+// A gap between samples is attributed to the preceding sample, and overlapping samples are
+// truncated.
+func (b *taskBuilder) phases(samples []sample) []Phase {
+	phases := []Phase{}
+	total := int64(0)
+
+	for i, s := range samples {
+		end := s.end
+		if i+1 < len(samples) {
+			end = samples[i+1].start
+		}
+		seconds := end - s.start
+		if seconds <= 0 {
+			continue
+		}
+		if max := int64(b.opts.MaxTaskSeconds); max > 0 && total+seconds > max {
+			seconds = max - total
+		}
+		total += seconds
+
+		usage := scaleResourceList(s.usage, b.opts.UsageRatio)
+		if n := len(phases); n > 0 && equality.Semantic.DeepEqual(phases[n-1].Usage, usage) {
+			phases[n-1].Seconds += int32(seconds)
+		} else {
+			phases = append(phases, Phase{Seconds: int32(seconds), Usage: usage})
+		}
+
+		if max := int64(b.opts.MaxTaskSeconds); max > 0 && total >= max {
+			break
+		}
+	}
+
+	return phases
+}
+
+func scaleResourceList(rl v1.ResourceList, ratio float64) v1.ResourceList {
+	if ratio == 1 {
+		return rl
+	}
+
+	scaled := v1.ResourceList{}
+	for rsrc, q := range rl {
+		if rsrc == v1.ResourceMemory {
+			scaled[rsrc] = *resource.NewQuantity(int64(float64(q.Value())*ratio), q.Format)
+		} else {
+			scaled[rsrc] = *resource.NewMilliQuantity(int64(float64(q.MilliValue())*ratio), q.Format)
+		}
+	}
+	return scaled
+}
+
+func sortedNames(rl v1.ResourceList) []v1.ResourceName {
+	names := make([]v1.ResourceName, 0, len(rl))
+	for rsrc := range rl {
+		names = append(names, rsrc)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// csvRecords calls f with each record of the CSV table read from r.
+// Records with fewer than minFields fields are rejected.
+func csvRecords(r io.Reader, minFields int, f func(record []string) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(record) < minFields {
+			return errors.Errorf("line %d: %d fields, but at least %d expected", line, len(record), minFields)
+		}
+		if err := f(record); err != nil {
+			return errors.Wrapf(err, "line %d", line)
+		}
+	}
+}
+
+// parseInt parses an integer field, treating an empty field (i.e., missing data) as 0.
+func parseInt(field string) (int64, error) {
+	if field == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(field, 10, 64)
+}
+
+// parseFloat parses a floating point field, treating an empty field (i.e., missing data) as 0.
+func parseFloat(field string) (float64, error) {
+	if field == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(field, 64)
+}
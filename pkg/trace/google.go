@@ -0,0 +1,236 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Timestamps of the Google traces are in microseconds.
+const googleTimeUnit = 1000000
+
+// googleSubmit is the type of the event that a task is submitted, in both Google traces.
+const googleSubmit = 0
+
+// ImportGoogle2011 imports the task_events and task_usage tables of the Google cluster-data 2011
+// trace (CSV without header).
+// The request and priority of a task are taken from its first SUBMIT event, and its phases from the
+// mean CPU usage rate and canonical memory usage of its usage records.
+// The resource amounts, normalized by the largest machine in the trace, are denormalized with
+// opts.MachineCPU and opts.MachineMemory.
+func ImportGoogle2011(events, usage io.Reader, opts Options) ([]*Task, error) {
+	b := newTaskBuilder(opts)
+
+	// time, missing info, job ID, task index, machine ID, event type, user, scheduling class,
+	// priority, CPU request, memory request, ...
+	err := csvRecords(events, 11, func(record []string) error {
+		if record[5] != strconv.Itoa(googleSubmit) {
+			return nil
+		}
+		task := b.task(record[2] + "-" + record[3])
+		if task.Arrival >= 0 {
+			return nil // re-submission after eviction or failure
+		}
+
+		t, err := parseInt(record[0])
+		if err != nil {
+			return err
+		}
+		prio, err := parseInt(record[8])
+		if err != nil {
+			return err
+		}
+		cpu, err := parseFloat(record[9])
+		if err != nil {
+			return err
+		}
+		mem, err := parseFloat(record[10])
+		if err != nil {
+			return err
+		}
+
+		task.Arrival = t / googleTimeUnit
+		task.Priority = int32(prio)
+		task.Request = v1.ResourceList{
+			v1.ResourceCPU:    b.opts.cpu(cpu),
+			v1.ResourceMemory: b.opts.memory(mem),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "task_events")
+	}
+
+	// start time, end time, job ID, task index, machine ID, mean CPU usage rate, canonical memory
+	// usage, ...
+	err = csvRecords(usage, 7, func(record []string) error {
+		start, err := parseInt(record[0])
+		if err != nil {
+			return err
+		}
+		end, err := parseInt(record[1])
+		if err != nil {
+			return err
+		}
+		cpu, err := parseFloat(record[5])
+		if err != nil {
+			return err
+		}
+		mem, err := parseFloat(record[6])
+		if err != nil {
+			return err
+		}
+
+		b.task(record[2] + "-" + record[3])
+		b.addSample(record[2]+"-"+record[3], start/googleTimeUnit, end/googleTimeUnit, v1.ResourceList{
+			v1.ResourceCPU:    b.opts.cpu(cpu),
+			v1.ResourceMemory: b.opts.memory(mem),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "task_usage")
+	}
+
+	return b.build(), nil
+}
+
+// googleInt is an integer that BigQuery exports either as a JSON number or as a JSON string.
+type googleInt int64
+
+func (i *googleInt) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		v, err := parseInt(str)
+		*i = googleInt(v)
+		return err
+	}
+
+	var v int64
+	err := json.Unmarshal(data, &v)
+	*i = googleInt(v)
+	return err
+}
+
+type googleResources struct {
+	CPUs   float64 `json:"cpus"`
+	Memory float64 `json:"memory"`
+}
+
+// ImportGoogle2019 imports the instance_events and instance_usage tables of the Google
+// cluster-data 2019 trace, exported from BigQuery as newline-delimited JSON.
+// The request and priority of an instance are taken from its first SUBMIT event, and its phases
+// from the average usage of its usage records.
+// The resource amounts, normalized by the largest machine in the trace, are denormalized with
+// opts.MachineCPU and opts.MachineMemory.
+func ImportGoogle2019(events, usage io.Reader, opts Options) ([]*Task, error) {
+	b := newTaskBuilder(opts)
+
+	type event struct {
+		Time            googleInt       `json:"time"`
+		Type            googleInt       `json:"type"`
+		CollectionID    googleInt       `json:"collection_id"`
+		InstanceIndex   googleInt       `json:"instance_index"`
+		Priority        googleInt       `json:"priority"`
+		ResourceRequest googleResources `json:"resource_request"`
+	}
+	err := jsonRecords(events, func(data []byte) error {
+		e := event{}
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		if e.Type != googleSubmit {
+			return nil
+		}
+		task := b.task(google2019ID(e.CollectionID, e.InstanceIndex))
+		if task.Arrival >= 0 {
+			return nil // re-submission after eviction or failure
+		}
+
+		task.Arrival = int64(e.Time) / googleTimeUnit
+		task.Priority = int32(e.Priority)
+		task.Request = v1.ResourceList{
+			v1.ResourceCPU:    b.opts.cpu(e.ResourceRequest.CPUs),
+			v1.ResourceMemory: b.opts.memory(e.ResourceRequest.Memory),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "instance_events")
+	}
+
+	type usageRecord struct {
+		StartTime     googleInt       `json:"start_time"`
+		EndTime       googleInt       `json:"end_time"`
+		CollectionID  googleInt       `json:"collection_id"`
+		InstanceIndex googleInt       `json:"instance_index"`
+		AverageUsage  googleResources `json:"average_usage"`
+	}
+	err = jsonRecords(usage, func(data []byte) error {
+		u := usageRecord{}
+		if err := json.Unmarshal(data, &u); err != nil {
+			return err
+		}
+
+		id := google2019ID(u.CollectionID, u.InstanceIndex)
+		b.task(id)
+		b.addSample(id, int64(u.StartTime)/googleTimeUnit, int64(u.EndTime)/googleTimeUnit, v1.ResourceList{
+			v1.ResourceCPU:    b.opts.cpu(u.AverageUsage.CPUs),
+			v1.ResourceMemory: b.opts.memory(u.AverageUsage.Memory),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "instance_usage")
+	}
+
+	return b.build(), nil
+}
+
+func google2019ID(collectionID, instanceIndex googleInt) string {
+	return strconv.FormatInt(int64(collectionID), 10) + "-" + strconv.FormatInt(int64(instanceIndex), 10)
+}
+
+// jsonRecords calls f with each non-empty line of the newline-delimited JSON read from r.
+func jsonRecords(r io.Reader, f func(data []byte) error) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := br.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(data) == 0) {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if len(data) > 0 && data[len(data)-1] == '\n' {
+			data = data[:len(data)-1]
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if err := f(data); err != nil {
+			return errors.Wrapf(err, "line %d", line)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trace imports public cluster workload traces into Tasks, a common intermediate format
+// from which simulated pods are created.
+package trace
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Task is one task, container, or VM of a workload trace.
+type Task struct {
+	// ID identifies the task in the trace; it is also used as the pod name, after being sanitized.
+	ID        string `json:"id"`
+	Namespace string `json:"namespace,omitempty"`
+	// Arrival is the submission time of the task, in seconds from the start of the trace.
+	Arrival  int64             `json:"arrival"`
+	Priority int32             `json:"priority"`
+	Request  v1.ResourceList   `json:"request"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	// Phases are the resource usage of the task, in the execution order.
+	Phases []Phase `json:"phases"`
+}
+
+// Phase is the resource usage of a task during a period.
+type Phase struct {
+	Seconds int32           `json:"seconds"`
+	Usage   v1.ResourceList `json:"usage"`
+}
+
+// Duration returns the total execution duration of this Task in seconds.
+func (t *Task) Duration() int64 {
+	d := int64(0)
+	for _, phase := range t.Phases {
+		d += int64(phase.Seconds)
+	}
+	return d
+}
+
+// ToPod creates a pod that requests the resources of this Task, and simulates its resource usage
+// through the "simSpec" annotation.
+// Returns error if this Task has no phase.
+func (t *Task) ToPod() (*v1.Pod, error) {
+	if len(t.Phases) == 0 {
+		return nil, errors.Errorf("task %q has no phase", t.ID)
+	}
+
+	namespace := t.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	prio := t.Priority
+
+	return &v1.Pod{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Pod",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PodName(t.ID),
+			Namespace: namespace,
+			Labels:    t.Labels,
+			Annotations: map[string]string{
+				"simSpec": t.simSpec(),
+			},
+		},
+		Spec: v1.PodSpec{
+			Priority: &prio,
+			Containers: []v1.Container{
+				{
+					Name:  "container",
+					Image: "container",
+					Resources: v1.ResourceRequirements{
+						Requests: t.Request,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
+func (t *Task) simSpec() string {
+	str := ""
+	for _, phase := range t.Phases {
+		str += fmt.Sprintf("- seconds: %d\n  resourceUsage:\n", phase.Seconds)
+		for _, rsrc := range sortedNames(phase.Usage) {
+			q := phase.Usage[rsrc]
+			str += fmt.Sprintf("    %s: %s\n", rsrc, q.String())
+		}
+	}
+	return str
+}
+
+// PodName converts a task ID into a valid pod name, by lower-casing it and replacing characters
+// not allowed in a DNS subdomain with '-'.
+func PodName(id string) string {
+	name := []rune(strings.ToLower(id))
+	for i, c := range name {
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '.') {
+			name[i] = '-'
+		}
+	}
+	return "task-" + strings.Trim(string(name), "-.")
+}
+
+// WriteTasks writes the tasks to w, one JSON object per line.
+func WriteTasks(w io.Writer, tasks []*Task) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, task := range tasks {
+		if err := enc.Encode(task); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// TaskReader reads tasks written by WriteTasks one by one.
+type TaskReader struct {
+	dec *json.Decoder
+}
+
+// NewTaskReader creates a new TaskReader that reads from r.
+func NewTaskReader(r io.Reader) *TaskReader {
+	return &TaskReader{dec: json.NewDecoder(bufio.NewReader(r))}
+}
+
+// Next reads the next task.
+// Returns io.EOF if there is no more task.
+func (r *TaskReader) Next() (*Task, error) {
+	task := Task{}
+	if err := r.dec.Decode(&task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// ReadTasks reads all tasks written by WriteTasks from r.
+func ReadTasks(r io.Reader) ([]*Task, error) {
+	tr := NewTaskReader(r)
+	tasks := []*Task{}
+	for {
+		task, err := tr.Next()
+		if err == io.EOF {
+			return tasks, nil
+		} else if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+}
+
+// Open opens the trace file at the given path, decompressing it if its name ends with ".gz", as
+// the public traces are distributed.
+func Open(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return file, nil
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &gzipFile{Reader: gz, file: file}, nil
+}
+
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+// Options configures how traces are imported.
+type Options struct {
+	// MachineCPU and MachineMemory are the resources of the machine by which a trace normalizes
+	// resource amounts (e.g., the largest machine of the Google traces).
+	// Default to 1 CPU and 1Gi memory.
+	MachineCPU    resource.Quantity
+	MachineMemory resource.Quantity
+	// UsageRatio scales the resource usage of every task. Defaults to 1.
+	UsageRatio float64
+	// MaxTaskSeconds truncates the phases of tasks longer than it. No limit if 0.
+	MaxTaskSeconds int32
+	// Namespace of the tasks. Defaults to "default".
+	Namespace string
+}
+
+func (o Options) withDefaults() Options {
+	if o.MachineCPU.IsZero() {
+		o.MachineCPU = resource.MustParse("1")
+	}
+	if o.MachineMemory.IsZero() {
+		o.MachineMemory = resource.MustParse("1Gi")
+	}
+	if o.UsageRatio == 0 {
+		o.UsageRatio = 1
+	}
+	return o
+}
+
+// cpu converts the CPU amount normalized by the machine into a quantity.
+func (o Options) cpu(normalized float64) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(normalized*float64(o.MachineCPU.MilliValue())), resource.DecimalSI)
+}
+
+// memory converts the memory amount normalized by the machine into a quantity.
+func (o Options) memory(normalized float64) resource.Quantity {
+	return *resource.NewQuantity(int64(normalized*float64(o.MachineMemory.Value())), resource.BinarySI)
+}
+
+// cores converts the number of cores into a quantity.
+func cores(n float64) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(n*1000), resource.DecimalSI)
+}
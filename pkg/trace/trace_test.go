@@ -0,0 +1,216 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func rl(cpu, mem string) v1.ResourceList {
+	return v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse(cpu),
+		v1.ResourceMemory: resource.MustParse(mem),
+	}
+}
+
+func assertPhases(t *testing.T, task *Task, expected []Phase) {
+	if len(task.Phases) != len(expected) {
+		t.Fatalf("got: %+v\nwant: %+v", task.Phases, expected)
+	}
+	for i, phase := range task.Phases {
+		if phase.Seconds != expected[i].Seconds {
+			t.Errorf("phase %d: got: %d seconds\nwant: %d seconds", i, phase.Seconds, expected[i].Seconds)
+		}
+		for rsrc, q := range expected[i].Usage {
+			if actual := phase.Usage[rsrc]; actual.Cmp(q) != 0 {
+				t.Errorf("phase %d: got: %s %s\nwant: %s %s", i, rsrc, actual.String(), rsrc, q.String())
+			}
+		}
+	}
+}
+
+func TestImportGoogle2011(t *testing.T) {
+	events := `600000000,,1,0,,0,user,2,9,0.5,0.25,0,0
+600000000,,1,0,,1,user,2,9,0.5,0.25,0,0
+610000000,,2,3,,0,user,2,0,0.25,0.125,0,0
+`
+	usage := `600000000,900000000,1,0,m,0.25,0.25
+900000000,1200000000,1,0,m,0.25,0.25
+1200000000,1500000000,1,0,m,0.5,0.125
+610000000,670000000,2,3,m,0.125,0.125
+`
+	tasks, err := ImportGoogle2011(strings.NewReader(events), strings.NewReader(usage), Options{
+		MachineCPU:    resource.MustParse("4"),
+		MachineMemory: resource.MustParse("8Gi"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got: %d tasks\nwant: 2 tasks", len(tasks))
+	}
+
+	task := tasks[0]
+	if task.ID != "1-0" || task.Arrival != 0 || task.Priority != 9 {
+		t.Errorf("got: %+v\nwant: task 1-0 arriving at 0 with priority 9", task)
+	}
+	if !reflect.DeepEqual(task.Request, v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(2000, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(2<<30, resource.BinarySI),
+	}) {
+		t.Errorf("got: %+v\nwant: 2 CPUs and 2Gi", task.Request)
+	}
+	assertPhases(t, task, []Phase{
+		{Seconds: 600, Usage: rl("1", "2Gi")},
+		{Seconds: 300, Usage: rl("2", "1Gi")},
+	})
+
+	if tasks[1].ID != "2-3" || tasks[1].Arrival != 10 {
+		t.Errorf("got: %+v\nwant: task 2-3 arriving at 10", tasks[1])
+	}
+}
+
+func TestImportGoogle2019(t *testing.T) {
+	events := `{"time":"0","type":"0","collection_id":"7","instance_index":1,"priority":200,"resource_request":{"cpus":0.5,"memory":0.5}}
+{"time":"5000000","type":"3","collection_id":"7","instance_index":1,"priority":200}
+`
+	usage := `{"start_time":"5000000","end_time":"305000000","collection_id":"7","instance_index":1,"average_usage":{"cpus":0.25,"memory":0.5}}
+`
+	tasks, err := ImportGoogle2019(strings.NewReader(events), strings.NewReader(usage), Options{
+		MachineCPU:    resource.MustParse("4"),
+		MachineMemory: resource.MustParse("8Gi"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != "7-1" || tasks[0].Priority != 200 {
+		t.Fatalf("got: %+v\nwant: task 7-1 with priority 200", tasks)
+	}
+	assertPhases(t, tasks[0], []Phase{{Seconds: 300, Usage: rl("1", "4Gi")}})
+}
+
+func TestImportAlibaba2018Batch(t *testing.T) {
+	taskTable := `M1,2,j_1,1,Terminated,100,200,50,1
+`
+	instanceTable := `ins_1,M1,j_1,1,Terminated,100,160,m_1,1,1,25,30,0.5,1
+ins_2,M1,j_1,1,Terminated,110,200,m_2,1,1,50,60,1,1
+`
+	tasks, err := ImportAlibaba2018Batch(strings.NewReader(taskTable), strings.NewReader(instanceTable), Options{
+		MachineMemory: resource.MustParse("100Gi"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[1].Arrival != 10 {
+		t.Fatalf("got: %+v\nwant: 2 tasks, the latter arriving at 10", tasks)
+	}
+	if q := tasks[0].Request[v1.ResourceCPU]; q.MilliValue() != 500 {
+		t.Errorf("got: %s\nwant: 500m", q.String())
+	}
+	assertPhases(t, tasks[0], []Phase{{Seconds: 60, Usage: rl("250m", "512Mi")}})
+}
+
+func TestImportAlibaba2018Container(t *testing.T) {
+	meta := `c_1,m_1,0,app_1,started,400,400,10
+c_1,m_1,100,app_1,started,400,400,10
+`
+	usage := `c_1,m_1,20,50,5
+c_1,m_1,10,25,5
+`
+	tasks, err := ImportAlibaba2018Container(strings.NewReader(meta), strings.NewReader(usage), Options{
+		MachineMemory: resource.MustParse("100Gi"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Priority != AlibabaContainerPriority {
+		t.Fatalf("got: %+v\nwant: 1 container", tasks)
+	}
+	assertPhases(t, tasks[0], []Phase{
+		{Seconds: 10, Usage: rl("1", "5Gi")},
+		{Seconds: 80, Usage: rl("2", "5Gi")},
+	})
+}
+
+func TestImportAzure(t *testing.T) {
+	vmtable := `vm1,s1,d1,0,1200,90,50,80,Interactive,2,4
+vm2,s1,d1,300,600,90,25,80,Unknown,>24,>64
+`
+	readings := `0,vm1,0,100,50
+300,vm1,0,100,100
+`
+	tasks, err := ImportAzure(strings.NewReader(vmtable), strings.NewReader(readings), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Priority != 1 {
+		t.Fatalf("got: %+v\nwant: only vm1 with readings", tasks)
+	}
+	assertPhases(t, tasks[0], []Phase{
+		{Seconds: 300, Usage: rl("1", "4Gi")},
+		{Seconds: 900, Usage: rl("2", "4Gi")},
+	})
+
+	tasks, err = ImportAzure(strings.NewReader(vmtable), nil, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got: %d tasks\nwant: 2 tasks", len(tasks))
+	}
+	assertPhases(t, tasks[1], []Phase{{Seconds: 300, Usage: rl("8", "128Gi")}})
+}
+
+func TestTaskRoundTripAndToPod(t *testing.T) {
+	tasks := []*Task{{
+		ID:       "J_1/ins.2",
+		Arrival:  10,
+		Priority: 3,
+		Request:  rl("1", "1Gi"),
+		Phases:   []Phase{{Seconds: 60, Usage: rl("500m", "512Mi")}},
+	}}
+
+	buf := bytes.Buffer{}
+	if err := WriteTasks(&buf, tasks); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadTasks(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != 1 || read[0].ID != tasks[0].ID || read[0].Arrival != 10 {
+		t.Fatalf("got: %+v\nwant: %+v", read, tasks)
+	}
+
+	pod, err := read[0].ToPod()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "task-j-1-ins.2", pod.Name)
+	assert.Equal(t, "default", pod.Namespace)
+	assert.Equal(t, int32(3), *pod.Spec.Priority)
+	assert.Equal(t, "- seconds: 60\n  resourceUsage:\n    cpu: 500m\n    memory: 512Mi\n",
+		pod.Annotations["simSpec"])
+
+	_, err = (&Task{ID: "empty"}).ToPod()
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"math/rand"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// alphanums are the characters of generated names, without vowels as in Kubernetes.
+	alphanums = "bcdfghjklmnpqrstvwxz2456789"
+	// maxNameLength and randomLength are the lengths of a generated name and its random suffix.
+	maxNameLength = 63
+	randomLength  = 5
+)
+
+// NameGenerator generates the names of pods from their generateName, and their UIDs.
+// The names and UIDs are reproducible from the seed, so that simulations are deterministic.
+type NameGenerator struct {
+	rand *rand.Rand
+}
+
+// NewNameGenerator creates a new NameGenerator with the given seed.
+func NewNameGenerator(seed int64) *NameGenerator {
+	return &NameGenerator{rand: rand.New(rand.NewSource(seed))}
+}
+
+// GenerateName returns the base followed by a random suffix, truncating the base as Kubernetes
+// does so that the name fits in 63 characters.
+func (g *NameGenerator) GenerateName(base string) string {
+	if len(base) > maxNameLength-randomLength {
+		base = base[:maxNameLength-randomLength]
+	}
+	suffix := make([]byte, randomLength)
+	for i := range suffix {
+		suffix[i] = alphanums[g.rand.Intn(len(alphanums))]
+	}
+	return base + string(suffix)
+}
+
+// UID returns a random (version 4) UUID.
+func (g *NameGenerator) UID() types.UID {
+	b := make([]byte, 16)
+	g.rand.Read(b) // nolint
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return types.UID(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]))
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+func TestNameGenerator(t *testing.T) {
+	g := util.NewNameGenerator(0)
+
+	name := g.GenerateName("job-")
+	if !regexp.MustCompile(`^job-[bcdfghjklmnpqrstvwxz2456789]{5}$`).MatchString(name) {
+		t.Errorf("got: %q\nwant: job- followed by 5 characters", name)
+	}
+	if actual := util.NewNameGenerator(0).GenerateName("job-"); actual != name {
+		t.Errorf("got: %q\nwant: %q", actual, name)
+	}
+	if actual := g.GenerateName(strings.Repeat("a", 100)); len(actual) != 63 {
+		t.Errorf("got: %d characters\nwant: 63 characters", len(actual))
+	}
+
+	uid := string(g.UID())
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uid) {
+		t.Errorf("got: %q\nwant: version 4 UUID", uid)
+	}
+	if uid == string(g.UID()) {
+		t.Errorf("got: %q twice\nwant: distinct UIDs", uid)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"github.com/cpuguy83/strongerrors"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/apis/scheduling"
+)
+
+// PreemptionPolicyAnnotation is the annotation of the preemption policy of a pod, set from its
+// priority class, since v1.PodSpec of this Kubernetes version has no such field.
+const PreemptionPolicyAnnotation = "kubesim.io/preemption-policy"
+
+const (
+	// PreemptLowerPriority lets a pod preempt pods of lower priority (default).
+	PreemptLowerPriority = "PreemptLowerPriority"
+	// PreemptNever never lets a pod preempt other pods; it waits in the queue instead.
+	PreemptNever = "Never"
+)
+
+// PriorityClass maps a priorityClassName of pods to their priority and preemption policy.
+type PriorityClass struct {
+	Name  string
+	Value int32
+	// GlobalDefault makes the class the default of pods with neither a priority class nor a
+	// priority.
+	GlobalDefault bool
+	// PreemptionPolicy is either PreemptLowerPriority (default if empty) or PreemptNever.
+	PreemptionPolicy string
+}
+
+// PriorityClasses resolves the priority classes of pods, as the Priority admission plugin does.
+type PriorityClasses struct {
+	classes      map[string]PriorityClass
+	defaultClass *PriorityClass
+}
+
+// NewPriorityClasses creates a new PriorityClasses with the given classes, in addition to the system
+// priority classes (system-cluster-critical and system-node-critical).
+// At most one class may be GlobalDefault.
+func NewPriorityClasses(classes []PriorityClass) *PriorityClasses {
+	p := PriorityClasses{classes: map[string]PriorityClass{}}
+	for _, class := range scheduling.SystemPriorityClasses() {
+		p.classes[class.Name] = PriorityClass{Name: class.Name, Value: class.Value}
+	}
+	for _, class := range classes {
+		p.classes[class.Name] = class
+		if class.GlobalDefault {
+			defaultClass := class
+			p.defaultClass = &defaultClass
+		}
+	}
+
+	return &p
+}
+
+// Resolve sets the priority and the preemption policy of the pod from its priority class, or from
+// the global default class if the pod has neither a priority class nor a priority.
+// Returns error if the priority class of the pod does not exist.
+func (p *PriorityClasses) Resolve(pod *v1.Pod) error {
+	var class PriorityClass
+	if pod.Spec.PriorityClassName != "" {
+		var ok bool
+		if class, ok = p.classes[pod.Spec.PriorityClassName]; !ok {
+			return strongerrors.InvalidArgument(errors.Errorf("no priority class %q", pod.Spec.PriorityClassName))
+		}
+	} else if pod.Spec.Priority == nil && p.defaultClass != nil {
+		class = *p.defaultClass
+		pod.Spec.PriorityClassName = class.Name
+	} else {
+		return nil
+	}
+
+	value := class.Value
+	pod.Spec.Priority = &value
+	if class.PreemptionPolicy != "" {
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[PreemptionPolicyAnnotation] = class.PreemptionPolicy
+	}
+
+	return nil
+}
+
+// PodPreemptionPolicy returns the preemption policy of the given pod.
+func PodPreemptionPolicy(pod *v1.Pod) string {
+	if policy, ok := pod.Annotations[PreemptionPolicyAnnotation]; ok && policy != "" {
+		return policy
+	}
+	return PreemptLowerPriority
+}
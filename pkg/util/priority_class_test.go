@@ -0,0 +1,69 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/util"
+)
+
+func TestPriorityClassesResolve(t *testing.T) {
+	classes := util.NewPriorityClasses([]util.PriorityClass{
+		{Name: "batch", Value: 10, GlobalDefault: true, PreemptionPolicy: util.PreemptNever},
+		{Name: "service", Value: 1000},
+	})
+
+	newPod := func(class string, priority *int32) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "pod-0"},
+			Spec:       v1.PodSpec{PriorityClassName: class, Priority: priority},
+		}
+	}
+	prio := int32(1)
+
+	tests := []struct {
+		pod            *v1.Pod
+		expectedClass  string
+		expectedPrio   int32
+		expectedPolicy string
+	}{
+		{newPod("service", nil), "service", 1000, util.PreemptLowerPriority},
+		{newPod("service", &prio), "service", 1000, util.PreemptLowerPriority},
+		{newPod("", nil), "batch", 10, util.PreemptNever},
+		{newPod("", &prio), "", 1, util.PreemptLowerPriority},
+		{newPod("system-node-critical", nil), "system-node-critical", 2000001000, util.PreemptLowerPriority},
+	}
+	for _, test := range tests {
+		if err := classes.Resolve(test.pod); err != nil {
+			t.Fatal(err)
+		}
+		actual := test.pod
+		if actual.Spec.PriorityClassName != test.expectedClass ||
+			util.PodPriority(actual) != test.expectedPrio ||
+			util.PodPreemptionPolicy(actual) != test.expectedPolicy {
+			t.Errorf("got: %q, %d, %q\nwant: %q, %d, %q",
+				actual.Spec.PriorityClassName, util.PodPriority(actual), util.PodPreemptionPolicy(actual),
+				test.expectedClass, test.expectedPrio, test.expectedPolicy)
+		}
+	}
+
+	err := classes.Resolve(newPod("unknown", nil))
+	assert.EqualError(t, err, "no priority class \"unknown\"")
+}
@@ -0,0 +1,49 @@
+// Copyright 2019 Preferred Networks, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import v1 "k8s.io/api/core/v1"
+
+const (
+	// LabelTopologyRegion is the label of the region of a node.
+	LabelTopologyRegion = "topology.kubernetes.io/region"
+	// LabelTopologyZone is the label of the zone of a node.
+	LabelTopologyZone = "topology.kubernetes.io/zone"
+	// LabelTopologyRack is the label of the rack of a node.
+	LabelTopologyRack = "topology.kubesim.io/rack"
+)
+
+// TopologyLabels are the labels of the topology domains of nodes, from the largest domain to the
+// smallest.
+var TopologyLabels = []string{LabelTopologyRegion, LabelTopologyZone, LabelTopologyRack}
+
+// TopologyLabelsOf returns the labels of a node in the given region, zone, and rack, including the
+// deprecated labels of the region and zone recognized by the kube-scheduler of this version.
+// Empty domains are omitted.
+func TopologyLabelsOf(region, zone, rack string) map[string]string {
+	labels := map[string]string{}
+	if region != "" {
+		labels[LabelTopologyRegion] = region
+		labels[v1.LabelZoneRegion] = region
+	}
+	if zone != "" {
+		labels[LabelTopologyZone] = zone
+		labels[v1.LabelZoneFailureDomain] = zone
+	}
+	if rack != "" {
+		labels[LabelTopologyRack] = rack
+	}
+	return labels
+}
@@ -50,6 +50,15 @@ func PodTotalResourceRequests(pod *v1.Pod) v1.ResourceList {
 	return result
 }
 
+// PodTotalResourceLimits extracts the total amount of resource limits of the given pod.
+func PodTotalResourceLimits(pod *v1.Pod) v1.ResourceList {
+	result := v1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		result = ResourceListSum(result, container.Resources.Limits)
+	}
+	return result
+}
+
 // ResourceListSum returns the sum of two resource lists.
 func ResourceListSum(r1, r2 v1.ResourceList) v1.ResourceList {
 	sum := r1.DeepCopy()